@@ -2,11 +2,9 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -18,9 +16,11 @@ import (
 	"github.com/xiaohongshu-image/internal/config"
 	"github.com/xiaohongshu-image/internal/db"
 	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/breaker"
 	"github.com/xiaohongshu-image/internal/services/intent"
 	"github.com/xiaohongshu-image/internal/services/mailer"
 	"github.com/xiaohongshu-image/internal/services/provider"
+	"github.com/xiaohongshu-image/internal/services/ratelimit"
 	"github.com/xiaohongshu-image/internal/services/storage"
 	"github.com/xiaohongshu-image/internal/services/xhsconnector"
 	"github.com/xiaohongshu-image/internal/worker"
@@ -52,7 +52,9 @@ func main() {
 		DB:       cfg.Redis.DB,
 	})
 
-	ctx := context.Background()
+	// 收到退出信号时取消，停止后台调度与订阅
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 	if err := redisClient.Ping(ctx).Err(); err != nil {
 		logger.Fatal("Failed to connect to Redis", zap.Error(err))
 	}
@@ -68,7 +70,7 @@ func main() {
 		logger.Fatal("Failed to create MinIO service", zap.Error(err))
 	}
 
-	mailerService := mailer.NewService(&cfg.SMTP)
+	mailerService := mailer.NewService(&cfg.SMTP, time.Duration(cfg.MinIO.PresignedExpiry)*time.Second)
 
 	llmHTTPClient := intent.NewRealHTTPClient(cfg.LLM.BaseURL, cfg.LLM.APIKey, cfg.LLM.Timeout)
 	intentService := intent.NewServiceWithClient(&cfg.LLM, llmHTTPClient)
@@ -83,6 +85,14 @@ func main() {
 		MCPServerCmd: setting.MCPServerCmd,
 		MCPServerURL: setting.MCPServerURL,
 		MCPAuth:      setting.MCPAuth,
+		Logger:       logger,
+
+		MockScenarioFile: cfg.Mock.ScenarioFile,
+		MockSpeed:        cfg.Mock.Speed,
+		RDB:              redisClient,
+
+		RecordDir: cfg.Recording.Dir,
+		ReplayDir: cfg.Recording.ReplayDir,
 	}
 
 	connector, err := xhsconnector.NewConnector(connectorCfg)
@@ -90,31 +100,28 @@ func main() {
 		logger.Fatal("Failed to create connector", zap.Error(err))
 	}
 
-	var providers []provider.ProviderConfig
-	if err := json.Unmarshal([]byte(setting.ProviderJSON), &providers); err != nil {
-		logger.Fatal("Failed to unmarshal provider configs", zap.Error(err))
-	}
+	providerRegistry := provider.NewRegistry(provider.NewFactory(redisClient, minioService), redisClient)
 
-	providersMap := make(map[string]provider.Provider)
-	for _, pCfg := range providers {
-		if pCfg.ProviderName == "mock" {
-			providersMap[pCfg.ProviderName] = provider.NewMockProvider(pCfg.ProviderName, minioService)
-		} else {
-			providersMap[pCfg.ProviderName] = provider.NewHTTPProvider(&pCfg)
-		}
-	}
+	providerBreaker := breaker.New(redisClient, "provider_breaker")
 
 	workerInstance := worker.NewWorker(
 		database,
 		asynqClient,
+		redisClient,
 		connector,
 		intentService,
-		providersMap,
+		providerRegistry,
 		minioService,
 		mailerService,
+		ratelimit.New(redisClient, "provider"),
+		providerBreaker,
 		logger,
 	)
 
+	if err := workerInstance.SyncProviders(); err != nil {
+		logger.Fatal("Failed to load providers", zap.Error(err))
+	}
+
 	asynqServer := asynq.NewServer(
 		asynq.RedisClientOpt{
 			Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
@@ -139,10 +146,12 @@ func main() {
 
 	gin.SetMode(cfg.Server.Mode)
 	router := gin.New()
+	// 路径参数中的笔记 URL 需要编码斜杠，按原始路径匹配路由
+	router.UseRawPath = true
 	router.Use(gin.Recovery())
 	router.Use(loggerMiddleware(logger))
 
-	handler := api.NewHandler(database, asynqClient, workerInstance, logger)
+	handler := api.NewHandler(database, asynqClient, workerInstance, providerBreaker, cfg.Ingest, logger)
 	handler.RegisterRoutes(router)
 
 	srv := &http.Server{
@@ -159,11 +168,12 @@ func main() {
 		}
 	}()
 
-	go startScheduler(ctx, database, asynqClient, setting, logger)
+	go startScheduler(ctx, workerInstance, setting, logger)
+	go startDiscoveryScheduler(ctx, asynqClient, setting, logger)
+	go workerInstance.RunHealthChecks(ctx)
+	go workerInstance.RunProviderReload(ctx)
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
 
 	logger.Info("Shutting down server...")
 
@@ -175,11 +185,14 @@ func main() {
 	}
 
 	asynqServer.Shutdown()
+	if err := connector.Close(); err != nil {
+		logger.Error("Failed to close connector", zap.Error(err))
+	}
 
 	logger.Info("Server exited")
 }
 
-func startScheduler(ctx context.Context, database *db.Database, client *asynq.Client, setting *models.Setting, logger *zap.Logger) {
+func startScheduler(ctx context.Context, workerInstance *worker.Worker, setting *models.Setting, logger *zap.Logger) {
 	ticker := time.NewTicker(time.Duration(setting.PollingIntervalSec) * time.Second)
 	defer ticker.Stop()
 
@@ -188,20 +201,38 @@ func startScheduler(ctx context.Context, database *db.Database, client *asynq.Cl
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			payload, _ := json.Marshal(worker.PollCommentsPayload{
-				NoteTarget: setting.NoteTarget,
-			})
-
-			_, err := client.Enqueue(
-				asynq.NewTask(worker.TypePollComments, payload, asynq.Queue("critical")),
-			)
-			if err != nil {
+			if _, err := workerInstance.EnqueuePolls(); err != nil {
 				logger.Error("Failed to enqueue poll task", zap.Error(err))
 			}
 		}
 	}
 }
 
+// startDiscoveryScheduler 启动时及每 DiscoveryIntervalSec 秒投递一次笔记发现任务
+func startDiscoveryScheduler(ctx context.Context, client *asynq.Client, setting *models.Setting, logger *zap.Logger) {
+	interval := time.Duration(setting.DiscoveryIntervalSec) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := client.Enqueue(
+			asynq.NewTask(worker.TypeDiscoverNotes, nil, asynq.Queue("default")),
+		)
+		if err != nil {
+			logger.Error("Failed to enqueue discovery task", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func loggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/fakeprovider"
+	"github.com/xiaohongshu-image/internal/services/provider"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	profilesFile := flag.String("profiles", "", "JSON file with an array of profiles (default: built-in profiles)")
+	publicURL := flag.String("public-url", "http://localhost:9090", "base URL used in -print-config output")
+	printConfig := flag.Bool("print-config", false, "print provider_json for the profiles and exit")
+	contract := flag.Bool("contract", false, "run the contract suite against an in-process server and exit")
+	flag.Parse()
+
+	profiles := fakeprovider.BuiltinProfiles()
+	if *profilesFile != "" {
+		var err error
+		profiles, err = loadProfiles(*profilesFile)
+		if err != nil {
+			log.Fatalf("Failed to load profiles: %v", err)
+		}
+	}
+
+	switch {
+	case *printConfig:
+		configs := make([]provider.ProviderConfig, 0, len(profiles))
+		for _, p := range profiles {
+			configs = append(configs, fakeprovider.SampleConfig(p, *publicURL))
+		}
+		out, _ := json.MarshalIndent(configs, "", "  ")
+		fmt.Println(string(out))
+	case *contract:
+		if !runContract(profiles) {
+			os.Exit(1)
+		}
+	default:
+		serve(*addr, profiles)
+	}
+}
+
+func loadProfiles(path string) ([]fakeprovider.Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var profiles []fakeprovider.Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid profiles JSON: %w", err)
+	}
+	return profiles, nil
+}
+
+func serve(addr string, profiles []fakeprovider.Profile) {
+	srv, err := fakeprovider.NewServer(profiles)
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
+	if err := srv.Start(addr); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	for _, p := range srv.Profiles() {
+		log.Printf("Profile %s (%s, auth=%s) at %s/%s", p.Name, p.Shape, p.Auth.Type, srv.URL(), p.Name)
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	srv.Close()
+	log.Println("Fake provider exited")
+}
+
+func runContract(profiles []fakeprovider.Profile) bool {
+	srv, err := fakeprovider.StartLocal(profiles...)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	passed := true
+	for _, p := range profiles {
+		result := fakeprovider.RunContract(ctx, p, srv.URL())
+		for _, c := range result.Checks {
+			status := "PASS"
+			switch {
+			case c.Skipped:
+				status = "SKIP"
+			case c.Error != "":
+				status = "FAIL"
+			}
+			fmt.Printf("%-4s %-16s %-16s %6dms %s\n", status, result.Profile, c.Name, c.Duration.Milliseconds(), c.Error)
+		}
+		if !result.Passed() {
+			passed = false
+		}
+	}
+	return passed
+}
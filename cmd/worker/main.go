@@ -1,19 +1,22 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
-	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 	"github.com/xiaohongshu-image/internal/config"
 	"github.com/xiaohongshu-image/internal/db"
+	"github.com/xiaohongshu-image/internal/services/breaker"
 	"github.com/xiaohongshu-image/internal/services/intent"
 	"github.com/xiaohongshu-image/internal/services/mailer"
 	"github.com/xiaohongshu-image/internal/services/provider"
+	"github.com/xiaohongshu-image/internal/services/ratelimit"
 	"github.com/xiaohongshu-image/internal/services/storage"
 	"github.com/xiaohongshu-image/internal/services/xhsconnector"
 	"github.com/xiaohongshu-image/internal/worker"
@@ -39,12 +42,22 @@ func main() {
 	}
 	defer database.Close()
 
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		logger.Fatal("Failed to connect to Redis", zap.Error(err))
+	}
+
 	minioService, err := storage.NewMinIOService(&cfg.MinIO)
 	if err != nil {
 		logger.Fatal("Failed to create MinIO service", zap.Error(err))
 	}
 
-	mailerService := mailer.NewService(&cfg.SMTP)
+	mailerService := mailer.NewService(&cfg.SMTP, time.Duration(cfg.MinIO.PresignedExpiry)*time.Second)
 
 	llmHTTPClient := intent.NewRealHTTPClient(cfg.LLM.BaseURL, cfg.LLM.APIKey, cfg.LLM.Timeout)
 	intentService := intent.NewServiceWithClient(&cfg.LLM, llmHTTPClient)
@@ -59,6 +72,14 @@ func main() {
 		MCPServerCmd: setting.MCPServerCmd,
 		MCPServerURL: setting.MCPServerURL,
 		MCPAuth:      setting.MCPAuth,
+		Logger:       logger,
+
+		MockScenarioFile: cfg.Mock.ScenarioFile,
+		MockSpeed:        cfg.Mock.Speed,
+		RDB:              redisClient,
+
+		RecordDir: cfg.Recording.Dir,
+		ReplayDir: cfg.Recording.ReplayDir,
 	}
 
 	connector, err := xhsconnector.NewConnector(connectorCfg)
@@ -66,19 +87,7 @@ func main() {
 		logger.Fatal("Failed to create connector", zap.Error(err))
 	}
 
-	var providers []provider.ProviderConfig
-	if err := json.Unmarshal([]byte(setting.ProviderJSON), &providers); err != nil {
-		logger.Fatal("Failed to unmarshal provider configs", zap.Error(err))
-	}
-
-	providersMap := make(map[string]provider.Provider)
-	for _, pCfg := range providers {
-		if pCfg.ProviderName == "mock" {
-			providersMap[pCfg.ProviderName] = provider.NewMockProvider(pCfg.ProviderName, minioService)
-		} else {
-			providersMap[pCfg.ProviderName] = provider.NewHTTPProvider(&pCfg)
-		}
-	}
+	providerRegistry := provider.NewRegistry(provider.NewFactory(redisClient, minioService), redisClient)
 
 	asynqClient := asynq.NewClient(asynq.RedisClientOpt{
 		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
@@ -86,17 +95,26 @@ func main() {
 		DB:       cfg.Asynq.RedisDB,
 	})
 
+	providerBreaker := breaker.New(redisClient, "provider_breaker")
+
 	workerInstance := worker.NewWorker(
 		database,
 		asynqClient,
+		redisClient,
 		connector,
 		intentService,
-		providersMap,
+		providerRegistry,
 		minioService,
 		mailerService,
+		ratelimit.New(redisClient, "provider"),
+		providerBreaker,
 		logger,
 	)
 
+	if err := workerInstance.SyncProviders(); err != nil {
+		logger.Fatal("Failed to load providers", zap.Error(err))
+	}
+
 	asynqServer := asynq.NewServer(
 		asynq.RedisClientOpt{
 			Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
@@ -109,6 +127,12 @@ func main() {
 		},
 	)
 
+	// 收到退出信号时取消，停止后台循环
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go workerInstance.RunProviderReload(ctx)
+
 	mux := asynq.NewServeMux()
 	workerInstance.RegisterHandlers(mux)
 
@@ -119,11 +143,12 @@ func main() {
 		}
 	}()
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
 
 	logger.Info("Shutting down worker...")
 	asynqServer.Shutdown()
+	if err := connector.Close(); err != nil {
+		logger.Error("Failed to close connector", zap.Error(err))
+	}
 	logger.Info("Worker exited")
 }
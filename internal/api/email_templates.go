@@ -0,0 +1,274 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/mailer"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// EmailTemplateResponse 为当前生效的邮件模板，Customized 为 false 时为内置模板
+type EmailTemplateResponse struct {
+	Name       string     `json:"name"`
+	Locale     string     `json:"locale"`
+	Subject    string     `json:"subject"`
+	HTMLBody   string     `json:"html_body"`
+	TextBody   string     `json:"text_body"`
+	Customized bool       `json:"customized"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+type UpdateEmailTemplateRequest struct {
+	Subject  string `json:"subject" binding:"required,max=500"`
+	HTMLBody string `json:"html_body" binding:"required"`
+	TextBody string `json:"text_body" binding:"required"`
+}
+
+// PreviewEmailTemplateRequest 中为空的字段使用当前生效的模板
+type PreviewEmailTemplateRequest struct {
+	Subject  string `json:"subject" binding:"max=500"`
+	HTMLBody string `json:"html_body"`
+	TextBody string `json:"text_body"`
+}
+
+func defaultEmailTemplateResponse(name, locale string) EmailTemplateResponse {
+	tpl, _ := mailer.DefaultTemplate(name, locale)
+	return EmailTemplateResponse{
+		Name:     name,
+		Locale:   locale,
+		Subject:  tpl.Subject,
+		HTMLBody: tpl.HTMLBody,
+		TextBody: tpl.TextBody,
+	}
+}
+
+func customEmailTemplateResponse(tpl *models.EmailTemplate) EmailTemplateResponse {
+	updatedAt := tpl.UpdatedAt
+	return EmailTemplateResponse{
+		Name:       tpl.Name,
+		Locale:     tpl.Locale,
+		Subject:    tpl.Subject,
+		HTMLBody:   tpl.HTMLBody,
+		TextBody:   tpl.TextBody,
+		Customized: true,
+		UpdatedAt:  &updatedAt,
+	}
+}
+
+// emailTemplateParams 解析路径中的模板名与语言，不受支持时返回 404
+func emailTemplateParams(c *gin.Context) (string, string, bool) {
+	name, locale := c.Param("name"), c.Param("locale")
+	if !mailer.IsValidTemplate(name, locale) {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    "NOT_FOUND",
+			Message: "Unknown email template",
+			Details: gin.H{"names": mailer.TemplateNames, "locales": mailer.Locales},
+		})
+		return "", "", false
+	}
+	return name, locale, true
+}
+
+func (h *Handler) effectiveEmailTemplate(name, locale string) (EmailTemplateResponse, error) {
+	tpl, err := h.db.GetEmailTemplate(name, locale)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultEmailTemplateResponse(name, locale), nil
+	}
+	if err != nil {
+		return EmailTemplateResponse{}, err
+	}
+	return customEmailTemplateResponse(tpl), nil
+}
+
+// ListEmailTemplates 列出全部模板名与语言当前生效的邮件模板
+func (h *Handler) ListEmailTemplates(c *gin.Context) {
+	custom, err := h.db.ListEmailTemplates()
+	if err != nil {
+		h.logger.Error("failed to list email templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to list email templates",
+		})
+		return
+	}
+
+	customized := make(map[string]*models.EmailTemplate, len(custom))
+	for i := range custom {
+		customized[custom[i].Name+"."+custom[i].Locale] = &custom[i]
+	}
+
+	templates := make([]EmailTemplateResponse, 0, len(mailer.TemplateNames)*len(mailer.Locales))
+	for _, name := range mailer.TemplateNames {
+		for _, locale := range mailer.Locales {
+			if tpl, ok := customized[name+"."+locale]; ok {
+				templates = append(templates, customEmailTemplateResponse(tpl))
+				continue
+			}
+			templates = append(templates, defaultEmailTemplateResponse(name, locale))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+	})
+}
+
+func (h *Handler) GetEmailTemplate(c *gin.Context) {
+	name, locale, ok := emailTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	tpl, err := h.effectiveEmailTemplate(name, locale)
+	if err != nil {
+		h.logger.Error("failed to get email template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get email template",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// UpdateEmailTemplate 保存自定义模板，保存前使用示例数据试渲染，语法错误或引用不存在的变量时拒绝
+func (h *Handler) UpdateEmailTemplate(c *gin.Context) {
+	name, locale, ok := emailTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateEmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	tpl := mailer.Template{
+		Subject:  req.Subject,
+		HTMLBody: req.HTMLBody,
+		TextBody: req.TextBody,
+	}
+	if _, err := h.worker.PreviewEmailTemplate(name, locale, &tpl); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_TEMPLATE",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	record := &models.EmailTemplate{
+		Name:     name,
+		Locale:   locale,
+		Subject:  req.Subject,
+		HTMLBody: req.HTMLBody,
+		TextBody: req.TextBody,
+	}
+	if err := h.db.SaveEmailTemplate(record); err != nil {
+		h.logger.Error("failed to save email template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to save email template",
+		})
+		return
+	}
+
+	h.logger.Info("email template updated", zap.String("name", name), zap.String("locale", locale))
+
+	saved, err := h.effectiveEmailTemplate(name, locale)
+	if err != nil {
+		h.logger.Error("failed to get email template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get email template",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// ResetEmailTemplate 删除自定义模板，恢复为内置模板
+func (h *Handler) ResetEmailTemplate(c *gin.Context) {
+	name, locale, ok := emailTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteEmailTemplate(name, locale); err != nil {
+		h.logger.Error("failed to delete email template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to reset email template",
+		})
+		return
+	}
+
+	h.logger.Info("email template reset", zap.String("name", name), zap.String("locale", locale))
+
+	c.JSON(http.StatusOK, defaultEmailTemplateResponse(name, locale))
+}
+
+// PreviewEmailTemplate 使用示例数据渲染模板，请求体中的字段可用于预览尚未保存的修改
+func (h *Handler) PreviewEmailTemplate(c *gin.Context) {
+	name, locale, ok := emailTemplateParams(c)
+	if !ok {
+		return
+	}
+
+	var req PreviewEmailTemplateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	current, err := h.effectiveEmailTemplate(name, locale)
+	if err != nil {
+		h.logger.Error("failed to get email template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get email template",
+		})
+		return
+	}
+
+	tpl := mailer.Template{
+		Subject:  current.Subject,
+		HTMLBody: current.HTMLBody,
+		TextBody: current.TextBody,
+	}
+	if req.Subject != "" {
+		tpl.Subject = req.Subject
+	}
+	if req.HTMLBody != "" {
+		tpl.HTMLBody = req.HTMLBody
+	}
+	if req.TextBody != "" {
+		tpl.TextBody = req.TextBody
+	}
+
+	rendered, err := h.worker.PreviewEmailTemplate(name, locale, &tpl)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_TEMPLATE",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, rendered)
+}
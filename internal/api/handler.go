@@ -1,35 +1,47 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
+	"github.com/xiaohongshu-image/internal/config"
 	"github.com/xiaohongshu-image/internal/db"
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/breaker"
+	"github.com/xiaohongshu-image/internal/services/provider"
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
 	"github.com/xiaohongshu-image/internal/worker"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	db     *db.Database
-	redis  *asynq.Client
-	worker *worker.Worker
-	logger *zap.Logger
+	db      *db.Database
+	redis   *asynq.Client
+	worker  *worker.Worker
+	breaker *breaker.Breaker
+	ingest  config.IngestConfig
+	logger  *zap.Logger
 }
 
 func NewHandler(
 	db *db.Database,
 	redis *asynq.Client,
 	worker *worker.Worker,
+	breaker *breaker.Breaker,
+	ingest config.IngestConfig,
 	logger *zap.Logger,
 ) *Handler {
 	return &Handler{
-		db:     db,
-		redis:  redis,
-		worker: worker,
-		logger: logger,
+		db:      db,
+		redis:   redis,
+		worker:  worker,
+		breaker: breaker,
+		ingest:  ingest,
+		logger:  logger,
 	}
 }
 
@@ -48,9 +60,27 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 		api.GET("/settings", h.GetSettings)
 		api.PUT("/settings", h.UpdateSettings)
 		api.POST("/poll/run", h.RunPoll)
+		api.POST("/notes/discover", h.RunDiscovery)
 		api.GET("/tasks", h.ListTasks)
 		api.GET("/tasks/:id", h.GetTask)
+		api.GET("/comments/:id", h.GetComment)
+		api.POST("/tasks/:id/cancel", h.CancelTask)
+		api.POST("/tasks/:id/approve-budget", h.ApproveTaskBudget)
 		api.GET("/files/:key", h.GetFile)
+		api.GET("/providers", h.ListProviders)
+		api.GET("/budgets", h.ListBudgets)
+		api.GET("/notes", h.ListNotes)
+		api.PUT("/notes/:id", h.UpdateNote)
+		api.GET("/commenters", h.ListCommenters)
+		api.GET("/commenters/:id", h.GetCommenter)
+		api.PUT("/commenters/:id", h.UpdateCommenter)
+		api.GET("/email-templates", h.ListEmailTemplates)
+		api.GET("/email-templates/:name/:locale", h.GetEmailTemplate)
+		api.PUT("/email-templates/:name/:locale", h.UpdateEmailTemplate)
+		api.DELETE("/email-templates/:name/:locale", h.ResetEmailTemplate)
+		api.POST("/email-templates/:name/:locale/preview", h.PreviewEmailTemplate)
+		api.POST("/mock/notes/:target/comments", h.InjectMockComment)
+		api.POST("/ingest/comments", h.IngestComments)
 	}
 }
 
@@ -81,23 +111,30 @@ func (h *Handler) GetSettings(c *gin.Context) {
 }
 
 type UpdateSettingsRequest struct {
-	ConnectorMode      *string  `json:"connector_mode" binding:"omitempty,oneof=mock mcp"`
-	MCPServerCmd       *string  `json:"mcp_server_cmd" binding:"omitempty"`
-	MCPServerURL       *string  `json:"mcp_server_url" binding:"omitempty"`
-	MCPAuth            *string  `json:"mcp_auth" binding:"omitempty"`
-	NoteTarget         *string  `json:"note_target" binding:"omitempty"`
-	PollingIntervalSec *int     `json:"polling_interval_sec" binding:"omitempty,min=10"`
-	LLMBaseURL         *string  `json:"llm_base_url" binding:"omitempty"`
-	LLMAPIKey          *string  `json:"llm_api_key" binding:"omitempty"`
-	LLMModel           *string  `json:"llm_model" binding:"omitempty"`
-	LLMTimeoutSec      *int     `json:"llm_timeout_sec" binding:"omitempty,min=5,max=300"`
-	IntentThreshold    *float64 `json:"intent_threshold" binding:"omitempty,min=0,max=1"`
-	SMTPHost           *string  `json:"smtp_host" binding:"omitempty"`
-	SMTPPort           *int     `json:"smtp_port" binding:"omitempty,min=1,max=65535"`
-	SMTPUser           *string  `json:"smtp_user" binding:"omitempty"`
-	SMTPPass           *string  `json:"smtp_pass" binding:"omitempty"`
-	SMTPFrom           *string  `json:"smtp_from" binding:"omitempty,email"`
-	ProviderJSON       *string  `json:"provider_json" binding:"omitempty"`
+	ConnectorMode        *string  `json:"connector_mode" binding:"omitempty,oneof=mock mcp replay"`
+	MCPServerCmd         *string  `json:"mcp_server_cmd" binding:"omitempty"`
+	MCPServerURL         *string  `json:"mcp_server_url" binding:"omitempty"`
+	MCPAuth              *string  `json:"mcp_auth" binding:"omitempty"`
+	NoteTarget           *string  `json:"note_target" binding:"omitempty"`
+	PollingIntervalSec   *int     `json:"polling_interval_sec" binding:"omitempty,min=10"`
+	LLMBaseURL           *string  `json:"llm_base_url" binding:"omitempty"`
+	LLMAPIKey            *string  `json:"llm_api_key" binding:"omitempty"`
+	LLMModel             *string  `json:"llm_model" binding:"omitempty"`
+	LLMTimeoutSec        *int     `json:"llm_timeout_sec" binding:"omitempty,min=5,max=300"`
+	IntentThreshold      *float64 `json:"intent_threshold" binding:"omitempty,min=0,max=1"`
+	SMTPHost             *string  `json:"smtp_host" binding:"omitempty"`
+	SMTPPort             *int     `json:"smtp_port" binding:"omitempty,min=1,max=65535"`
+	SMTPUser             *string  `json:"smtp_user" binding:"omitempty"`
+	SMTPPass             *string  `json:"smtp_pass" binding:"omitempty"`
+	SMTPFrom             *string  `json:"smtp_from" binding:"omitempty,email"`
+	ProviderJSON         *string  `json:"provider_json" binding:"omitempty"`
+	DailyBudget          *float64 `json:"daily_budget" binding:"omitempty,min=0"`
+	MonthlyBudget        *float64 `json:"monthly_budget" binding:"omitempty,min=0"`
+	ReplyTemplates       *string  `json:"reply_templates" binding:"omitempty"`
+	WatchAccount         *string  `json:"watch_account" binding:"omitempty"`
+	DiscoveryIntervalSec *int     `json:"discovery_interval_sec" binding:"omitempty,min=300"`
+	NoteMaxAgeDays       *int     `json:"note_max_age_days" binding:"omitempty,min=0"`
+	AlertEmail           *string  `json:"alert_email" binding:"omitempty,email"`
 }
 
 func (h *Handler) UpdateSettings(c *gin.Context) {
@@ -168,7 +205,49 @@ func (h *Handler) UpdateSettings(c *gin.Context) {
 	if req.SMTPFrom != nil {
 		setting.SMTPFrom = req.SMTPFrom
 	}
+	if req.DailyBudget != nil {
+		setting.DailyBudget = req.DailyBudget
+	}
+	if req.MonthlyBudget != nil {
+		setting.MonthlyBudget = req.MonthlyBudget
+	}
+	if req.ReplyTemplates != nil {
+		if err := h.worker.ValidateReplyTemplates(*req.ReplyTemplates); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_REPLY_TEMPLATES",
+				Message: err.Error(),
+			})
+			return
+		}
+		setting.ReplyTemplates = req.ReplyTemplates
+		if *req.ReplyTemplates == "" {
+			setting.ReplyTemplates = nil
+		}
+	}
+	if req.WatchAccount != nil {
+		setting.WatchAccount = req.WatchAccount
+	}
+	if req.DiscoveryIntervalSec != nil {
+		setting.DiscoveryIntervalSec = *req.DiscoveryIntervalSec
+	}
+	if req.NoteMaxAgeDays != nil {
+		setting.NoteMaxAgeDays = *req.NoteMaxAgeDays
+	}
+	if req.AlertEmail != nil {
+		setting.AlertEmail = req.AlertEmail
+		if *req.AlertEmail == "" {
+			setting.AlertEmail = nil
+		}
+	}
+	providersChanged := req.ProviderJSON != nil && *req.ProviderJSON != setting.ProviderJSON
 	if req.ProviderJSON != nil {
+		if err := h.worker.ValidateProviders(*req.ProviderJSON); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_PROVIDER_JSON",
+				Message: err.Error(),
+			})
+			return
+		}
 		setting.ProviderJSON = *req.ProviderJSON
 	}
 
@@ -181,29 +260,21 @@ func (h *Handler) UpdateSettings(c *gin.Context) {
 		return
 	}
 
+	if providersChanged {
+		if err := h.worker.ReloadProviders(c.Request.Context()); err != nil {
+			// 配置已保存，其他进程会在下一个同步周期加载
+			h.logger.Error("failed to reload providers", zap.Error(err))
+		}
+	}
+
 	c.JSON(http.StatusOK, setting)
 }
 
+// RunPoll 立即轮询 NoteTarget 与所有定时轮询的笔记
 func (h *Handler) RunPoll(c *gin.Context) {
-	setting, err := h.db.GetSetting()
-	if err != nil {
-		h.logger.Error("failed to get settings", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Code:    "INTERNAL_ERROR",
-			Message: "Failed to get settings",
-		})
-		return
-	}
-
-	payload, _ := json.Marshal(worker.PollCommentsPayload{
-		NoteTarget: setting.NoteTarget,
-	})
-
-	_, err = h.redis.Enqueue(
-		asynq.NewTask(worker.TypePollComments, payload, asynq.Queue("critical")),
-	)
+	enqueued, err := h.worker.EnqueuePolls()
 	if err != nil {
-		h.logger.Error("failed to enqueue poll task", zap.Error(err))
+		h.logger.Error("failed to enqueue poll tasks", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    "INTERNAL_ERROR",
 			Message: "Failed to enqueue poll task",
@@ -213,6 +284,7 @@ func (h *Handler) RunPoll(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Poll task enqueued",
+		"notes":   enqueued,
 	})
 }
 
@@ -270,6 +342,154 @@ func (h *Handler) GetTask(c *gin.Context) {
 	c.JSON(http.StatusOK, task)
 }
 
+// GetComment 返回评论及其上级评论与直接回复，用于展示楼中楼
+func (h *Handler) GetComment(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_ID",
+			Message: "Invalid comment ID",
+		})
+		return
+	}
+
+	comment, err := h.db.GetCommentThread(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get comment", zap.Error(err))
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    "NOT_FOUND",
+			Message: "Comment not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+type CancelTaskRequest struct {
+	Reason string `json:"reason" binding:"omitempty,max=500"`
+}
+
+func (h *Handler) CancelTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_ID",
+			Message: "Invalid task ID",
+		})
+		return
+	}
+
+	var req CancelTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	task, err := h.db.GetTaskByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    "NOT_FOUND",
+			Message: "Task not found",
+		})
+		return
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "cancelled by operator"
+	}
+
+	cancelled, err := h.worker.CancelTask(task.ID, reason)
+	if err != nil {
+		h.logger.Error("failed to cancel task", zap.Error(err), zap.Uint("task_id", task.ID))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to cancel task",
+		})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Code:    "TASK_NOT_CANCELLABLE",
+			Message: "Task has already finished",
+			Details: gin.H{"status": task.Status},
+		})
+		return
+	}
+
+	task, err = h.db.GetTaskByID(task.ID)
+	if err != nil {
+		h.logger.Error("failed to get task", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get task",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// ApproveTaskBudget 允许 QUEUED_BUDGET 任务超出预算并立即提交
+func (h *Handler) ApproveTaskBudget(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_ID",
+			Message: "Invalid task ID",
+		})
+		return
+	}
+
+	task, err := h.db.GetTaskByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    "NOT_FOUND",
+			Message: "Task not found",
+		})
+		return
+	}
+
+	approved, err := h.worker.ApproveBudget(task.ID)
+	if err != nil {
+		h.logger.Error("failed to approve task budget", zap.Error(err), zap.Uint("task_id", task.ID))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to approve task budget",
+		})
+		return
+	}
+	if !approved {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Code:    "TASK_NOT_QUEUED_BUDGET",
+			Message: "Task is not waiting for budget",
+			Details: gin.H{"status": task.Status},
+		})
+		return
+	}
+
+	task, err = h.db.GetTaskByID(task.ID)
+	if err != nil {
+		h.logger.Error("failed to get task", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get task",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
 func (h *Handler) GetFile(c *gin.Context) {
 	key := c.Param("key")
 	if key == "" {
@@ -285,3 +505,393 @@ func (h *Handler) GetFile(c *gin.Context) {
 		"key":     key,
 	})
 }
+
+type ProviderInfo struct {
+	ProviderName string          `json:"provider_name"`
+	Type         string          `json:"type"`
+	Mode         string          `json:"mode"`
+	BaseURL      string          `json:"base_url"`
+	HealthCheck  bool            `json:"health_check"`
+	Breaker      *breaker.Status `json:"breaker,omitempty"`
+}
+
+// ListProviders 返回供应商列表及熔断器、健康检查状态，不包含密钥
+func (h *Handler) ListProviders(c *gin.Context) {
+	setting, err := h.db.GetSetting()
+	if err != nil {
+		h.logger.Error("failed to get settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get settings",
+		})
+		return
+	}
+
+	configs, err := provider.ParseProviderConfigs(setting.ProviderJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INVALID_PROVIDER_JSON",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	providers := make([]ProviderInfo, 0, len(configs))
+	for i := range configs {
+		cfg := &configs[i]
+		info := ProviderInfo{
+			ProviderName: cfg.ProviderName,
+			Type:         cfg.Type,
+			Mode:         provider.ProviderModeAsync,
+			BaseURL:      cfg.BaseURL,
+			HealthCheck:  cfg.HealthCheck != nil,
+		}
+		if cfg.IsSync() {
+			info.Mode = provider.ProviderModeSync
+		}
+
+		if h.breaker != nil {
+			status, err := h.breaker.Status(c.Request.Context(), cfg.ProviderName, cfg.BreakerSettings())
+			if err != nil {
+				h.logger.Error("failed to get breaker status", zap.Error(err), zap.String("provider", cfg.ProviderName))
+				c.JSON(http.StatusInternalServerError, ErrorResponse{
+					Code:    "INTERNAL_ERROR",
+					Message: "Failed to get provider status",
+				})
+				return
+			}
+			info.Breaker = status
+		}
+
+		providers = append(providers, info)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"providers": providers,
+	})
+}
+
+// ListBudgets 返回全局与各供应商预算在当前日、月窗口内的用量
+func (h *Handler) ListBudgets(c *gin.Context) {
+	budgets, err := h.worker.BudgetUsages()
+	if err != nil {
+		h.logger.Error("failed to get budget usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get budget usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": budgets,
+	})
+}
+
+// RunDiscovery 立即发现 WatchAccount 的新笔记
+func (h *Handler) RunDiscovery(c *gin.Context) {
+	_, err := h.redis.Enqueue(
+		asynq.NewTask(worker.TypeDiscoverNotes, nil, asynq.Queue("default")),
+	)
+	if err != nil {
+		h.logger.Error("failed to enqueue discovery task", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to enqueue discovery task",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Discovery task enqueued",
+	})
+}
+
+func (h *Handler) ListNotes(c *gin.Context) {
+	notes, err := h.db.ListNotes()
+	if err != nil {
+		h.logger.Error("failed to list notes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to list notes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notes": notes,
+	})
+}
+
+type UpdateNoteRequest struct {
+	ReplyEnabled *bool `json:"reply_enabled" binding:"omitempty"`
+	Watched      *bool `json:"watched" binding:"omitempty"`
+}
+
+// UpdateNote 修改笔记的回复开关与是否定时轮询
+func (h *Handler) UpdateNote(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_ID",
+			Message: "Invalid note ID",
+		})
+		return
+	}
+
+	var req UpdateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	note, err := h.db.GetNoteByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    "NOT_FOUND",
+			Message: "Note not found",
+		})
+		return
+	}
+
+	fields := map[string]interface{}{}
+	if req.ReplyEnabled != nil {
+		fields["reply_enabled"] = *req.ReplyEnabled
+	}
+	if req.Watched != nil {
+		// 手动修改过轮询状态的笔记不再被自动停止
+		fields["watched"] = *req.Watched
+		fields["source"] = models.NoteSourceManual
+		fields["retired_at"] = nil
+		if *req.Watched {
+			// 重新开启轮询时清除退避，立即恢复拉取
+			fields["consecutive_failures"] = 0
+			fields["next_poll_at"] = nil
+		}
+	}
+
+	if len(fields) > 0 {
+		if err := h.db.UpdateNoteFields(note.ID, fields); err != nil {
+			h.logger.Error("failed to update note", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to update note",
+			})
+			return
+		}
+	}
+
+	note, err = h.db.GetNoteByID(note.ID)
+	if err != nil {
+		h.logger.Error("failed to get note", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get note",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// ListCommenters 列出评论用户，支持按昵称或用户 ID 搜索（q）与按屏蔽状态过滤（blocked）
+func (h *Handler) ListCommenters(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "100")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	offsetStr := c.DefaultQuery("offset", "0")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	var blocked *bool
+	if blockedStr := c.Query("blocked"); blockedStr != "" {
+		value, err := strconv.ParseBool(blockedStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_REQUEST",
+				Message: "blocked must be true or false",
+			})
+			return
+		}
+		blocked = &value
+	}
+
+	commenters, total, err := h.db.ListCommenters(c.Query("q"), blocked, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list commenters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to list commenters",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"commenters": commenters,
+		"total":      total,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+// GetCommenter 返回评论用户及其在所有笔记下的评论与任务
+func (h *Handler) GetCommenter(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_ID",
+			Message: "Invalid commenter ID",
+		})
+		return
+	}
+
+	commenter, err := h.db.GetCommenterByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    "NOT_FOUND",
+			Message: "Commenter not found",
+		})
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "100")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	comments, err := h.db.ListCommenterComments(commenter.ID, limit)
+	if err != nil {
+		h.logger.Error("failed to list commenter comments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to list commenter comments",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"commenter": commenter,
+		"comments":  comments,
+	})
+}
+
+type UpdateCommenterRequest struct {
+	Blocked       *bool   `json:"blocked" binding:"required"`
+	BlockedReason *string `json:"blocked_reason" binding:"omitempty,max=500"`
+}
+
+// UpdateCommenter 屏蔽或解除屏蔽评论用户，被屏蔽用户的新评论不再创建任务
+func (h *Handler) UpdateCommenter(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_ID",
+			Message: "Invalid commenter ID",
+		})
+		return
+	}
+
+	var req UpdateCommenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	commenter, err := h.db.GetCommenterByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    "NOT_FOUND",
+			Message: "Commenter not found",
+		})
+		return
+	}
+
+	if err := h.db.UpdateCommenterBlock(commenter.ID, *req.Blocked, req.BlockedReason); err != nil {
+		h.logger.Error("failed to update commenter", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to update commenter",
+		})
+		return
+	}
+
+	commenter, err = h.db.GetCommenterByID(commenter.ID)
+	if err != nil {
+		h.logger.Error("failed to get commenter", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to get commenter",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, commenter)
+}
+
+type InjectMockCommentRequest struct {
+	CommentID       string   `json:"comment_id" binding:"omitempty,max=100"`
+	UserID          string   `json:"user_id" binding:"omitempty,max=100"`
+	UserName        string   `json:"user_name" binding:"omitempty,max=200"`
+	Content         string   `json:"content"`
+	ImageURLs       []string `json:"image_urls" binding:"omitempty,dive,url"`
+	ParentCommentID string   `json:"parent_comment_id" binding:"omitempty,max=100"`
+}
+
+// InjectMockComment 在 Mock 模式下为笔记注入一条评论，target 为 URL 时需要进行 URL 编码；
+// 请求体为空或 content 为空时生成一条随机的生成请求
+func (h *Handler) InjectMockComment(c *gin.Context) {
+	target := c.Param("target")
+
+	var req InjectMockCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	comment, err := h.worker.InjectComment(c.Request.Context(), target, xhsconnector.Comment{
+		CommentID:       req.CommentID,
+		UserID:          req.UserID,
+		UserName:        req.UserName,
+		Content:         req.Content,
+		ImageURLs:       req.ImageURLs,
+		ParentCommentID: req.ParentCommentID,
+	})
+	if errors.Is(err, worker.ErrInjectionUnsupported) {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Code:    "MOCK_MODE_REQUIRED",
+			Message: "Comment injection is only available in mock mode",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to inject mock comment", zap.Error(err), zap.String("note_target", target))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to inject comment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"note_target": target,
+		"comment":     comment,
+	})
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
+	"github.com/xiaohongshu-image/internal/worker"
+	"go.uber.org/zap"
+)
+
+const (
+	ingestTimestampHeader = "X-Timestamp"
+	ingestSignatureHeader = "X-Signature"
+	// ingestMaxBodyBytes 限制单次推送的请求体大小
+	ingestMaxBodyBytes = 5 << 20
+)
+
+type IngestCommentsRequest struct {
+	NoteTarget string          `json:"note_target" binding:"required,max=500"`
+	Comments   []IngestComment `json:"comments" binding:"required,min=1,max=500,dive"`
+}
+
+// IngestComment 为推送的评论，CommentUID 为平台评论 ID，与轮询入库的评论共用去重，UserID 为平台用户 ID；
+// ParentCommentUID 为被回复评论的 comment_uid，CreatedAt 为空时使用收到的时间
+type IngestComment struct {
+	CommentUID       string     `json:"comment_uid" binding:"required,max=100"`
+	UserID           string     `json:"user_id" binding:"max=100"`
+	UserName         string     `json:"user_name" binding:"max=200"`
+	UserAvatar       string     `json:"user_avatar" binding:"omitempty,url,max=1000"`
+	Content          string     `json:"content"`
+	CreatedAt        *time.Time `json:"created_at"`
+	ImageURLs        []string   `json:"image_urls" binding:"omitempty,dive,url"`
+	ParentCommentUID string     `json:"parent_comment_uid" binding:"omitempty,max=100"`
+}
+
+// verifyIngestSignature 校验推送请求的签名：X-Signature 为 hex(HMAC-SHA256(secret, timestamp + "." + body))，
+// X-Timestamp 为 Unix 秒，与服务器时间相差超过 maxSkew 时拒绝以防重放
+func verifyIngestSignature(secret string, maxSkew time.Duration, timestamp, signature string, body []byte, now time.Time) error {
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("missing %s or %s header", ingestTimestampHeader, ingestSignatureHeader)
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %s", timestamp)
+	}
+	// 时间差超出 Duration 范围时 Sub 返回饱和值，不能取绝对值后再比较
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("timestamp outside allowed window of %s", maxSkew)
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return fmt.Errorf("signature is not hex encoded")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// IngestComments 接收合作方推送的评论，按 comment_uid 去重后与轮询一样投递 process:comment 任务
+func (h *Handler) IngestComments(c *gin.Context) {
+	if h.ingest.Secret == "" {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Code:    "INGEST_DISABLED",
+			Message: "Comment ingestion is not configured",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, ingestMaxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+				Code:    "REQUEST_TOO_LARGE",
+				Message: fmt.Sprintf("Request body exceeds %d bytes", ingestMaxBodyBytes),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err = verifyIngestSignature(h.ingest.Secret, h.ingest.MaxSkew,
+		c.GetHeader(ingestTimestampHeader), c.GetHeader(ingestSignatureHeader), body, time.Now())
+	if err != nil {
+		h.logger.Warn("rejected comment ingestion", zap.Error(err), zap.String("client_ip", c.ClientIP()))
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    "INVALID_SIGNATURE",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	var req IngestCommentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	comments := make([]xhsconnector.Comment, 0, len(req.Comments))
+	for _, comment := range req.Comments {
+		createdAt := now
+		if comment.CreatedAt != nil {
+			createdAt = *comment.CreatedAt
+		}
+		comments = append(comments, xhsconnector.Comment{
+			CommentID:        comment.CommentUID,
+			UserID:           comment.UserID,
+			UserName:         comment.UserName,
+			UserAvatar:       comment.UserAvatar,
+			Content:          comment.Content,
+			CommentCreatedAt: createdAt,
+			ImageURLs:        comment.ImageURLs,
+			ParentCommentID:  comment.ParentCommentUID,
+		})
+	}
+
+	results, err := h.worker.IngestComments(req.NoteTarget, comments)
+	if err != nil {
+		h.logger.Error("failed to ingest comments", zap.Error(err), zap.String("note_target", req.NoteTarget))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: "Failed to ingest comments",
+		})
+		return
+	}
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	h.logger.Info("comments ingested",
+		zap.String("note_target", req.NoteTarget),
+		zap.Int("created", counts[worker.IngestStatusCreated]),
+		zap.Int("duplicate", counts[worker.IngestStatusDuplicate]),
+		zap.Int("failed", counts[worker.IngestStatusFailed]),
+		zap.Int("ignored", counts[worker.IngestStatusIgnored]),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"note_target": req.NoteTarget,
+		"created":     counts[worker.IngestStatusCreated],
+		"duplicates":  counts[worker.IngestStatusDuplicate],
+		"failed":      counts[worker.IngestStatusFailed],
+		"ignored":     counts[worker.IngestStatusIgnored],
+		"results":     results,
+	})
+}
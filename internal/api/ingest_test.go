@@ -0,0 +1,71 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signIngest(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyIngestSignature(t *testing.T) {
+	const secret = "s3cret"
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"note_target":"note_1","comments":[{"comment_uid":"c1","content":"画一只猫"}]}`)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	valid := signIngest(secret, ts, body)
+
+	at := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).Unix(), 10)
+	}
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		want      string
+	}{
+		{name: "valid", timestamp: ts, signature: valid, body: body},
+		{name: "valid with sha256 prefix", timestamp: ts, signature: "sha256=" + valid, body: body},
+		{name: "uppercase hex", timestamp: ts, signature: strings.ToUpper(valid), body: body},
+		{name: "within skew in the past", timestamp: at(-5 * time.Minute), signature: signIngest(secret, at(-5*time.Minute), body), body: body},
+		{name: "within skew in the future", timestamp: at(5 * time.Minute), signature: signIngest(secret, at(5*time.Minute), body), body: body},
+		{name: "tampered body", timestamp: ts, signature: valid, body: []byte(strings.Replace(string(body), "c1", "c2", 1)), want: "signature mismatch"},
+		{name: "wrong secret", timestamp: ts, signature: signIngest("other", ts, body), body: body, want: "signature mismatch"},
+		{name: "signature for another timestamp", timestamp: at(time.Second), signature: valid, body: body, want: "signature mismatch"},
+		{name: "truncated signature", timestamp: ts, signature: valid[:32], body: body, want: "signature mismatch"},
+		{name: "too old", timestamp: at(-5*time.Minute - time.Second), signature: signIngest(secret, at(-5*time.Minute-time.Second), body), body: body, want: "outside allowed window"},
+		{name: "too far in the future", timestamp: at(5*time.Minute + time.Second), signature: signIngest(secret, at(5*time.Minute+time.Second), body), body: body, want: "outside allowed window"},
+		{name: "non-numeric timestamp", timestamp: "yesterday", signature: signIngest(secret, "yesterday", body), body: body, want: "invalid timestamp"},
+		{name: "millisecond timestamp", timestamp: strconv.FormatInt(now.UnixMilli(), 10), signature: valid, body: body, want: "outside allowed window"},
+		{name: "malformed hex", timestamp: ts, signature: "zz" + valid[2:], body: body, want: "not hex encoded"},
+		{name: "odd length hex", timestamp: ts, signature: valid[1:], body: body, want: "not hex encoded"},
+		{name: "missing timestamp", signature: valid, body: body, want: "missing"},
+		{name: "missing signature", timestamp: ts, body: body, want: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyIngestSignature(secret, 5*time.Minute, tt.timestamp, tt.signature, tt.body, now)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -16,14 +16,17 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server" json:"server"`
-	Database DatabaseConfig `mapstructure:"database" json:"database"`
-	Redis    RedisConfig    `mapstructure:"redis" json:"redis"`
-	MinIO    MinIOConfig    `mapstructure:"minio" json:"minio"`
-	LLM      LLMConfig      `mapstructure:"llm" json:"llm"`
-	SMTP     SMTPConfig     `mapstructure:"smtp" json:"smtp"`
-	Asynq    AsynqConfig    `mapstructure:"asynq" json:"asynq"`
-	Nacos    NacosConfig    `mapstructure:"nacos" json:"nacos"`
+	Server    ServerConfig    `mapstructure:"server" json:"server"`
+	Database  DatabaseConfig  `mapstructure:"database" json:"database"`
+	Redis     RedisConfig     `mapstructure:"redis" json:"redis"`
+	MinIO     MinIOConfig     `mapstructure:"minio" json:"minio"`
+	LLM       LLMConfig       `mapstructure:"llm" json:"llm"`
+	SMTP      SMTPConfig      `mapstructure:"smtp" json:"smtp"`
+	Asynq     AsynqConfig     `mapstructure:"asynq" json:"asynq"`
+	Mock      MockConfig      `mapstructure:"mock" json:"mock"`
+	Ingest    IngestConfig    `mapstructure:"ingest" json:"ingest"`
+	Recording RecordingConfig `mapstructure:"recording" json:"recording"`
+	Nacos     NacosConfig     `mapstructure:"nacos" json:"nacos"`
 }
 
 type ServerConfig struct {
@@ -85,6 +88,25 @@ type AsynqConfig struct {
 	Queues        map[string]int `mapstructure:"queues" json:"queues"`
 }
 
+// MockConfig 配置 Mock 模式的连接器，ScenarioFile 为空时使用内置评论，Speed 为 0 时使用场景文件中的倍速
+type MockConfig struct {
+	ScenarioFile string  `mapstructure:"scenario_file" json:"scenario_file"`
+	Speed        float64 `mapstructure:"speed" json:"speed"`
+}
+
+// IngestConfig 配置评论推送接口，Secret 为空时接口关闭；MaxSkew 为签名时间戳允许的最大偏差
+type IngestConfig struct {
+	Secret  string        `mapstructure:"secret" json:"secret"`
+	MaxSkew time.Duration `mapstructure:"max_skew" json:"max_skew"`
+}
+
+// RecordingConfig 配置连接器录制与回放，Dir 非空时录制 mock/mcp 模式下的评论拉取；
+// ReplayDir 为 replay 模式读取的录制目录，为空时使用 Dir
+type RecordingConfig struct {
+	Dir       string `mapstructure:"dir" json:"dir"`
+	ReplayDir string `mapstructure:"replay_dir" json:"replay_dir"`
+}
+
 type NacosConfig struct {
 	Addr      string `mapstructure:"addr"`
 	Port      uint64 `mapstructure:"port"`
@@ -277,6 +299,14 @@ func setDefaults(cfg *Config) {
 		cfg.LLM.MaxRetries = 2
 	}
 
+	if cfg.Ingest.MaxSkew == 0 {
+		cfg.Ingest.MaxSkew = 5 * time.Minute
+	}
+
+	if cfg.Recording.ReplayDir == "" {
+		cfg.Recording.ReplayDir = cfg.Recording.Dir
+	}
+
 	if cfg.Asynq.Concurrency == 0 {
 		cfg.Asynq.Concurrency = 10
 	}
@@ -8,6 +8,7 @@ import (
 	"github.com/xiaohongshu-image/internal/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -44,10 +45,16 @@ func New(cfg *config.DatabaseConfig) (*Database, error) {
 	if err := db.AutoMigrate(
 		&models.Setting{},
 		&models.Note{},
+		&models.Commenter{},
+		&models.CommenterEmail{},
 		&models.Comment{},
+		&models.CommentImage{},
+		&models.CommentReply{},
 		&models.Task{},
 		&models.Delivery{},
+		&models.Artifact{},
 		&models.AuditLog{},
+		&models.EmailTemplate{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to auto migrate: %w", err)
 	}
@@ -87,8 +94,86 @@ func (d *Database) GetOrCreateNote(noteTarget string) (*models.Note, error) {
 	return &note, nil
 }
 
+// UpdateNote 只保存轮询状态，避免覆盖同时修改的笔记设置
 func (d *Database) UpdateNote(note *models.Note) error {
-	return d.DB.Save(note).Error
+	return d.DB.Model(note).
+		Select("last_cursor", "last_polled_at", "last_error", "last_error_kind", "consecutive_failures", "next_poll_at").
+		Updates(note).Error
+}
+
+func (d *Database) GetNoteByID(id uint) (*models.Note, error) {
+	var note models.Note
+	err := d.DB.Where("id = ?", id).First(&note).Error
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (d *Database) GetNoteByTarget(noteTarget string) (*models.Note, error) {
+	var note models.Note
+	err := d.DB.Where("note_target = ?", noteTarget).First(&note).Error
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// UpdateNoteFields 只更新指定列，避免覆盖轮询同时写入的游标
+func (d *Database) UpdateNoteFields(id uint, fields map[string]interface{}) error {
+	return d.DB.Model(&models.Note{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// ListWatchedNoteTargets 返回需要定时轮询的笔记
+func (d *Database) ListWatchedNoteTargets() ([]string, error) {
+	var targets []string
+	err := d.DB.Model(&models.Note{}).Where("watched = ?", true).Order("id ASC").Pluck("note_target", &targets).Error
+	return targets, err
+}
+
+// RegisterDiscoveredNote 登记自动发现的笔记，笔记已存在时不做修改，返回是否新建
+func (d *Database) RegisterDiscoveredNote(note *models.Note) (bool, error) {
+	note.Source = models.NoteSourceDiscovered
+	note.Watched = true
+	result := d.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(note)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RegisterPushedNote 登记通过推送接口收到评论的笔记，新登记的笔记默认不定时轮询；笔记已存在时不做修改
+func (d *Database) RegisterPushedNote(noteTarget string) error {
+	// 使用 map 创建，避免 watched 的零值被列默认值 true 替换
+	now := time.Now()
+	return d.DB.Model(&models.Note{}).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]interface{}{
+			"note_target": noteTarget,
+			"source":      models.NoteSourcePushed,
+			"watched":     false,
+			"created_at":  now,
+			"updated_at":  now,
+		}).Error
+}
+
+// RetireDiscoveredNotes 停止轮询发布时间早于 cutoff 的自动发现笔记，发布时间未知时按登记时间计算，返回停止的数量
+func (d *Database) RetireDiscoveredNotes(cutoff time.Time) (int64, error) {
+	result := d.DB.Model(&models.Note{}).
+		Where("source = ? AND watched = ? AND COALESCE(published_at, created_at) < ?", models.NoteSourceDiscovered, true, cutoff).
+		Updates(map[string]interface{}{
+			"watched":    false,
+			"retired_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (d *Database) ListNotes() ([]models.Note, error) {
+	var notes []models.Note
+	err := d.DB.Order("created_at DESC").Find(&notes).Error
+	return notes, err
 }
 
 func (d *Database) CreateComment(comment *models.Comment) error {
@@ -110,13 +195,215 @@ func (d *Database) GetCommentByUID(commentUID string) (*models.Comment, error) {
 	return &comment, nil
 }
 
+func (d *Database) GetCommentByID(id uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := d.DB.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).Where("id = ?", id).First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// GetCommentThread 返回评论及其上级评论与直接回复
+func (d *Database) GetCommentThread(id uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := d.DB.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).Preload("Parent").Preload("Children", func(db *gorm.DB) *gorm.DB {
+		return db.Order("comment_created_at ASC, id ASC")
+	}).Where("id = ?", id).First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// UpdateCommentThread 保存一级评论的回复数与回复拉取进度
+func (d *Database) UpdateCommentThread(comment *models.Comment) error {
+	return d.DB.Model(comment).Select("sub_comment_count", "sub_cursor", "sub_polled_at").Updates(comment).Error
+}
+
+// ListThreadsToPoll 返回笔记下 since 之后入库的一级评论，从未拉取过回复与最久未拉取的排在前面
+func (d *Database) ListThreadsToPoll(noteTarget string, since time.Time, limit int) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := d.DB.Where("note_target = ? AND depth = 0 AND ingested_at >= ?", noteTarget, since).
+		Order("sub_polled_at ASC").
+		Limit(limit).
+		Find(&comments).Error
+	return comments, err
+}
+
+// UpsertCommenter 登记评论用户，已存在时更新昵称、头像与出现时间并累加评论数
+func (d *Database) UpsertCommenter(userID, userName, avatarURL string, seenAt time.Time) (*models.Commenter, error) {
+	commenter := &models.Commenter{
+		UserID:       userID,
+		CommentCount: 1,
+		FirstSeenAt:  seenAt,
+		LastSeenAt:   seenAt,
+	}
+	updates := map[string]interface{}{
+		"comment_count": gorm.Expr("comment_count + 1"),
+		"first_seen_at": gorm.Expr("LEAST(first_seen_at, ?)", seenAt),
+		"last_seen_at":  gorm.Expr("GREATEST(last_seen_at, ?)", seenAt),
+		"updated_at":    time.Now(),
+	}
+	if userName != "" {
+		commenter.UserName = &userName
+		updates["user_name"] = userName
+	}
+	if avatarURL != "" {
+		commenter.AvatarURL = &avatarURL
+		updates["avatar_url"] = avatarURL
+	}
+
+	err := d.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(updates),
+	}).Create(commenter).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.Commenter
+	if err := d.DB.Where("user_id = ?", userID).First(&result).Error; err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (d *Database) GetCommenterByID(id uint) (*models.Commenter, error) {
+	var commenter models.Commenter
+	err := d.DB.Preload("Emails", func(db *gorm.DB) *gorm.DB {
+		return db.Order("last_used_at DESC")
+	}).Where("id = ?", id).First(&commenter).Error
+	if err != nil {
+		return nil, err
+	}
+	return &commenter, nil
+}
+
+// ListCommenters 按最近出现时间列出评论用户，query 匹配昵称或用户 ID，blocked 非空时按屏蔽状态过滤
+func (d *Database) ListCommenters(query string, blocked *bool, limit int, offset int) ([]models.Commenter, int64, error) {
+	db := d.DB.Model(&models.Commenter{})
+	if query != "" {
+		like := "%" + query + "%"
+		db = db.Where("user_name LIKE ? OR user_id LIKE ?", like, like)
+	}
+	if blocked != nil {
+		db = db.Where("blocked = ?", *blocked)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var commenters []models.Commenter
+	err := db.Preload("Emails", func(db *gorm.DB) *gorm.DB {
+		return db.Order("last_used_at DESC")
+	}).Order("last_seen_at DESC").Limit(limit).Offset(offset).Find(&commenters).Error
+	return commenters, total, err
+}
+
+// ListCommenterComments 返回评论用户在所有笔记下的评论及其任务，最新的在前
+func (d *Database) ListCommenterComments(commenterID uint, limit int) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := d.DB.Preload("Task").
+		Where("commenter_id = ?", commenterID).
+		Order("comment_created_at DESC, id DESC").
+		Limit(limit).
+		Find(&comments).Error
+	return comments, err
+}
+
+// RecordCommenterRequest 累加评论用户的请求数，并记录本次请求使用的邮箱
+func (d *Database) RecordCommenterRequest(commenterID uint, email *string, at time.Time) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Commenter{}).Where("id = ?", commenterID).
+			UpdateColumn("request_count", gorm.Expr("request_count + 1")).Error
+		if err != nil {
+			return err
+		}
+		if email == nil || *email == "" {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "commenter_id"}, {Name: "email"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"use_count":    gorm.Expr("use_count + 1"),
+				"last_used_at": at,
+			}),
+		}).Create(&models.CommenterEmail{
+			CommenterID: commenterID,
+			Email:       *email,
+			UseCount:    1,
+			FirstUsedAt: at,
+			LastUsedAt:  at,
+		}).Error
+	})
+}
+
+// IncrementCommenterSuccess 为评论所属的用户累加成功数，评论没有关联用户时不做修改
+func (d *Database) IncrementCommenterSuccess(commentID uint) error {
+	return d.DB.Model(&models.Commenter{}).
+		Where("id = (?)", d.DB.Model(&models.Comment{}).Select("commenter_id").Where("id = ?", commentID)).
+		UpdateColumn("success_count", gorm.Expr("success_count + 1")).Error
+}
+
+// UpdateCommenterBlock 屏蔽或解除屏蔽评论用户
+func (d *Database) UpdateCommenterBlock(id uint, blocked bool, reason *string) error {
+	fields := map[string]interface{}{
+		"blocked":        blocked,
+		"blocked_reason": nil,
+		"blocked_at":     nil,
+	}
+	if blocked {
+		fields["blocked_reason"] = reason
+		fields["blocked_at"] = time.Now()
+	}
+	return d.DB.Model(&models.Commenter{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (d *Database) UpdateCommentImage(image *models.CommentImage) error {
+	return d.DB.Save(image).Error
+}
+
+// GetCommentReply 返回评论在某阶段的回复记录，不存在时返回 gorm.ErrRecordNotFound
+func (d *Database) GetCommentReply(commentID uint, stage models.ReplyStage) (*models.CommentReply, error) {
+	var reply models.CommentReply
+	err := d.DB.Where("comment_id = ? AND stage = ?", commentID, stage).First(&reply).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// IsOwnReplyID 判断平台评论 ID 是否为本系统发出的回复
+func (d *Database) IsOwnReplyID(replyID string) (bool, error) {
+	var count int64
+	err := d.DB.Model(&models.CommentReply{}).Where("reply_id = ?", replyID).Count(&count).Error
+	return count > 0, err
+}
+
+func (d *Database) SaveCommentReply(reply *models.CommentReply) error {
+	return d.DB.Save(reply).Error
+}
+
 func (d *Database) CreateTask(task *models.Task) error {
 	return d.DB.Create(task).Error
 }
 
 func (d *Database) GetTaskByID(id uint) (*models.Task, error) {
 	var task models.Task
-	err := d.DB.Preload("Comment").Preload("Deliveries").Where("id = ?", id).First(&task).Error
+	err := d.DB.Preload("Comment").Preload("Comment.Parent").Preload("Comment.Commenter").Preload("Comment.Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).Preload("Comment.Replies", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Deliveries").Preload("Artifacts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).Where("id = ?", id).First(&task).Error
 	if err != nil {
 		return nil, err
 	}
@@ -136,12 +423,132 @@ func (d *Database) UpdateTask(task *models.Task) error {
 	return d.DB.Save(task).Error
 }
 
+// finishedTaskStatuses 为任务的终态，处于终态的任务不再被取消或改写
+var finishedTaskStatuses = []models.TaskStatus{
+	models.TaskStatusSucceeded,
+	models.TaskStatusEmailed,
+	models.TaskStatusFailed,
+	models.TaskStatusCancelled,
+}
+
+// taskFinishColumns 为任务结束时写入的字段
+var taskFinishColumns = []string{
+	"status", "error", "result_url", "result_object_key",
+	"provider_name", "provider_job_id", "submitted_at", "updated_at",
+}
+
+// FinishTask 仅在任务尚未结束时写入终态（SUCCEEDED / FAILED）与结果字段，artifacts 非 nil 时在同一事务中替换产物。
+// 返回任务是否被更新；查询状态期间任务被取消时不会被覆盖。
+func (d *Database) FinishTask(task *models.Task, artifacts []models.Artifact) (bool, error) {
+	updated := false
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(task).Select(taskFinishColumns).
+			Where("status NOT IN ?", finishedTaskStatuses).
+			Updates(task)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+		if artifacts == nil {
+			return nil
+		}
+		return replaceTaskArtifacts(tx, task.ID, artifacts)
+	})
+	return updated, err
+}
+
+// CancelTask 将未结束的任务标记为 CANCELLED，返回任务是否被取消
+func (d *Database) CancelTask(id uint, reason string) (bool, error) {
+	result := d.DB.Model(&models.Task{}).
+		Where("id = ? AND status NOT IN ?", id, finishedTaskStatuses).
+		Updates(map[string]interface{}{
+			"status": models.TaskStatusCancelled,
+			"error":  reason,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListUnfinishedTaskIDsByNote 返回笔记下评论产生的、尚未结束的任务 ID
+func (d *Database) ListUnfinishedTaskIDsByNote(noteTarget string) ([]uint, error) {
+	var ids []uint
+	err := d.DB.Model(&models.Task{}).
+		Joins("JOIN comments ON comments.id = tasks.comment_id").
+		Where("comments.note_target = ? AND tasks.status NOT IN ?", noteTarget, finishedTaskStatuses).
+		Order("tasks.id ASC").
+		Pluck("tasks.id", &ids).Error
+	return ids, err
+}
+
+// QueueTaskForBudget 将等待提交的任务标记为 QUEUED_BUDGET 并记录预估费用，返回任务是否被标记
+func (d *Database) QueueTaskForBudget(id uint, cost float64, reason string) (bool, error) {
+	result := d.DB.Model(&models.Task{}).
+		Where("id = ? AND status IN ?", id, []models.TaskStatus{
+			models.TaskStatusExtracted,
+			models.TaskStatusQueuedBudget,
+		}).
+		Updates(map[string]interface{}{
+			"status":         models.TaskStatusQueuedBudget,
+			"estimated_cost": cost,
+			"error":          reason,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ApproveTaskBudget 允许 QUEUED_BUDGET 任务超出预算提交，返回任务是否处于等待预算状态
+func (d *Database) ApproveTaskBudget(id uint) (bool, error) {
+	result := d.DB.Model(&models.Task{}).
+		Where("id = ? AND status = ?", id, models.TaskStatusQueuedBudget).
+		Update("budget_approved", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (d *Database) SetTaskEstimatedCost(id uint, cost float64) error {
+	return d.DB.Model(&models.Task{}).Where("id = ?", id).Update("estimated_cost", cost).Error
+}
+
+// SumSubmittedCost 汇总 since 之后提交的任务的预估费用，providerName 为空时汇总全部供应商
+func (d *Database) SumSubmittedCost(providerName string, since time.Time) (float64, error) {
+	var total float64
+	query := d.DB.Model(&models.Task{}).Where("submitted_at >= ?", since)
+	if providerName != "" {
+		query = query.Where("provider_name = ?", providerName)
+	}
+	err := query.Select("COALESCE(SUM(estimated_cost), 0)").Scan(&total).Error
+	return total, err
+}
+
 func (d *Database) ListTasks(limit int, offset int) ([]models.Task, error) {
 	var tasks []models.Task
 	err := d.DB.Preload("Comment").Order("created_at DESC").Limit(limit).Offset(offset).Find(&tasks).Error
 	return tasks, err
 }
 
+// replaceTaskArtifacts 替换任务的全部产物，重复执行时不会产生重复记录
+func replaceTaskArtifacts(tx *gorm.DB, taskID uint, artifacts []models.Artifact) error {
+	if err := tx.Where("task_id = ?", taskID).Delete(&models.Artifact{}).Error; err != nil {
+		return err
+	}
+	if len(artifacts) == 0 {
+		return nil
+	}
+	for i := range artifacts {
+		artifacts[i].TaskID = taskID
+	}
+	return tx.Create(&artifacts).Error
+}
+
 func (d *Database) CreateDelivery(delivery *models.Delivery) error {
 	return d.DB.Create(delivery).Error
 }
@@ -149,3 +556,32 @@ func (d *Database) CreateDelivery(delivery *models.Delivery) error {
 func (d *Database) CreateAuditLog(log *models.AuditLog) error {
 	return d.DB.Create(log).Error
 }
+
+// GetEmailTemplate 返回自定义的邮件模板，不存在时返回 gorm.ErrRecordNotFound
+func (d *Database) GetEmailTemplate(name, locale string) (*models.EmailTemplate, error) {
+	var tpl models.EmailTemplate
+	err := d.DB.Where("name = ? AND locale = ?", name, locale).First(&tpl).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+func (d *Database) ListEmailTemplates() ([]models.EmailTemplate, error) {
+	var templates []models.EmailTemplate
+	err := d.DB.Order("name ASC, locale ASC").Find(&templates).Error
+	return templates, err
+}
+
+// SaveEmailTemplate 按模板名与语言创建或覆盖自定义模板
+func (d *Database) SaveEmailTemplate(tpl *models.EmailTemplate) error {
+	return d.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject", "html_body", "text_body", "updated_at"}),
+	}).Create(tpl).Error
+}
+
+// DeleteEmailTemplate 删除自定义模板，之后使用内置模板
+func (d *Database) DeleteEmailTemplate(name, locale string) error {
+	return d.DB.Where("name = ? AND locale = ?", name, locale).Delete(&models.EmailTemplate{}).Error
+}
@@ -0,0 +1,262 @@
+package fakeprovider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/services/provider"
+)
+
+const (
+	contractPollInterval = 100 * time.Millisecond
+	contractJobTimeout   = 30 * time.Second
+)
+
+// MemoryStorage 在内存中保存上传的对象，供同步供应商的契约检查使用
+type MemoryStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{objects: make(map[string][]byte)}
+}
+
+func (m *MemoryStorage) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	return "memory://" + key, nil
+}
+
+func (m *MemoryStorage) GetPresignedURL(ctx context.Context, key string, expiry int) (string, error) {
+	return "memory://" + key, nil
+}
+
+func (m *MemoryStorage) Object(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	return data, ok
+}
+
+// CheckResult 为一项契约检查的结果，Skipped 表示该 Profile 不适用此项检查
+type CheckResult struct {
+	Name     string        `json:"name"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+type ContractResult struct {
+	Profile string        `json:"profile"`
+	Checks  []CheckResult `json:"checks"`
+}
+
+func (r *ContractResult) Passed() bool {
+	for _, c := range r.Checks {
+		if c.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+var errSkipped = fmt.Errorf("skipped")
+
+// RunContract 用 SampleConfig 创建 HTTPProvider，对 baseURL 上的模拟服务器执行端到端检查：
+// 配置校验、健康检查、成功任务（含产物下载）、失败任务、取消任务以及错误凭据被拒绝
+func RunContract(ctx context.Context, p Profile, baseURL string) *ContractResult {
+	result := &ContractResult{Profile: p.Name}
+	cfg := SampleConfig(p, baseURL)
+	storage := NewMemoryStorage()
+
+	check := func(name string, fn func() error) {
+		start := time.Now()
+		err := fn()
+		c := CheckResult{Name: name, Duration: time.Since(start)}
+		switch {
+		case err == errSkipped:
+			c.Skipped = true
+		case err != nil:
+			c.Error = err.Error()
+		}
+		result.Checks = append(result.Checks, c)
+	}
+
+	var prov *provider.HTTPProvider
+	check("config", func() error {
+		if err := provider.ValidateProviderConfig(&cfg); err != nil {
+			return err
+		}
+		var err error
+		prov, err = provider.NewHTTPProvider(&cfg, storage)
+		return err
+	})
+	if prov == nil {
+		return result
+	}
+	defer prov.Close()
+
+	check("health", func() error {
+		return prov.CheckHealth(ctx)
+	})
+
+	check("succeeded", func() error {
+		status, err := runJob(ctx, prov, "a red fox in the snow")
+		if err != nil {
+			return err
+		}
+		if status.Status != provider.JobStatusSucceeded {
+			return fmt.Errorf("expected succeeded, got %s (raw %q)", status.Status, status.RawStatus)
+		}
+		if len(status.Artifacts) == 0 {
+			return fmt.Errorf("no artifacts in result")
+		}
+		return fetchArtifact(ctx, storage, status.Artifacts[0])
+	})
+
+	check("failed", func() error {
+		status, err := runJob(ctx, prov, "a red fox in the snow "+FailMarker)
+		if err != nil {
+			return err
+		}
+		if status.Status != provider.JobStatusFailed {
+			return fmt.Errorf("expected failed, got %s (raw %q)", status.Status, status.RawStatus)
+		}
+		if status.Error == nil || *status.Error == "" {
+			return fmt.Errorf("failed result has no error message")
+		}
+		return nil
+	})
+
+	check("cancelled", func() error {
+		if cfg.CancelPathTemplate == "" {
+			return errSkipped
+		}
+		submitted, err := prov.Submit(ctx, contractRequest("a red fox in the snow"))
+		if err != nil {
+			return fmt.Errorf("submit: %w", err)
+		}
+		if err := prov.Cancel(ctx, submitted.ProviderJobID); err != nil {
+			return err
+		}
+		status, err := prov.Status(ctx, submitted.ProviderJobID)
+		if err != nil {
+			return fmt.Errorf("status: %w", err)
+		}
+		if status.Status != provider.JobStatusFailed {
+			return fmt.Errorf("expected failed after cancel, got %s", status.Status)
+		}
+		return nil
+	})
+
+	check("bad_credentials", func() error {
+		if p.Auth.Type == "" || p.Auth.Type == "none" {
+			return errSkipped
+		}
+		bad := withBadCredentials(cfg)
+		badProv, err := provider.NewHTTPProvider(&bad, storage)
+		if err != nil {
+			return err
+		}
+		defer badProv.Close()
+		if _, err := badProv.Submit(ctx, contractRequest("a red fox in the snow")); err == nil {
+			return fmt.Errorf("submit with bad credentials was accepted")
+		}
+		return nil
+	})
+
+	return result
+}
+
+func contractRequest(prompt string) provider.UnifiedGenRequest {
+	return provider.UnifiedGenRequest{
+		RequestID: fmt.Sprintf("contract_%d", time.Now().UnixNano()),
+		Type:      provider.RequestTypeImage,
+		Prompt:    prompt,
+	}
+}
+
+// runJob 提交任务并轮询到结束，途中出现无法识别的状态值即视为失败
+func runJob(ctx context.Context, prov *provider.HTTPProvider, prompt string) (*provider.StatusResult, error) {
+	submitted, err := prov.Submit(ctx, contractRequest(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("submit: %w", err)
+	}
+	if submitted.Result != nil {
+		return submitted.Result, nil
+	}
+	if submitted.ProviderJobID == "" {
+		return nil, fmt.Errorf("submit returned empty job ID")
+	}
+
+	deadline := time.Now().Add(contractJobTimeout)
+	for time.Now().Before(deadline) {
+		status, err := prov.Status(ctx, submitted.ProviderJobID)
+		if err != nil {
+			return nil, fmt.Errorf("status: %w", err)
+		}
+		switch status.Status {
+		case provider.JobStatusSucceeded, provider.JobStatusFailed:
+			return status, nil
+		case provider.JobStatusUnknown:
+			return nil, fmt.Errorf("unrecognized status value %q", status.RawStatus)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(contractPollInterval):
+		}
+	}
+	return nil, fmt.Errorf("job %s did not finish within %s", submitted.ProviderJobID, contractJobTimeout)
+}
+
+// fetchArtifact 确认产物可以下载且为图片
+func fetchArtifact(ctx context.Context, storage *MemoryStorage, a provider.Artifact) error {
+	if a.ObjectKey != "" {
+		if _, ok := storage.Object(a.ObjectKey); !ok {
+			return fmt.Errorf("artifact %s was not uploaded", a.ObjectKey)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download artifact: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read artifact: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("artifact download returned status %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(http.DetectContentType(data), "image/") {
+		return fmt.Errorf("artifact is not an image")
+	}
+	return nil
+}
+
+func withBadCredentials(cfg provider.ProviderConfig) provider.ProviderConfig {
+	cfg.APIKey = "wrong-key"
+	if cfg.Auth != nil {
+		auth := *cfg.Auth
+		auth.SecretKey = "wrong-secret"
+		auth.ClientSecret = "wrong-secret"
+		cfg.Auth = &auth
+	}
+	return cfg
+}
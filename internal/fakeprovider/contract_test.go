@@ -0,0 +1,56 @@
+package fakeprovider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBuiltinProfilesPassContract(t *testing.T) {
+	profiles := BuiltinProfiles()
+	server, err := StartLocal(profiles...)
+	if err != nil {
+		t.Fatalf("StartLocal: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	for _, p := range profiles {
+		p := p
+		t.Run(p.Name, func(t *testing.T) {
+			t.Parallel()
+
+			result := RunContract(context.Background(), p, server.URL())
+			for _, c := range result.Checks {
+				switch {
+				case c.Error != "":
+					t.Errorf("%s: %s", c.Name, c.Error)
+				case c.Skipped:
+					t.Logf("%s: skipped", c.Name)
+				}
+			}
+			if len(result.Checks) == 0 {
+				t.Fatal("no checks were run")
+			}
+		})
+	}
+}
+
+func TestBuiltinProfilesAreValid(t *testing.T) {
+	seen := make(map[string]bool)
+	shapes := make(map[string]bool)
+	for _, p := range BuiltinProfiles() {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s: %v", p.Name, err)
+		}
+		if seen[p.Name] {
+			t.Errorf("duplicate profile name %s", p.Name)
+		}
+		seen[p.Name] = true
+		shapes[p.Shape] = true
+	}
+
+	for _, shape := range []string{ShapeNested, ShapeFlat, ShapeEnvelope, ShapeSyncB64, ShapeAsyncB64} {
+		if !shapes[shape] {
+			t.Errorf("no builtin profile for shape %s", shape)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package fakeprovider 模拟各类异步生成接口，用于在没有网络的环境下
+// 对 HTTPProvider 与 Mapper 做端到端的契约测试。
+package fakeprovider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xiaohongshu-image/internal/services/provider"
+)
+
+// 响应结构
+const (
+	// ShapeNested 提交返回 {"data":{"id"}}，状态返回 {"status","progress","output":{"url"},"error"}
+	ShapeNested = "nested"
+	// ShapeFlat 提交返回 {"task_id"}，状态返回 {"state","percent","result_urls":[...],"message"}
+	ShapeFlat = "flat"
+	// ShapeEnvelope 所有响应包在 {"code","msg","data"} 中，产物为带尺寸的 outputs 数组
+	ShapeEnvelope = "envelope"
+	// ShapeSyncB64 提交即返回 {"created","data":[{"b64_json"}]}，没有状态接口
+	ShapeSyncB64 = "sync_b64"
+	// ShapeAsyncB64 提交返回 {"id"}，状态返回 {"status","images":[{"b64_json"}],"error"}，产物内联在状态响应中
+	ShapeAsyncB64 = "async_b64"
+)
+
+// FailMarker 出现在提交请求体中时任务必定失败，用于稳定地覆盖失败路径
+const FailMarker = "[fail]"
+
+// AuthSpec 描述模拟接口要求的鉴权方式，与 provider.AuthConfig 的类型一一对应
+type AuthSpec struct {
+	Type         string `json:"type"`
+	Key          string `json:"key,omitempty"`
+	HeaderName   string `json:"header_name,omitempty"`
+	Prefix       string `json:"prefix,omitempty"`
+	QueryParam   string `json:"query_param,omitempty"`
+	AccessKey    string `json:"access_key,omitempty"`
+	SecretKey    string `json:"secret_key,omitempty"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	TokenTTLSec  int    `json:"token_ttl_sec,omitempty"`
+}
+
+// Profile 描述一个模拟供应商。
+// StatusValues 将 pending/running/succeeded/failed 映射为接口返回的原始状态值，纯数字的值以 JSON 数字返回。
+// FailureRate 为任务失败的概率，ErrorRate 为任意请求返回 503 的概率。
+type Profile struct {
+	Name          string            `json:"name"`
+	Shape         string            `json:"shape"`
+	Auth          AuthSpec          `json:"auth"`
+	StatusValues  map[string]string `json:"status_values,omitempty"`
+	LatencyMs     int               `json:"latency_ms,omitempty"`
+	JitterMs      int               `json:"jitter_ms,omitempty"`
+	JobDurationMs int               `json:"job_duration_ms,omitempty"`
+	FailureRate   float64           `json:"failure_rate,omitempty"`
+	ErrorRate     float64           `json:"error_rate,omitempty"`
+	Cancelable    bool              `json:"cancelable,omitempty"`
+}
+
+const defaultJobDurationMs = 2000
+
+var defaultStatusValues = map[string]string{
+	string(provider.JobStatusPending):   "pending",
+	string(provider.JobStatusRunning):   "running",
+	string(provider.JobStatusSucceeded): "succeeded",
+	string(provider.JobStatusFailed):    "failed",
+}
+
+// Validate 检查 Profile 的取值
+func (p *Profile) Validate() error {
+	if p.Name == "" || strings.ContainsAny(p.Name, "/ ") {
+		return fmt.Errorf("invalid profile name: %q", p.Name)
+	}
+
+	switch p.Shape {
+	case ShapeNested, ShapeFlat, ShapeEnvelope, ShapeSyncB64, ShapeAsyncB64:
+	default:
+		return fmt.Errorf("profile %s: unsupported shape: %s", p.Name, p.Shape)
+	}
+
+	switch p.Auth.Type {
+	case "", "none", provider.AuthTypeBearer, provider.AuthTypeHeader, provider.AuthTypeQuery, provider.AuthTypeHMAC, provider.AuthTypeOAuth2:
+	default:
+		return fmt.Errorf("profile %s: unsupported auth type: %s", p.Name, p.Auth.Type)
+	}
+
+	for key := range p.StatusValues {
+		if _, ok := defaultStatusValues[key]; !ok {
+			return fmt.Errorf("profile %s: unknown status %s", p.Name, key)
+		}
+	}
+
+	if p.FailureRate < 0 || p.FailureRate > 1 || p.ErrorRate < 0 || p.ErrorRate > 1 {
+		return fmt.Errorf("profile %s: failure_rate and error_rate must be within [0, 1]", p.Name)
+	}
+	if p.LatencyMs < 0 || p.JitterMs < 0 || p.JobDurationMs < 0 {
+		return fmt.Errorf("profile %s: latency and duration must not be negative", p.Name)
+	}
+	return nil
+}
+
+func (p *Profile) rawStatus(status provider.JobStatus) string {
+	if v, ok := p.StatusValues[string(status)]; ok {
+		return v
+	}
+	return defaultStatusValues[string(status)]
+}
+
+func (p *Profile) jobDurationMs() int64 {
+	if p.JobDurationMs > 0 {
+		return int64(p.JobDurationMs)
+	}
+	return defaultJobDurationMs
+}
+
+// BuiltinProfiles 覆盖全部响应结构与鉴权方式，以及几种常见的状态词表
+func BuiltinProfiles() []Profile {
+	return []Profile{
+		{
+			Name:       "nested-bearer",
+			Shape:      ShapeNested,
+			Auth:       AuthSpec{Type: provider.AuthTypeBearer, Key: "fake-bearer-key"},
+			LatencyMs:  20,
+			JitterMs:   20,
+			Cancelable: true,
+		},
+		{
+			Name:  "flat-header",
+			Shape: ShapeFlat,
+			Auth:  AuthSpec{Type: provider.AuthTypeHeader, Key: "fake-header-key", HeaderName: "X-API-Key"},
+			StatusValues: map[string]string{
+				"pending":   "QUEUED",
+				"running":   "PROCESSING",
+				"succeeded": "SUCCESS",
+				"failed":    "FAILURE",
+			},
+			LatencyMs: 50,
+		},
+		{
+			Name:  "envelope-hmac",
+			Shape: ShapeEnvelope,
+			Auth:  AuthSpec{Type: provider.AuthTypeHMAC, AccessKey: "fake-access-key", SecretKey: "fake-secret-key"},
+			StatusValues: map[string]string{
+				"pending":   "0",
+				"running":   "1",
+				"succeeded": "2",
+				"failed":    "-1",
+			},
+			LatencyMs:  30,
+			Cancelable: true,
+		},
+		{
+			Name:  "nested-oauth2",
+			Shape: ShapeNested,
+			Auth:  AuthSpec{Type: provider.AuthTypeOAuth2, ClientID: "fake-client", ClientSecret: "fake-client-secret", TokenTTLSec: 3600},
+			StatusValues: map[string]string{
+				"pending":   "submitted",
+				"running":   "in_progress",
+				"succeeded": "completed",
+				"failed":    "error",
+			},
+		},
+		{
+			Name:      "sync-query",
+			Shape:     ShapeSyncB64,
+			Auth:      AuthSpec{Type: provider.AuthTypeQuery, Key: "fake-query-key", QueryParam: "api_key"},
+			LatencyMs: 100,
+		},
+		{
+			Name:      "async-b64",
+			Shape:     ShapeAsyncB64,
+			Auth:      AuthSpec{Type: provider.AuthTypeBearer, Key: "fake-async-key"},
+			LatencyMs: 20,
+		},
+	}
+}
+
+// SampleConfig 返回对接该 Profile 的供应商配置，baseURL 为模拟服务器的根地址
+func SampleConfig(p Profile, baseURL string) provider.ProviderConfig {
+	base := strings.TrimRight(baseURL, "/") + "/" + p.Name
+	cfg := provider.ProviderConfig{
+		ProviderName:       p.Name,
+		Type:               "fake",
+		BaseURL:            base,
+		SubmitPath:         "/submit",
+		StatusPathTemplate: "/jobs/{id}",
+		HealthCheck:        &provider.HealthCheckConfig{Path: "/health"},
+		StatusValues:       make(map[string]string),
+	}
+
+	for _, status := range []provider.JobStatus{provider.JobStatusPending, provider.JobStatusRunning, provider.JobStatusSucceeded, provider.JobStatusFailed} {
+		cfg.StatusValues[p.rawStatus(status)] = string(status)
+	}
+	if p.Cancelable {
+		cfg.CancelPathTemplate = "/jobs/{id}/cancel"
+	}
+
+	switch p.Shape {
+	case ShapeNested:
+		cfg.RequestMapping = map[string]interface{}{
+			"prompt": "$.prompt",
+			"type":   "$.type",
+		}
+		cfg.ResponseMapping = map[string]string{
+			"job_id_jsonpath":     "$.data.id",
+			"status_jsonpath":     "$.status",
+			"progress_jsonpath":   "$.progress",
+			"result_url_jsonpath": "$.output.url",
+			"error_jsonpath":      "$.error",
+		}
+	case ShapeFlat:
+		cfg.RequestMapping = map[string]interface{}{
+			"input": map[string]interface{}{"text": "$.prompt"},
+			"kind":  "$.type",
+		}
+		cfg.ResponseMapping = map[string]string{
+			"job_id_jsonpath":     "$.task_id",
+			"status_jsonpath":     "$.state",
+			"progress_jsonpath":   "$.percent",
+			"result_url_jsonpath": "$.result_urls[*]",
+			"error_jsonpath":      "$.message",
+		}
+	case ShapeEnvelope:
+		cfg.RequestMapping = map[string]interface{}{
+			"req_key": "{{ .Request.Type }}_generation",
+			"params": map[string]interface{}{
+				"prompt": "$.prompt",
+				"seed":   "$.seed",
+			},
+		}
+		cfg.ResponseMapping = map[string]string{
+			"job_id_jsonpath":    "$.data.job_id",
+			"status_jsonpath":    "$.data.task_status",
+			"progress_jsonpath":  "$.data.progress",
+			"artifacts_jsonpath": "$.data.outputs[*]",
+			"error_jsonpath":     "$.data.fail_reason",
+		}
+	case ShapeAsyncB64:
+		cfg.RequestMapping = map[string]interface{}{
+			"prompt": "$.prompt",
+		}
+		cfg.ResponseMapping = map[string]string{
+			"job_id_jsonpath":     "$.id",
+			"status_jsonpath":     "$.status",
+			"result_b64_jsonpath": "$.images[*].b64_json",
+			"error_jsonpath":      "$.error",
+		}
+	case ShapeSyncB64:
+		cfg.Mode = provider.ProviderModeSync
+		cfg.StatusPathTemplate = ""
+		cfg.CancelPathTemplate = ""
+		cfg.RequestMapping = map[string]interface{}{
+			"model":           "fake-image",
+			"prompt":          "$.prompt",
+			"response_format": "b64_json",
+		}
+		cfg.ResponseMapping = map[string]string{
+			"result_b64_jsonpath": "$.data[*].b64_json",
+			"error_jsonpath":      "$.error.message",
+		}
+	}
+
+	switch p.Auth.Type {
+	case provider.AuthTypeBearer:
+		cfg.APIKey = p.Auth.Key
+	case provider.AuthTypeHeader:
+		cfg.APIKey = p.Auth.Key
+		cfg.Auth = &provider.AuthConfig{Type: provider.AuthTypeHeader, HeaderName: p.Auth.HeaderName, Prefix: p.Auth.Prefix}
+	case provider.AuthTypeQuery:
+		cfg.APIKey = p.Auth.Key
+		cfg.Auth = &provider.AuthConfig{Type: provider.AuthTypeQuery, QueryParam: p.Auth.QueryParam}
+	case provider.AuthTypeHMAC:
+		cfg.Auth = &provider.AuthConfig{Type: provider.AuthTypeHMAC, AccessKey: p.Auth.AccessKey, SecretKey: p.Auth.SecretKey}
+	case provider.AuthTypeOAuth2:
+		cfg.Auth = &provider.AuthConfig{
+			Type:         provider.AuthTypeOAuth2,
+			TokenURL:     base + "/oauth/token",
+			ClientID:     p.Auth.ClientID,
+			ClientSecret: p.Auth.ClientSecret,
+		}
+	}
+
+	return cfg
+}
@@ -0,0 +1,522 @@
+package fakeprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/services/provider"
+)
+
+const (
+	// hmacMaxSkew 为 HMAC 签名时间戳允许的最大偏差
+	hmacMaxSkew     = 5 * time.Minute
+	defaultTokenTTL = time.Hour
+	outputSide      = 64
+)
+
+// Server 按 Profile 模拟供应商接口，每个 Profile 挂载在 /<name>/ 下：
+//
+//	POST /<name>/submit             提交任务
+//	GET  /<name>/jobs/<id>          查询状态
+//	POST /<name>/jobs/<id>/cancel   取消任务（Cancelable 为 true 时）
+//	GET  /<name>/health             健康检查
+//	POST /<name>/oauth/token        OAuth2 client credentials 令牌
+//	GET  /<name>/files/<id>.png     任务产物，无需鉴权
+//
+// 任务状态只保存在内存中，进度按提交后经过的时间计算。
+type Server struct {
+	profiles map[string]*Profile
+	names    []string
+
+	mu     sync.Mutex
+	rnd    *rand.Rand
+	seq    int64
+	jobs   map[string]*job
+	tokens map[string]time.Time
+
+	listener net.Listener
+	httpSrv  *http.Server
+}
+
+type job struct {
+	ID        string
+	CreatedAt time.Time
+	Duration  time.Duration
+	Fail      bool
+	Cancelled bool
+}
+
+func NewServer(profiles []Profile) (*Server, error) {
+	s := &Server{
+		profiles: make(map[string]*Profile),
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		jobs:     make(map[string]*job),
+		tokens:   make(map[string]time.Time),
+	}
+	for i := range profiles {
+		p := profiles[i]
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := s.profiles[p.Name]; exists {
+			return nil, fmt.Errorf("duplicate profile: %s", p.Name)
+		}
+		s.profiles[p.Name] = &p
+		s.names = append(s.names, p.Name)
+	}
+	return s, nil
+}
+
+// StartLocal 在本机随机端口上启动模拟服务器，未指定 Profile 时使用内置 Profile
+func StartLocal(profiles ...Profile) (*Server, error) {
+	if len(profiles) == 0 {
+		profiles = BuiltinProfiles()
+	}
+	s, err := NewServer(profiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Start("127.0.0.1:0"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start 在 addr 上监听并在后台提供服务
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s.listener = listener
+	s.httpSrv = &http.Server{Handler: s}
+	go s.httpSrv.Serve(listener)
+	return nil
+}
+
+// URL 返回服务器的根地址，仅在 Start 之后有效
+func (s *Server) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+	return "http://" + s.listener.Addr().String()
+}
+
+func (s *Server) Close() error {
+	if s.httpSrv == nil {
+		return nil
+	}
+	return s.httpSrv.Close()
+}
+
+// Profiles 返回服务器上挂载的 Profile，顺序与创建时一致
+func (s *Server) Profiles() []Profile {
+	profiles := make([]Profile, 0, len(s.names))
+	for _, name := range s.names {
+		profiles = append(profiles, *s.profiles[name])
+	}
+	return profiles
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 2)
+	if parts[0] == "" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"profiles": s.names})
+		return
+	}
+
+	p, ok := s.profiles[parts[0]]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown profile"})
+		return
+	}
+	route := ""
+	if len(parts) == 2 {
+		route = parts[1]
+	}
+
+	if strings.HasPrefix(route, "files/") && r.Method == http.MethodGet {
+		s.serveFile(w, strings.TrimSuffix(strings.TrimPrefix(route, "files/"), ".png"))
+		return
+	}
+
+	if !s.delay(r.Context(), p) {
+		return
+	}
+	if s.chance(p.ErrorRate) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "service temporarily unavailable"})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read body"})
+		return
+	}
+
+	if route == "oauth/token" && r.Method == http.MethodPost {
+		s.issueToken(w, r, p, body)
+		return
+	}
+
+	if err := s.authenticate(r, body, p); err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch {
+	case route == "health" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case route == "submit" && r.Method == http.MethodPost:
+		s.submit(w, r, p, body)
+	case strings.HasPrefix(route, "jobs/") && strings.HasSuffix(route, "/cancel") && r.Method == http.MethodPost:
+		if !p.Cancelable {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "cancel not supported"})
+			return
+		}
+		s.cancel(w, p, strings.TrimSuffix(strings.TrimPrefix(route, "jobs/"), "/cancel"))
+	case strings.HasPrefix(route, "jobs/") && r.Method == http.MethodGet:
+		s.status(w, r, p, strings.TrimPrefix(route, "jobs/"))
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request, p *Profile, body []byte) {
+	if !json.Valid(body) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "request body is not valid JSON"})
+		return
+	}
+
+	fail := bytes.Contains(body, []byte(FailMarker)) || s.chance(p.FailureRate)
+
+	s.mu.Lock()
+	s.seq++
+	j := &job{
+		ID:        fmt.Sprintf("%s-%06d", p.Name, s.seq),
+		CreatedAt: time.Now(),
+		Duration:  time.Duration(p.jobDurationMs()) * time.Millisecond,
+		Fail:      fail,
+	}
+	s.jobs[j.ID] = j
+	s.mu.Unlock()
+
+	switch p.Shape {
+	case ShapeNested:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]string{"id": j.ID}})
+	case ShapeFlat:
+		writeJSON(w, http.StatusOK, map[string]string{"task_id": j.ID})
+	case ShapeEnvelope:
+		writeJSON(w, http.StatusOK, envelope(map[string]string{"job_id": j.ID}))
+	case ShapeAsyncB64:
+		writeJSON(w, http.StatusOK, map[string]string{"id": j.ID})
+	case ShapeSyncB64:
+		if fail {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"created": j.CreatedAt.Unix(),
+				"data":    []interface{}{},
+				"error":   map[string]string{"message": "simulated generation failure"},
+			})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"created": j.CreatedAt.Unix(),
+			"data":    []map[string]string{{"b64_json": base64.StdEncoding.EncodeToString(renderOutput(j.ID))}},
+		})
+	}
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request, p *Profile, id string) {
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	var snapshot job
+	if ok {
+		snapshot = *j
+	}
+	s.mu.Unlock()
+	if !ok || !strings.HasPrefix(id, p.Name+"-") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+
+	status, progress, errMsg := snapshot.state(time.Now())
+	raw := rawValue(p.rawStatus(status))
+	fileURL := fmt.Sprintf("http://%s/%s/files/%s.png", r.Host, p.Name, id)
+
+	switch p.Shape {
+	case ShapeNested:
+		resp := map[string]interface{}{"status": raw, "progress": progress}
+		if status == provider.JobStatusSucceeded {
+			resp["output"] = map[string]string{"url": fileURL}
+		}
+		if errMsg != "" {
+			resp["error"] = errMsg
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case ShapeFlat:
+		resp := map[string]interface{}{"task_id": id, "state": raw, "percent": progress, "result_urls": []string{}}
+		if status == provider.JobStatusSucceeded {
+			resp["result_urls"] = []string{fileURL, strings.TrimSuffix(fileURL, ".png") + "-alt.png"}
+		}
+		if errMsg != "" {
+			resp["message"] = errMsg
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case ShapeEnvelope:
+		data := map[string]interface{}{"job_id": id, "task_status": raw, "progress": progress}
+		if status == provider.JobStatusSucceeded {
+			data["outputs"] = []map[string]interface{}{{
+				"type":      "image",
+				"url":       fileURL,
+				"mime_type": "image/png",
+				"width":     outputSide,
+				"height":    outputSide,
+			}}
+		}
+		if errMsg != "" {
+			data["fail_reason"] = errMsg
+		}
+		writeJSON(w, http.StatusOK, envelope(data))
+	case ShapeAsyncB64:
+		resp := map[string]interface{}{"status": raw, "images": []interface{}{}}
+		if status == provider.JobStatusSucceeded {
+			resp["images"] = []map[string]string{{"b64_json": base64.StdEncoding.EncodeToString(renderOutput(id))}}
+		}
+		if errMsg != "" {
+			resp["error"] = errMsg
+		}
+		writeJSON(w, http.StatusOK, resp)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "status not supported"})
+	}
+}
+
+func (s *Server) cancel(w http.ResponseWriter, p *Profile, id string) {
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	if ok {
+		j.Cancelled = true
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+
+	if p.Shape == ShapeEnvelope {
+		writeJSON(w, http.StatusOK, envelope(map[string]string{"job_id": id}))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"cancelled": true})
+}
+
+// state 按经过的时间计算任务状态：前 20% 为 pending，之后为 running，到期后结束
+func (j *job) state(now time.Time) (provider.JobStatus, int, string) {
+	if j.Cancelled {
+		return provider.JobStatusFailed, 100, "cancelled"
+	}
+
+	elapsed := now.Sub(j.CreatedAt)
+	switch {
+	case elapsed < j.Duration/5:
+		return provider.JobStatusPending, 0, ""
+	case elapsed < j.Duration:
+		return provider.JobStatusRunning, int(10 + 80*elapsed/j.Duration), ""
+	case j.Fail:
+		return provider.JobStatusFailed, 100, "simulated generation failure"
+	default:
+		return provider.JobStatusSucceeded, 100, ""
+	}
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	_, ok := s.jobs[strings.TrimSuffix(id, "-alt")]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "file not found"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(renderOutput(id))
+}
+
+func (s *Server) authenticate(r *http.Request, body []byte, p *Profile) error {
+	a := p.Auth
+	switch a.Type {
+	case provider.AuthTypeBearer:
+		if r.Header.Get("Authorization") != "Bearer "+a.Key {
+			return fmt.Errorf("invalid bearer token")
+		}
+	case provider.AuthTypeHeader:
+		if r.Header.Get(a.HeaderName) != a.Prefix+a.Key {
+			return fmt.Errorf("invalid %s header", a.HeaderName)
+		}
+	case provider.AuthTypeQuery:
+		if r.URL.Query().Get(a.QueryParam) != a.Key {
+			return fmt.Errorf("invalid %s query parameter", a.QueryParam)
+		}
+	case provider.AuthTypeHMAC:
+		return verifyHMAC(r, body, a)
+	case provider.AuthTypeOAuth2:
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		expiresAt, ok := s.tokens[token]
+		s.mu.Unlock()
+		if !ok || time.Now().After(expiresAt) {
+			return fmt.Errorf("invalid or expired access token")
+		}
+	}
+	return nil
+}
+
+// verifyHMAC 使用与 provider 相同的默认签名模板校验请求
+func verifyHMAC(r *http.Request, body []byte, a AuthSpec) error {
+	if r.Header.Get("X-Access-Key") != a.AccessKey {
+		return fmt.Errorf("invalid access key")
+	}
+
+	timestamp := r.Header.Get("X-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > hmacMaxSkew || skew < -hmacMaxSkew {
+		return fmt.Errorf("timestamp out of range")
+	}
+
+	stringToSign := provider.BuildStringToSign("", r, body, a.AccessKey, timestamp, r.Header.Get("X-Nonce"))
+	expected := provider.SignHMAC(sha256.New, a.SecretKey, stringToSign, "hex")
+	signature, err := hex.DecodeString(r.Header.Get("X-Signature"))
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding")
+	}
+	expectedBytes, _ := hex.DecodeString(expected)
+	if !hmac.Equal(signature, expectedBytes) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func (s *Server) issueToken(w http.ResponseWriter, r *http.Request, p *Profile, body []byte) {
+	if p.Auth.Type != provider.AuthTypeOAuth2 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != p.Auth.ClientID || clientSecret != p.Auth.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil || form.Get("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	ttl := defaultTokenTTL
+	if p.Auth.TokenTTLSec > 0 {
+		ttl = time.Duration(p.Auth.TokenTTLSec) * time.Second
+	}
+
+	s.mu.Lock()
+	s.seq++
+	token := fmt.Sprintf("fake-token-%d-%d", s.seq, s.rnd.Int63())
+	s.tokens[token] = time.Now().Add(ttl)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   int(ttl.Seconds()),
+	})
+}
+
+// delay 模拟接口延迟，请求被取消时返回 false
+func (s *Server) delay(ctx context.Context, p *Profile) bool {
+	d := time.Duration(p.LatencyMs) * time.Millisecond
+	if p.JitterMs > 0 {
+		s.mu.Lock()
+		d += time.Duration(s.rnd.Intn(p.JitterMs+1)) * time.Millisecond
+		s.mu.Unlock()
+	}
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Server) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rnd.Float64() < rate
+}
+
+func envelope(data interface{}) map[string]interface{} {
+	return map[string]interface{}{"code": 0, "msg": "success", "data": data}
+}
+
+// rawValue 将纯数字的状态值作为 JSON 数字返回
+func rawValue(raw string) interface{} {
+	if _, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return json.Number(raw)
+	}
+	return raw
+}
+
+// renderOutput 生成一张颜色由 ID 决定的纯色 PNG
+func renderOutput(id string) []byte {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	sum := h.Sum32()
+	c := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, outputSide, outputSide))
+	for y := 0; y < outputSide; y++ {
+		for x := 0; x < outputSide; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -7,8 +7,9 @@ import (
 type ConnectorMode string
 
 const (
-	ConnectorModeMock ConnectorMode = "mock"
-	ConnectorModeMCP  ConnectorMode = "mcp"
+	ConnectorModeMock   ConnectorMode = "mock"
+	ConnectorModeMCP    ConnectorMode = "mcp"
+	ConnectorModeReplay ConnectorMode = "replay"
 )
 
 type TaskStatus string
@@ -21,6 +22,9 @@ const (
 	TaskStatusSucceeded TaskStatus = "SUCCEEDED"
 	TaskStatusEmailed   TaskStatus = "EMAILED"
 	TaskStatusFailed    TaskStatus = "FAILED"
+	TaskStatusCancelled TaskStatus = "CANCELLED"
+	// TaskStatusQueuedBudget 表示提交会超出花费预算，等待预算窗口重置或运营人员批准
+	TaskStatusQueuedBudget TaskStatus = "QUEUED_BUDGET"
 )
 
 type RequestType string
@@ -28,6 +32,8 @@ type RequestType string
 const (
 	RequestTypeImage RequestType = "image"
 	RequestTypeVideo RequestType = "video"
+	// RequestTypeEdit 为基于评论附图的图生图请求
+	RequestTypeEdit RequestType = "edit"
 )
 
 type DeliveryStatus string
@@ -37,66 +43,201 @@ const (
 	DeliveryStatusFailed DeliveryStatus = "FAILED"
 )
 
+const (
+	NoteSourceManual     = "manual"
+	NoteSourceDiscovered = "discovered"
+	NoteSourcePushed     = "pushed"
+)
+
+// ReplyStage 为在平台上回复评论的时机
+type ReplyStage string
+
+const (
+	// ReplyStageReceived 已识别出生成请求并创建任务
+	ReplyStageReceived ReplyStage = "received"
+	// ReplyStageMissingEmail 评论包含生成请求但没有有效邮箱
+	ReplyStageMissingEmail ReplyStage = "missing_email"
+	// ReplyStageSent 结果已发送到邮箱
+	ReplyStageSent ReplyStage = "sent"
+)
+
+// Setting 为全局配置。ReplyTemplates 为各回复阶段的模板（JSON 对象，键为 ReplyStage），
+// 未配置的阶段使用默认模板。WatchAccount 非空时每 DiscoveryIntervalSec 秒发现该账号的新笔记，
+// 发布超过 NoteMaxAgeDays 天的自动发现笔记停止轮询，0 表示不停止。AlertEmail 接收登录过期等运维告警。
 type Setting struct {
-	ID                 uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	ConnectorMode      string    `gorm:"type:varchar(20);not null;default:'mock'" json:"connector_mode"`
-	MCPServerCmd       *string   `gorm:"type:text" json:"mcp_server_cmd,omitempty"`
-	MCPServerURL       *string   `gorm:"type:varchar(500)" json:"mcp_server_url,omitempty"`
-	MCPAuth            *string   `gorm:"type:text" json:"mcp_auth,omitempty"`
-	NoteTarget         string    `gorm:"type:varchar(500);not null" json:"note_target"`
-	PollingIntervalSec int       `gorm:"not null;default:120" json:"polling_interval_sec"`
-	LLMBaseURL         *string   `gorm:"type:varchar(500)" json:"llm_base_url,omitempty"`
-	LLMAPIKey          *string   `gorm:"type:varchar(200)" json:"llm_api_key,omitempty"`
-	LLMModel           *string   `gorm:"type:varchar(100)" json:"llm_model,omitempty"`
-	LLMTimeoutSec      int       `gorm:"default:15" json:"llm_timeout_sec"`
-	IntentThreshold    float64   `gorm:"type:decimal(3,2);not null;default:0.70" json:"intent_threshold"`
-	SMTPHost           *string   `gorm:"type:varchar(200)" json:"smtp_host,omitempty"`
-	SMTPPort           *int      `json:"smtp_port,omitempty"`
-	SMTPUser           *string   `gorm:"type:varchar(200)" json:"smtp_user,omitempty"`
-	SMTPPass           *string   `gorm:"type:varchar(200)" json:"smtp_pass,omitempty"`
-	SMTPFrom           *string   `gorm:"type:varchar(200)" json:"smtp_from,omitempty"`
-	ProviderJSON       string    `gorm:"type:json" json:"provider_json"`
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	ID                   uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConnectorMode        string    `gorm:"type:varchar(20);not null;default:'mock'" json:"connector_mode"`
+	MCPServerCmd         *string   `gorm:"type:text" json:"mcp_server_cmd,omitempty"`
+	MCPServerURL         *string   `gorm:"type:varchar(500)" json:"mcp_server_url,omitempty"`
+	MCPAuth              *string   `gorm:"type:text" json:"mcp_auth,omitempty"`
+	NoteTarget           string    `gorm:"type:varchar(500);not null" json:"note_target"`
+	PollingIntervalSec   int       `gorm:"not null;default:120" json:"polling_interval_sec"`
+	LLMBaseURL           *string   `gorm:"type:varchar(500)" json:"llm_base_url,omitempty"`
+	LLMAPIKey            *string   `gorm:"type:varchar(200)" json:"llm_api_key,omitempty"`
+	LLMModel             *string   `gorm:"type:varchar(100)" json:"llm_model,omitempty"`
+	LLMTimeoutSec        int       `gorm:"default:15" json:"llm_timeout_sec"`
+	IntentThreshold      float64   `gorm:"type:decimal(3,2);not null;default:0.70" json:"intent_threshold"`
+	SMTPHost             *string   `gorm:"type:varchar(200)" json:"smtp_host,omitempty"`
+	SMTPPort             *int      `json:"smtp_port,omitempty"`
+	SMTPUser             *string   `gorm:"type:varchar(200)" json:"smtp_user,omitempty"`
+	SMTPPass             *string   `gorm:"type:varchar(200)" json:"smtp_pass,omitempty"`
+	SMTPFrom             *string   `gorm:"type:varchar(200)" json:"smtp_from,omitempty"`
+	ProviderJSON         string    `gorm:"type:json" json:"provider_json"`
+	DailyBudget          *float64  `gorm:"type:decimal(12,4)" json:"daily_budget,omitempty"`
+	MonthlyBudget        *float64  `gorm:"type:decimal(12,4)" json:"monthly_budget,omitempty"`
+	ReplyTemplates       *string   `gorm:"type:json" json:"reply_templates,omitempty"`
+	WatchAccount         *string   `gorm:"type:varchar(500)" json:"watch_account,omitempty"`
+	DiscoveryIntervalSec int       `gorm:"not null;default:3600" json:"discovery_interval_sec"`
+	NoteMaxAgeDays       int       `gorm:"not null;default:30" json:"note_max_age_days"`
+	AlertEmail           *string   `gorm:"type:varchar(200)" json:"alert_email,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
 }
 
 func (Setting) TableName() string {
 	return "settings"
 }
 
+// Note 为轮询的笔记，Watched 为 false 的笔记不再定时轮询（RetiredAt 为自动停止的时间）。
+// ReplyEnabled 为 true 时才在该笔记下回复评论，默认关闭以免刷屏。
+// ConsecutiveFailures 为连续拉取失败次数，LastErrorKind 为最近一次平台错误的类型，
+// 被限流或要求验证码时 NextPollAt 之前不再拉取。
 type Note struct {
-	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	NoteTarget   string     `gorm:"type:varchar(500);uniqueIndex;not null" json:"note_target"`
-	LastCursor   *string    `gorm:"type:text" json:"last_cursor,omitempty"`
-	LastPolledAt *time.Time `json:"last_polled_at,omitempty"`
-	LastError    *string    `gorm:"type:text" json:"last_error,omitempty"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID                  uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	NoteTarget          string     `gorm:"type:varchar(500);uniqueIndex;not null" json:"note_target"`
+	LastCursor          *string    `gorm:"type:text" json:"last_cursor,omitempty"`
+	LastPolledAt        *time.Time `json:"last_polled_at,omitempty"`
+	LastError           *string    `gorm:"type:text" json:"last_error,omitempty"`
+	LastErrorKind       *string    `gorm:"type:varchar(30)" json:"last_error_kind,omitempty"`
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+	NextPollAt          *time.Time `json:"next_poll_at,omitempty"`
+	ReplyEnabled        bool       `gorm:"not null;default:false" json:"reply_enabled"`
+	Watched             bool       `gorm:"not null;default:true;index:idx_watched" json:"watched"`
+	Source              string     `gorm:"type:varchar(20);not null;default:'manual'" json:"source"`
+	Title               *string    `gorm:"type:varchar(500)" json:"title,omitempty"`
+	PublishedAt         *time.Time `json:"published_at,omitempty"`
+	RetiredAt           *time.Time `json:"retired_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
 }
 
 func (Note) TableName() string {
 	return "notes"
 }
 
+// Comment 为入库的评论。楼中楼回复的 ParentID 指向被回复的评论，Depth 为回复层级（一级评论为 0）；
+// 一级评论的 SubCursor、SubPolledAt 记录其回复的拉取进度，SubCommentCount 为平台返回的回复数。
+// 连接器返回平台用户 ID 时 CommenterID 指向对应的评论用户。
 type Comment struct {
-	ID               uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	NoteTarget       string     `gorm:"type:varchar(500);not null;index:idx_note_target" json:"note_target"`
-	CommentUID       string     `gorm:"type:varchar(100);uniqueIndex:uk_comment_uid;not null" json:"comment_uid"`
-	UserName         *string    `gorm:"type:varchar(200)" json:"user_name,omitempty"`
-	Content          string     `gorm:"type:text" json:"content"`
-	CommentCreatedAt *time.Time `json:"comment_created_at,omitempty"`
-	IngestedAt       time.Time  `json:"ingested_at"`
+	ID               uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	NoteTarget       string         `gorm:"type:varchar(500);not null;index:idx_note_target" json:"note_target"`
+	CommentUID       string         `gorm:"type:varchar(100);uniqueIndex:uk_comment_uid;not null" json:"comment_uid"`
+	ParentID         *uint          `gorm:"index:idx_parent_id" json:"parent_id,omitempty"`
+	Depth            int            `gorm:"not null;default:0" json:"depth"`
+	CommenterID      *uint          `gorm:"index:idx_commenter_id" json:"commenter_id,omitempty"`
+	UserName         *string        `gorm:"type:varchar(200)" json:"user_name,omitempty"`
+	Content          string         `gorm:"type:text" json:"content"`
+	CommentCreatedAt *time.Time     `json:"comment_created_at,omitempty"`
+	IngestedAt       time.Time      `json:"ingested_at"`
+	SubCommentCount  int            `gorm:"not null;default:0" json:"sub_comment_count"`
+	SubCursor        *string        `gorm:"type:varchar(200)" json:"-"`
+	SubPolledAt      *time.Time     `json:"sub_polled_at,omitempty"`
+	Images           []CommentImage `gorm:"foreignKey:CommentID" json:"images,omitempty"`
+	Replies          []CommentReply `gorm:"foreignKey:CommentID" json:"replies,omitempty"`
+	Parent           *Comment       `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
+	Children         []Comment      `gorm:"foreignKey:ParentID" json:"children,omitempty"`
+	Commenter        *Commenter     `gorm:"foreignKey:CommenterID" json:"commenter,omitempty"`
+	Task             *Task          `gorm:"foreignKey:CommentID" json:"task,omitempty"`
 }
 
 func (Comment) TableName() string {
 	return "comments"
 }
 
+// Commenter 为按平台用户 ID 聚合的评论用户，UserName 与 AvatarURL 为最近一次看到的值。
+// RequestCount 为识别出生成请求的评论数，SuccessCount 为生成成功的任务数；Blocked 的用户的评论不再创建任务。
+type Commenter struct {
+	ID            uint             `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID        string           `gorm:"type:varchar(100);uniqueIndex:uk_user_id;not null" json:"user_id"`
+	UserName      *string          `gorm:"type:varchar(200)" json:"user_name,omitempty"`
+	AvatarURL     *string          `gorm:"type:varchar(1000)" json:"avatar_url,omitempty"`
+	CommentCount  int              `gorm:"not null;default:0" json:"comment_count"`
+	RequestCount  int              `gorm:"not null;default:0" json:"request_count"`
+	SuccessCount  int              `gorm:"not null;default:0" json:"success_count"`
+	Blocked       bool             `gorm:"not null;default:false;index:idx_blocked" json:"blocked"`
+	BlockedReason *string          `gorm:"type:varchar(500)" json:"blocked_reason,omitempty"`
+	BlockedAt     *time.Time       `json:"blocked_at,omitempty"`
+	FirstSeenAt   time.Time        `json:"first_seen_at"`
+	LastSeenAt    time.Time        `gorm:"index:idx_last_seen_at" json:"last_seen_at"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Emails        []CommenterEmail `gorm:"foreignKey:CommenterID" json:"emails,omitempty"`
+}
+
+func (Commenter) TableName() string {
+	return "commenters"
+}
+
+// CommenterEmail 为评论用户在生成请求中使用过的邮箱
+type CommenterEmail struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CommenterID uint      `gorm:"not null;uniqueIndex:uk_commenter_email,priority:1" json:"commenter_id"`
+	Email       string    `gorm:"type:varchar(200);not null;uniqueIndex:uk_commenter_email,priority:2" json:"email"`
+	UseCount    int       `gorm:"not null;default:0" json:"use_count"`
+	FirstUsedAt time.Time `json:"first_used_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+}
+
+func (CommenterEmail) TableName() string {
+	return "commenter_emails"
+}
+
+// CommentImage 为评论附带的图片。SourceURL 为平台原始地址，
+// 转存到对象存储后 ObjectKey 非空，提交供应商时使用转存后的地址。
+type CommentImage struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CommentID uint      `gorm:"not null;index:idx_comment_order,priority:1" json:"comment_id"`
+	SourceURL string    `gorm:"type:varchar(1000);not null" json:"source_url"`
+	ObjectKey *string   `gorm:"type:varchar(500)" json:"object_key,omitempty"`
+	URL       *string   `gorm:"type:varchar(1000)" json:"url,omitempty"`
+	MimeType  *string   `gorm:"type:varchar(100)" json:"mime_type,omitempty"`
+	SizeBytes *int64    `json:"size_bytes,omitempty"`
+	Error     *string   `gorm:"type:text" json:"error,omitempty"`
+	SortOrder int       `gorm:"not null;default:0;index:idx_comment_order,priority:2" json:"sort_order"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (CommentImage) TableName() string {
+	return "comment_images"
+}
+
+// CommentReply 为在平台上对评论的回复记录，同一评论的每个阶段只回复一次
+type CommentReply struct {
+	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	CommentID uint           `gorm:"not null;uniqueIndex:uk_comment_stage,priority:1" json:"comment_id"`
+	TaskID    *uint          `gorm:"index:idx_task_id" json:"task_id,omitempty"`
+	Stage     ReplyStage     `gorm:"type:varchar(50);not null;uniqueIndex:uk_comment_stage,priority:2" json:"stage"`
+	Content   string         `gorm:"type:text;not null" json:"content"`
+	ReplyID   *string        `gorm:"type:varchar(200);index:idx_reply_id" json:"reply_id,omitempty"`
+	Status    DeliveryStatus `gorm:"type:enum('SENT','FAILED');not null" json:"status"`
+	Error     *string        `gorm:"type:text" json:"error,omitempty"`
+	Attempts  int            `gorm:"not null;default:0" json:"attempts"`
+	SentAt    *time.Time     `json:"sent_at,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func (CommentReply) TableName() string {
+	return "comment_replies"
+}
+
+// Task 为一次生成任务。EstimatedCost 为提交前按供应商价格估算的费用，
+// SubmittedAt 非空的任务计入预算；BudgetApproved 表示运营人员允许其超出预算提交。
 type Task struct {
 	ID              uint        `gorm:"primaryKey;autoIncrement" json:"id"`
 	CommentID       uint        `gorm:"not null;uniqueIndex:uk_comment_id" json:"comment_id"`
-	Status          TaskStatus  `gorm:"type:enum('PENDING','EXTRACTED','SUBMITTED','RUNNING','SUCCEEDED','EMAILED','FAILED');not null;default:'PENDING'" json:"status"`
-	RequestType     RequestType `gorm:"type:enum('image','video');not null" json:"request_type"`
+	Status          TaskStatus  `gorm:"type:enum('PENDING','EXTRACTED','QUEUED_BUDGET','SUBMITTED','RUNNING','SUCCEEDED','EMAILED','FAILED','CANCELLED');not null;default:'PENDING'" json:"status"`
+	RequestType     RequestType `gorm:"type:enum('image','video','edit');not null" json:"request_type"`
 	Email           *string     `gorm:"type:varchar(200);index:idx_email" json:"email,omitempty"`
 	Prompt          *string     `gorm:"type:text" json:"prompt,omitempty"`
 	Confidence      *float64    `gorm:"type:decimal(3,2)" json:"confidence,omitempty"`
@@ -106,16 +247,38 @@ type Task struct {
 	ResultURL       *string     `gorm:"type:varchar(1000)" json:"result_url,omitempty"`
 	Error           *string     `gorm:"type:text" json:"error,omitempty"`
 	RetryCount      int         `gorm:"default:0" json:"retry_count"`
+	EstimatedCost   *float64    `gorm:"type:decimal(12,4)" json:"estimated_cost,omitempty"`
+	BudgetApproved  bool        `gorm:"not null;default:false" json:"budget_approved"`
+	SubmittedAt     *time.Time  `gorm:"index:idx_submitted_at" json:"submitted_at,omitempty"`
 	CreatedAt       time.Time   `json:"created_at"`
 	UpdatedAt       time.Time   `json:"updated_at"`
 	Comment         *Comment    `gorm:"foreignKey:CommentID" json:"comment,omitempty"`
 	Deliveries      []Delivery  `gorm:"foreignKey:TaskID" json:"deliveries,omitempty"`
+	Artifacts       []Artifact  `gorm:"foreignKey:TaskID" json:"artifacts,omitempty"`
 }
 
 func (Task) TableName() string {
 	return "tasks"
 }
 
+type Artifact struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID    uint      `gorm:"not null;index:idx_task_order,priority:1" json:"task_id"`
+	Kind      string    `gorm:"type:varchar(50);not null" json:"kind"`
+	ObjectKey *string   `gorm:"type:varchar(500)" json:"object_key,omitempty"`
+	URL       string    `gorm:"type:varchar(1000);not null" json:"url"`
+	MimeType  *string   `gorm:"type:varchar(100)" json:"mime_type,omitempty"`
+	SizeBytes *int64    `json:"size_bytes,omitempty"`
+	Width     *int      `json:"width,omitempty"`
+	Height    *int      `json:"height,omitempty"`
+	SortOrder int       `gorm:"not null;default:0;index:idx_task_order,priority:2" json:"sort_order"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Artifact) TableName() string {
+	return "artifacts"
+}
+
 type Delivery struct {
 	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`
 	TaskID    uint           `gorm:"not null;index:idx_task_id" json:"task_id"`
@@ -141,3 +304,20 @@ type AuditLog struct {
 func (AuditLog) TableName() string {
 	return "audit_logs"
 }
+
+// EmailTemplate 为运营人员自定义的邮件模板，Name 为 result 或 error，Locale 为 zh 或 en；
+// 没有记录时使用内置模板
+type EmailTemplate struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_name_locale,priority:1" json:"name"`
+	Locale    string    `gorm:"type:varchar(10);not null;uniqueIndex:uk_name_locale,priority:2" json:"locale"`
+	Subject   string    `gorm:"type:varchar(500);not null" json:"subject"`
+	HTMLBody  string    `gorm:"type:text;not null" json:"html_body"`
+	TextBody  string    `gorm:"type:text;not null" json:"text_body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (EmailTemplate) TableName() string {
+	return "email_templates"
+}
@@ -0,0 +1,417 @@
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// Outcome 是一次供应商调用的结果
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "ok"
+	OutcomeSlow    Outcome = "slow"
+	OutcomeFailure Outcome = "fail"
+)
+
+// Settings 为单个熔断器的参数，零值字段使用默认值
+type Settings struct {
+	Disabled     bool
+	FailureRate  float64
+	SlowCallRate float64
+	WindowSize   int
+	MinRequests  int
+	OpenDuration time.Duration
+	ProbeTimeout time.Duration
+}
+
+const (
+	defaultFailureRate  = 0.5
+	defaultSlowCallRate = 0.8
+	defaultWindowSize   = 20
+	defaultMinRequests  = 5
+	defaultOpenDuration = time.Minute
+	defaultProbeTimeout = time.Minute
+)
+
+func (s Settings) withDefaults() Settings {
+	if s.FailureRate <= 0 {
+		s.FailureRate = defaultFailureRate
+	}
+	if s.SlowCallRate <= 0 {
+		s.SlowCallRate = defaultSlowCallRate
+	}
+	if s.WindowSize <= 0 {
+		s.WindowSize = defaultWindowSize
+	}
+	if s.MinRequests <= 0 {
+		s.MinRequests = defaultMinRequests
+	}
+	if s.MinRequests > s.WindowSize {
+		s.MinRequests = s.WindowSize
+	}
+	if s.OpenDuration <= 0 {
+		s.OpenDuration = defaultOpenDuration
+	}
+	if s.ProbeTimeout <= 0 {
+		s.ProbeTimeout = defaultProbeTimeout
+	}
+	return s
+}
+
+// allowScript 判断是否放行请求。open 状态超过 OpenDuration 后转为 half_open，
+// half_open 状态同一时间只放行一个探测请求，探测租约过期后可再次放行。
+// 每个探测请求分配一个递增编号，只有持有当前编号的调用结果会改变 half_open 状态。
+// 返回 {是否放行, 需要等待的毫秒数, 探测编号（0 表示不是探测请求）}
+var allowScript = redis.NewScript(`
+local key = KEYS[1]
+local openMs = tonumber(ARGV[1])
+local probeMs = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HGET', key, 'state') or 'closed'
+if state == 'closed' then
+	return {1, 0, 0}
+end
+
+if state == 'open' then
+	local openedAt = tonumber(redis.call('HGET', key, 'opened_at') or '0')
+	local wait = openedAt + openMs - now
+	if wait > 0 then
+		return {0, wait, 0}
+	end
+	local probe = redis.call('HINCRBY', key, 'probe_seq', 1)
+	redis.call('HSET', key, 'state', 'half_open', 'probe_until', now + probeMs, 'probe', probe)
+	return {1, 0, probe}
+end
+
+local probeUntil = tonumber(redis.call('HGET', key, 'probe_until') or '0')
+if probeUntil > now then
+	return {0, probeUntil - now, 0}
+end
+local probe = redis.call('HINCRBY', key, 'probe_seq', 1)
+redis.call('HSET', key, 'probe_until', now + probeMs, 'probe', probe)
+return {1, 0, probe}
+`)
+
+// recordScript 记录一次调用结果并在滑动窗口内评估失败率与慢调用率。
+// half_open 状态下只接受当前探测请求的结果：成功即闭合，失败或慢调用重新打开；
+// 其他调用（如探测前已提交任务的状态查询）被忽略。返回记录后的状态
+var recordScript = redis.NewScript(`
+local key = KEYS[1]
+local calls = KEYS[2]
+local outcome = ARGV[1]
+local window = tonumber(ARGV[2])
+local minRequests = tonumber(ARGV[3])
+local failureRate = tonumber(ARGV[4])
+local slowRate = tonumber(ARGV[5])
+local probe = ARGV[6]
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HGET', key, 'state') or 'closed'
+if state == 'open' then
+	return state
+end
+
+if state == 'half_open' then
+	if probe == '0' or redis.call('HGET', key, 'probe') ~= probe then
+		return state
+	end
+	if outcome == 'ok' then
+		redis.call('HSET', key, 'state', 'closed', 'reason', '', 'probe_until', 0, 'probe', 0)
+		redis.call('DEL', calls)
+		return 'closed'
+	end
+	redis.call('HSET', key, 'state', 'open', 'opened_at', now, 'probe_until', 0, 'probe', 0, 'reason', 'probe ' .. outcome)
+	return 'open'
+end
+
+redis.call('LPUSH', calls, outcome)
+redis.call('LTRIM', calls, 0, window - 1)
+
+local items = redis.call('LRANGE', calls, 0, -1)
+local n = #items
+if n < minRequests then
+	return 'closed'
+end
+
+local failures, slow = 0, 0
+for _, v in ipairs(items) do
+	if v == 'fail' then
+		failures = failures + 1
+	elseif v == 'slow' then
+		slow = slow + 1
+	end
+end
+
+local reason = nil
+if failures / n >= failureRate then
+	reason = string.format('failure rate %d/%d', failures, n)
+elseif slow / n >= slowRate then
+	reason = string.format('slow call rate %d/%d', slow, n)
+end
+if reason then
+	redis.call('HSET', key, 'state', 'open', 'opened_at', now, 'probe_until', 0, 'probe', 0, 'reason', reason)
+	redis.call('DEL', calls)
+	return 'open'
+end
+return 'closed'
+`)
+
+// healthScript 保存健康检查结果。检查失败时打开（或保持）熔断器；
+// 熔断器打开期间检查成功则转为 half_open，允许立即探测
+var healthScript = redis.NewScript(`
+local key = KEYS[1]
+local healthy = ARGV[1] == '1'
+local latency = ARGV[2]
+local errMsg = ARGV[3]
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('HSET', key, 'health_ok', ARGV[1], 'health_checked_at', now, 'health_latency_ms', latency, 'health_error', errMsg)
+
+local state = redis.call('HGET', key, 'state') or 'closed'
+if not healthy then
+	redis.call('HSET', key, 'state', 'open', 'opened_at', now, 'probe_until', 0, 'probe', 0, 'reason', 'health check failed: ' .. errMsg)
+	return 'open'
+end
+if state == 'open' then
+	redis.call('HSET', key, 'state', 'half_open', 'probe_until', 0, 'probe', 0)
+	return 'half_open'
+end
+return state
+`)
+
+// releaseProbeScript 在 half_open 状态下清除仍属于该探测请求的租约，使下一个请求可以立即探测
+var releaseProbeScript = redis.NewScript(`
+local key = KEYS[1]
+local probe = ARGV[1]
+if redis.call('HGET', key, 'state') == 'half_open' and redis.call('HGET', key, 'probe') == probe then
+	redis.call('HSET', key, 'probe_until', 0, 'probe', 0)
+	return 1
+end
+return 0
+`)
+
+// Breaker 基于 Redis 实现集群范围的熔断器，API 与 Worker 进程共享状态
+type Breaker struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func New(rdb *redis.Client, prefix string) *Breaker {
+	if prefix == "" {
+		prefix = "breaker"
+	}
+	return &Breaker{
+		rdb:    rdb,
+		prefix: prefix,
+	}
+}
+
+func (b *Breaker) stateKey(name string) string {
+	return fmt.Sprintf("%s:%s", b.prefix, name)
+}
+
+func (b *Breaker) callsKey(name string) string {
+	return fmt.Sprintf("%s:%s:calls", b.prefix, name)
+}
+
+func (b *Breaker) healthLeaseKey(name string) string {
+	return fmt.Sprintf("%s:%s:health_lease", b.prefix, name)
+}
+
+// Permit 是 Allow 的判断结果。Probe 非 0 表示放行的是 half_open 状态下的探测请求，
+// 记录结果或归还探测名额时需要带上它
+type Permit struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	Probe      int64
+}
+
+// Allow 判断是否可以向 name 发送请求；被拒绝时返回建议的等待时长
+func (b *Breaker) Allow(ctx context.Context, name string, s Settings) (Permit, error) {
+	if s.Disabled {
+		return Permit{Allowed: true}, nil
+	}
+	s = s.withDefaults()
+
+	res, err := allowScript.Run(ctx, b.rdb, []string{b.stateKey(name)}, s.OpenDuration.Milliseconds(), s.ProbeTimeout.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Permit{}, fmt.Errorf("failed to check breaker: %w", err)
+	}
+	if len(res) != 3 {
+		return Permit{}, fmt.Errorf("unexpected breaker response: %v", res)
+	}
+	return Permit{
+		Allowed:    res[0] == 1,
+		RetryAfter: time.Duration(res[1]) * time.Millisecond,
+		Probe:      res[2],
+	}, nil
+}
+
+// ReleaseProbe 归还 Allow 放行后没有实际发出的探测请求；
+// probe 为 0、熔断器不处于 half_open 或名额已被新的探测请求占用时不做任何事
+func (b *Breaker) ReleaseProbe(ctx context.Context, name string, probe int64) error {
+	if probe == 0 {
+		return nil
+	}
+	if err := releaseProbeScript.Run(ctx, b.rdb, []string{b.stateKey(name)}, probe).Err(); err != nil {
+		return fmt.Errorf("failed to release breaker probe: %w", err)
+	}
+	return nil
+}
+
+// Record 记录一次调用结果，返回记录后的熔断器状态。
+// probe 为 Allow 返回的探测编号，不是探测请求的调用传 0。
+func (b *Breaker) Record(ctx context.Context, name string, s Settings, probe int64, outcome Outcome) (string, error) {
+	if s.Disabled {
+		return StateClosed, nil
+	}
+	s = s.withDefaults()
+
+	state, err := recordScript.Run(ctx, b.rdb, []string{b.stateKey(name), b.callsKey(name)},
+		string(outcome), s.WindowSize, s.MinRequests, s.FailureRate, s.SlowCallRate, probe).Text()
+	if err != nil {
+		return "", fmt.Errorf("failed to record breaker outcome: %w", err)
+	}
+	return state, nil
+}
+
+// RecordHealth 保存健康检查结果，返回更新后的熔断器状态
+func (b *Breaker) RecordHealth(ctx context.Context, name string, s Settings, latency time.Duration, checkErr error) (string, error) {
+	healthy, errMsg := "1", ""
+	if checkErr != nil {
+		healthy, errMsg = "0", checkErr.Error()
+	}
+	if s.Disabled {
+		// 熔断关闭时仍保存检查结果供展示，但不改变状态
+		err := b.rdb.HSet(ctx, b.stateKey(name),
+			"health_ok", healthy,
+			"health_checked_at", time.Now().UnixMilli(),
+			"health_latency_ms", latency.Milliseconds(),
+			"health_error", errMsg,
+		).Err()
+		if err != nil {
+			return "", fmt.Errorf("failed to record health: %w", err)
+		}
+		return StateClosed, nil
+	}
+
+	state, err := healthScript.Run(ctx, b.rdb, []string{b.stateKey(name)}, healthy, latency.Milliseconds(), errMsg).Text()
+	if err != nil {
+		return "", fmt.Errorf("failed to record health: %w", err)
+	}
+	return state, nil
+}
+
+// ClaimHealthCheck 在 interval 内只允许一个进程执行 name 的健康检查
+func (b *Breaker) ClaimHealthCheck(ctx context.Context, name string, interval time.Duration) (bool, error) {
+	ok, err := b.rdb.SetNX(ctx, b.healthLeaseKey(name), 1, interval).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim health check: %w", err)
+	}
+	return ok, nil
+}
+
+// Reset 清除熔断器状态与调用窗口
+func (b *Breaker) Reset(ctx context.Context, name string) error {
+	if err := b.rdb.Del(ctx, b.stateKey(name), b.callsKey(name)).Err(); err != nil {
+		return fmt.Errorf("failed to reset breaker: %w", err)
+	}
+	return nil
+}
+
+// Health 是最近一次健康检查的结果
+type Health struct {
+	Healthy   bool      `json:"healthy"`
+	CheckedAt time.Time `json:"checked_at"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Status 是熔断器的当前快照
+type Status struct {
+	State      string     `json:"state"`
+	Reason     string     `json:"reason,omitempty"`
+	OpenedAt   *time.Time `json:"opened_at,omitempty"`
+	RetryAfter int64      `json:"retry_after_sec,omitempty"`
+	Calls      int        `json:"calls"`
+	Failures   int        `json:"failures"`
+	SlowCalls  int        `json:"slow_calls"`
+	Health     *Health    `json:"health,omitempty"`
+}
+
+func (b *Breaker) Status(ctx context.Context, name string, s Settings) (*Status, error) {
+	s = s.withDefaults()
+
+	fields, err := b.rdb.HGetAll(ctx, b.stateKey(name)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get breaker state: %w", err)
+	}
+	calls, err := b.rdb.LRange(ctx, b.callsKey(name), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get breaker calls: %w", err)
+	}
+
+	status := &Status{
+		State:  fields["state"],
+		Reason: fields["reason"],
+		Calls:  len(calls),
+	}
+	if status.State == "" {
+		status.State = StateClosed
+	}
+	for _, c := range calls {
+		switch Outcome(c) {
+		case OutcomeFailure:
+			status.Failures++
+		case OutcomeSlow:
+			status.SlowCalls++
+		}
+	}
+
+	if status.State != StateClosed {
+		if openedAt := parseMillis(fields["opened_at"]); openedAt != nil {
+			status.OpenedAt = openedAt
+			if status.State == StateOpen {
+				if wait := time.Until(openedAt.Add(s.OpenDuration)); wait > 0 {
+					status.RetryAfter = int64(wait.Seconds()) + 1
+				}
+			}
+		}
+	}
+
+	if checkedAt := parseMillis(fields["health_checked_at"]); checkedAt != nil {
+		latency, _ := strconv.ParseInt(fields["health_latency_ms"], 10, 64)
+		status.Health = &Health{
+			Healthy:   fields["health_ok"] == "1",
+			CheckedAt: *checkedAt,
+			LatencyMs: latency,
+			Error:     fields["health_error"],
+		}
+	}
+
+	return status, nil
+}
+
+func parseMillis(v string) *time.Time {
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms <= 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms)
+	return &t
+}
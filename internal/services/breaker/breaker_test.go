@@ -0,0 +1,269 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+var testSettings = Settings{
+	FailureRate:  0.5,
+	SlowCallRate: 0.8,
+	WindowSize:   4,
+	MinRequests:  2,
+	OpenDuration: 10 * time.Second,
+	ProbeTimeout: 5 * time.Second,
+}
+
+func newTestBreaker(t *testing.T) (*Breaker, *miniredis.Miniredis) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return New(rdb, "test"), m
+}
+
+// step 为熔断器上的一次操作。probe 为第几次放行得到的探测编号（从 1 开始），0 表示不带探测编号
+type step struct {
+	op      string // allow / record / release / health
+	advance time.Duration
+	outcome Outcome
+	healthy bool
+	probe   int
+
+	allowed bool
+	wait    time.Duration
+	isProbe bool
+	state   string
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	open := []step{
+		{op: "record", outcome: OutcomeFailure, state: StateClosed},
+		{op: "record", outcome: OutcomeFailure, state: StateOpen},
+	}
+	halfOpen := append(append([]step{}, open...),
+		step{op: "allow", advance: 10 * time.Second, allowed: true, isProbe: true, state: StateHalfOpen},
+	)
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "closed below min requests",
+			steps: []step{
+				{op: "record", outcome: OutcomeFailure, state: StateClosed},
+				{op: "allow", allowed: true, state: StateClosed},
+			},
+		},
+		{
+			name: "opens on failure rate",
+			steps: []step{
+				{op: "record", outcome: OutcomeSuccess, state: StateClosed},
+				{op: "record", outcome: OutcomeFailure, state: StateOpen},
+				{op: "allow", wait: 10 * time.Second, state: StateOpen},
+				{op: "allow", advance: 4 * time.Second, wait: 6 * time.Second, state: StateOpen},
+			},
+		},
+		{
+			name: "opens on slow call rate",
+			steps: []step{
+				{op: "record", outcome: OutcomeSlow, state: StateClosed},
+				{op: "record", outcome: OutcomeSlow, state: StateOpen},
+			},
+		},
+		{
+			name: "open ignores outcomes",
+			steps: append(append([]step{}, open...),
+				step{op: "record", outcome: OutcomeSuccess, state: StateOpen},
+				step{op: "allow", advance: 9 * time.Second, wait: time.Second, state: StateOpen},
+			),
+		},
+		{
+			name: "probe success closes",
+			steps: append(append([]step{}, halfOpen...),
+				step{op: "allow", wait: 5 * time.Second, state: StateHalfOpen},
+				step{op: "record", outcome: OutcomeSuccess, probe: 1, state: StateClosed},
+				step{op: "allow", allowed: true, state: StateClosed},
+			),
+		},
+		{
+			name: "probe failure reopens",
+			steps: append(append([]step{}, halfOpen...),
+				step{op: "record", outcome: OutcomeFailure, probe: 1, state: StateOpen},
+				step{op: "allow", wait: 10 * time.Second, state: StateOpen},
+			),
+		},
+		{
+			name: "slow probe reopens",
+			steps: append(append([]step{}, halfOpen...),
+				step{op: "record", outcome: OutcomeSlow, probe: 1, state: StateOpen},
+			),
+		},
+		{
+			name: "calls without the probe are ignored",
+			steps: append(append([]step{}, halfOpen...),
+				step{op: "record", outcome: OutcomeFailure, state: StateHalfOpen},
+				step{op: "record", outcome: OutcomeSuccess, state: StateHalfOpen},
+				step{op: "allow", wait: 5 * time.Second, state: StateHalfOpen},
+				step{op: "record", outcome: OutcomeSuccess, probe: 1, state: StateClosed},
+			),
+		},
+		{
+			name: "expired probe lease admits a new probe",
+			steps: append(append([]step{}, halfOpen...),
+				step{op: "allow", advance: 4 * time.Second, wait: time.Second, state: StateHalfOpen},
+				step{op: "allow", advance: time.Second, allowed: true, isProbe: true, state: StateHalfOpen},
+				step{op: "record", outcome: OutcomeFailure, probe: 1, state: StateHalfOpen},
+				step{op: "record", outcome: OutcomeSuccess, probe: 2, state: StateClosed},
+			),
+		},
+		{
+			name: "released probe admits the next request",
+			steps: append(append([]step{}, halfOpen...),
+				step{op: "release", probe: 1, state: StateHalfOpen},
+				step{op: "allow", allowed: true, isProbe: true, state: StateHalfOpen},
+				step{op: "record", outcome: OutcomeSuccess, probe: 1, state: StateHalfOpen},
+				step{op: "record", outcome: OutcomeSuccess, probe: 2, state: StateClosed},
+			),
+		},
+		{
+			name: "stale release keeps the newer probe",
+			steps: append(append([]step{}, halfOpen...),
+				step{op: "allow", advance: 5 * time.Second, allowed: true, isProbe: true, state: StateHalfOpen},
+				step{op: "release", probe: 1, state: StateHalfOpen},
+				step{op: "allow", wait: 5 * time.Second, state: StateHalfOpen},
+			),
+		},
+		{
+			name: "release outside half open does nothing",
+			steps: append(append([]step{}, open...),
+				step{op: "release", state: StateOpen},
+				step{op: "allow", wait: 10 * time.Second, state: StateOpen},
+			),
+		},
+		{
+			name: "failed health check opens",
+			steps: []step{
+				{op: "health", healthy: false, state: StateOpen},
+				{op: "allow", wait: 10 * time.Second, state: StateOpen},
+			},
+		},
+		{
+			name: "healthy check moves open to half open",
+			steps: append(append([]step{}, open...),
+				step{op: "health", healthy: true, state: StateHalfOpen},
+				step{op: "allow", allowed: true, isProbe: true, state: StateHalfOpen},
+				step{op: "record", outcome: OutcomeSuccess, probe: 1, state: StateClosed},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, m := newTestBreaker(t)
+			ctx := context.Background()
+			now := time.Now()
+			var probes []int64
+
+			probeAt := func(i int) int64 {
+				if i == 0 {
+					return 0
+				}
+				return probes[i-1]
+			}
+
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				m.SetTime(now)
+
+				var state string
+				switch s.op {
+				case "allow":
+					permit, err := b.Allow(ctx, "p", testSettings)
+					if err != nil {
+						t.Fatalf("step %d: Allow: %v", i, err)
+					}
+					if permit.Allowed != s.allowed || permit.RetryAfter != s.wait || (permit.Probe != 0) != s.isProbe {
+						t.Fatalf("step %d: got %+v, want allowed=%v wait=%s probe=%v", i, permit, s.allowed, s.wait, s.isProbe)
+					}
+					if permit.Probe != 0 {
+						probes = append(probes, permit.Probe)
+					}
+				case "record":
+					var err error
+					state, err = b.Record(ctx, "p", testSettings, probeAt(s.probe), s.outcome)
+					if err != nil {
+						t.Fatalf("step %d: Record: %v", i, err)
+					}
+				case "release":
+					if err := b.ReleaseProbe(ctx, "p", probeAt(s.probe)); err != nil {
+						t.Fatalf("step %d: ReleaseProbe: %v", i, err)
+					}
+				case "health":
+					var checkErr error
+					if !s.healthy {
+						checkErr = errors.New("connection refused")
+					}
+					var err error
+					state, err = b.RecordHealth(ctx, "p", testSettings, 10*time.Millisecond, checkErr)
+					if err != nil {
+						t.Fatalf("step %d: RecordHealth: %v", i, err)
+					}
+				}
+
+				if state != "" && state != s.state {
+					t.Fatalf("step %d: %s returned state %s, want %s", i, s.op, state, s.state)
+				}
+				status, err := b.Status(ctx, "p", testSettings)
+				if err != nil {
+					t.Fatalf("step %d: Status: %v", i, err)
+				}
+				if status.State != s.state {
+					t.Fatalf("step %d: state %s, want %s", i, status.State, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b, _ := newTestBreaker(t)
+	ctx := context.Background()
+	s := testSettings
+	s.Disabled = true
+
+	for i := 0; i < 5; i++ {
+		if state, err := b.Record(ctx, "p", s, 0, OutcomeFailure); err != nil || state != StateClosed {
+			t.Fatalf("Record = %s, %v", state, err)
+		}
+	}
+	permit, err := b.Allow(ctx, "p", s)
+	if err != nil || !permit.Allowed {
+		t.Fatalf("Allow = %+v, %v", permit, err)
+	}
+}
+
+func TestBreakerReset(t *testing.T) {
+	b, m := newTestBreaker(t)
+	ctx := context.Background()
+	m.SetTime(time.Now())
+
+	b.Record(ctx, "p", testSettings, 0, OutcomeFailure)
+	b.Record(ctx, "p", testSettings, 0, OutcomeFailure)
+	if err := b.Reset(ctx, "p"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	status, err := b.Status(ctx, "p", testSettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != StateClosed || status.Calls != 0 {
+		t.Fatalf("status after reset: %+v", status)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +20,9 @@ type IntentResult struct {
 	Confidence  float64         `json:"confidence"`
 	Reason      string          `json:"reason"`
 	RawJSON     json.RawMessage `json:"-"`
+
+	// MissingEmail 表示评论包含生成关键词但没有有效邮箱
+	MissingEmail bool `json:"-"`
 }
 
 type Service struct {
@@ -53,16 +57,20 @@ type Choice struct {
 }
 
 const (
-	SystemPrompt = `你是一个意图抽取器。你只能输出 JSON，不能输出任何解释、Markdown、代码块。请从评论中判断是否存在明确的"生成图片/生成视频"请求，并抽取用于生成模型的 prompt，同时抽取邮箱（如果存在）。不确定时必须返回 has_request=false。
+	SystemPrompt = `你是一个意图抽取器。你只能输出 JSON，不能输出任何解释、Markdown、代码块。请从评论中判断是否存在明确的"生成图片/生成视频/修改评论附图"请求，并抽取用于生成模型的 prompt，同时抽取邮箱（如果存在）。不确定时必须返回 has_request=false。
 
 输出字段必须严格为：
-has_request(boolean), request_type("image"|"video"|"unknown"), prompt(string), email(string|null), confidence(number 0..1), reason(string)`
+has_request(boolean), request_type("image"|"video"|"edit"|"unknown"), prompt(string), email(string|null), confidence(number 0..1), reason(string)`
 
 	UserPromptTemplate = `评论文本如下：
 <<<COMMENT>>>
 
+评论附带图片：<<<IMAGE_COUNT>>> 张
+
 规则：
-- 如果评论没有明确要求生成图片/视频，has_request=false
+- 如果评论没有明确要求生成图片/视频或修改附图，has_request=false
+- 如果评论要求基于附带的图片进行修改（如换风格、换背景），且附带图片数量大于 0，request_type="edit"，prompt 描述修改后的效果
+- 没有附带图片时不能返回 request_type="edit"
 - 如果无法可靠判断类型，request_type="unknown"，has_request=false
 - prompt 必须是可直接用于生成模型的描述，去掉邮箱和无关寒暄
 - 只要邮箱缺失或疑似无效，email=null，has_request=false
@@ -80,6 +88,12 @@ var (
 		"做短片", "生成短片", "做个短片",
 	}
 
+	// editKeywords 仅在评论附带图片时生效
+	editKeywords = []string{
+		"改成", "换成", "变成", "转成", "改图", "修图", "p图", "这张图", "这张照片",
+		"改一下", "改个", "换个风格", "换背景",
+	}
+
 	emailRegex = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
 )
 
@@ -92,10 +106,12 @@ func NewService(cfg *config.LLMConfig) *Service {
 	}
 }
 
-func (s *Service) ExtractIntent(ctx context.Context, comment string, threshold float64) (*IntentResult, error) {
+// ExtractIntent 抽取评论中的生成请求，imageCount 为评论附带的图片数量，
+// 有附图时才可能返回 edit 类型的请求
+func (s *Service) ExtractIntent(ctx context.Context, comment string, imageCount int, threshold float64) (*IntentResult, error) {
 	email := s.extractEmail(comment)
 
-	if !s.hasGenerationKeywords(comment) {
+	if !s.hasGenerationKeywords(comment, imageCount > 0) {
 		return &IntentResult{
 			HasRequest:  false,
 			RequestType: "unknown",
@@ -108,16 +124,18 @@ func (s *Service) ExtractIntent(ctx context.Context, comment string, threshold f
 
 	if email == nil {
 		return &IntentResult{
-			HasRequest:  false,
-			RequestType: "unknown",
-			Prompt:      "",
-			Email:       nil,
-			Confidence:  0,
-			Reason:      "评论未包含有效邮箱",
+			HasRequest:   false,
+			RequestType:  "unknown",
+			Prompt:       "",
+			Email:        nil,
+			Confidence:   0,
+			Reason:       "评论未包含有效邮箱",
+			MissingEmail: true,
 		}, nil
 	}
 
 	userPrompt := strings.Replace(UserPromptTemplate, "<<<COMMENT>>>", comment, 1)
+	userPrompt = strings.Replace(userPrompt, "<<<IMAGE_COUNT>>>", strconv.Itoa(imageCount), 1)
 
 	intentResult, err := s.callLLM(ctx, userPrompt)
 	if err != nil {
@@ -127,7 +145,7 @@ func (s *Service) ExtractIntent(ctx context.Context, comment string, threshold f
 	intentResult.Email = email
 	intentResult.RawJSON = nil
 
-	if !s.isClearIntent(intentResult, threshold) {
+	if !s.isClearIntent(intentResult, imageCount, threshold) {
 		intentResult.HasRequest = false
 		intentResult.Reason = fmt.Sprintf("意图不明确: %s", intentResult.Reason)
 	}
@@ -177,7 +195,7 @@ func (s *Service) isValidEmail(email string) bool {
 	return true
 }
 
-func (s *Service) hasGenerationKeywords(comment string) bool {
+func (s *Service) hasGenerationKeywords(comment string, hasImages bool) bool {
 	lowerComment := strings.ToLower(comment)
 
 	for _, kw := range imageKeywords {
@@ -192,15 +210,29 @@ func (s *Service) hasGenerationKeywords(comment string) bool {
 		}
 	}
 
+	if hasImages {
+		for _, kw := range editKeywords {
+			if strings.Contains(lowerComment, strings.ToLower(kw)) {
+				return true
+			}
+		}
+	}
+
 	return false
 }
 
-func (s *Service) isClearIntent(result *IntentResult, threshold float64) bool {
+func (s *Service) isClearIntent(result *IntentResult, imageCount int, threshold float64) bool {
 	if !result.HasRequest {
 		return false
 	}
 
-	if result.RequestType != "image" && result.RequestType != "video" {
+	switch result.RequestType {
+	case "image", "video":
+	case "edit":
+		if imageCount == 0 {
+			return false
+		}
+	default:
 		return false
 	}
 
@@ -9,27 +9,37 @@ import (
 	"gopkg.in/gomail.v2"
 )
 
+// Email 为一封邮件，IsHTML 且 TextBody 非空时以 multipart/alternative 同时发送纯文本与 HTML
 type Email struct {
-	To      string
-	Subject string
-	Body    string
-	IsHTML  bool
+	To       string
+	Subject  string
+	Body     string
+	TextBody string
+	IsHTML   bool
 }
 
 type Service struct {
-	dialer *gomail.Dialer
-	from   string
+	dialer     *gomail.Dialer
+	from       string
+	linkExpiry time.Duration
 }
 
-func NewService(cfg *config.SMTPConfig) *Service {
+// NewService 创建邮件服务，linkExpiry 为结果邮件中下载链接的有效期
+func NewService(cfg *config.SMTPConfig, linkExpiry time.Duration) *Service {
 	dialer := gomail.NewDialer(cfg.Host, cfg.Port, cfg.User, cfg.Password)
 
 	return &Service{
-		dialer: dialer,
-		from:   cfg.From,
+		dialer:     dialer,
+		from:       cfg.From,
+		linkExpiry: linkExpiry,
 	}
 }
 
+// LinkExpiry 返回结果邮件中自有存储链接的有效期
+func (s *Service) LinkExpiry() time.Duration {
+	return s.linkExpiry
+}
+
 func (s *Service) Send(email Email) error {
 	return s.SendWithTimeout(email, 30*time.Second)
 }
@@ -40,7 +50,10 @@ func (s *Service) SendWithTimeout(email Email, timeout time.Duration) error {
 	m.SetHeader("To", email.To)
 	m.SetHeader("Subject", email.Subject)
 
-	if email.IsHTML {
+	if email.IsHTML && email.TextBody != "" {
+		m.SetBody("text/plain", email.TextBody)
+		m.AddAlternative("text/html", email.Body)
+	} else if email.IsHTML {
 		m.SetBody("text/html", email.Body)
 	} else {
 		m.SetBody("text/plain", email.Body)
@@ -63,67 +76,89 @@ func (s *Service) SendWithTimeout(email Email, timeout time.Duration) error {
 	}
 }
 
-func (s *Service) SendResultEmail(to, requestType, prompt, resultURL string) error {
-	subject := fmt.Sprintf("您的%s生成结果已就绪", s.getRequestTypeText(requestType))
-
-	body := fmt.Sprintf(`您好！
-
-您请求的%s已经生成完成。
+// ResultLink 是结果邮件中的一个下载链接，Hosted 表示 URL 为按 linkExpiry 签发的自有存储地址
+type ResultLink struct {
+	Kind   string
+	URL    string
+	Hosted bool
+}
 
-请求描述：%s
+// Message 为结果或失败邮件的内容，Locale 为空时使用中文；RequestType 与 ResultLink.Kind 为原始类型，渲染时本地化
+type Message struct {
+	Locale      string
+	RequestType string
+	Prompt      string
+	NoteTitle   string
+	NoteTarget  string
+	Links       []ResultLink
+	PreviewURL  string
+	Error       string
+}
 
-下载链接：%s
+// Render 使用模板渲染邮件，tpl 为 nil 时使用对应语言的内置模板
+func (s *Service) Render(name string, tpl *Template, msg Message) (*Rendered, error) {
+	locale := msg.Locale
+	if locale == "" {
+		locale = LocaleZH
+	}
+	if tpl == nil {
+		defaultTpl, ok := DefaultTemplate(name, locale)
+		if !ok {
+			return nil, fmt.Errorf("unknown email template %s.%s", name, locale)
+		}
+		tpl = &defaultTpl
+	}
 
-链接有效期为1小时，请及时下载。
+	data := TemplateData{
+		RequestType: requestTypeText(locale, msg.RequestType),
+		Prompt:      msg.Prompt,
+		NoteTitle:   msg.NoteTitle,
+		NoteTarget:  msg.NoteTarget,
+		PreviewURL:  msg.PreviewURL,
+		Error:       msg.Error,
+	}
+	// 仅当全部链接都由自有存储签发时才能给出确切的有效期，供应商直链的有效期未知
+	hosted := len(msg.Links) > 0
+	for i, link := range msg.Links {
+		hosted = hosted && link.Hosted
+		data.Links = append(data.Links, TemplateLink{
+			Index: i + 1,
+			Kind:  kindText(locale, link.Kind),
+			URL:   link.URL,
+		})
+	}
 
-此邮件由系统自动发送，请勿回复。`,
-		s.getRequestTypeText(requestType),
-		prompt,
-		resultURL,
-	)
+	if hosted {
+		data.Expiry = formatExpiry(locale, s.linkExpiry)
+	}
 
-	return s.Send(Email{
-		To:      to,
-		Subject: subject,
-		Body:    body,
-		IsHTML:  false,
-	})
+	return RenderTemplate(*tpl, data)
 }
 
-func (s *Service) SendErrorEmail(to, requestType, prompt, errorMsg string) error {
-	subject := fmt.Sprintf("您的%s生成失败", s.getRequestTypeText(requestType))
-
-	body := fmt.Sprintf(`您好！
-
-很抱歉，您请求的%s生成失败。
-
-请求描述：%s
+// Preview 使用示例数据渲染模板，tpl 为 nil 时使用内置模板
+func (s *Service) Preview(name, locale string, tpl *Template) (*Rendered, error) {
+	return s.Render(name, tpl, sampleMessage(name, locale))
+}
 
-错误信息：%s
+func (s *Service) SendResultEmail(to string, tpl *Template, msg Message) error {
+	return s.sendTemplate(to, TemplateResult, tpl, msg)
+}
 
-请稍后重试或联系管理员。
+func (s *Service) SendErrorEmail(to string, tpl *Template, msg Message) error {
+	return s.sendTemplate(to, TemplateError, tpl, msg)
+}
 
-此邮件由系统自动发送，请勿回复。`,
-		s.getRequestTypeText(requestType),
-		prompt,
-		errorMsg,
-	)
+func (s *Service) sendTemplate(to, name string, tpl *Template, msg Message) error {
+	rendered, err := s.Render(name, tpl, msg)
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
 
 	return s.Send(Email{
-		To:      to,
-		Subject: subject,
-		Body:    body,
-		IsHTML:  false,
+		To:       to,
+		Subject:  rendered.Subject,
+		Body:     rendered.HTML,
+		TextBody: rendered.Text,
+		IsHTML:   true,
 	})
 }
-
-func (s *Service) getRequestTypeText(requestType string) string {
-	switch requestType {
-	case "image":
-		return "图片"
-	case "video":
-		return "视频"
-	default:
-		return "内容"
-	}
-}
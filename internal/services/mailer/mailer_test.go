@@ -0,0 +1,70 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/config"
+)
+
+func TestRenderExpiryOnlyForHostedLinks(t *testing.T) {
+	s := NewService(&config.SMTPConfig{}, 2*time.Hour)
+
+	tests := []struct {
+		name   string
+		locale string
+		links  []ResultLink
+		want   string
+		absent string
+	}{
+		{
+			name:   "hosted zh",
+			locale: LocaleZH,
+			links:  []ResultLink{{Kind: "image", URL: "https://minio.local/a.png", Hosted: true}},
+			want:   "链接有效期为2小时",
+			absent: "可能会过期",
+		},
+		{
+			name:   "provider link zh",
+			locale: LocaleZH,
+			links:  []ResultLink{{Kind: "image", URL: "https://provider.example/a.png"}},
+			want:   "可能会过期",
+			absent: "链接有效期为",
+		},
+		{
+			name:   "mixed en",
+			locale: LocaleEN,
+			links: []ResultLink{
+				{Kind: "image", URL: "https://minio.local/a.png", Hosted: true},
+				{Kind: "image", URL: "https://provider.example/b.png"},
+			},
+			want:   "may expire",
+			absent: "expire in",
+		},
+		{
+			name:   "hosted en",
+			locale: LocaleEN,
+			links:  []ResultLink{{Kind: "image", URL: "https://minio.local/a.png", Hosted: true}},
+			want:   "expire in 2 hours",
+			absent: "may expire",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := s.Render(TemplateResult, nil, Message{Locale: tt.locale, RequestType: "image", Links: tt.links})
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			for _, body := range []string{rendered.HTML, rendered.Text} {
+				if !strings.Contains(body, tt.want) {
+					t.Errorf("body does not contain %q:\n%s", tt.want, body)
+				}
+				if strings.Contains(body, tt.absent) {
+					t.Errorf("body unexpectedly contains %q:\n%s", tt.absent, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode"
+)
+
+const (
+	TemplateResult = "result"
+	TemplateError  = "error"
+
+	LocaleZH = "zh"
+	LocaleEN = "en"
+)
+
+var (
+	TemplateNames = []string{TemplateResult, TemplateError}
+	Locales       = []string{LocaleZH, LocaleEN}
+)
+
+// Template 为一封邮件的模板：Subject 与 TextBody 使用 text/template，HTMLBody 使用 html/template，
+// 可用变量见 TemplateData
+type Template struct {
+	Subject  string `json:"subject"`
+	HTMLBody string `json:"html_body"`
+	TextBody string `json:"text_body"`
+}
+
+// TemplateData 为渲染模板时可用的变量，RequestType 与 Links 中的 Kind 已按语言本地化
+// Expiry 仅在全部链接都由自有存储签发时非空，供应商直链的有效期未知
+type TemplateData struct {
+	RequestType string
+	Prompt      string
+	NoteTitle   string
+	NoteTarget  string
+	Links       []TemplateLink
+	PreviewURL  string
+	Expiry      string
+	Error       string
+}
+
+// TemplateLink 为模板中的下载链接，Index 从 1 开始
+type TemplateLink struct {
+	Index int
+	Kind  string
+	URL   string
+}
+
+// Rendered 为渲染后的邮件
+type Rendered struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// IsValidTemplate 判断模板名与语言是否受支持
+func IsValidTemplate(name, locale string) bool {
+	return containsString(TemplateNames, name) && containsString(Locales, locale)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTemplate 返回内置模板，不受支持的模板名或语言返回 false
+func DefaultTemplate(name, locale string) (Template, bool) {
+	tpl, ok := defaultTemplates[name+"."+locale]
+	return tpl, ok
+}
+
+// RenderTemplate 渲染模板，模板语法错误或引用了不存在的变量时返回错误
+func RenderTemplate(tpl Template, data TemplateData) (*Rendered, error) {
+	subject, err := executeText("subject", tpl.Subject, data)
+	if err != nil {
+		return nil, err
+	}
+	text, err := executeText("text_body", tpl.TextBody, data)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTpl, err := htmltemplate.New("html_body").Option("missingkey=error").Parse(tpl.HTMLBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html_body: %w", err)
+	}
+	var html bytes.Buffer
+	if err := htmlTpl.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render html_body: %w", err)
+	}
+
+	return &Rendered{
+		// 主题不能换行
+		Subject: strings.Join(strings.Fields(subject), " "),
+		HTML:    html.String(),
+		Text:    text,
+	}, nil
+}
+
+func executeText(name, body string, data TemplateData) (string, error) {
+	tpl, err := texttemplate.New(name).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+var localeStripPattern = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}|https?://\S+`)
+
+// DetectLocale 按评论内容选择邮件语言：包含中文时为 zh，去掉邮箱与链接后只有英文字母时为 en，否则为 zh
+func DetectLocale(text string) string {
+	text = localeStripPattern.ReplaceAllString(text, "")
+	hasLatin := false
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return LocaleZH
+		}
+		if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			hasLatin = true
+		}
+	}
+	if hasLatin {
+		return LocaleEN
+	}
+	return LocaleZH
+}
+
+// formatExpiry 将链接有效期格式化为对应语言的文字，如 "1小时"、"2 hours"
+func formatExpiry(locale string, d time.Duration) string {
+	type unit struct {
+		size   time.Duration
+		zh, en string
+	}
+	units := []unit{
+		{24 * time.Hour, "天", "day"},
+		{time.Hour, "小时", "hour"},
+		{time.Minute, "分钟", "minute"},
+	}
+	for _, u := range units {
+		if d >= u.size && d%u.size == 0 {
+			n := int(d / u.size)
+			if locale == LocaleEN {
+				if n == 1 {
+					return fmt.Sprintf("1 %s", u.en)
+				}
+				return fmt.Sprintf("%d %ss", n, u.en)
+			}
+			return fmt.Sprintf("%d%s", n, u.zh)
+		}
+	}
+	if locale == LocaleEN {
+		return fmt.Sprintf("%d seconds", int(d/time.Second))
+	}
+	return fmt.Sprintf("%d秒", int(d/time.Second))
+}
+
+func requestTypeText(locale, requestType string) string {
+	switch requestType {
+	case "image", "edit":
+		if locale == LocaleEN {
+			return "image"
+		}
+		return "图片"
+	case "video":
+		if locale == LocaleEN {
+			return "video"
+		}
+		return "视频"
+	default:
+		if locale == LocaleEN {
+			return "content"
+		}
+		return "内容"
+	}
+}
+
+func kindText(locale, kind string) string {
+	switch kind {
+	case "cover":
+		if locale == LocaleEN {
+			return "cover"
+		}
+		return "封面"
+	case "thumbnail":
+		if locale == LocaleEN {
+			return "thumbnail"
+		}
+		return "缩略图"
+	default:
+		return requestTypeText(locale, kind)
+	}
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<body style="margin:0;padding:24px;background:#f5f5f5;font-family:-apple-system,'PingFang SC','Microsoft YaHei',Arial,sans-serif;color:#333;">
+<div style="max-width:560px;margin:0 auto;background:#fff;border-radius:8px;padding:32px;">
+`
+
+const htmlFooter = `</div>
+</body>
+</html>
+`
+
+var defaultTemplates = map[string]Template{
+	TemplateResult + "." + LocaleZH: {
+		Subject: `您的{{.RequestType}}生成结果已就绪`,
+		HTMLBody: htmlHeader + `<p>您好！</p>
+<p>您请求的{{.RequestType}}已经生成完成。</p>
+{{if .NoteTitle}}<p style="color:#666;">来自笔记：{{.NoteTitle}}</p>
+{{end}}<p style="color:#666;">请求描述：{{.Prompt}}</p>
+{{if .PreviewURL}}<p><img src="{{.PreviewURL}}" alt="预览" style="max-width:100%;border-radius:4px;"></p>
+{{end}}<ul style="padding-left:20px;">
+{{range .Links}}<li><a href="{{.URL}}">下载{{.Kind}}</a></li>
+{{end}}</ul>
+<p>{{if .Expiry}}链接有效期为{{.Expiry}}，请及时下载。{{else}}链接由生成服务提供，可能会过期，请及时下载。{{end}}</p>
+<p style="color:#999;font-size:12px;">此邮件由系统自动发送，请勿回复。</p>
+` + htmlFooter,
+		TextBody: `您好！
+
+您请求的{{.RequestType}}已经生成完成。
+{{if .NoteTitle}}
+来自笔记：{{.NoteTitle}}
+{{end}}
+请求描述：{{.Prompt}}
+
+下载链接：{{if eq (len .Links) 1}}{{(index .Links 0).URL}}{{else}}{{range .Links}}
+{{.Index}}. [{{.Kind}}] {{.URL}}{{end}}{{end}}
+
+{{if .Expiry}}链接有效期为{{.Expiry}}，请及时下载。{{else}}链接由生成服务提供，可能会过期，请及时下载。{{end}}
+
+此邮件由系统自动发送，请勿回复。`,
+	},
+	TemplateResult + "." + LocaleEN: {
+		Subject: `Your {{.RequestType}} is ready`,
+		HTMLBody: htmlHeader + `<p>Hello,</p>
+<p>The {{.RequestType}} you requested has been generated.</p>
+{{if .NoteTitle}}<p style="color:#666;">From the note: {{.NoteTitle}}</p>
+{{end}}<p style="color:#666;">Request: {{.Prompt}}</p>
+{{if .PreviewURL}}<p><img src="{{.PreviewURL}}" alt="Preview" style="max-width:100%;border-radius:4px;"></p>
+{{end}}<ul style="padding-left:20px;">
+{{range .Links}}<li><a href="{{.URL}}">Download {{.Kind}}</a></li>
+{{end}}</ul>
+<p>{{if .Expiry}}The links expire in {{.Expiry}}, please download soon.{{else}}The links are provided by the generation service and may expire, please download soon.{{end}}</p>
+<p style="color:#999;font-size:12px;">This email was sent automatically, please do not reply.</p>
+` + htmlFooter,
+		TextBody: `Hello,
+
+The {{.RequestType}} you requested has been generated.
+{{if .NoteTitle}}
+From the note: {{.NoteTitle}}
+{{end}}
+Request: {{.Prompt}}
+
+Download: {{if eq (len .Links) 1}}{{(index .Links 0).URL}}{{else}}{{range .Links}}
+{{.Index}}. [{{.Kind}}] {{.URL}}{{end}}{{end}}
+
+{{if .Expiry}}The links expire in {{.Expiry}}, please download soon.{{else}}The links are provided by the generation service and may expire, please download soon.{{end}}
+
+This email was sent automatically, please do not reply.`,
+	},
+	TemplateError + "." + LocaleZH: {
+		Subject: `您的{{.RequestType}}生成失败`,
+		HTMLBody: htmlHeader + `<p>您好！</p>
+<p>很抱歉，您请求的{{.RequestType}}生成失败。</p>
+{{if .NoteTitle}}<p style="color:#666;">来自笔记：{{.NoteTitle}}</p>
+{{end}}<p style="color:#666;">请求描述：{{.Prompt}}</p>
+<p style="color:#c00;">错误信息：{{.Error}}</p>
+<p>请稍后重试或联系管理员。</p>
+<p style="color:#999;font-size:12px;">此邮件由系统自动发送，请勿回复。</p>
+` + htmlFooter,
+		TextBody: `您好！
+
+很抱歉，您请求的{{.RequestType}}生成失败。
+{{if .NoteTitle}}
+来自笔记：{{.NoteTitle}}
+{{end}}
+请求描述：{{.Prompt}}
+
+错误信息：{{.Error}}
+
+请稍后重试或联系管理员。
+
+此邮件由系统自动发送，请勿回复。`,
+	},
+	TemplateError + "." + LocaleEN: {
+		Subject: `Your {{.RequestType}} could not be generated`,
+		HTMLBody: htmlHeader + `<p>Hello,</p>
+<p>Sorry, the {{.RequestType}} you requested could not be generated.</p>
+{{if .NoteTitle}}<p style="color:#666;">From the note: {{.NoteTitle}}</p>
+{{end}}<p style="color:#666;">Request: {{.Prompt}}</p>
+<p style="color:#c00;">Error: {{.Error}}</p>
+<p>Please try again later or contact the administrator.</p>
+<p style="color:#999;font-size:12px;">This email was sent automatically, please do not reply.</p>
+` + htmlFooter,
+		TextBody: `Hello,
+
+Sorry, the {{.RequestType}} you requested could not be generated.
+{{if .NoteTitle}}
+From the note: {{.NoteTitle}}
+{{end}}
+Request: {{.Prompt}}
+
+Error: {{.Error}}
+
+Please try again later or contact the administrator.
+
+This email was sent automatically, please do not reply.`,
+	},
+}
+
+// sampleMessage 为预览模板使用的示例数据
+func sampleMessage(name, locale string) Message {
+	msg := Message{
+		Locale:      locale,
+		RequestType: "image",
+		Prompt:      "雪山日出，油画风格",
+		NoteTitle:   "周末出游灵感",
+		NoteTarget:  "https://www.xiaohongshu.com/explore/demo_note_001",
+		Links: []ResultLink{
+			{Kind: "image", URL: "https://example.com/files/sample-1.png", Hosted: true},
+			{Kind: "image", URL: "https://example.com/files/sample-2.png", Hosted: true},
+		},
+		PreviewURL: "https://picsum.photos/seed/preview/480/320",
+	}
+	if locale == LocaleEN {
+		msg.Prompt = "A sunrise over snowy mountains, oil painting style"
+		msg.NoteTitle = "Weekend trip ideas"
+	}
+	if name == TemplateError {
+		msg.Links = nil
+		msg.PreviewURL = ""
+		msg.Error = "provider timeout"
+	}
+	return msg
+}
@@ -0,0 +1,310 @@
+package provider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AuthTypeBearer = "bearer"
+	AuthTypeHeader = "header"
+	AuthTypeQuery  = "query"
+	AuthTypeHMAC   = "hmac"
+	AuthTypeOAuth2 = "oauth2"
+)
+
+// AuthConfig 描述供应商的鉴权方式，未配置时沿用 APIKey + Bearer 头
+type AuthConfig struct {
+	Type string `json:"type"`
+
+	// header / query：APIKey 放在指定的请求头或查询参数中
+	HeaderName string `json:"header_name,omitempty"`
+	QueryParam string `json:"query_param,omitempty"`
+	Prefix     string `json:"prefix,omitempty"`
+
+	// hmac：按 StringToSign 模板签名，占位符见 buildStringToSign
+	AccessKey       string `json:"access_key,omitempty"`
+	SecretKey       string `json:"secret_key,omitempty"`
+	Algorithm       string `json:"algorithm,omitempty"`
+	Encoding        string `json:"encoding,omitempty"`
+	StringToSign    string `json:"string_to_sign,omitempty"`
+	AccessKeyHeader string `json:"access_key_header,omitempty"`
+	SignatureHeader string `json:"signature_header,omitempty"`
+	TimestampHeader string `json:"timestamp_header,omitempty"`
+	NonceHeader     string `json:"nonce_header,omitempty"`
+	TimestampUnit   string `json:"timestamp_unit,omitempty"`
+
+	// oauth2：client credentials 模式
+	TokenURL     string `json:"token_url,omitempty"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+const defaultStringToSign = "{method}\n{path}\n{query}\n{timestamp}\n{nonce}\n{body_sha256}"
+
+// Authenticator 为发往供应商的请求附加鉴权信息
+type Authenticator interface {
+	Apply(ctx context.Context, req *http.Request, body []byte) error
+}
+
+// tokenInvalidator 由缓存令牌的鉴权方式实现，供应商返回 401 时清除缓存
+type tokenInvalidator interface {
+	Invalidate()
+}
+
+func NewAuthenticator(cfg *ProviderConfig, client *http.Client) (Authenticator, error) {
+	auth := cfg.Auth
+	if auth == nil || auth.Type == "" {
+		return &headerAuth{name: "Authorization", prefix: "Bearer ", key: cfg.APIKey}, nil
+	}
+
+	switch strings.ToLower(auth.Type) {
+	case AuthTypeBearer:
+		return &headerAuth{name: "Authorization", prefix: "Bearer ", key: cfg.APIKey}, nil
+
+	case AuthTypeHeader:
+		if auth.HeaderName == "" {
+			return nil, fmt.Errorf("auth.header_name is required for header auth")
+		}
+		return &headerAuth{name: auth.HeaderName, prefix: auth.Prefix, key: cfg.APIKey}, nil
+
+	case AuthTypeQuery:
+		if auth.QueryParam == "" {
+			return nil, fmt.Errorf("auth.query_param is required for query auth")
+		}
+		return &queryAuth{param: auth.QueryParam, key: cfg.APIKey}, nil
+
+	case AuthTypeHMAC:
+		return newHMACAuth(auth)
+
+	case AuthTypeOAuth2:
+		if auth.TokenURL == "" || auth.ClientID == "" || auth.ClientSecret == "" {
+			return nil, fmt.Errorf("auth.token_url, client_id and client_secret are required for oauth2 auth")
+		}
+		return &oauth2Auth{cfg: auth, client: client}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported auth type: %s", auth.Type)
+}
+
+type headerAuth struct {
+	name   string
+	prefix string
+	key    string
+}
+
+func (a *headerAuth) Apply(ctx context.Context, req *http.Request, body []byte) error {
+	if a.key != "" {
+		req.Header.Set(a.name, a.prefix+a.key)
+	}
+	return nil
+}
+
+type queryAuth struct {
+	param string
+	key   string
+}
+
+func (a *queryAuth) Apply(ctx context.Context, req *http.Request, body []byte) error {
+	q := req.URL.Query()
+	q.Set(a.param, a.key)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+type hmacAuth struct {
+	cfg     *AuthConfig
+	newHash func() hash.Hash
+	now     func() time.Time
+}
+
+func newHMACAuth(cfg *AuthConfig) (*hmacAuth, error) {
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("auth.access_key and secret_key are required for hmac auth")
+	}
+
+	a := &hmacAuth{cfg: cfg, now: time.Now}
+	switch strings.ToLower(cfg.Algorithm) {
+	case "", "sha256", "hmac-sha256":
+		a.newHash = sha256.New
+	case "sha1", "hmac-sha1":
+		a.newHash = sha1.New
+	default:
+		return nil, fmt.Errorf("unsupported hmac algorithm: %s", cfg.Algorithm)
+	}
+
+	switch strings.ToLower(cfg.Encoding) {
+	case "", "hex", "base64":
+	default:
+		return nil, fmt.Errorf("unsupported signature encoding: %s", cfg.Encoding)
+	}
+
+	switch cfg.TimestampUnit {
+	case "", "s", "ms":
+	default:
+		return nil, fmt.Errorf("unsupported timestamp_unit: %s", cfg.TimestampUnit)
+	}
+
+	return a, nil
+}
+
+func (a *hmacAuth) Apply(ctx context.Context, req *http.Request, body []byte) error {
+	timestamp := strconv.FormatInt(a.now().Unix(), 10)
+	if a.cfg.TimestampUnit == "ms" {
+		timestamp = strconv.FormatInt(a.now().UnixMilli(), 10)
+	}
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+
+	stringToSign := BuildStringToSign(a.cfg.StringToSign, req, body, a.cfg.AccessKey, timestamp, nonce)
+	signature := SignHMAC(a.newHash, a.cfg.SecretKey, stringToSign, a.cfg.Encoding)
+
+	req.Header.Set(headerOrDefault(a.cfg.AccessKeyHeader, "X-Access-Key"), a.cfg.AccessKey)
+	req.Header.Set(headerOrDefault(a.cfg.TimestampHeader, "X-Timestamp"), timestamp)
+	req.Header.Set(headerOrDefault(a.cfg.NonceHeader, "X-Nonce"), nonce)
+	req.Header.Set(headerOrDefault(a.cfg.SignatureHeader, "X-Signature"), signature)
+	return nil
+}
+
+// BuildStringToSign 替换签名模板中的占位符：
+// {method} {path} {query}（按键排序） {timestamp} {nonce} {access_key} {body} {body_sha256}
+func BuildStringToSign(tpl string, req *http.Request, body []byte, accessKey, timestamp, nonce string) string {
+	if tpl == "" {
+		tpl = defaultStringToSign
+	}
+
+	bodyHash := sha256.Sum256(body)
+	replacer := strings.NewReplacer(
+		"{method}", req.Method,
+		"{path}", req.URL.EscapedPath(),
+		"{query}", req.URL.Query().Encode(),
+		"{timestamp}", timestamp,
+		"{nonce}", nonce,
+		"{access_key}", accessKey,
+		"{body}", string(body),
+		"{body_sha256}", hex.EncodeToString(bodyHash[:]),
+	)
+	return replacer.Replace(tpl)
+}
+
+func SignHMAC(newHash func() hash.Hash, secret, stringToSign, encoding string) string {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	sum := mac.Sum(nil)
+	if strings.ToLower(encoding) == "base64" {
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+	return hex.EncodeToString(sum)
+}
+
+func headerOrDefault(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// tokenRefreshMargin 在令牌过期前提前刷新
+const tokenRefreshMargin = 60 * time.Second
+
+type oauth2Auth struct {
+	cfg    *AuthConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (a *oauth2Auth) Apply(ctx context.Context, req *http.Request, body []byte) error {
+	token, err := a.getToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *oauth2Auth) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+	a.expiresAt = time.Time{}
+}
+
+func (a *oauth2Auth) getToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Add(tokenRefreshMargin).Before(a.expiresAt) {
+		return a.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if a.cfg.Scope != "" {
+		form.Set("scope", a.cfg.Scope)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.SetBasicAuth(url.QueryEscape(a.cfg.ClientID), url.QueryEscape(a.cfg.ClientSecret))
+
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("token endpoint returned no access_token")
+	}
+
+	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = time.Hour
+	}
+
+	a.token = tokenResp.AccessToken
+	a.expiresAt = time.Now().Add(expiresIn)
+	return a.token, nil
+}
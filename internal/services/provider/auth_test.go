@@ -0,0 +1,378 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newAuthTestProvider 创建只用于发送请求的 HTTPProvider，鉴权方式由 cfg 决定
+func newAuthTestProvider(t *testing.T, cfg *ProviderConfig) *HTTPProvider {
+	t.Helper()
+	client := &http.Client{}
+	auth, err := NewAuthenticator(cfg, client)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	return &HTTPProvider{cfg: cfg, client: client, auth: auth}
+}
+
+func TestKeyAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    *ProviderConfig
+		verify func(r *http.Request) error
+	}{
+		{
+			name: "default bearer",
+			cfg:  &ProviderConfig{APIKey: "secret"},
+			verify: func(r *http.Request) error {
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					return fmt.Errorf("Authorization = %q", got)
+				}
+				return nil
+			},
+		},
+		{
+			name: "bearer",
+			cfg:  &ProviderConfig{APIKey: "secret", Auth: &AuthConfig{Type: AuthTypeBearer}},
+			verify: func(r *http.Request) error {
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					return fmt.Errorf("Authorization = %q", got)
+				}
+				return nil
+			},
+		},
+		{
+			name: "header with prefix",
+			cfg:  &ProviderConfig{APIKey: "secret", Auth: &AuthConfig{Type: AuthTypeHeader, HeaderName: "X-API-Key", Prefix: "Key "}},
+			verify: func(r *http.Request) error {
+				if got := r.Header.Get("X-API-Key"); got != "Key secret" {
+					return fmt.Errorf("X-API-Key = %q", got)
+				}
+				if got := r.Header.Get("Authorization"); got != "" {
+					return fmt.Errorf("unexpected Authorization %q", got)
+				}
+				return nil
+			},
+		},
+		{
+			name: "query keeps existing params",
+			cfg:  &ProviderConfig{APIKey: "se cret", Auth: &AuthConfig{Type: AuthTypeQuery, QueryParam: "api_key"}},
+			verify: func(r *http.Request) error {
+				q := r.URL.Query()
+				if got := q.Get("api_key"); got != "se cret" {
+					return fmt.Errorf("api_key = %q", got)
+				}
+				if got := q.Get("page"); got != "2" {
+					return fmt.Errorf("page = %q", got)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verifyErr error
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				verifyErr = tt.verify(r)
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			p := newAuthTestProvider(t, tt.cfg)
+			if _, err := p.do(context.Background(), http.MethodGet, server.URL+"/jobs/1?page=2", nil); err != nil {
+				t.Fatalf("do: %v", err)
+			}
+			if verifyErr != nil {
+				t.Fatal(verifyErr)
+			}
+		})
+	}
+}
+
+func TestAuthConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		auth *AuthConfig
+	}{
+		{name: "header without name", auth: &AuthConfig{Type: AuthTypeHeader}},
+		{name: "query without param", auth: &AuthConfig{Type: AuthTypeQuery}},
+		{name: "hmac without secret", auth: &AuthConfig{Type: AuthTypeHMAC, AccessKey: "ak"}},
+		{name: "hmac bad algorithm", auth: &AuthConfig{Type: AuthTypeHMAC, AccessKey: "ak", SecretKey: "sk", Algorithm: "md5"}},
+		{name: "hmac bad encoding", auth: &AuthConfig{Type: AuthTypeHMAC, AccessKey: "ak", SecretKey: "sk", Encoding: "base32"}},
+		{name: "hmac bad timestamp unit", auth: &AuthConfig{Type: AuthTypeHMAC, AccessKey: "ak", SecretKey: "sk", TimestampUnit: "us"}},
+		{name: "oauth2 without secret", auth: &AuthConfig{Type: AuthTypeOAuth2, TokenURL: "http://x/token", ClientID: "id"}},
+		{name: "unknown type", auth: &AuthConfig{Type: "kerberos"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAuthenticator(&ProviderConfig{APIKey: "k", Auth: tt.auth}, http.DefaultClient); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestHMACAuthSignature(t *testing.T) {
+	fixed := time.Unix(1700000000, 123000000)
+
+	tests := []struct {
+		name    string
+		auth    AuthConfig
+		newHash func() hash.Hash
+		ts      string
+		headers [4]string
+	}{
+		{
+			name:    "defaults",
+			auth:    AuthConfig{Type: AuthTypeHMAC, AccessKey: "ak", SecretKey: "sk"},
+			newHash: sha256.New,
+			ts:      "1700000000",
+			headers: [4]string{"X-Access-Key", "X-Timestamp", "X-Nonce", "X-Signature"},
+		},
+		{
+			name: "custom template sha1 base64 ms",
+			auth: AuthConfig{
+				Type: AuthTypeHMAC, AccessKey: "ak", SecretKey: "sk",
+				Algorithm: "hmac-sha1", Encoding: "base64", TimestampUnit: "ms",
+				StringToSign:    "{access_key}|{method}|{path}|{query}|{timestamp}|{nonce}|{body}",
+				AccessKeyHeader: "X-Ca-Key", TimestampHeader: "X-Ca-Timestamp", NonceHeader: "X-Ca-Nonce", SignatureHeader: "X-Ca-Signature",
+			},
+			newHash: sha1.New,
+			ts:      "1700000000123",
+			headers: [4]string{"X-Ca-Key", "X-Ca-Timestamp", "X-Ca-Nonce", "X-Ca-Signature"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(`{"prompt":"a cat"}`)
+
+			// 服务端按相同模板独立计算签名并比对
+			var verifyErr error
+			nonces := make(map[string]bool)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				verifyErr = func() error {
+					if got := r.Header.Get(tt.headers[0]); got != "ak" {
+						return fmt.Errorf("%s = %q", tt.headers[0], got)
+					}
+					ts := r.Header.Get(tt.headers[1])
+					if ts != tt.ts {
+						return fmt.Errorf("%s = %q, want %q", tt.headers[1], ts, tt.ts)
+					}
+					nonce := r.Header.Get(tt.headers[2])
+					if nonce == "" || nonces[nonce] {
+						return fmt.Errorf("nonce %q is empty or reused", nonce)
+					}
+					nonces[nonce] = true
+					want := SignHMAC(tt.newHash, "sk", BuildStringToSign(tt.auth.StringToSign, r, body, "ak", ts, nonce), tt.auth.Encoding)
+					if got := r.Header.Get(tt.headers[3]); got != want {
+						return fmt.Errorf("%s = %q, want %q", tt.headers[3], got, want)
+					}
+					return nil
+				}()
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			auth := tt.auth
+			p := newAuthTestProvider(t, &ProviderConfig{Auth: &auth})
+			p.auth.(*hmacAuth).now = func() time.Time { return fixed }
+
+			for i := 0; i < 2; i++ {
+				if _, err := p.do(context.Background(), http.MethodPost, server.URL+"/v1/submit?b=2&a=1", body); err != nil {
+					t.Fatalf("do: %v", err)
+				}
+				if verifyErr != nil {
+					t.Fatal(verifyErr)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildStringToSignSortsQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/v1/jobs/a%2Fb?z=1&a=2", nil)
+	got := BuildStringToSign("{method} {path} {query} {body_sha256}", req, nil, "ak", "1", "n")
+	want := "GET /v1/jobs/a%2Fb a=2&z=1 e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// oauth2Server 模拟令牌接口与业务接口，revoke 后业务接口对旧令牌返回 401
+type oauth2Server struct {
+	mu           sync.Mutex
+	issued       int
+	current      string
+	expiresIn    int
+	lastScope    string
+	apiCalls     int
+	basicAuthErr error
+}
+
+func (s *oauth2Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/oauth/token" {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "client-secret" {
+			s.basicAuthErr = fmt.Errorf("basic auth = %q/%q", id, secret)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.lastScope = r.PostForm.Get("scope")
+		s.issued++
+		s.current = fmt.Sprintf("token-%d", s.issued)
+		fmt.Fprintf(w, `{"access_token":%q,"expires_in":%d}`, s.current, s.expiresIn)
+		return
+	}
+
+	s.apiCalls++
+	if r.Header.Get("Authorization") != "Bearer "+s.current {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid token"}`))
+		return
+	}
+	w.Write([]byte(`{"ok":true}`))
+}
+
+func (s *oauth2Server) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = "revoked"
+}
+
+func newOAuth2TestProvider(t *testing.T, url string) *HTTPProvider {
+	return newAuthTestProvider(t, &ProviderConfig{Auth: &AuthConfig{
+		Type:         AuthTypeOAuth2,
+		TokenURL:     url + "/oauth/token",
+		ClientID:     "client",
+		ClientSecret: "client-secret",
+		Scope:        "images",
+	}})
+}
+
+func TestOAuth2TokenCaching(t *testing.T) {
+	state := &oauth2Server{expiresIn: 3600}
+	server := httptest.NewServer(state)
+	defer server.Close()
+
+	p := newOAuth2TestProvider(t, server.URL)
+	for i := 0; i < 3; i++ {
+		if _, err := p.do(context.Background(), http.MethodGet, server.URL+"/jobs/1", nil); err != nil {
+			t.Fatalf("do: %v", err)
+		}
+	}
+	if state.basicAuthErr != nil {
+		t.Fatal(state.basicAuthErr)
+	}
+	if state.issued != 1 {
+		t.Fatalf("issued %d tokens, want 1", state.issued)
+	}
+	if state.lastScope != "images" {
+		t.Fatalf("scope = %q", state.lastScope)
+	}
+}
+
+func TestOAuth2RefreshesExpiringToken(t *testing.T) {
+	// 有效期短于提前刷新的余量，每次请求都应重新获取
+	state := &oauth2Server{expiresIn: int(tokenRefreshMargin/time.Second) - 1}
+	server := httptest.NewServer(state)
+	defer server.Close()
+
+	p := newOAuth2TestProvider(t, server.URL)
+	for i := 0; i < 2; i++ {
+		if _, err := p.do(context.Background(), http.MethodGet, server.URL+"/jobs/1", nil); err != nil {
+			t.Fatalf("do: %v", err)
+		}
+	}
+	if state.issued != 2 {
+		t.Fatalf("issued %d tokens, want 2", state.issued)
+	}
+}
+
+func TestOAuth2RefreshOn401(t *testing.T) {
+	state := &oauth2Server{expiresIn: 3600}
+	server := httptest.NewServer(state)
+	defer server.Close()
+
+	p := newOAuth2TestProvider(t, server.URL)
+	if _, err := p.do(context.Background(), http.MethodGet, server.URL+"/jobs/1", nil); err != nil {
+		t.Fatalf("do: %v", err)
+	}
+
+	state.revoke()
+	body, err := p.do(context.Background(), http.MethodGet, server.URL+"/jobs/1", nil)
+	if err != nil {
+		t.Fatalf("do after revoke: %v", err)
+	}
+	if !strings.Contains(string(body), `"ok":true`) {
+		t.Fatalf("unexpected body %s", body)
+	}
+	if state.issued != 2 {
+		t.Fatalf("issued %d tokens, want 2", state.issued)
+	}
+	// 首次请求 + 401 + 重试
+	if state.apiCalls != 3 {
+		t.Fatalf("api called %d times, want 3", state.apiCalls)
+	}
+}
+
+func TestOAuth2RetriesOnlyOnce(t *testing.T) {
+	state := &oauth2Server{expiresIn: 3600}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth/token" {
+			state.mu.Lock()
+			state.apiCalls++
+			state.mu.Unlock()
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		state.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	p := newOAuth2TestProvider(t, server.URL)
+	_, err := p.do(context.Background(), http.MethodGet, server.URL+"/jobs/1", nil)
+	if err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Fatalf("expected 401 error, got %v", err)
+	}
+	if state.apiCalls != 2 || state.issued != 2 {
+		t.Fatalf("api calls %d, tokens %d; want 2 and 2", state.apiCalls, state.issued)
+	}
+}
+
+func TestKeyAuthDoesNotRetryOn401(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	p := newAuthTestProvider(t, &ProviderConfig{APIKey: "wrong"})
+	if _, err := p.do(context.Background(), http.MethodGet, server.URL, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Fatalf("called %d times, want 1", calls)
+	}
+}
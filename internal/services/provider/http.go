@@ -6,32 +6,56 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
-	"time"
 )
 
 type HTTPProvider struct {
-	cfg    *ProviderConfig
-	client *http.Client
-	mapper *Mapper
+	cfg     *ProviderConfig
+	client  *http.Client
+	mapper  *Mapper
+	auth    Authenticator
+	storage Storage
 }
 
-func NewHTTPProvider(cfg *ProviderConfig) *HTTPProvider {
-	return &HTTPProvider{
-		cfg: cfg,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		mapper: NewMapper(cfg.RequestMapping),
+func NewHTTPProvider(cfg *ProviderConfig, storage Storage) (*HTTPProvider, error) {
+	mapper, err := NewMapper(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping for provider %s: %w", cfg.ProviderName, err)
+	}
+
+	// 超时由每次调用的 context 控制，见 SubmitTimeout / StatusTimeout
+	client := &http.Client{}
+
+	auth, err := NewAuthenticator(cfg, client)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth for provider %s: %w", cfg.ProviderName, err)
 	}
+
+	return &HTTPProvider{
+		cfg:     cfg,
+		client:  client,
+		mapper:  mapper,
+		auth:    auth,
+		storage: storage,
+	}, nil
 }
 
 func (p *HTTPProvider) Name() string {
 	return p.cfg.ProviderName
 }
 
+// Close 释放空闲连接，实例被 Registry 替换后调用
+func (p *HTTPProvider) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
 func (p *HTTPProvider) Submit(ctx context.Context, req UnifiedGenRequest) (*SubmitResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.SubmitTimeout())
+	defer cancel()
+
 	mappedReq, err := p.mapper.MapRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to map request: %w", err)
@@ -42,86 +66,234 @@ func (p *HTTPProvider) Submit(ctx context.Context, req UnifiedGenRequest) (*Subm
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := p.cfg.BaseURL + p.cfg.SubmitPath
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	body, err := p.do(ctx, "POST", p.cfg.BaseURL+p.cfg.SubmitPath, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	for k, v := range p.cfg.Headers {
-		httpReq.Header.Set(k, v)
+	if p.cfg.IsSync() {
+		return p.syncResult(ctx, req, body)
 	}
 
-	if p.cfg.APIKey != "" {
-		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.cfg.APIKey))
+	jobID, err := p.mapper.ExtractJobID(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract job ID: %w", err)
 	}
 
-	resp, err := p.client.Do(httpReq)
+	return &SubmitResult{
+		ProviderJobID: jobID,
+	}, nil
+}
+
+func (p *HTTPProvider) Status(ctx context.Context, jobID string) (*StatusResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.StatusTimeout())
+	defer cancel()
+
+	statusPath := strings.Replace(p.cfg.StatusPathTemplate, "{id}", jobID, -1)
+
+	body, err := p.do(ctx, "GET", p.cfg.BaseURL+statusPath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	result, err := p.mapper.ExtractStatus(body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to extract status: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(body))
+	// 异步任务完成时同样可能返回 base64 或 data: URL 产物
+	if result.Status == JobStatusSucceeded {
+		if err := p.uploadInlineArtifacts(ctx, result, jobID); err != nil {
+			return nil, err
+		}
 	}
 
-	jobID, err := p.mapper.ExtractJobID(body)
+	return result, nil
+}
+
+// Cancel 调用 cancel_path_template 取消供应商任务，默认使用 POST
+func (p *HTTPProvider) Cancel(ctx context.Context, jobID string) error {
+	if p.cfg.CancelPathTemplate == "" {
+		return ErrCancelNotSupported
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.StatusTimeout())
+	defer cancel()
+
+	method := p.cfg.CancelMethod
+	if method == "" {
+		method = "POST"
+	}
+
+	cancelPath := strings.Replace(p.cfg.CancelPathTemplate, "{id}", jobID, -1)
+	if _, err := p.do(ctx, method, p.cfg.BaseURL+cancelPath, nil); err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return nil
+}
+
+// CheckHealth 请求 HealthCheck.Path，状态码与 ExpectStatus 一致（未配置时为任意 2xx）即视为健康
+func (p *HTTPProvider) CheckHealth(ctx context.Context) error {
+	hc := p.cfg.HealthCheck
+	if hc == nil || hc.Path == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hc.Timeout())
+	defer cancel()
+
+	method := hc.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	statusCode, body, err := p.send(ctx, method, p.cfg.BaseURL+hc.Path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract job ID: %w", err)
+		return err
+	}
+
+	if hc.ExpectStatus > 0 {
+		if statusCode != hc.ExpectStatus {
+			return fmt.Errorf("health check returned status %d, expected %d", statusCode, hc.ExpectStatus)
+		}
+		return nil
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		return fmt.Errorf("health check returned status %d: %s", statusCode, truncate(string(body), 200))
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// uploadInlineArtifacts 把内联的 base64 / data: URL 产物上传到存储并替换为存储地址，
+// 对象名以 name（同步为请求 ID，异步为供应商任务 ID）区分；有产物时 ResultURL 指向第一个产物
+func (p *HTTPProvider) uploadInlineArtifacts(ctx context.Context, result *StatusResult, name string) error {
+	for i := range result.Artifacts {
+		a := &result.Artifacts[i]
+		if a.Data == nil {
+			continue
+		}
+		if p.storage == nil {
+			return fmt.Errorf("storage is required for inline provider output")
+		}
+
+		objectKey := fmt.Sprintf("results/%s/%s_%d%s", p.cfg.ProviderName, strings.ReplaceAll(name, "/", "_"), i, extensionForMime(a.MimeType))
+		url, err := p.storage.Upload(ctx, objectKey, a.Data, a.MimeType)
+		if err != nil {
+			return fmt.Errorf("failed to upload inline output: %w", err)
+		}
+		a.URL = url
+		a.ObjectKey = objectKey
+		a.Data = nil
+	}
+	if len(result.Artifacts) > 0 {
+		url := result.Artifacts[0].URL
+		result.ResultURL = &url
+	}
+	return nil
+}
+
+// syncResult 将同步供应商的提交响应映射为结果，并把内联的 base64 产物上传到存储
+func (p *HTTPProvider) syncResult(ctx context.Context, req UnifiedGenRequest, body []byte) (*SubmitResult, error) {
+	result, err := p.mapper.ExtractSyncResult(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract result: %w", err)
 	}
 
+	if err := p.uploadInlineArtifacts(ctx, result, req.RequestID); err != nil {
+		return nil, err
+	}
+
+	// 同步响应中的任务 ID 可选，仅用于排查
+	jobID, _ := p.mapper.ExtractJobID(body)
+
 	return &SubmitResult{
 		ProviderJobID: jobID,
+		Result:        result,
 	}, nil
 }
 
-func (p *HTTPProvider) Status(ctx context.Context, jobID string) (*StatusResult, error) {
-	statusPath := strings.Replace(p.cfg.StatusPathTemplate, "{id}", jobID, -1)
-	url := p.cfg.BaseURL + statusPath
+func extensionForMime(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	case "video/mp4":
+		return ".mp4"
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// do 发送请求并返回响应体；使用缓存令牌的鉴权方式在收到 401 时会刷新令牌并重试一次
+func (p *HTTPProvider) do(ctx context.Context, method, url string, reqBody []byte) ([]byte, error) {
+	statusCode, body, err := p.send(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode == http.StatusUnauthorized {
+		if inv, ok := p.auth.(tokenInvalidator); ok {
+			inv.Invalidate()
+			statusCode, body, err = p.send(ctx, method, url, reqBody)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if statusCode < 200 || statusCode >= 300 {
+		return nil, fmt.Errorf("provider returned status %d: %s", statusCode, string(body))
+	}
+
+	return body, nil
+}
+
+func (p *HTTPProvider) send(ctx context.Context, method, url string, reqBody []byte) (int, []byte, error) {
+	var bodyReader io.Reader
+	if reqBody != nil {
+		bodyReader = bytes.NewReader(reqBody)
+	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return 0, nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
+	if reqBody != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
 	for k, v := range p.cfg.Headers {
 		httpReq.Header.Set(k, v)
 	}
 
-	if p.cfg.APIKey != "" {
-		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.cfg.APIKey))
+	if err := p.auth.Apply(ctx, httpReq, reqBody); err != nil {
+		return 0, nil, fmt.Errorf("failed to authenticate request: %w", err)
 	}
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return 0, nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(body))
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	status, progress, resultURL, err := p.mapper.ExtractStatus(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract status: %w", err)
-	}
-
-	return &StatusResult{
-		Status:    status,
-		Progress:  progress,
-		ResultURL: resultURL,
-	}, nil
+	return resp.StatusCode, body, nil
 }
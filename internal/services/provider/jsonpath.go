@@ -0,0 +1,602 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath 是预编译的 JSONPath 表达式，支持以下语法：
+//
+//	$.a.b          子字段
+//	$['a b']       带引号的字段名
+//	$.items[0]     数组下标（支持负数）
+//	$.items[1:3]   数组切片
+//	$.items[*]     通配符（也可写作 $.data.*）
+//	$..url         递归查找
+//	$.items[?(@.kind == 'image' && @.width >= 512)]  过滤器
+type JSONPath struct {
+	raw   string
+	steps []pathStep
+}
+
+type stepKind int
+
+const (
+	stepChild stepKind = iota
+	stepIndex
+	stepSlice
+	stepWildcard
+	stepRecursive
+	stepFilter
+)
+
+type pathStep struct {
+	kind   stepKind
+	name   string
+	index  int
+	start  *int
+	end    *int
+	filter *filterExpr
+}
+
+func CompileJSONPath(path string) (*JSONPath, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("empty JSONPath")
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath must start with $: %s", path)
+	}
+
+	p := &pathParser{src: path, pos: 1}
+	steps, err := p.parseSteps()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %s: %w", path, err)
+	}
+
+	return &JSONPath{raw: path, steps: steps}, nil
+}
+
+func (p *JSONPath) String() string {
+	return p.raw
+}
+
+// Get 返回所有匹配的节点
+func (p *JSONPath) Get(data interface{}) []interface{} {
+	return evalSteps(p.steps, []interface{}{data})
+}
+
+// First 返回第一个匹配的节点
+func (p *JSONPath) First(data interface{}) (interface{}, bool) {
+	results := p.Get(data)
+	if len(results) == 0 {
+		return nil, false
+	}
+	return results[0], true
+}
+
+// Definite 表示路径是否只会匹配单个节点（不含通配符、切片、递归或过滤器）
+func (p *JSONPath) Definite() bool {
+	for _, s := range p.steps {
+		if s.kind != stepChild && s.kind != stepIndex {
+			return false
+		}
+	}
+	return true
+}
+
+func evalSteps(steps []pathStep, nodes []interface{}) []interface{} {
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			next = append(next, applyStep(step, node)...)
+		}
+		nodes = next
+		if len(nodes) == 0 {
+			break
+		}
+	}
+	return nodes
+}
+
+func applyStep(step pathStep, node interface{}) []interface{} {
+	switch step.kind {
+	case stepChild:
+		if obj, ok := node.(map[string]interface{}); ok {
+			if v, exists := obj[step.name]; exists {
+				return []interface{}{v}
+			}
+		}
+		return nil
+
+	case stepIndex:
+		arr, ok := node.([]interface{})
+		if !ok {
+			return nil
+		}
+		i := step.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return nil
+		}
+		return []interface{}{arr[i]}
+
+	case stepSlice:
+		arr, ok := node.([]interface{})
+		if !ok {
+			return nil
+		}
+		start, end := 0, len(arr)
+		if step.start != nil {
+			start = normalizeIndex(*step.start, len(arr))
+		}
+		if step.end != nil {
+			end = normalizeIndex(*step.end, len(arr))
+		}
+		if start >= end {
+			return nil
+		}
+		return append([]interface{}{}, arr[start:end]...)
+
+	case stepWildcard:
+		return children(node)
+
+	case stepRecursive:
+		var out []interface{}
+		walk(node, func(n interface{}) {
+			if step.name == "" {
+				out = append(out, n)
+				return
+			}
+			if step.name == "*" {
+				out = append(out, children(n)...)
+				return
+			}
+			if obj, ok := n.(map[string]interface{}); ok {
+				if v, exists := obj[step.name]; exists {
+					out = append(out, v)
+				}
+			}
+		})
+		return out
+
+	case stepFilter:
+		var out []interface{}
+		for _, child := range children(node) {
+			if step.filter.match(child) {
+				out = append(out, child)
+			}
+		}
+		return out
+	}
+
+	return nil
+}
+
+func normalizeIndex(i, length int) int {
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case []interface{}:
+		return append([]interface{}{}, v...)
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		out := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, v[k])
+		}
+		return out
+	}
+	return nil
+}
+
+func walk(node interface{}, fn func(interface{})) {
+	fn(node)
+	for _, child := range children(node) {
+		walk(child, fn)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type pathParser struct {
+	src string
+	pos int
+}
+
+func (p *pathParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *pathParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *pathParser) parseSteps() ([]pathStep, error) {
+	var steps []pathStep
+	for !p.eof() {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			if p.peek() == '.' {
+				p.pos++
+				if p.peek() == '[' {
+					// $..[0] 等价于对自身及所有后代应用括号表达式
+					steps = append(steps, pathStep{kind: stepRecursive})
+					continue
+				}
+				name := p.readName()
+				if name == "" {
+					return nil, fmt.Errorf("expected name after .. at %d", p.pos)
+				}
+				steps = append(steps, pathStep{kind: stepRecursive, name: name})
+				continue
+			}
+			name := p.readName()
+			if name == "" {
+				return nil, fmt.Errorf("expected name after . at %d", p.pos)
+			}
+			if name == "*" {
+				steps = append(steps, pathStep{kind: stepWildcard})
+			} else {
+				steps = append(steps, pathStep{kind: stepChild, name: name})
+			}
+		case '[':
+			step, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", p.peek(), p.pos)
+		}
+	}
+	return steps, nil
+}
+
+func (p *pathParser) readName() string {
+	start := p.pos
+	if p.peek() == '*' {
+		p.pos++
+		return "*"
+	}
+	for !p.eof() {
+		c := p.peek()
+		if c == '.' || c == '[' || c == ' ' || c == ')' || c == '=' || c == '!' || c == '<' || c == '>' || c == '&' || c == '|' {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *pathParser) parseBracket() (pathStep, error) {
+	p.pos++ // '['
+	closeAt := p.findClosingBracket()
+	if closeAt < 0 {
+		return pathStep{}, fmt.Errorf("unterminated [ at %d", p.pos-1)
+	}
+	inner := strings.TrimSpace(p.src[p.pos:closeAt])
+	p.pos = closeAt + 1
+
+	switch {
+	case inner == "*":
+		return pathStep{kind: stepWildcard}, nil
+
+	case strings.HasPrefix(inner, "?"):
+		expr := strings.TrimSpace(inner[1:])
+		if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+			expr = expr[1 : len(expr)-1]
+		}
+		f, err := parseFilter(expr)
+		if err != nil {
+			return pathStep{}, err
+		}
+		return pathStep{kind: stepFilter, filter: f}, nil
+
+	case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+		return pathStep{kind: stepChild, name: inner[1 : len(inner)-1]}, nil
+
+	case strings.Contains(inner, ":"):
+		parts := strings.SplitN(inner, ":", 2)
+		step := pathStep{kind: stepSlice}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return pathStep{}, fmt.Errorf("invalid slice bound %q", part)
+			}
+			if i == 0 {
+				step.start = &n
+			} else {
+				step.end = &n
+			}
+		}
+		return step, nil
+
+	default:
+		n, err := strconv.Atoi(inner)
+		if err != nil {
+			return pathStep{}, fmt.Errorf("invalid index %q", inner)
+		}
+		return pathStep{kind: stepIndex, index: n}, nil
+	}
+}
+
+// findClosingBracket 查找与当前位置匹配的 ]，跳过引号与嵌套括号
+func (p *pathParser) findClosingBracket() int {
+	depth := 0
+	var quote byte
+	for i := p.pos; i < len(p.src); i++ {
+		c := p.src[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// filterExpr 是 OR 连接的 AND 子句
+type filterExpr struct {
+	any [][]filterCond
+}
+
+type filterCond struct {
+	left  filterOperand
+	op    string
+	right *filterOperand
+}
+
+type filterOperand struct {
+	path    []pathStep
+	isPath  bool
+	literal interface{}
+}
+
+func parseFilter(expr string) (*filterExpr, error) {
+	f := &filterExpr{}
+	for _, orPart := range splitOutsideQuotes(expr, "||") {
+		var clause []filterCond
+		for _, andPart := range splitOutsideQuotes(orPart, "&&") {
+			cond, err := parseCond(strings.TrimSpace(andPart))
+			if err != nil {
+				return nil, err
+			}
+			clause = append(clause, cond)
+		}
+		f.any = append(f.any, clause)
+	}
+	return f, nil
+}
+
+func parseCond(s string) (filterCond, error) {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if idx := indexOutsideQuotes(s, op); idx >= 0 {
+			left, err := parseOperand(strings.TrimSpace(s[:idx]))
+			if err != nil {
+				return filterCond{}, err
+			}
+			right, err := parseOperand(strings.TrimSpace(s[idx+len(op):]))
+			if err != nil {
+				return filterCond{}, err
+			}
+			return filterCond{left: left, op: op, right: &right}, nil
+		}
+	}
+
+	left, err := parseOperand(s)
+	if err != nil {
+		return filterCond{}, err
+	}
+	if !left.isPath {
+		return filterCond{}, fmt.Errorf("filter condition must reference @: %s", s)
+	}
+	return filterCond{left: left}, nil
+}
+
+func parseOperand(s string) (filterOperand, error) {
+	switch {
+	case s == "":
+		return filterOperand{}, fmt.Errorf("empty filter operand")
+	case strings.HasPrefix(s, "@"):
+		p := &pathParser{src: s, pos: 1}
+		steps, err := p.parseSteps()
+		if err != nil {
+			return filterOperand{}, err
+		}
+		return filterOperand{path: steps, isPath: true}, nil
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		return filterOperand{literal: s[1 : len(s)-1]}, nil
+	case s == "true":
+		return filterOperand{literal: true}, nil
+	case s == "false":
+		return filterOperand{literal: false}, nil
+	case s == "null":
+		return filterOperand{literal: nil}, nil
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return filterOperand{}, fmt.Errorf("invalid filter operand %q", s)
+	}
+	return filterOperand{literal: n}, nil
+}
+
+func (f *filterExpr) match(node interface{}) bool {
+	for _, clause := range f.any {
+		ok := true
+		for _, cond := range clause {
+			if !cond.match(node) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c filterCond) match(node interface{}) bool {
+	left, leftOK := c.left.resolve(node)
+	if c.right == nil {
+		return leftOK && left != nil && left != false
+	}
+	right, rightOK := c.right.resolve(node)
+	if !leftOK || !rightOK {
+		return c.op == "!=" && leftOK != rightOK
+	}
+
+	switch c.op {
+	case "==":
+		return valuesEqual(left, right)
+	case "!=":
+		return !valuesEqual(left, right)
+	}
+
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch c.op {
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		case ">":
+			return lf > rf
+		case ">=":
+			return lf >= rf
+		}
+	}
+
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		switch c.op {
+		case "<":
+			return ls < rs
+		case "<=":
+			return ls <= rs
+		case ">":
+			return ls > rs
+		case ">=":
+			return ls >= rs
+		}
+	}
+	return false
+}
+
+func (o filterOperand) resolve(node interface{}) (interface{}, bool) {
+	if !o.isPath {
+		return o.literal, true
+	}
+	results := evalSteps(o.path, []interface{}{node})
+	if len(results) == 0 {
+		return nil, false
+	}
+	return results[0], true
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			return af == bf
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func splitOutsideQuotes(s, sep string) []string {
+	var parts []string
+	for {
+		idx := indexOutsideQuotes(s, sep)
+		if idx < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:idx])
+		s = s[idx+len(sep):]
+	}
+}
+
+func indexOutsideQuotes(s, sub string) int {
+	var quote byte
+	for i := 0; i+len(sub) <= len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '\'' || c == '"' {
+			quote = c
+			continue
+		}
+		if s[i:i+len(sub)] == sub {
+			return i
+		}
+	}
+	return -1
+}
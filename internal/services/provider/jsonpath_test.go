@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const jsonPathTestDoc = `{
+	"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel", "title": "Sayings", "price": 8.95},
+			{"category": "fiction", "author": "Evelyn", "title": "Sword", "price": 12.99, "isbn": "0-553"},
+			{"category": "fiction", "author": "Herman", "title": "Moby", "price": 8.99, "isbn": "0-395"},
+			{"category": "fiction", "author": "Tolkien", "title": "Lord", "price": 22.99}
+		],
+		"bicycle": {"color": "red", "price": 19.95}
+	},
+	"outputs": [
+		{"type": "image", "url": "a.png", "width": 1024},
+		{"type": "video", "url": "b.mp4", "width": 512},
+		{"type": "image", "url": "c.png", "width": 256}
+	],
+	"weird key": {"x": 1},
+	"zero": 0
+}`
+
+func mustDecode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+	return v
+}
+
+func TestJSONPathGet(t *testing.T) {
+	doc := mustDecode(t, jsonPathTestDoc)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		// 子字段与引号
+		{`$.store.bicycle.color`, `["red"]`},
+		{`$['weird key'].x`, `[1]`},
+		{`$["weird key"]["x"]`, `[1]`},
+		{`$.zero`, `[0]`},
+		{`$.missing.deep`, `[]`},
+		{`$.store.bicycle.color.length`, `[]`},
+
+		// 下标与切片
+		{`$.store.book[0].title`, `["Sayings"]`},
+		{`$.store.book[-1].title`, `["Lord"]`},
+		{`$.store.book[10]`, `[]`},
+		{`$.store.book[-10]`, `[]`},
+		{`$.store.book[1:3].title`, `["Sword", "Moby"]`},
+		{`$.store.book[:2].title`, `["Sayings", "Sword"]`},
+		{`$.store.book[-2:].title`, `["Moby", "Lord"]`},
+		{`$.store.book[2:100].title`, `["Moby", "Lord"]`},
+		{`$.store.book[3:1]`, `[]`},
+		{`$.store.bicycle[0:1]`, `[]`},
+
+		// 通配符，对象按键名排序
+		{`$.store.book[*].author`, `["Nigel", "Evelyn", "Herman", "Tolkien"]`},
+		{`$.store.bicycle.*`, `["red", 19.95]`},
+		{`$.store.bicycle[*]`, `["red", 19.95]`},
+
+		// 递归，先父节点后子节点，同级按键名排序
+		{`$..price`, `[19.95, 8.95, 12.99, 8.99, 22.99]`},
+		{`$..isbn`, `["0-553", "0-395"]`},
+		{`$..book[0].title`, `["Sayings"]`},
+		{`$..[0].title`, `["Sayings"]`},
+		{`$.store.bicycle..*`, `["red", 19.95]`},
+
+		// 过滤器
+		{`$.store.book[?(@.price < 10)].title`, `["Sayings", "Moby"]`},
+		{`$.store.book[?(@.price <= 8.95)].title`, `["Sayings"]`},
+		{`$.store.book[?(@.isbn)].title`, `["Sword", "Moby"]`},
+		{`$.store.book[?(@.category == 'fiction' && @.price > 20)].title`, `["Lord"]`},
+		{`$.store.book[?(@.price < 9 || @.author == "Evelyn")].title`, `["Sayings", "Sword", "Moby"]`},
+		{`$.store.book[?(@.author >= 'O')].title`, `["Lord"]`},
+		{`$.outputs[?(@.type != 'image')].url`, `["b.mp4"]`},
+		{`$.outputs[?(@.width >= 512 && @.type == 'image')].url`, `["a.png"]`},
+		{`$.outputs[?@.width == 256].url`, `["c.png"]`},
+		// 字段缺失时只有 != 成立
+		{`$.store.book[?(@.isbn != '0-553')].title`, `["Sayings", "Moby", "Lord"]`},
+		{`$.store.book[?(@.isbn == '0-553')].title`, `["Sword"]`},
+		// 引号中的 ] 与运算符不结束表达式
+		{`$.store.book[?(@.title == 'a]b && c')]`, `[]`},
+		{`$..book[?(@.price > 20)].author`, `["Tolkien"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p, err := CompileJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("CompileJSONPath: %v", err)
+			}
+			got := p.Get(doc)
+			want := mustDecode(t, tt.want).([]interface{})
+			if len(got) == 0 && len(want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestJSONPathJSONNumber(t *testing.T) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(`{"items":[{"w":512,"url":"a"},{"w":1024,"url":"b"}]}`)))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := CompileJSONPath(`$.items[?(@.w >= 1000 || @.w == 512.0)].url`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Get(doc); !reflect.DeepEqual(got, []interface{}{"a", "b"}) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestJSONPathFirst(t *testing.T) {
+	doc := mustDecode(t, jsonPathTestDoc)
+
+	p, _ := CompileJSONPath(`$.outputs[*].url`)
+	if v, ok := p.First(doc); !ok || v != "a.png" {
+		t.Fatalf("First = %v, %v", v, ok)
+	}
+	p, _ = CompileJSONPath(`$.outputs[5]`)
+	if _, ok := p.First(doc); ok {
+		t.Fatal("First on no match returned ok")
+	}
+}
+
+func TestJSONPathDefinite(t *testing.T) {
+	tests := map[string]bool{
+		`$`:                  true,
+		`$.a.b`:              true,
+		`$.a[0]['b c']`:      true,
+		`$.a[-1]`:            true,
+		`$.a[*]`:             false,
+		`$.a.*`:              false,
+		`$.a[1:2]`:           false,
+		`$..a`:               false,
+		`$.a[?(@.b == 1)].c`: false,
+	}
+	for path, want := range tests {
+		p, err := CompileJSONPath(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if got := p.Definite(); got != want {
+			t.Errorf("%s: Definite() = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCompileJSONPathErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`   `,
+		`store.book`,
+		`@.a`,
+		`$.`,
+		`$..`,
+		`$ .a`,
+		`$a`,
+		`$.store[`,
+		`$.store['a]`,
+		`$.store[abc]`,
+		`$.store[1.5]`,
+		`$.store[1:x]`,
+		`$.store[?(@.a ==)]`,
+		`$.store[?(== 1)]`,
+		`$.store[?('x')]`,
+		`$.store[?(@.a == bogus)]`,
+		`$.store[?(@.a && )]`,
+	}
+	for _, path := range tests {
+		t.Run(path, func(t *testing.T) {
+			if p, err := CompileJSONPath(path); err == nil {
+				t.Fatalf("expected error, got %v", p.steps)
+			}
+		})
+	}
+}
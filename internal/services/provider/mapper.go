@@ -1,64 +1,286 @@
 package provider
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
+	"text/template"
 )
 
+const (
+	MappingJobIDPath     = "job_id_jsonpath"
+	MappingStatusPath    = "status_jsonpath"
+	MappingProgressPath  = "progress_jsonpath"
+	MappingResultURLPath = "result_url_jsonpath"
+	MappingErrorPath     = "error_jsonpath"
+	MappingArtifactsPath = "artifacts_jsonpath"
+	MappingResultB64Path = "result_b64_jsonpath"
+)
+
+// artifact_*_path 相对于 artifacts_jsonpath 匹配到的每个元素求值
+var defaultArtifactPaths = map[string]string{
+	"artifact_url_path":    "$.url",
+	"artifact_kind_path":   "$.type",
+	"artifact_mime_path":   "$.mime_type",
+	"artifact_size_path":   "$.size",
+	"artifact_width_path":  "$.width",
+	"artifact_height_path": "$.height",
+	"artifact_b64_path":    "$.b64_json",
+}
+
+var defaultResponsePaths = map[string]string{
+	MappingJobIDPath:     "$.data.id",
+	MappingStatusPath:    "$.status",
+	MappingProgressPath:  "$.progress",
+	MappingResultURLPath: "$.output.url",
+	MappingErrorPath:     "$.error",
+	MappingResultB64Path: "$.data[*].b64_json",
+}
+
+// defaultStatusValues 是常见供应商的状态词表，可被 ProviderConfig.StatusValues 覆盖
+var defaultStatusValues = map[string]JobStatus{
+	"pending":     JobStatusPending,
+	"queued":      JobStatusPending,
+	"queue":       JobStatusPending,
+	"queueing":    JobStatusPending,
+	"waiting":     JobStatusPending,
+	"submitted":   JobStatusPending,
+	"created":     JobStatusPending,
+	"running":     JobStatusRunning,
+	"processing":  JobStatusRunning,
+	"in_progress": JobStatusRunning,
+	"generating":  JobStatusRunning,
+	"started":     JobStatusRunning,
+	"succeeded":   JobStatusSucceeded,
+	"success":     JobStatusSucceeded,
+	"successful":  JobStatusSucceeded,
+	"completed":   JobStatusSucceeded,
+	"complete":    JobStatusSucceeded,
+	"finished":    JobStatusSucceeded,
+	"done":        JobStatusSucceeded,
+	"failed":      JobStatusFailed,
+	"failure":     JobStatusFailed,
+	"fail":        JobStatusFailed,
+	"error":       JobStatusFailed,
+	"cancelled":   JobStatusFailed,
+	"canceled":    JobStatusFailed,
+	"timeout":     JobStatusFailed,
+	"expired":     JobStatusFailed,
+}
+
 type Mapper struct {
 	requestMapping map[string]interface{}
+	paths          map[string]*JSONPath
+	statusValues   map[string]JobStatus
+	templates      map[string]*template.Template
+	providerInfo   ProviderTemplateInfo
 }
 
-func NewMapper(requestMapping map[string]interface{}) *Mapper {
+func NewMapper(cfg *ProviderConfig) (*Mapper, error) {
+	requestMapping := cfg.RequestMapping
 	if requestMapping == nil {
 		requestMapping = make(map[string]interface{})
 	}
-	return &Mapper{
+
+	m := &Mapper{
 		requestMapping: requestMapping,
+		paths:          make(map[string]*JSONPath),
+		statusValues:   make(map[string]JobStatus),
+		templates:      make(map[string]*template.Template),
+		providerInfo: ProviderTemplateInfo{
+			Name:    cfg.ProviderName,
+			Type:    cfg.Type,
+			BaseURL: cfg.BaseURL,
+			Headers: cfg.Headers,
+		},
 	}
-}
 
-func (m *Mapper) MapRequest(req UnifiedGenRequest) (map[string]interface{}, error) {
-	result := make(map[string]interface{})
+	for raw, status := range defaultStatusValues {
+		m.statusValues[raw] = status
+	}
+	for raw, target := range cfg.StatusValues {
+		status := JobStatus(strings.ToLower(strings.TrimSpace(target)))
+		switch status {
+		case JobStatusPending, JobStatusRunning, JobStatusSucceeded, JobStatusFailed:
+		default:
+			return nil, fmt.Errorf("invalid status_values target %q for %q", target, raw)
+		}
+		m.statusValues[normalizeStatusValue(raw)] = status
+	}
 
-	for key, value := range m.requestMapping {
-		mappedValue, err := m.mapValue(value, req)
+	for key, def := range defaultResponsePaths {
+		raw := lookupMapping(cfg, key)
+		if raw == "" {
+			raw = def
+		}
+		path, err := CompileJSONPath(raw)
 		if err != nil {
-			return nil, fmt.Errorf("failed to map key %s: %w", key, err)
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
 		}
-		result[key] = mappedValue
+		m.paths[key] = path
 	}
 
-	if len(result) == 0 {
-		reqJSON, err := json.Marshal(req)
+	if raw := lookupMapping(cfg, MappingArtifactsPath); raw != "" {
+		path, err := CompileJSONPath(raw)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid %s: %w", MappingArtifactsPath, err)
 		}
-		var resultMap map[string]interface{}
-		if err := json.Unmarshal(reqJSON, &resultMap); err != nil {
-			return nil, err
+		m.paths[MappingArtifactsPath] = path
+
+		for key, def := range defaultArtifactPaths {
+			raw := lookupMapping(cfg, key)
+			if raw == "" {
+				raw = def
+			}
+			path, err := CompileJSONPath(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			m.paths[key] = path
 		}
-		return resultMap, nil
+	}
+
+	if err := m.compileTemplates(requestMapping, "request_mapping"); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// lookupMapping 先在 status_mapping 中查找，再回退到 response_mapping，
+// 以兼容两种配置习惯（种子数据把 job_id 放在 response_mapping，其余放在 status_mapping）
+func lookupMapping(cfg *ProviderConfig, key string) string {
+	if v, ok := cfg.StatusMapping[key]; ok && v != "" {
+		return v
+	}
+	if v, ok := cfg.ResponseMapping[key]; ok && v != "" {
+		return v
+	}
+	return ""
+}
+
+// compileTemplates 预编译 request_mapping 中的模板，使语法错误在保存配置时即可发现
+func (m *Mapper) compileTemplates(value interface{}, at string) error {
+	switch v := value.(type) {
+	case string:
+		if !isTemplateString(v) {
+			return nil
+		}
+		if _, ok := m.templates[v]; ok {
+			return nil
+		}
+		tpl, err := compileTemplate(v)
+		if err != nil {
+			return fmt.Errorf("invalid template at %s: %w", at, err)
+		}
+		m.templates[v] = tpl
+	case map[string]interface{}:
+		if expr, ok := v[templateExprKey]; ok {
+			src, isString := expr.(string)
+			if !isString {
+				return fmt.Errorf("%s at %s must be a string", templateExprKey, at)
+			}
+			if typ, ok := v[templateTypeKey]; ok {
+				if _, isString := typ.(string); !isString {
+					return fmt.Errorf("%s at %s must be a string", templateTypeKey, at)
+				}
+			}
+			tpl, err := compileTemplate(src)
+			if err != nil {
+				return fmt.Errorf("invalid template at %s: %w", at, err)
+			}
+			m.templates[src] = tpl
+			return nil
+		}
+		for key, val := range v {
+			if err := m.compileTemplates(val, at+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			if err := m.compileTemplates(val, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type mapContext struct {
+	req     UnifiedGenRequest
+	reqJSON map[string]interface{}
+	data    *TemplateData
+}
+
+func (m *Mapper) MapRequest(req UnifiedGenRequest) (map[string]interface{}, error) {
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var reqMap map[string]interface{}
+	if err := json.Unmarshal(reqJSON, &reqMap); err != nil {
+		return nil, err
+	}
+
+	if len(m.requestMapping) == 0 {
+		return reqMap, nil
+	}
+
+	mc := &mapContext{
+		req:     req,
+		reqJSON: reqMap,
+		data: &TemplateData{
+			Request:  req,
+			Provider: m.providerInfo,
+		},
+	}
+	if req.Task != nil {
+		mc.data.Task = *req.Task
+	}
+
+	result := make(map[string]interface{})
+	for key, value := range m.requestMapping {
+		mappedValue, err := m.mapValue(value, mc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map key %s: %w", key, err)
+		}
+		result[key] = mappedValue
 	}
 
 	return result, nil
 }
 
-func (m *Mapper) mapValue(value interface{}, req UnifiedGenRequest) (interface{}, error) {
+func (m *Mapper) mapValue(value interface{}, mc *mapContext) (interface{}, error) {
 	switch v := value.(type) {
 	case string:
+		if isTemplateString(v) {
+			return renderTemplate(m.templates[v], mc.data)
+		}
 		if strings.HasPrefix(v, "$.") {
-			return m.extractField(v, req)
+			return m.extractField(v, mc)
 		}
 		return v, nil
 	case map[string]interface{}:
+		if expr, ok := v[templateExprKey].(string); ok {
+			out, err := renderTemplate(m.templates[expr], mc.data)
+			if err != nil {
+				return nil, err
+			}
+			typ, _ := v[templateTypeKey].(string)
+			return coerceTemplateValue(out, typ)
+		}
 		result := make(map[string]interface{})
 		for key, val := range v {
-			mapped, err := m.mapValue(val, req)
+			mapped, err := m.mapValue(val, mc)
 			if err != nil {
 				return nil, err
 			}
@@ -68,7 +290,7 @@ func (m *Mapper) mapValue(value interface{}, req UnifiedGenRequest) (interface{}
 	case []interface{}:
 		result := make([]interface{}, len(v))
 		for i, val := range v {
-			mapped, err := m.mapValue(val, req)
+			mapped, err := m.mapValue(val, mc)
 			if err != nil {
 				return nil, err
 			}
@@ -80,156 +302,511 @@ func (m *Mapper) mapValue(value interface{}, req UnifiedGenRequest) (interface{}
 	}
 }
 
-func (m *Mapper) extractField(path string, req UnifiedGenRequest) (interface{}, error) {
+// extractField 解析 $.xxx 引用：优先按 JSON 字段名（如 $.duration_sec、$.extra.model）查找，
+// 并兼容旧配置中的 Go 字段名（如 $.Prompt）
+func (m *Mapper) extractField(path string, mc *mapContext) (interface{}, error) {
+	jp, err := CompileJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := jp.First(mc.reqJSON); ok {
+		return v, nil
+	}
+
 	parts := strings.Split(path, ".")
-	if len(parts) < 2 {
+	fieldName := parts[1]
+	if fieldName == "" {
 		return nil, fmt.Errorf("invalid path: %s", path)
 	}
 
-	fieldName := parts[1]
+	val := reflect.ValueOf(mc.req)
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonName == "-" || (f.Name != fieldName && jsonName != fieldName) {
+			continue
+		}
 
-	val := reflect.ValueOf(req)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+		field := val.Field(i)
+		if len(parts) > 2 {
+			// 嵌套字段不存在（例如 extra 中缺少的键）
+			return nil, nil
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				return nil, nil
+			}
+			return field.Elem().Interface(), nil
+		}
+		return field.Interface(), nil
 	}
 
-	field := val.FieldByName(fieldName)
-	if !field.IsValid() {
-		return nil, fmt.Errorf("field not found: %s", fieldName)
+	return nil, fmt.Errorf("field not found: %s", fieldName)
+}
+
+// ParseProviderConfigs 解析并校验 Setting.ProviderJSON
+func ParseProviderConfigs(raw string) ([]ProviderConfig, error) {
+	var configs []ProviderConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, fmt.Errorf("invalid provider JSON: %w", err)
 	}
 
-	if field.Kind() == reflect.Ptr {
-		if field.IsNil() {
-			return nil, nil
+	seen := make(map[string]bool)
+	for i := range configs {
+		if err := ValidateProviderConfig(&configs[i]); err != nil {
+			return nil, fmt.Errorf("provider[%d] %s: %w", i, configs[i].ProviderName, err)
 		}
-		return field.Elem().Interface(), nil
+		if seen[configs[i].ProviderName] {
+			return nil, fmt.Errorf("duplicate provider_name: %s", configs[i].ProviderName)
+		}
+		seen[configs[i].ProviderName] = true
 	}
 
-	return field.Interface(), nil
+	return configs, nil
 }
 
-func (m *Mapper) ExtractJobID(responseBody []byte) (string, error) {
-	var response map[string]interface{}
-	if err := json.Unmarshal(responseBody, &response); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+// ValidateProviderConfig 校验供应商配置：编译所有 JSONPath 与模板，并用示例请求试渲染一次
+func ValidateProviderConfig(cfg *ProviderConfig) error {
+	if cfg.ProviderName == "" {
+		return fmt.Errorf("provider_name is required")
+	}
+
+	switch strings.ToLower(cfg.Mode) {
+	case "", ProviderModeAsync, ProviderModeSync:
+	default:
+		return fmt.Errorf("unsupported mode: %s", cfg.Mode)
+	}
+
+	if cfg.MaxConcurrentJobs < 0 || cfg.SubmitQPS < 0 || cfg.SubmitTimeoutSec < 0 || cfg.StatusTimeoutSec < 0 {
+		return fmt.Errorf("limits and timeouts must not be negative")
+	}
+
+	if cfg.Mock != nil && (cfg.Mock.FailureRate < 0 || cfg.Mock.FailureRate > 1 || cfg.Mock.DurationSec < 0) {
+		return fmt.Errorf("mock.failure_rate must be within [0, 1] and mock.duration_sec must not be negative")
+	}
+
+	if cb := cfg.CircuitBreaker; cb != nil {
+		if cb.FailureRate < 0 || cb.FailureRate > 1 || cb.SlowCallRate < 0 || cb.SlowCallRate > 1 {
+			return fmt.Errorf("circuit_breaker rates must be within [0, 1]")
+		}
+		if cb.SlowCallSec < 0 || cb.WindowSize < 0 || cb.MinRequests < 0 || cb.OpenSec < 0 {
+			return fmt.Errorf("circuit_breaker values must not be negative")
+		}
+	}
+
+	for _, capability := range cfg.Capabilities {
+		switch strings.ToLower(capability) {
+		case CapabilityImage, CapabilityVideo, CapabilityImg2Img:
+		default:
+			return fmt.Errorf("unsupported capability: %s", capability)
+		}
+	}
+
+	if cfg.Pricing != nil {
+		if err := cfg.Pricing.validate(); err != nil {
+			return err
+		}
+	}
+	if b := cfg.Budget; b != nil && (b.DailyLimit < 0 || b.MonthlyLimit < 0) {
+		return fmt.Errorf("budget limits must not be negative")
+	}
+
+	if hc := cfg.HealthCheck; hc != nil {
+		if hc.Path == "" {
+			return fmt.Errorf("health_check.path is required")
+		}
+		if hc.IntervalSec < 0 || hc.TimeoutSec < 0 {
+			return fmt.Errorf("health_check interval and timeout must not be negative")
+		}
 	}
 
-	jobID, err := m.extractByJSONPath(response, "$.data.id")
+	m, err := NewMapper(cfg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := NewAuthenticator(cfg, http.DefaultClient); err != nil {
+		return err
+	}
+
+	width, height, duration, seed := 1024, 1536, 5, 42
+	sample := UnifiedGenRequest{
+		RequestID:      "task_0",
+		Type:           RequestTypeImage,
+		Prompt:         "a cat",
+		NegativePrompt: "blurry",
+		Style:          "anime",
+		Width:          &width,
+		Height:         &height,
+		DurationSec:    &duration,
+		Ratio:          "2:3",
+		Seed:           &seed,
+		Task:           &TaskContext{ID: 0, NoteTarget: "sample"},
+	}
+
+	for _, typ := range []RequestType{RequestTypeImage, RequestTypeVideo, RequestTypeEdit} {
+		sample.Type = typ
+		sample.ReferenceImages = nil
+		if typ == RequestTypeEdit {
+			sample.ReferenceImages = []string{"https://example.com/reference.png"}
+		}
+		if !cfg.Supports(sample) {
+			continue
+		}
+		if _, err := m.MapRequest(sample); err != nil {
+			return fmt.Errorf("request_mapping failed for sample %s request: %w", typ, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Mapper) ExtractJobID(responseBody []byte) (string, error) {
+	response, err := decodeResponse(responseBody)
 	if err != nil {
 		return "", err
 	}
 
-	jobIDStr, ok := jobID.(string)
+	path := m.paths[MappingJobIDPath]
+	jobID, ok := path.First(response)
 	if !ok {
-		return "", fmt.Errorf("job ID is not a string")
+		return "", fmt.Errorf("path not found: %s", path)
+	}
+
+	jobIDStr, ok := scalarString(jobID)
+	if !ok || jobIDStr == "" {
+		return "", fmt.Errorf("job ID at %s is not a string or number", path)
 	}
 
 	return jobIDStr, nil
 }
 
-func (m *Mapper) ExtractStatus(responseBody []byte) (JobStatus, int, *string, error) {
-	var response map[string]interface{}
-	if err := json.Unmarshal(responseBody, &response); err != nil {
-		return "", 0, nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	statusStr, err := m.extractByJSONPath(response, "$.status")
+func (m *Mapper) ExtractStatus(responseBody []byte) (*StatusResult, error) {
+	response, err := decodeResponse(responseBody)
 	if err != nil {
-		return "", 0, nil, err
+		return nil, err
 	}
 
-	status := JobStatus(strings.ToLower(fmt.Sprintf("%v", statusStr)))
+	result := &StatusResult{
+		Error: m.extractError(response),
+	}
 
-	progress := 0
-	if progressVal, err := m.extractByJSONPath(response, "$.progress"); err == nil {
-		if p, ok := progressVal.(float64); ok {
-			progress = int(p)
+	statusPath := m.paths[MappingStatusPath]
+	statusVal, ok := statusPath.First(response)
+	switch {
+	case ok && statusVal != nil:
+		raw, isScalar := scalarString(statusVal)
+		if !isScalar {
+			return nil, fmt.Errorf("status at %s is not a scalar value", statusPath)
 		}
+		result.RawStatus = raw
+		result.Status = m.TranslateStatus(raw)
+	case result.Error != nil:
+		// 部分供应商失败时只返回错误对象而没有状态字段
+		result.Status = JobStatusFailed
+	default:
+		return nil, fmt.Errorf("path not found: %s", statusPath)
 	}
 
-	var resultURL *string
-	if urlVal, err := m.extractByJSONPath(response, "$.output.url"); err == nil {
-		if url, ok := urlVal.(string); ok && url != "" {
-			resultURL = &url
+	if progressVal, ok := m.paths[MappingProgressPath].First(response); ok {
+		if p, ok := toFloat(progressVal); ok {
+			result.Progress = int(p)
 		}
 	}
 
-	return status, progress, resultURL, nil
+	if err := m.fillArtifacts(result, response); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-func (m *Mapper) extractByJSONPath(data map[string]interface{}, path string) (interface{}, error) {
-	parts := strings.Split(path, ".")
+// ExtractSyncResult 将同步供应商的提交响应直接映射为结果。
+// 状态字段可选：缺失时有产物即视为成功，否则视为失败。
+func (m *Mapper) ExtractSyncResult(responseBody []byte) (*StatusResult, error) {
+	response, err := decodeResponse(responseBody)
+	if err != nil {
+		return nil, err
+	}
 
-	current := data
-	for i := 1; i < len(parts); i++ {
-		part := parts[i]
+	result := &StatusResult{
+		Error: m.extractError(response),
+	}
 
-		val, exists := current[part]
-		if !exists {
-			return nil, fmt.Errorf("path not found: %s", path)
-		}
+	if err := m.fillArtifacts(result, response); err != nil {
+		return nil, err
+	}
 
-		if i == len(parts)-1 {
-			return val, nil
+	if statusVal, ok := m.paths[MappingStatusPath].First(response); ok && statusVal != nil {
+		raw, isScalar := scalarString(statusVal)
+		if !isScalar {
+			return nil, fmt.Errorf("status at %s is not a scalar value", m.paths[MappingStatusPath])
 		}
+		result.RawStatus = raw
+		result.Status = m.TranslateStatus(raw)
+	}
 
-		nextMap, ok := val.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("path segment %s is not an object", part)
+	switch result.Status {
+	case JobStatusSucceeded, JobStatusFailed:
+	default:
+		// 同步响应没有后续轮询，非终态一律按是否有产物判定
+		if result.Error == nil && len(result.Artifacts) > 0 {
+			result.Status = JobStatusSucceeded
+		} else {
+			result.Status = JobStatusFailed
 		}
+	}
 
-		current = nextMap
+	if result.Status == JobStatusSucceeded && len(result.Artifacts) == 0 {
+		result.Status = JobStatusFailed
+	}
+	if result.Status == JobStatusFailed && result.Error == nil {
+		msg := "no output in provider response"
+		result.Error = &msg
 	}
+	result.Progress = 100
 
-	return current, nil
+	return result, nil
 }
 
-func (m *Mapper) extractByGJSONPath(data map[string]interface{}, path string) (interface{}, error) {
-	path = strings.TrimPrefix(path, "$.")
-
-	parts := regexp.MustCompile(`\[(\d+)\]`).Split(path, -1)
-	indices := regexp.MustCompile(`\[(\d+)\]`).FindAllStringSubmatch(path, -1)
+func (m *Mapper) fillArtifacts(result *StatusResult, response interface{}) error {
+	artifacts, err := m.extractArtifacts(response)
+	if err != nil {
+		return err
+	}
+	result.Artifacts = artifacts
+	if len(artifacts) > 0 && artifacts[0].URL != "" {
+		url := artifacts[0].URL
+		result.ResultURL = &url
+	}
+	return nil
+}
 
-	current := data
-	for i, part := range parts {
-		if part == "" {
-			continue
+// extractArtifacts 优先使用 artifacts_jsonpath 提取产物数组；未配置时
+// result_url_jsonpath 与 result_b64_jsonpath 的所有匹配（如 $.output.images[*].url）都作为产物。
+// data: URL 与 base64 内容会被解码到 Artifact.Data，由调用方上传到存储。
+func (m *Mapper) extractArtifacts(response interface{}) ([]Artifact, error) {
+	var artifacts []Artifact
+	seen := make(map[string]bool)
+	add := func(a Artifact) error {
+		if strings.HasPrefix(a.URL, "data:") {
+			data, mimeType, err := decodeInlineData(a.URL)
+			if err != nil {
+				return err
+			}
+			a.URL, a.Data = "", data
+			if a.MimeType == "" {
+				a.MimeType = mimeType
+			}
 		}
-
-		val, exists := current[part]
-		if !exists {
-			return nil, fmt.Errorf("path not found: %s", path)
+		if a.Data != nil {
+			if a.MimeType == "" {
+				a.MimeType = http.DetectContentType(a.Data)
+			}
+			if a.Size == 0 {
+				a.Size = int64(len(a.Data))
+			}
+			artifacts = append(artifacts, a)
+			return nil
+		}
+		if a.URL == "" || seen[a.URL] {
+			return nil
+		}
+		seen[a.URL] = true
+		if a.MimeType == "" {
+			a.MimeType = guessMimeType(a.URL)
 		}
+		artifacts = append(artifacts, a)
+		return nil
+	}
 
-		if i < len(indices) && len(indices[i]) > 1 {
-			index, err := strconv.Atoi(indices[i][1])
-			if err != nil {
-				return nil, fmt.Errorf("invalid index: %s", indices[i][1])
+	artifactsPath, ok := m.paths[MappingArtifactsPath]
+	if !ok {
+		for _, urlVal := range m.paths[MappingResultURLPath].Get(response) {
+			if url, ok := urlVal.(string); ok {
+				if err := add(Artifact{URL: url}); err != nil {
+					return nil, err
+				}
 			}
+		}
+		for _, b64Val := range m.paths[MappingResultB64Path].Get(response) {
+			if encoded, ok := b64Val.(string); ok && encoded != "" {
+				data, mimeType, err := decodeInlineData(encoded)
+				if err != nil {
+					return nil, err
+				}
+				if err := add(Artifact{Data: data, MimeType: mimeType}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return artifacts, nil
+	}
 
-			arr, ok := val.([]interface{})
-			if !ok {
-				return nil, fmt.Errorf("path segment %s is not an array", part)
+	for _, item := range artifactsPath.Get(response) {
+		if url, ok := item.(string); ok {
+			if err := add(Artifact{URL: url}); err != nil {
+				return nil, err
 			}
+			continue
+		}
 
-			if index >= len(arr) {
-				return nil, fmt.Errorf("index out of bounds: %d", index)
+		a := Artifact{}
+		if v, ok := m.paths["artifact_url_path"].First(item); ok {
+			a.URL, _ = v.(string)
+		}
+		if v, ok := m.paths["artifact_b64_path"].First(item); ok {
+			if encoded, _ := v.(string); encoded != "" {
+				data, mimeType, err := decodeInlineData(encoded)
+				if err != nil {
+					return nil, err
+				}
+				a.Data, a.MimeType = data, mimeType
+			}
+		}
+		if v, ok := m.paths["artifact_kind_path"].First(item); ok {
+			a.Kind, _ = scalarString(v)
+		}
+		if v, ok := m.paths["artifact_mime_path"].First(item); ok {
+			a.MimeType, _ = v.(string)
+		}
+		if v, ok := m.paths["artifact_size_path"].First(item); ok {
+			if f, ok := toFloat(v); ok {
+				a.Size = int64(f)
+			}
+		}
+		if v, ok := m.paths["artifact_width_path"].First(item); ok {
+			if f, ok := toFloat(v); ok {
+				a.Width = int(f)
 			}
+		}
+		if v, ok := m.paths["artifact_height_path"].First(item); ok {
+			if f, ok := toFloat(v); ok {
+				a.Height = int(f)
+			}
+		}
+		if err := add(a); err != nil {
+			return nil, err
+		}
+	}
+
+	return artifacts, nil
+}
 
-			val = arr[index]
+// decodeInlineData 解码 data: URL 或裸 base64 字符串，返回数据与 data: URL 中声明的 MIME 类型
+func decodeInlineData(s string) ([]byte, string, error) {
+	var mimeType string
+	if strings.HasPrefix(s, "data:") {
+		comma := strings.Index(s, ",")
+		if comma < 0 {
+			return nil, "", fmt.Errorf("invalid data URL")
 		}
+		meta := s[len("data:"):comma]
+		if !strings.HasSuffix(meta, ";base64") {
+			return nil, "", fmt.Errorf("only base64 data URLs are supported")
+		}
+		mimeType = strings.TrimSuffix(meta, ";base64")
+		s = s[comma+1:]
+	}
 
-		if i == len(parts)-1 {
-			return val, nil
+	s = strings.TrimSpace(s)
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode base64 output: %w", err)
 		}
+	}
+	return data, mimeType, nil
+}
+
+func guessMimeType(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	if ext == "" {
+		return ""
+	}
+	return strings.Split(mime.TypeByExtension(ext), ";")[0]
+}
+
+// TranslateStatus 根据状态词表翻译原始状态值，未知值返回 JobStatusUnknown
+func (m *Mapper) TranslateStatus(raw string) JobStatus {
+	if status, ok := m.statusValues[normalizeStatusValue(raw)]; ok {
+		return status
+	}
+	return JobStatusUnknown
+}
+
+func normalizeStatusValue(raw string) string {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	raw = strings.ReplaceAll(raw, "-", "_")
+	raw = strings.ReplaceAll(raw, " ", "_")
+	return raw
+}
+
+func (m *Mapper) extractError(response interface{}) *string {
+	errVal, ok := m.paths[MappingErrorPath].First(response)
+	if !ok || errVal == nil {
+		return nil
+	}
 
-		nextMap, ok := val.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("path segment %s is not an object", part)
+	var msg string
+	switch v := errVal.(type) {
+	case string:
+		msg = v
+	case map[string]interface{}:
+		// {"code":0,"message":"success"} 之类的错误对象，数值错误码为 0 时视为成功
+		if code, ok := toFloat(v["code"]); ok && code == 0 {
+			break
+		}
+		if s, ok := v["message"].(string); ok {
+			msg = s
+		} else if s, ok := v["msg"].(string); ok {
+			msg = s
+		} else if len(v) > 0 {
+			b, _ := json.Marshal(v)
+			msg = string(b)
 		}
+	case bool:
+		// error: false 表示没有错误
+	default:
+		// 数值错误码：0 视为成功
+		if f, ok := toFloat(v); ok && f != 0 {
+			msg = fmt.Sprintf("error code %v", v)
+		}
+	}
 
-		current = nextMap
+	if msg == "" {
+		return nil
 	}
+	return &msg
+}
+
+func decodeResponse(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
 
-	return current, nil
+	var response interface{}
+	if err := decoder.Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return response, nil
+}
+
+func scalarString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case json.Number:
+		return s.String(), true
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(s), true
+	}
+	return "", false
 }
@@ -0,0 +1,52 @@
+package provider
+
+import "testing"
+
+func TestExtractError(t *testing.T) {
+	m, err := NewMapper(&ProviderConfig{
+		ProviderName:    "fake",
+		ResponseMapping: map[string]string{MappingErrorPath: "$.error"},
+	})
+	if err != nil {
+		t.Fatalf("NewMapper: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "absent", body: `{}`},
+		{name: "null", body: `{"error":null}`},
+		{name: "empty string", body: `{"error":""}`},
+		{name: "false", body: `{"error":false}`},
+		{name: "zero code", body: `{"error":0}`},
+		{name: "empty object", body: `{"error":{}}`},
+		{name: "object with zero code", body: `{"error":{"code":0,"message":"success"}}`},
+		{name: "object with zero float code", body: `{"error":{"code":0.0,"msg":"ok"}}`},
+		{name: "string", body: `{"error":"content rejected"}`, want: "content rejected"},
+		{name: "numeric code", body: `{"error":1001}`, want: "error code 1001"},
+		{name: "object message", body: `{"error":{"code":400,"message":"bad prompt"}}`, want: "bad prompt"},
+		{name: "object msg", body: `{"error":{"msg":"quota exceeded"}}`, want: "quota exceeded"},
+		{name: "object string code", body: `{"error":{"code":"0","message":"failed"}}`, want: "failed"},
+		{name: "object without message", body: `{"error":{"type":"timeout"}}`, want: `{"type":"timeout"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := decodeResponse([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := m.extractError(response)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Fatalf("got error %q, want none", *got)
+			case tt.want != "" && got == nil:
+				t.Fatalf("got no error, want %q", tt.want)
+			case tt.want != "" && *got != tt.want:
+				t.Fatalf("got %q, want %q", *got, tt.want)
+			}
+		})
+	}
+}
@@ -2,48 +2,108 @@ package provider
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	mathrand "math/rand"
+	"strconv"
 	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MockConfig 控制 MockProvider 的行为。
+// Deterministic 为 true 时任务耗时固定为 DurationSec，是否失败由 Seed 与请求 ID 决定，
+// 同一请求 ID 重复提交会得到同一个任务。
+type MockConfig struct {
+	Deterministic bool    `json:"deterministic,omitempty"`
+	Seed          int64   `json:"seed,omitempty"`
+	DurationSec   int     `json:"duration_sec,omitempty"`
+	FailureRate   float64 `json:"failure_rate,omitempty"`
+}
+
+const (
+	mockJobTTL             = 24 * time.Hour
+	mockFinishLockTTL      = time.Minute
+	mockPendingDuration    = time.Second
+	defaultMockDurationSec = 10
 )
 
+var releaseMockLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// MockProvider 的任务状态保存在 Redis 中，API 与 Worker 进程看到的是同一份数据。
+// 进度按提交后经过的时间计算，任务到期后第一个查询者在锁内生成结果。
 type MockProvider struct {
 	name    string
-	jobs    map[string]*mockJob
+	cfg     MockConfig
+	rdb     *redis.Client
 	storage Storage
 }
 
 type mockJob struct {
-	status      JobStatus
-	progress    int
-	resultURL   *string
-	error       *string
-	createdAt   time.Time
-	completedAt *time.Time
-}
-
-func NewMockProvider(name string, storage Storage) *MockProvider {
-	return &MockProvider{
-		name:    name,
-		jobs:    make(map[string]*mockJob),
+	Request    UnifiedGenRequest `json:"request"`
+	CreatedAt  int64             `json:"created_at"`
+	DurationMs int64             `json:"duration_ms"`
+	Fail       bool              `json:"fail"`
+	Status     JobStatus         `json:"status"`
+	Artifacts  []Artifact        `json:"artifacts,omitempty"`
+	Error      *string           `json:"error,omitempty"`
+}
+
+func NewMockProvider(cfg *ProviderConfig, rdb *redis.Client, storage Storage) *MockProvider {
+	p := &MockProvider{
+		name:    cfg.ProviderName,
+		rdb:     rdb,
 		storage: storage,
 	}
+	if cfg.Mock != nil {
+		p.cfg = *cfg.Mock
+	}
+	return p
 }
 
 func (p *MockProvider) Name() string {
 	return p.name
 }
 
+func (p *MockProvider) jobKey(jobID string) string {
+	return fmt.Sprintf("mock:%s:job:%s", p.name, jobID)
+}
+
 func (p *MockProvider) Submit(ctx context.Context, req UnifiedGenRequest) (*SubmitResult, error) {
-	jobID := fmt.Sprintf("mock_job_%d_%s", time.Now().UnixNano(), req.RequestID)
+	now, err := p.now(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobID := fmt.Sprintf("mock_job_%d_%s", now.UnixNano(), req.RequestID)
+	if p.cfg.Deterministic {
+		jobID = fmt.Sprintf("mock_job_%s", req.RequestID)
+	}
 
-	p.jobs[jobID] = &mockJob{
-		status:    JobStatusPending,
-		progress:  0,
-		createdAt: time.Now(),
+	job := &mockJob{
+		Request:    req,
+		CreatedAt:  now.UnixMilli(),
+		DurationMs: p.plannedDuration().Milliseconds(),
+		Fail:       p.shouldFail(req),
+		Status:     JobStatusPending,
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal mock job: %w", err)
 	}
 
-	go p.simulateJob(jobID, req)
+	if err := p.rdb.SetNX(ctx, p.jobKey(jobID), data, mockJobTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to save mock job: %w", err)
+	}
 
 	return &SubmitResult{
 		ProviderJobID: jobID,
@@ -51,60 +111,231 @@ func (p *MockProvider) Submit(ctx context.Context, req UnifiedGenRequest) (*Subm
 }
 
 func (p *MockProvider) Status(ctx context.Context, jobID string) (*StatusResult, error) {
-	job, exists := p.jobs[jobID]
-	if !exists {
-		return nil, fmt.Errorf("job not found: %s", jobID)
+	job, err := p.loadJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == JobStatusSucceeded || job.Status == JobStatusFailed {
+		return job.result(), nil
 	}
 
-	return &StatusResult{
-		Status:    job.status,
-		Progress:  job.progress,
-		ResultURL: job.resultURL,
-		Error:     job.error,
-	}, nil
-}
+	now, err := p.now(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-func (p *MockProvider) simulateJob(jobID string, req UnifiedGenRequest) {
-	job := p.jobs[jobID]
+	elapsed := now.UnixMilli() - job.CreatedAt
+	switch {
+	case elapsed < mockPendingDuration.Milliseconds() && elapsed < job.DurationMs:
+		return &StatusResult{Status: JobStatusPending}, nil
+	case elapsed < job.DurationMs:
+		return &StatusResult{Status: JobStatusRunning, Progress: int(20 + 79*elapsed/job.DurationMs)}, nil
+	}
 
-	time.Sleep(1 * time.Second)
+	return p.finish(ctx, jobID)
+}
 
-	job.status = JobStatusRunning
-	job.progress = 20
+// finish 在分布式锁内生成结果，保证多个进程同时查询时只生成一次
+func (p *MockProvider) finish(ctx context.Context, jobID string) (*StatusResult, error) {
+	lockKey := p.jobKey(jobID) + ":lock"
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
 
-	steps := []int{40, 60, 80, 100}
-	for _, step := range steps {
-		time.Sleep(time.Duration(rand.Intn(5)+3) * time.Second)
-		job.progress = step
+	acquired, err := p.rdb.SetNX(ctx, lockKey, token, mockFinishLockTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock mock job: %w", err)
 	}
+	if !acquired {
+		// 其他进程正在生成结果
+		return &StatusResult{Status: JobStatusRunning, Progress: 99}, nil
+	}
+	defer releaseMockLockScript.Run(context.Background(), p.rdb, []string{lockKey}, token)
 
-	resultURL, err := p.generateMockResult(req)
+	job, err := p.loadJob(ctx, jobID)
 	if err != nil {
-		job.status = JobStatusFailed
+		return nil, err
+	}
+	if job.Status == JobStatusSucceeded || job.Status == JobStatusFailed {
+		return job.result(), nil
+	}
+
+	if job.Fail {
+		job.Status = JobStatusFailed
+		errMsg := "mock provider simulated failure"
+		job.Error = &errMsg
+	} else if artifacts, err := p.generateArtifacts(ctx, jobID, job.Request); err != nil {
+		job.Status = JobStatusFailed
 		errMsg := fmt.Sprintf("failed to generate result: %v", err)
-		job.error = &errMsg
-		now := time.Now()
-		job.completedAt = &now
-		return
+		job.Error = &errMsg
+	} else {
+		job.Status = JobStatusSucceeded
+		job.Artifacts = artifacts
+	}
+
+	if err := p.saveJob(ctx, jobID, job); err != nil {
+		return nil, err
 	}
 
-	job.status = JobStatusSucceeded
-	job.progress = 100
-	job.resultURL = &resultURL
-	now := time.Now()
-	job.completedAt = &now
+	return job.result(), nil
 }
 
-func (p *MockProvider) generateMockResult(req UnifiedGenRequest) (string, error) {
-	objectKey := fmt.Sprintf("mock/%s/%d", req.Type, time.Now().UnixNano())
+// Cancel 将未完成的任务标记为失败，已完成的任务不受影响
+func (p *MockProvider) Cancel(ctx context.Context, jobID string) error {
+	lockKey := p.jobKey(jobID) + ":lock"
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
 
-	content := fmt.Sprintf("Mock generated %s for request: %s\nPrompt: %s",
-		req.Type, req.RequestID, req.Prompt)
+	acquired, err := p.rdb.SetNX(ctx, lockKey, token, mockFinishLockTTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to lock mock job: %w", err)
+	}
+	if !acquired {
+		return fmt.Errorf("mock job %s is being finalized", jobID)
+	}
+	defer releaseMockLockScript.Run(context.Background(), p.rdb, []string{lockKey}, token)
 
-	url, err := p.storage.Upload(context.Background(), objectKey, []byte(content), "text/plain")
+	job, err := p.loadJob(ctx, jobID)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if job.Status == JobStatusSucceeded || job.Status == JobStatusFailed {
+		return nil
 	}
 
-	return url, nil
+	job.Status = JobStatusFailed
+	errMsg := "cancelled"
+	job.Error = &errMsg
+	return p.saveJob(ctx, jobID, job)
+}
+
+func (p *MockProvider) saveJob(ctx context.Context, jobID string, job *mockJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mock job: %w", err)
+	}
+	if err := p.rdb.Set(ctx, p.jobKey(jobID), data, mockJobTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save mock job: %w", err)
+	}
+	return nil
+}
+
+func (p *MockProvider) loadJob(ctx context.Context, jobID string) (*mockJob, error) {
+	data, err := p.rdb.Get(ctx, p.jobKey(jobID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("job not found: %s", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mock job: %w", err)
+	}
+
+	var job mockJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mock job: %w", err)
+	}
+	return &job, nil
+}
+
+func (j *mockJob) result() *StatusResult {
+	result := &StatusResult{
+		Status:    j.Status,
+		Progress:  100,
+		Artifacts: j.Artifacts,
+		Error:     j.Error,
+	}
+	if len(j.Artifacts) > 0 {
+		url := j.Artifacts[0].URL
+		result.ResultURL = &url
+	}
+	return result
+}
+
+// now 使用 Redis 服务器时间，避免多个进程之间的时钟偏差
+func (p *MockProvider) now(ctx context.Context) (time.Time, error) {
+	now, err := p.rdb.Time(ctx).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get redis time: %w", err)
+	}
+	return now, nil
+}
+
+func (p *MockProvider) plannedDuration() time.Duration {
+	if p.cfg.DurationSec > 0 {
+		return time.Duration(p.cfg.DurationSec) * time.Second
+	}
+	if p.cfg.Deterministic {
+		return defaultMockDurationSec * time.Second
+	}
+	// 与真实供应商相近的 13~33 秒
+	return time.Duration(13+mathrand.Intn(21)) * time.Second
+}
+
+func (p *MockProvider) shouldFail(req UnifiedGenRequest) bool {
+	if p.cfg.FailureRate <= 0 {
+		return false
+	}
+	if !p.cfg.Deterministic {
+		return mathrand.Float64() < p.cfg.FailureRate
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatInt(p.cfg.Seed, 10)))
+	h.Write([]byte(req.RequestID))
+	return float64(h.Sum64()%10000)/10000 < p.cfg.FailureRate
+}
+
+// generateArtifacts 生成渲染了提示词的占位 PNG；视频请求额外生成 MP4 占位文件，PNG 作为封面
+func (p *MockProvider) generateArtifacts(ctx context.Context, jobID string, req UnifiedGenRequest) ([]Artifact, error) {
+	width, height := placeholderSize(req)
+	frame, err := encodePlaceholderPNG(renderPlaceholder(req, width, height), req.Prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type != RequestTypeVideo {
+		image, err := p.upload(ctx, fmt.Sprintf("mock/image/%s.png", jobID), frame, "image/png")
+		if err != nil {
+			return nil, err
+		}
+		image.Kind, image.Width, image.Height = string(RequestTypeImage), width, height
+		return []Artifact{*image}, nil
+	}
+
+	video, err := p.upload(ctx, fmt.Sprintf("mock/video/%s.mp4", jobID), buildPlaceholderMP4(frame, req.Prompt), "video/mp4")
+	if err != nil {
+		return nil, err
+	}
+	video.Kind, video.Width, video.Height = string(RequestTypeVideo), width, height
+
+	cover, err := p.upload(ctx, fmt.Sprintf("mock/video/%s_cover.png", jobID), frame, "image/png")
+	if err != nil {
+		return nil, err
+	}
+	cover.Kind, cover.Width, cover.Height = "cover", width, height
+
+	return []Artifact{*video, *cover}, nil
+}
+
+func (p *MockProvider) upload(ctx context.Context, objectKey string, data []byte, contentType string) (*Artifact, error) {
+	url, err := p.storage.Upload(ctx, objectKey, data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return &Artifact{
+		URL:       url,
+		ObjectKey: objectKey,
+		MimeType:  contentType,
+		Size:      int64(len(data)),
+	}, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
 }
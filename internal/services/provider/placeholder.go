@@ -0,0 +1,265 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"unicode"
+)
+
+const (
+	glyphWidth   = 5
+	glyphHeight  = 7
+	glyphAdvance = glyphWidth + 1
+	lineAdvance  = glyphHeight + 3
+)
+
+// glyphs 是 5x7 点阵字体，每行低 5 位从左到右；小写字母按大写绘制，
+// 未收录的字符（包括中文）按 transliterate 绘制为码位，原文写入 PNG 的 iTXt 块
+var glyphs = map[rune][glyphHeight]uint8{
+	'A':  {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'B':  {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C':  {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D':  {0x1E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x1E},
+	'E':  {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F':  {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G':  {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H':  {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I':  {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J':  {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K':  {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L':  {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M':  {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N':  {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O':  {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P':  {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q':  {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R':  {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S':  {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T':  {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W':  {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X':  {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y':  {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z':  {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	'0':  {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1':  {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2':  {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3':  {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4':  {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5':  {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6':  {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7':  {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8':  {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9':  {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	' ':  {},
+	'.':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	',':  {0x00, 0x00, 0x00, 0x00, 0x0C, 0x04, 0x08},
+	'-':  {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'_':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+	':':  {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+	'!':  {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
+	'?':  {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	'/':  {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'\'': {0x04, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00},
+	'(':  {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')':  {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'#':  {0x0A, 0x0A, 0x1F, 0x0A, 0x1F, 0x0A, 0x0A},
+	'<':  {0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02},
+	'>':  {0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},
+}
+
+var missingGlyph = [glyphHeight]uint8{0x1F, 0x11, 0x11, 0x11, 0x11, 0x11, 0x1F}
+
+const (
+	defaultPlaceholderSide = 512
+	maxPlaceholderSide     = 1024
+	minPlaceholderSide     = 64
+)
+
+// placeholderSize 按请求尺寸计算占位图大小，等比缩放到最长边不超过 1024
+func placeholderSize(req UnifiedGenRequest) (int, int) {
+	width, height := defaultPlaceholderSide, defaultPlaceholderSide
+	if req.Type == RequestTypeVideo {
+		width, height = 640, 360
+	}
+	if req.Width != nil && req.Height != nil && *req.Width > 0 && *req.Height > 0 {
+		width, height = *req.Width, *req.Height
+	}
+
+	if longest := maxInt(width, height); longest > maxPlaceholderSide {
+		width = width * maxPlaceholderSide / longest
+		height = height * maxPlaceholderSide / longest
+	}
+	return maxInt(width, minPlaceholderSide), maxInt(height, minPlaceholderSide)
+}
+
+// renderPlaceholder 绘制带有请求类型、尺寸、提示词和请求 ID 的占位图，背景色由提示词决定
+func renderPlaceholder(req UnifiedGenRequest, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	sum := promptHash(req.Prompt)
+	bg := color.RGBA{R: uint8(40 + sum%120), G: uint8(40 + (sum>>8)%120), B: uint8(40 + (sum>>16)%120), A: 255}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, bg)
+		}
+	}
+
+	margin := width / 16
+	scale := maxInt(1, (width-2*margin)/(32*glyphAdvance))
+	cols := maxInt(1, (width-2*margin)/(glyphAdvance*scale))
+	rows := (height - 2*margin) / (lineAdvance * scale)
+
+	fg := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	for i, line := range placeholderLines(req, width, height, cols, rows) {
+		drawText(img, line, margin, margin+i*lineAdvance*scale, scale, fg)
+	}
+	return img
+}
+
+func promptHash(prompt string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(prompt))
+	return h.Sum32()
+}
+
+// placeholderLines 返回占位图上的文字。标题行带有提示词哈希，提示词被截断时仍能与原文对应
+func placeholderLines(req UnifiedGenRequest, width, height, cols, rows int) []string {
+	lines := []string{
+		fmt.Sprintf("MOCK %s %dX%d #%08X", req.Type, width, height, promptHash(req.Prompt)),
+		"",
+	}
+	if len(req.ReferenceImages) > 0 {
+		lines = append(lines, fmt.Sprintf("REFS %d", len(req.ReferenceImages)), "")
+	}
+	lines = append(lines, wrapText(transliterate(req.Prompt), cols)...)
+	if rows > 0 && len(lines)+2 > rows {
+		lines = lines[:maxInt(0, rows-2)]
+	}
+	return append(lines, "", req.RequestID)
+}
+
+// transliterate 将字体未收录的字符替换为 <十六进制码位>，如 "雪" 为 <96EA>，
+// 使中文提示词在占位图上可以辨认，而不是一排相同的方框
+func transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if _, ok := glyphs[unicode.ToUpper(r)]; ok || r == '\n' {
+			b.WriteRune(r)
+			continue
+		}
+		fmt.Fprintf(&b, "<%04X>", r)
+	}
+	return b.String()
+}
+
+func wrapText(text string, cols int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		runes := []rune(paragraph)
+		for len(runes) > cols {
+			cut := cols
+			for i := cols; i > cols/2; i-- {
+				if unicode.IsSpace(runes[i]) {
+					cut = i
+					break
+				}
+			}
+			lines = append(lines, strings.TrimSpace(string(runes[:cut])))
+			runes = []rune(strings.TrimLeftFunc(string(runes[cut:]), unicode.IsSpace))
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+func drawText(img *image.RGBA, text string, x, y, scale int, c color.RGBA) {
+	for _, r := range text {
+		glyph, ok := glyphs[unicode.ToUpper(r)]
+		if !ok {
+			glyph = missingGlyph
+		}
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row]&(1<<uint(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						img.SetRGBA(x+col*scale+dx, y+row*scale+dy, c)
+					}
+				}
+			}
+		}
+		x += glyphAdvance * scale
+	}
+}
+
+// encodePlaceholderPNG 编码 PNG，并把原始提示词写入 iTXt 块（UTF-8）
+func encodePlaceholderPNG(img image.Image, description string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	raw := buf.Bytes()
+
+	// 8 字节签名 + IHDR 块（长度 4 + 类型 4 + 数据 13 + CRC 4）
+	const ihdrEnd = 8 + 25
+	if len(raw) < ihdrEnd {
+		return raw, nil
+	}
+
+	data := []byte("Description\x00\x00\x00\x00\x00" + description)
+	chunk := pngChunk("iTXt", data)
+
+	out := make([]byte, 0, len(raw)+len(chunk))
+	out = append(out, raw[:ihdrEnd]...)
+	out = append(out, chunk...)
+	out = append(out, raw[ihdrEnd:]...)
+	return out, nil
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk[:4], uint32(len(data)))
+	copy(chunk[4:8], typ)
+	chunk = append(chunk, data...)
+	crc := crc32.ChecksumIEEE(chunk[4:])
+	return binary.BigEndian.AppendUint32(chunk, crc)
+}
+
+// buildPlaceholderMP4 生成只有 ftyp/free/mdat 的 ISO BMFF 文件，free 中记录提示词，
+// mdat 中为封面帧。它能被按 MP4 识别和存储，但没有 moov，无法播放
+func buildPlaceholderMP4(frame []byte, description string) []byte {
+	ftyp := []byte("isom")
+	ftyp = binary.BigEndian.AppendUint32(ftyp, 0x200)
+	ftyp = append(ftyp, "isomiso2mp41"...)
+
+	var buf bytes.Buffer
+	buf.Write(mp4Box("ftyp", ftyp))
+	buf.Write(mp4Box("free", []byte(description)))
+	buf.Write(mp4Box("mdat", frame))
+	return buf.Bytes()
+}
+
+func mp4Box(typ string, data []byte) []byte {
+	box := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint32(box[:4], uint32(8+len(data)))
+	copy(box[4:8], typ)
+	return append(box, data...)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
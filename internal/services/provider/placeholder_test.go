@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+)
+
+type memoryStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memoryStorage) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.objects == nil {
+		m.objects = make(map[string][]byte)
+	}
+	m.objects[key] = append([]byte(nil), data...)
+	return "memory://" + key, nil
+}
+
+func (m *memoryStorage) GetPresignedURL(ctx context.Context, key string, expiry int) (string, error) {
+	return "memory://" + key, nil
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"a red fox", "a red fox"},
+		{"雪山日出", "<96EA><5C71><65E5><51FA>"},
+		{"画一只cat，油画", "<753B><4E00><53EA>cat<FF0C><6CB9><753B>"},
+		{"line1\nline2", "line1\nline2"},
+		{"café 🎨", "caf<00E9> <1F3A8>"},
+	}
+	for _, tt := range tests {
+		if got := transliterate(tt.in); got != tt.want {
+			t.Errorf("transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceholderLinesRenderCJK(t *testing.T) {
+	req := UnifiedGenRequest{RequestID: "req_1", Type: RequestTypeImage, Prompt: "雪山日出，油画风格"}
+	other := req
+	other.Prompt = "海边日落，水彩风格"
+
+	lines := placeholderLines(req, 512, 512, 32, 40)
+	otherLines := placeholderLines(other, 512, 512, 32, 40)
+
+	// 每个字符都有字形，不会退化为方框
+	for _, line := range lines {
+		for _, r := range line {
+			if _, ok := glyphs[unicode.ToUpper(r)]; !ok {
+				t.Fatalf("line %q has character %q without a glyph", line, r)
+			}
+		}
+		if len([]rune(line)) > 32 {
+			t.Fatalf("line %q exceeds 32 columns", line)
+		}
+	}
+
+	text := strings.Join(lines, "")
+	if !strings.Contains(text, "<96EA><5C71>") {
+		t.Fatalf("prompt is not rendered: %q", lines)
+	}
+	if strings.Join(otherLines, "") == text {
+		t.Fatal("different CJK prompts render the same text")
+	}
+	if lines[0] == otherLines[0] || !strings.HasPrefix(lines[0], "MOCK image 512X512 #") {
+		t.Fatalf("header does not identify the prompt: %q vs %q", lines[0], otherLines[0])
+	}
+	if lines[len(lines)-1] != "req_1" {
+		t.Fatalf("last line is %q, want the request ID", lines[len(lines)-1])
+	}
+}
+
+func TestPlaceholderLinesTruncateLongPrompt(t *testing.T) {
+	req := UnifiedGenRequest{RequestID: "req_1", Type: RequestTypeImage, Prompt: strings.Repeat("雪", 200)}
+	lines := placeholderLines(req, 512, 512, 32, 10)
+	if len(lines) != 10 {
+		t.Fatalf("got %d lines, want 10", len(lines))
+	}
+	if lines[len(lines)-1] != "req_1" {
+		t.Fatalf("request ID dropped: %q", lines)
+	}
+}
+
+func TestMockDeterministic(t *testing.T) {
+	cfg := &ProviderConfig{
+		ProviderName: "mock",
+		Mock:         &MockConfig{Deterministic: true, Seed: 42, FailureRate: 0.5},
+	}
+	req := UnifiedGenRequest{RequestID: "req_1", Type: RequestTypeImage, Prompt: "雪山日出，油画风格"}
+
+	first := NewMockProvider(cfg, nil, &memoryStorage{})
+	second := NewMockProvider(cfg, nil, &memoryStorage{})
+
+	if got := first.plannedDuration(); got != defaultMockDurationSec*time.Second {
+		t.Fatalf("planned duration %s, want %ds", got, defaultMockDurationSec)
+	}
+
+	// 是否失败只由 Seed 与请求 ID 决定
+	failures := 0
+	for i := 0; i < 200; i++ {
+		r := UnifiedGenRequest{RequestID: "req_" + strings.Repeat("x", i)}
+		if first.shouldFail(r) != second.shouldFail(r) {
+			t.Fatalf("shouldFail differs between instances for %s", r.RequestID)
+		}
+		if first.shouldFail(r) {
+			failures++
+		}
+	}
+	if failures == 0 || failures == 200 {
+		t.Fatalf("failure rate 0.5 produced %d failures out of 200", failures)
+	}
+
+	// 同一请求生成的占位图逐字节相同
+	a, err := first.generateArtifacts(context.Background(), "mock_job_req_1", req)
+	if err != nil {
+		t.Fatalf("generateArtifacts: %v", err)
+	}
+	b, err := second.generateArtifacts(context.Background(), "mock_job_req_1", req)
+	if err != nil {
+		t.Fatalf("generateArtifacts: %v", err)
+	}
+	if len(a) != 1 || len(b) != 1 || a[0].ObjectKey != b[0].ObjectKey {
+		t.Fatalf("unexpected artifacts %+v / %+v", a, b)
+	}
+	dataA := first.storage.(*memoryStorage).objects[a[0].ObjectKey]
+	dataB := second.storage.(*memoryStorage).objects[b[0].ObjectKey]
+	if len(dataA) == 0 || !bytes.Equal(dataA, dataB) {
+		t.Fatal("deterministic placeholders differ")
+	}
+	if !bytes.Contains(dataA, []byte(req.Prompt)) {
+		t.Fatal("original prompt missing from iTXt chunk")
+	}
+
+	other := req
+	other.Prompt = "海边日落，水彩风格"
+	c, err := first.generateArtifacts(context.Background(), "mock_job_req_2", other)
+	if err != nil {
+		t.Fatalf("generateArtifacts: %v", err)
+	}
+	if bytes.Equal(first.storage.(*memoryStorage).objects[c[0].ObjectKey], dataA) {
+		t.Fatal("different prompts produced the same placeholder")
+	}
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultVideoSec 为请求未指定时长时用于估算视频费用的秒数
+const defaultVideoSec = 5
+
+// PricingConfig 描述供应商的价格，金额单位由使用方自行约定（与预算上限一致即可）。
+// Resolutions 按分辨率覆盖单价：取像素数不小于请求尺寸的最小档位，
+// 请求未指定尺寸或大于所有档位时使用基础单价。
+type PricingConfig struct {
+	PerRequest      float64           `json:"per_request,omitempty"`
+	PerImage        float64           `json:"per_image,omitempty"`
+	PerVideoSecond  float64           `json:"per_video_second,omitempty"`
+	DefaultVideoSec int               `json:"default_video_sec,omitempty"`
+	Resolutions     []ResolutionPrice `json:"resolutions,omitempty"`
+}
+
+// ResolutionPrice 为一个分辨率档位，MaxResolution 形如 "1024x1024"，为 0 的单价沿用基础单价
+type ResolutionPrice struct {
+	MaxResolution  string  `json:"max_resolution"`
+	PerImage       float64 `json:"per_image,omitempty"`
+	PerVideoSecond float64 `json:"per_video_second,omitempty"`
+}
+
+// BudgetConfig 为供应商的花费上限，按自然日与自然月统计，0 表示不限制
+type BudgetConfig struct {
+	DailyLimit   float64 `json:"daily_limit,omitempty"`
+	MonthlyLimit float64 `json:"monthly_limit,omitempty"`
+}
+
+func parseResolution(s string) (int, int, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid resolution: %q", s)
+	}
+	width, err := strconv.Atoi(parts[0])
+	if err != nil || width <= 0 {
+		return 0, 0, fmt.Errorf("invalid resolution: %q", s)
+	}
+	height, err := strconv.Atoi(parts[1])
+	if err != nil || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid resolution: %q", s)
+	}
+	return width, height, nil
+}
+
+func (p *PricingConfig) validate() error {
+	if p.PerRequest < 0 || p.PerImage < 0 || p.PerVideoSecond < 0 || p.DefaultVideoSec < 0 {
+		return fmt.Errorf("pricing values must not be negative")
+	}
+	for _, r := range p.Resolutions {
+		if _, _, err := parseResolution(r.MaxResolution); err != nil {
+			return fmt.Errorf("pricing.resolutions: %w", err)
+		}
+		if r.PerImage < 0 || r.PerVideoSecond < 0 {
+			return fmt.Errorf("pricing values must not be negative")
+		}
+	}
+	return nil
+}
+
+// rates 返回请求尺寸对应的图片单价与视频每秒单价
+func (p *PricingConfig) rates(req UnifiedGenRequest) (float64, float64) {
+	perImage, perSecond := p.PerImage, p.PerVideoSecond
+	if req.Width == nil || req.Height == nil || len(p.Resolutions) == 0 {
+		return perImage, perSecond
+	}
+
+	type tier struct {
+		pixels int
+		price  ResolutionPrice
+	}
+	tiers := make([]tier, 0, len(p.Resolutions))
+	for _, r := range p.Resolutions {
+		width, height, err := parseResolution(r.MaxResolution)
+		if err != nil {
+			continue
+		}
+		tiers = append(tiers, tier{pixels: width * height, price: r})
+	}
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].pixels < tiers[j].pixels })
+
+	pixels := *req.Width * *req.Height
+	for _, t := range tiers {
+		if pixels > t.pixels {
+			continue
+		}
+		if t.price.PerImage > 0 {
+			perImage = t.price.PerImage
+		}
+		if t.price.PerVideoSecond > 0 {
+			perSecond = t.price.PerVideoSecond
+		}
+		break
+	}
+	return perImage, perSecond
+}
+
+// EstimateCost 按 pricing 估算一次请求的费用，未配置价格时为 0
+func (c *ProviderConfig) EstimateCost(req UnifiedGenRequest) float64 {
+	p := c.Pricing
+	if p == nil {
+		return 0
+	}
+
+	perImage, perSecond := p.rates(req)
+	cost := p.PerRequest
+	switch req.Type {
+	case RequestTypeVideo:
+		seconds := p.DefaultVideoSec
+		if seconds <= 0 {
+			seconds = defaultVideoSec
+		}
+		if req.DurationSec != nil && *req.DurationSec > 0 {
+			seconds = *req.DurationSec
+		}
+		cost += perSecond * float64(seconds)
+	default:
+		cost += perImage
+	}
+	return cost
+}
@@ -2,6 +2,11 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/services/breaker"
 )
 
 type RequestType string
@@ -9,6 +14,15 @@ type RequestType string
 const (
 	RequestTypeImage RequestType = "image"
 	RequestTypeVideo RequestType = "video"
+	// RequestTypeEdit 为图生图请求，ReferenceImages 中至少有一张参考图
+	RequestTypeEdit RequestType = "edit"
+)
+
+// 供应商能力，未配置 capabilities 时视为支持 image 与 video（mock 供应商支持全部能力）
+const (
+	CapabilityImage   = "image"
+	CapabilityVideo   = "video"
+	CapabilityImg2Img = "img2img"
 )
 
 type UnifiedGenRequest struct {
@@ -23,6 +37,10 @@ type UnifiedGenRequest struct {
 	Ratio          string                 `json:"ratio,omitempty"`
 	Seed           *int                   `json:"seed,omitempty"`
 	Extra          map[string]interface{} `json:"extra,omitempty"`
+	// ReferenceImages 为参考图地址（已转存到我方存储），仅路由到声明了 img2img 能力的供应商
+	ReferenceImages []string `json:"reference_images,omitempty"`
+	// Task 仅供请求模板使用（.Task），不会出现在默认请求体中
+	Task *TaskContext `json:"-"`
 }
 
 type JobStatus string
@@ -32,17 +50,36 @@ const (
 	JobStatusRunning   JobStatus = "running"
 	JobStatusSucceeded JobStatus = "succeeded"
 	JobStatusFailed    JobStatus = "failed"
+	// JobStatusUnknown 表示供应商返回了状态表中不存在的值
+	JobStatusUnknown JobStatus = "unknown"
 )
 
 type SubmitResult struct {
 	ProviderJobID string `json:"provider_job_id"`
+	// Result 非空表示供应商在提交响应中直接返回了结果（同步模式），无需轮询状态
+	Result *StatusResult `json:"result,omitempty"`
+}
+
+// Artifact 是任务的一个输出产物，例如多张候选图片或视频加封面
+type Artifact struct {
+	Kind      string `json:"kind,omitempty"`
+	URL       string `json:"url"`
+	ObjectKey string `json:"object_key,omitempty"`
+	MimeType  string `json:"mime_type,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	// Data 为响应中内联的 base64 内容解码后的数据，上传到存储后清空
+	Data []byte `json:"-"`
 }
 
 type StatusResult struct {
-	Status    JobStatus `json:"status"`
-	Progress  int       `json:"progress"`
-	ResultURL *string   `json:"result_url,omitempty"`
-	Error     *string   `json:"error,omitempty"`
+	Status    JobStatus  `json:"status"`
+	RawStatus string     `json:"raw_status,omitempty"`
+	Progress  int        `json:"progress"`
+	ResultURL *string    `json:"result_url,omitempty"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	Error     *string    `json:"error,omitempty"`
 }
 
 type Provider interface {
@@ -51,15 +88,160 @@ type Provider interface {
 	Name() string
 }
 
+// Canceler 由支持取消任务的供应商实现；未配置取消接口时返回 ErrCancelNotSupported
+type Canceler interface {
+	Cancel(ctx context.Context, jobID string) error
+}
+
+var ErrCancelNotSupported = errors.New("provider does not support cancellation")
+
+// HealthChecker 由支持主动健康检查的供应商实现
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+const (
+	ProviderModeAsync = "async"
+	ProviderModeSync  = "sync"
+)
+
+// ProviderConfig 描述一个生成供应商。
+// Mode 为 sync 时提交响应即为最终结果，不再调用状态接口；默认为 async。
+// StatusValues 将原始状态值映射为 pending/running/succeeded/failed；
+// MaxConcurrentJobs 与 SubmitQPS 为集群范围的限制，0 表示不限制。
+// Pricing 用于提交前估算费用，Budget 为该供应商的花费上限。
 type ProviderConfig struct {
 	ProviderName       string                 `json:"provider_name"`
 	Type               string                 `json:"type"`
+	Mode               string                 `json:"mode,omitempty"`
 	BaseURL            string                 `json:"base_url"`
 	APIKey             string                 `json:"api_key"`
 	SubmitPath         string                 `json:"submit_path"`
 	StatusPathTemplate string                 `json:"status_path_template"`
+	CancelPathTemplate string                 `json:"cancel_path_template,omitempty"`
+	CancelMethod       string                 `json:"cancel_method,omitempty"`
 	Headers            map[string]string      `json:"headers"`
 	RequestMapping     map[string]interface{} `json:"request_mapping"`
 	ResponseMapping    map[string]string      `json:"response_mapping"`
 	StatusMapping      map[string]string      `json:"status_mapping"`
+	StatusValues       map[string]string      `json:"status_values,omitempty"`
+	MaxConcurrentJobs  int                    `json:"max_concurrent_jobs,omitempty"`
+	SubmitQPS          float64                `json:"submit_qps,omitempty"`
+	SubmitTimeoutSec   int                    `json:"submit_timeout_sec,omitempty"`
+	StatusTimeoutSec   int                    `json:"status_timeout_sec,omitempty"`
+	Auth               *AuthConfig            `json:"auth,omitempty"`
+	Mock               *MockConfig            `json:"mock,omitempty"`
+	CircuitBreaker     *CircuitBreakerConfig  `json:"circuit_breaker,omitempty"`
+	HealthCheck        *HealthCheckConfig     `json:"health_check,omitempty"`
+	Capabilities       []string               `json:"capabilities,omitempty"`
+	Pricing            *PricingConfig         `json:"pricing,omitempty"`
+	Budget             *BudgetConfig          `json:"budget,omitempty"`
+}
+
+// CircuitBreakerConfig 控制供应商的熔断器，零值字段使用默认值。
+// SlowCallSec 为慢调用阈值，默认取对应请求超时时间的 80%。
+type CircuitBreakerConfig struct {
+	Disabled     bool    `json:"disabled,omitempty"`
+	FailureRate  float64 `json:"failure_rate,omitempty"`
+	SlowCallSec  int     `json:"slow_call_sec,omitempty"`
+	SlowCallRate float64 `json:"slow_call_rate,omitempty"`
+	WindowSize   int     `json:"window_size,omitempty"`
+	MinRequests  int     `json:"min_requests,omitempty"`
+	OpenSec      int     `json:"open_sec,omitempty"`
+}
+
+// HealthCheckConfig 描述供应商的健康检查接口，Path 相对于 BaseURL
+type HealthCheckConfig struct {
+	Path         string `json:"path"`
+	Method       string `json:"method,omitempty"`
+	IntervalSec  int    `json:"interval_sec,omitempty"`
+	TimeoutSec   int    `json:"timeout_sec,omitempty"`
+	ExpectStatus int    `json:"expect_status,omitempty"`
+}
+
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 5 * time.Second
+)
+
+func (h *HealthCheckConfig) Interval() time.Duration {
+	if h.IntervalSec > 0 {
+		return time.Duration(h.IntervalSec) * time.Second
+	}
+	return defaultHealthInterval
+}
+
+func (h *HealthCheckConfig) Timeout() time.Duration {
+	if h.TimeoutSec > 0 {
+		return time.Duration(h.TimeoutSec) * time.Second
+	}
+	return defaultHealthTimeout
+}
+
+func (c *ProviderConfig) BreakerSettings() breaker.Settings {
+	cb := c.CircuitBreaker
+	if cb == nil {
+		return breaker.Settings{ProbeTimeout: c.SubmitTimeout()}
+	}
+	return breaker.Settings{
+		Disabled:     cb.Disabled,
+		FailureRate:  cb.FailureRate,
+		SlowCallRate: cb.SlowCallRate,
+		WindowSize:   cb.WindowSize,
+		MinRequests:  cb.MinRequests,
+		OpenDuration: time.Duration(cb.OpenSec) * time.Second,
+		ProbeTimeout: c.SubmitTimeout(),
+	}
+}
+
+// SlowCallThreshold 返回超时时间为 timeout 的调用被视为慢调用的耗时
+func (c *ProviderConfig) SlowCallThreshold(timeout time.Duration) time.Duration {
+	if c.CircuitBreaker != nil && c.CircuitBreaker.SlowCallSec > 0 {
+		return time.Duration(c.CircuitBreaker.SlowCallSec) * time.Second
+	}
+	return timeout * 4 / 5
+}
+
+func (c *ProviderConfig) HasCapability(capability string) bool {
+	if len(c.Capabilities) == 0 {
+		return c.IsMock() || capability == CapabilityImage || capability == CapabilityVideo
+	}
+	for _, v := range c.Capabilities {
+		if strings.EqualFold(v, capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// Supports 判断供应商能否处理该请求：带参考图的请求需要 img2img 能力
+func (c *ProviderConfig) Supports(req UnifiedGenRequest) bool {
+	if req.Type == RequestTypeEdit || len(req.ReferenceImages) > 0 {
+		return c.HasCapability(CapabilityImg2Img)
+	}
+	return c.HasCapability(string(req.Type))
+}
+
+func (c *ProviderConfig) IsMock() bool {
+	return c.ProviderName == "mock" || c.Type == "mock"
+}
+
+func (c *ProviderConfig) IsSync() bool {
+	return strings.EqualFold(c.Mode, ProviderModeSync)
+}
+
+const defaultProviderTimeout = 30 * time.Second
+
+func (c *ProviderConfig) SubmitTimeout() time.Duration {
+	if c.SubmitTimeoutSec > 0 {
+		return time.Duration(c.SubmitTimeoutSec) * time.Second
+	}
+	return defaultProviderTimeout
+}
+
+func (c *ProviderConfig) StatusTimeout() time.Duration {
+	if c.StatusTimeoutSec > 0 {
+		return time.Duration(c.StatusTimeoutSec) * time.Second
+	}
+	return defaultProviderTimeout
 }
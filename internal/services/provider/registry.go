@@ -0,0 +1,225 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ReloadChannel 为供应商配置变更的通知频道，消息内容无意义，收到后各进程从数据库重新加载
+const ReloadChannel = "settings:providers"
+
+// drainTimeout 为被替换的供应商实例等待进行中调用结束的最长时间
+const drainTimeout = 5 * time.Minute
+
+// Factory 根据配置创建供应商实例
+type Factory func(cfg *ProviderConfig) (Provider, error)
+
+// Closer 由持有连接等资源的供应商实现，实例被替换且调用全部结束后调用
+type Closer interface {
+	Close() error
+}
+
+// NewFactory 返回默认的供应商工厂：mock 配置创建 MockProvider，其余创建 HTTPProvider
+func NewFactory(rdb *redis.Client, storage Storage) Factory {
+	return func(cfg *ProviderConfig) (Provider, error) {
+		if cfg.IsMock() {
+			return NewMockProvider(cfg, rdb, storage), nil
+		}
+		return NewHTTPProvider(cfg, storage)
+	}
+}
+
+// Registry 持有当前生效的供应商配置与实例。
+// Reload 时配置未变的实例会被复用（保留令牌缓存等状态），变更或删除的实例
+// 在进行中的调用结束后关闭。调用方通过 Acquire/Release 使用实例。
+type Registry struct {
+	factory Factory
+	rdb     *redis.Client
+
+	reloadMu sync.Mutex
+	mu       sync.RWMutex
+	loaded   bool
+	raw      string
+	configs  []ProviderConfig
+	entries  map[string]*registryEntry
+}
+
+type registryEntry struct {
+	provider    Provider
+	fingerprint string
+	inflight    sync.WaitGroup
+}
+
+// Lease 为一次对供应商实例的使用，使用完毕后必须调用 Release
+type Lease struct {
+	Provider
+	entry *registryEntry
+	once  sync.Once
+}
+
+func (l *Lease) Release() {
+	l.once.Do(l.entry.inflight.Done)
+}
+
+func NewRegistry(factory Factory, rdb *redis.Client) *Registry {
+	return &Registry{
+		factory: factory,
+		rdb:     rdb,
+		entries: make(map[string]*registryEntry),
+	}
+}
+
+// Reload 校验并应用新的供应商配置，配置未变化时返回 false。
+// 任何一个供应商创建失败时保持原有配置不变。
+func (r *Registry) Reload(raw string) (bool, error) {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	r.mu.RLock()
+	unchanged := r.loaded && raw == r.raw
+	old := r.entries
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	configs, err := ParseProviderConfigs(raw)
+	if err != nil {
+		return false, err
+	}
+
+	entries := make(map[string]*registryEntry, len(configs))
+	var created []Provider
+	for i := range configs {
+		cfg := &configs[i]
+		fingerprint, err := json.Marshal(cfg)
+		if err != nil {
+			closeProviders(created)
+			return false, fmt.Errorf("failed to marshal provider %s: %w", cfg.ProviderName, err)
+		}
+
+		if entry, ok := old[cfg.ProviderName]; ok && entry.fingerprint == string(fingerprint) {
+			entries[cfg.ProviderName] = entry
+			continue
+		}
+
+		prov, err := r.factory(cfg)
+		if err != nil {
+			closeProviders(created)
+			return false, fmt.Errorf("failed to create provider %s: %w", cfg.ProviderName, err)
+		}
+		created = append(created, prov)
+		entries[cfg.ProviderName] = &registryEntry{
+			provider:    prov,
+			fingerprint: string(fingerprint),
+		}
+	}
+
+	r.mu.Lock()
+	r.loaded = true
+	r.raw = raw
+	r.configs = configs
+	r.entries = entries
+	r.mu.Unlock()
+
+	for name, entry := range old {
+		if entries[name] != entry {
+			go drain(entry)
+		}
+	}
+	return true, nil
+}
+
+// Validate 解析配置并试创建所有供应商，不影响当前生效的实例
+func (r *Registry) Validate(raw string) error {
+	configs, err := ParseProviderConfigs(raw)
+	if err != nil {
+		return err
+	}
+
+	var created []Provider
+	defer func() { closeProviders(created) }()
+	for i := range configs {
+		prov, err := r.factory(&configs[i])
+		if err != nil {
+			return fmt.Errorf("provider[%d] %s: %w", i, configs[i].ProviderName, err)
+		}
+		created = append(created, prov)
+	}
+	return nil
+}
+
+// Configs 返回当前生效配置的副本，顺序与配置一致
+func (r *Registry) Configs() []ProviderConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	configs := make([]ProviderConfig, len(r.configs))
+	copy(configs, r.configs)
+	return configs
+}
+
+// Config 返回指定供应商当前生效的配置，不存在时返回 nil
+func (r *Registry) Config(name string) *ProviderConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.configs {
+		if r.configs[i].ProviderName == name {
+			cfg := r.configs[i]
+			return &cfg
+		}
+	}
+	return nil
+}
+
+// Acquire 获取指定供应商的实例，实例在 Release 之前不会被关闭
+func (r *Registry) Acquire(name string) (*Lease, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[name]
+	if !ok {
+		return nil, false
+	}
+	entry.inflight.Add(1)
+	return &Lease{Provider: entry.provider, entry: entry}, true
+}
+
+// Publish 通知所有进程重新加载供应商配置
+func (r *Registry) Publish(ctx context.Context) error {
+	if err := r.rdb.Publish(ctx, ReloadChannel, time.Now().UnixNano()).Err(); err != nil {
+		return fmt.Errorf("failed to publish provider reload: %w", err)
+	}
+	return nil
+}
+
+// Subscribe 订阅供应商配置变更通知，调用方负责关闭
+func (r *Registry) Subscribe(ctx context.Context) *redis.PubSub {
+	return r.rdb.Subscribe(ctx, ReloadChannel)
+}
+
+// drain 等待旧实例上进行中的调用结束后关闭实例，超时后强制关闭
+func drain(entry *registryEntry) {
+	done := make(chan struct{})
+	go func() {
+		entry.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(drainTimeout):
+	}
+	closeProviders([]Provider{entry.provider})
+}
+
+func closeProviders(providers []Provider) {
+	for _, p := range providers {
+		if closer, ok := p.(Closer); ok {
+			closer.Close()
+		}
+	}
+}
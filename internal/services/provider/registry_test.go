@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	name   string
+	closed chan struct{}
+}
+
+func (p *fakeProvider) Submit(ctx context.Context, req UnifiedGenRequest) (*SubmitResult, error) {
+	return nil, nil
+}
+
+func (p *fakeProvider) Status(ctx context.Context, jobID string) (*StatusResult, error) {
+	return nil, nil
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Close() error {
+	close(p.closed)
+	return nil
+}
+
+func (p *fakeProvider) isClosed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// fakeFactory 记录创建的实例，failFor 中的供应商创建失败
+type fakeFactory struct {
+	mu      sync.Mutex
+	created []*fakeProvider
+	failFor string
+}
+
+func (f *fakeFactory) create(cfg *ProviderConfig) (Provider, error) {
+	if cfg.ProviderName == f.failFor {
+		return nil, errors.New("boom")
+	}
+	p := &fakeProvider{name: cfg.ProviderName, closed: make(chan struct{})}
+	f.mu.Lock()
+	f.created = append(f.created, p)
+	f.mu.Unlock()
+	return p, nil
+}
+
+func (f *fakeFactory) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.created)
+}
+
+func acquire(t *testing.T, r *Registry, name string) *Lease {
+	t.Helper()
+	lease, ok := r.Acquire(name)
+	if !ok {
+		t.Fatalf("Acquire(%q) failed", name)
+	}
+	return lease
+}
+
+func waitClosed(t *testing.T, p *fakeProvider) {
+	t.Helper()
+	select {
+	case <-p.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("provider %s was not closed", p.name)
+	}
+}
+
+func mustReload(t *testing.T, r *Registry, raw string, want bool) {
+	t.Helper()
+	changed, err := r.Reload(raw)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if changed != want {
+		t.Fatalf("Reload changed = %v, want %v", changed, want)
+	}
+}
+
+const (
+	twoProviders   = `[{"provider_name":"a","type":"mock"},{"provider_name":"b","type":"mock"}]`
+	bChanged       = `[{"provider_name":"a","type":"mock"},{"provider_name":"b","type":"mock","submit_qps":2}]`
+	onlyA          = `[{"provider_name":"a","type":"mock"}]`
+	reformattedTwo = `[ {"type":"mock","provider_name":"a"}, {"provider_name":"b","type":"mock"} ]`
+)
+
+func TestRegistryReloadReusesUnchangedProviders(t *testing.T) {
+	factory := &fakeFactory{}
+	r := NewRegistry(factory.create, nil)
+
+	mustReload(t, r, twoProviders, true)
+	if factory.count() != 2 {
+		t.Fatalf("created %d providers, want 2", factory.count())
+	}
+	a, b := factory.created[0], factory.created[1]
+
+	// 原文相同时不重新解析
+	mustReload(t, r, twoProviders, false)
+
+	// 原文不同但配置相同时复用实例
+	mustReload(t, r, reformattedTwo, true)
+	if factory.count() != 2 {
+		t.Fatalf("reformatted config created %d providers, want 2", factory.count())
+	}
+
+	// 只有变更的供应商重新创建，未变的实例保留
+	mustReload(t, r, bChanged, true)
+	if factory.count() != 3 {
+		t.Fatalf("changed config created %d providers, want 3", factory.count())
+	}
+	waitClosed(t, b)
+	if a.isClosed() {
+		t.Fatal("unchanged provider a was closed")
+	}
+
+	lease := acquire(t, r, "b")
+	defer lease.Release()
+	if lease.Provider != factory.created[2] {
+		t.Fatal("Acquire returned the replaced instance")
+	}
+	if cfg := r.Config("b"); cfg == nil || cfg.SubmitQPS != 2 {
+		t.Fatalf("Config(b) = %+v, want submit_qps 2", cfg)
+	}
+}
+
+func TestRegistryDrainsReplacedProviders(t *testing.T) {
+	factory := &fakeFactory{}
+	r := NewRegistry(factory.create, nil)
+	mustReload(t, r, twoProviders, true)
+	b := factory.created[1]
+
+	first := acquire(t, r, "b")
+	second := acquire(t, r, "b")
+
+	// b 被删除后，进行中的调用结束前不关闭
+	mustReload(t, r, onlyA, true)
+	if _, ok := r.Acquire("b"); ok {
+		t.Fatal("removed provider b is still acquirable")
+	}
+
+	first.Release()
+	first.Release() // 重复 Release 不重复计数
+	time.Sleep(50 * time.Millisecond)
+	if b.isClosed() {
+		t.Fatal("provider b closed while a lease is outstanding")
+	}
+
+	second.Release()
+	waitClosed(t, b)
+}
+
+func TestRegistryReloadFailureKeepsConfig(t *testing.T) {
+	factory := &fakeFactory{}
+	r := NewRegistry(factory.create, nil)
+	mustReload(t, r, onlyA, true)
+	a := factory.created[0]
+
+	factory.failFor = "c"
+	raw := `[{"provider_name":"a","type":"mock","submit_qps":1},{"provider_name":"b","type":"mock"},{"provider_name":"c","type":"mock"}]`
+	if _, err := r.Reload(raw); err == nil {
+		t.Fatal("Reload succeeded, want factory error")
+	}
+
+	// 本次创建的实例全部关闭，原有实例与配置保持不变
+	for _, p := range factory.created[1:] {
+		if !p.isClosed() {
+			t.Fatalf("provider %s created by the failed reload was not closed", p.name)
+		}
+	}
+	if a.isClosed() {
+		t.Fatal("current provider a was closed")
+	}
+	lease := acquire(t, r, "a")
+	defer lease.Release()
+	if lease.Provider != a {
+		t.Fatal("Acquire(a) returned a different instance")
+	}
+	if configs := r.Configs(); len(configs) != 1 || configs[0].SubmitQPS != 0 {
+		t.Fatalf("Configs() = %+v, want the original config", configs)
+	}
+
+	if _, err := r.Reload(`[{"provider_name":""}]`); err == nil {
+		t.Fatal("Reload accepted an invalid config")
+	}
+	if _, ok := r.Acquire("missing"); ok {
+		t.Fatal("Acquire of an unknown provider succeeded")
+	}
+}
@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	templateExprKey = "$expr"
+	templateTypeKey = "$type"
+)
+
+// TaskContext 是模板中可访问的任务信息（.Task）
+type TaskContext struct {
+	ID         uint   `json:"id"`
+	CommentID  uint   `json:"comment_id"`
+	NoteTarget string `json:"note_target"`
+	Email      string `json:"email"`
+}
+
+// TemplateData 是请求模板的渲染上下文
+type TemplateData struct {
+	Request  UnifiedGenRequest
+	Task     TaskContext
+	Provider ProviderTemplateInfo
+}
+
+// ProviderTemplateInfo 是模板中可访问的供应商配置，不包含密钥
+type ProviderTemplateInfo struct {
+	Name    string
+	Type    string
+	BaseURL string
+	Headers map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	"default": tplDefault,
+	"coalesce": func(values ...interface{}) interface{} {
+		for _, v := range values {
+			if !isEmptyValue(v) {
+				return indirectValue(v)
+			}
+		}
+		return nil
+	},
+	"ternary": func(cond bool, a, b interface{}) interface{} {
+		if cond {
+			return a
+		}
+		return b
+	},
+	"add":   func(a, b interface{}) float64 { return toNumber(a) + toNumber(b) },
+	"sub":   func(a, b interface{}) float64 { return toNumber(a) - toNumber(b) },
+	"mul":   func(a, b interface{}) float64 { return toNumber(a) * toNumber(b) },
+	"div":   tplDiv,
+	"int":   func(v interface{}) int64 { return int64(math.Round(toNumber(v))) },
+	"float": toNumber,
+	"size": func(w, h interface{}) string {
+		return fmt.Sprintf("%dx%d", int64(toNumber(w)), int64(toNumber(h)))
+	},
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(indirectValue(v))
+		return string(b), err
+	},
+	"lower":     func(v interface{}) string { return strings.ToLower(toString(v)) },
+	"upper":     func(v interface{}) string { return strings.ToUpper(toString(v)) },
+	"trim":      func(v interface{}) string { return strings.TrimSpace(toString(v)) },
+	"replace":   func(old, new string, v interface{}) string { return strings.ReplaceAll(toString(v), old, new) },
+	"join":      func(sep string, v interface{}) string { return strings.Join(toStringSlice(v), sep) },
+	"contains":  func(sub string, v interface{}) bool { return strings.Contains(toString(v), sub) },
+	"hasPrefix": func(prefix string, v interface{}) bool { return strings.HasPrefix(toString(v), prefix) },
+	"empty":     isEmptyValue,
+	"deref":     indirectValue,
+}
+
+func isTemplateString(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+func compileTemplate(src string) (*template.Template, error) {
+	return template.New("request_mapping").Funcs(templateFuncs).Option("missingkey=zero").Parse(src)
+}
+
+func renderTemplate(tpl *template.Template, data *TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// coerceTemplateValue 按 $type 将模板输出转换为目标类型
+func coerceTemplateValue(out string, typ string) (interface{}, error) {
+	out = strings.TrimSpace(out)
+	switch typ {
+	case "", "string":
+		return out, nil
+	case "int":
+		if out == "" || out == "<nil>" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(out, 64)
+		if err != nil {
+			return nil, fmt.Errorf("template output %q is not an int", out)
+		}
+		return int64(math.Round(f)), nil
+	case "float":
+		if out == "" || out == "<nil>" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(out, 64)
+		if err != nil {
+			return nil, fmt.Errorf("template output %q is not a number", out)
+		}
+		return f, nil
+	case "bool":
+		if out == "" {
+			return false, nil
+		}
+		b, err := strconv.ParseBool(out)
+		if err != nil {
+			return nil, fmt.Errorf("template output %q is not a bool", out)
+		}
+		return b, nil
+	case "json":
+		if out == "" {
+			return nil, nil
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			return nil, fmt.Errorf("template output is not valid JSON: %w", err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported %s %q", templateTypeKey, typ)
+}
+
+func tplDefault(def interface{}, v interface{}) interface{} {
+	if isEmptyValue(v) {
+		return def
+	}
+	return indirectValue(v)
+}
+
+func tplDiv(a, b interface{}) (float64, error) {
+	d := toNumber(b)
+	if d == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return toNumber(a) / d, nil
+}
+
+func indirectValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+func isEmptyValue(v interface{}) bool {
+	v = indirectValue(v)
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	}
+	return false
+}
+
+func toNumber(v interface{}) float64 {
+	v = indirectValue(v)
+	if v == nil {
+		return 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+		return f
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1
+		}
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	v = indirectValue(v)
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func toStringSlice(v interface{}) []string {
+	v = indirectValue(v)
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		if s := toString(v); s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+	out := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		out = append(out, toString(rv.Index(i).Interface()))
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func templateTestRequest() UnifiedGenRequest {
+	width, height := 1024, 768
+	return UnifiedGenRequest{
+		RequestID:       "req_1",
+		Type:            RequestTypeImage,
+		Prompt:          "a cat",
+		Width:           &width,
+		Height:          &height,
+		Extra:           map[string]interface{}{"model": "x-1", "steps": 30},
+		ReferenceImages: []string{"https://s3/u1.png", "https://s3/u2.png"},
+		Task:            &TaskContext{ID: 7, NoteTarget: "note_1"},
+	}
+}
+
+func TestMapRequestTemplates(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "jsonpath", value: "$.prompt", want: "a cat"},
+		{name: "jsonpath extra", value: "$.extra.model", want: "x-1"},
+		{name: "jsonpath missing extra", value: "$.extra.missing", want: nil},
+		{name: "jsonpath nil pointer", value: "$.seed", want: nil},
+		{name: "legacy go field", value: "$.Prompt", want: "a cat"},
+		{name: "literal", value: "fixed", want: "fixed"},
+		{name: "literal number", value: 3.5, want: 3.5},
+		{name: "inline template", value: "{{ .Request.Type }}_generation", want: "image_generation"},
+		{name: "provider and task", value: "{{ upper .Provider.Name }}-{{ .Task.ID }}-{{ .Task.NoteTarget }}", want: "FAKE-7-note_1"},
+		{name: "size", value: map[string]interface{}{"$expr": "{{ size .Request.Width .Request.Height }}"}, want: "1024x768"},
+		{name: "join", value: map[string]interface{}{"$expr": `{{ join "," .Request.ReferenceImages }}`}, want: "https://s3/u1.png,https://s3/u2.png"},
+		{name: "coalesce", value: map[string]interface{}{"$expr": `{{ coalesce .Request.Style .Request.Ratio "1:1" }}`}, want: "1:1"},
+		{name: "int", value: map[string]interface{}{"$expr": "{{ div .Request.Width 2 }}", "$type": "int"}, want: int64(512)},
+		{name: "int rounds", value: map[string]interface{}{"$expr": "{{ mul .Request.Height 0.333 }}", "$type": "int"}, want: int64(256)},
+		{name: "int default", value: map[string]interface{}{"$expr": "{{ default 42 .Request.Seed }}", "$type": "int"}, want: int64(42)},
+		{name: "int nil", value: map[string]interface{}{"$expr": "{{ .Request.Seed }}", "$type": "int"}, want: nil},
+		{name: "float", value: map[string]interface{}{"$expr": "{{ add .Request.Width 0.5 }}", "$type": "float"}, want: 1024.5},
+		{name: "bool", value: map[string]interface{}{"$expr": "{{ not (empty .Request.Style) }}", "$type": "bool"}, want: false},
+		{name: "bool ternary", value: map[string]interface{}{"$expr": `{{ ternary (gt (len .Request.ReferenceImages) 0) "true" "false" }}`, "$type": "bool"}, want: true},
+		{name: "json", value: map[string]interface{}{"$expr": "{{ json .Request.Extra }}", "$type": "json"}, want: map[string]interface{}{"model": "x-1", "steps": float64(30)}},
+		{name: "string type", value: map[string]interface{}{"$expr": " {{ .Request.Prompt }} ", "$type": "string"}, want: "a cat"},
+		{
+			name:  "nested",
+			value: map[string]interface{}{"input": []interface{}{map[string]interface{}{"text": "$.prompt"}, "{{ lower \"RAW\" }}"}},
+			want:  map[string]interface{}{"input": []interface{}{map[string]interface{}{"text": "a cat"}, "raw"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMapper(&ProviderConfig{
+				ProviderName:   "fake",
+				RequestMapping: map[string]interface{}{"value": tt.value},
+			})
+			if err != nil {
+				t.Fatalf("NewMapper: %v", err)
+			}
+			body, err := m.MapRequest(templateTestRequest())
+			if err != nil {
+				t.Fatalf("MapRequest: %v", err)
+			}
+			if got := body["value"]; !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapRequestWithoutMappingUsesRequest(t *testing.T) {
+	m, err := NewMapper(&ProviderConfig{ProviderName: "fake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := m.MapRequest(templateTestRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["prompt"] != "a cat" || body["width"] != float64(1024) {
+		t.Fatalf("unexpected body %v", body)
+	}
+	if _, ok := body["Task"]; ok {
+		t.Fatal("task context leaked into the default request body")
+	}
+}
+
+func TestTemplateCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping map[string]interface{}
+		want    string
+	}{
+		{name: "syntax", mapping: map[string]interface{}{"a": "{{ .Request.Prompt "}, want: "request_mapping.a"},
+		{name: "nested syntax", mapping: map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "{{ end }}"}}}, want: "request_mapping.a[0].b"},
+		{name: "unknown function", mapping: map[string]interface{}{"a": map[string]interface{}{"$expr": "{{ nope 1 }}"}}, want: "invalid template"},
+		{name: "expr not string", mapping: map[string]interface{}{"a": map[string]interface{}{"$expr": 1}}, want: "$expr"},
+		{name: "type not string", mapping: map[string]interface{}{"a": map[string]interface{}{"$expr": "{{ 1 }}", "$type": true}}, want: "$type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMapper(&ProviderConfig{ProviderName: "fake", RequestMapping: tt.mapping})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateRenderErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value map[string]interface{}
+		want  string
+	}{
+		{name: "not an int", value: map[string]interface{}{"$expr": "{{ .Request.Prompt }}", "$type": "int"}, want: "not an int"},
+		{name: "not a number", value: map[string]interface{}{"$expr": "{{ .Request.Prompt }}", "$type": "float"}, want: "not a number"},
+		{name: "not a bool", value: map[string]interface{}{"$expr": "{{ .Request.Prompt }}", "$type": "bool"}, want: "not a bool"},
+		{name: "not json", value: map[string]interface{}{"$expr": "{{ .Request.Prompt }}", "$type": "json"}, want: "not valid JSON"},
+		{name: "unsupported type", value: map[string]interface{}{"$expr": "{{ 1 }}", "$type": "date"}, want: "unsupported $type"},
+		{name: "division by zero", value: map[string]interface{}{"$expr": "{{ div .Request.Width 0 }}"}, want: "division by zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMapper(&ProviderConfig{ProviderName: "fake", RequestMapping: map[string]interface{}{"value": tt.value}})
+			if err != nil {
+				t.Fatalf("NewMapper: %v", err)
+			}
+			_, err = m.MapRequest(templateTestRequest())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want error containing %q", err, tt.want)
+			}
+			if !strings.Contains(err.Error(), "value") {
+				t.Fatalf("error does not name the mapping key: %v", err)
+			}
+		})
+	}
+}
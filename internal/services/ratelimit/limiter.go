@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireSlotScript 在有序集合中登记持有者，score 为租约到期时间（毫秒）。
+// 已过期的租约会先被清理，已持有的持有者直接续约。
+var acquireSlotScript = redis.NewScript(`
+local key = KEYS[1]
+local max = tonumber(ARGV[1])
+local holder = ARGV[2]
+local ttl = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+if redis.call('ZSCORE', key, holder) then
+	redis.call('ZADD', key, now + ttl, holder)
+	redis.call('PEXPIRE', key, ttl)
+	return 1
+end
+if redis.call('ZCARD', key) >= max then
+	return 0
+end
+redis.call('ZADD', key, now + ttl, holder)
+redis.call('PEXPIRE', key, ttl)
+return 1
+`)
+
+// refreshSlotScript 仅为已存在的持有者续约
+var refreshSlotScript = redis.NewScript(`
+local key = KEYS[1]
+local holder = ARGV[1]
+local ttl = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+if not redis.call('ZSCORE', key, holder) then
+	return 0
+end
+redis.call('ZADD', key, now + ttl, holder)
+redis.call('PEXPIRE', key, ttl)
+return 1
+`)
+
+// rateScript 实现 GCRA 限速，返回需要等待的毫秒数（0 表示放行）
+var rateScript = redis.NewScript(`
+local key = KEYS[1]
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local tat = tonumber(redis.call('GET', key) or '0')
+if tat < now then
+	tat = now
+end
+local newTat = tat + interval
+local wait = newTat - now - interval * burst
+if wait > 0 then
+	return wait
+end
+redis.call('SET', key, newTat, 'PX', math.ceil(newTat - now))
+return 0
+`)
+
+// Limiter 基于 Redis 实现集群范围的并发槽位与速率限制
+type Limiter struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func New(rdb *redis.Client, prefix string) *Limiter {
+	if prefix == "" {
+		prefix = "ratelimit"
+	}
+	return &Limiter{
+		rdb:    rdb,
+		prefix: prefix,
+	}
+}
+
+func (l *Limiter) slotKey(name string) string {
+	return fmt.Sprintf("%s:slots:%s", l.prefix, name)
+}
+
+func (l *Limiter) rateKey(name string) string {
+	return fmt.Sprintf("%s:rate:%s", l.prefix, name)
+}
+
+// AcquireSlot 尝试为 holder 占用 name 下的一个并发槽位，max 为槽位上限。
+// 槽位以租约形式持有，超过 ttl 未续约会自动释放，防止进程崩溃导致泄漏。
+func (l *Limiter) AcquireSlot(ctx context.Context, name string, max int, holder string, ttl time.Duration) (bool, error) {
+	if max <= 0 {
+		return true, nil
+	}
+	res, err := acquireSlotScript.Run(ctx, l.rdb, []string{l.slotKey(name)}, max, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire slot: %w", err)
+	}
+	return res == 1, nil
+}
+
+// RefreshSlot 为已持有的槽位续约，返回槽位是否仍被持有
+func (l *Limiter) RefreshSlot(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	res, err := refreshSlotScript.Run(ctx, l.rdb, []string{l.slotKey(name)}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to refresh slot: %w", err)
+	}
+	return res == 1, nil
+}
+
+func (l *Limiter) ReleaseSlot(ctx context.Context, name string, holder string) error {
+	if err := l.rdb.ZRem(ctx, l.slotKey(name), holder).Err(); err != nil {
+		return fmt.Errorf("failed to release slot: %w", err)
+	}
+	return nil
+}
+
+// InUse 返回 name 下当前未过期的槽位数
+func (l *Limiter) InUse(ctx context.Context, name string) (int64, error) {
+	now := time.Now().UnixMilli()
+	return l.rdb.ZCount(ctx, l.slotKey(name), fmt.Sprintf("(%d", now), "+inf").Result()
+}
+
+// Allow 按每秒 qps 次的速率放行请求，允许 ceil(qps) 次突发。
+// 被拒绝时返回需要等待的时长。
+func (l *Limiter) Allow(ctx context.Context, name string, qps float64) (bool, time.Duration, error) {
+	if qps <= 0 {
+		return true, 0, nil
+	}
+	interval := int64(math.Ceil(1000 / qps))
+	burst := int64(math.Max(1, math.Ceil(qps)))
+
+	wait, err := rateScript.Run(ctx, l.rdb, []string{l.rateKey(name)}, interval, burst).Int64()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check rate: %w", err)
+	}
+	if wait > 0 {
+		return false, time.Duration(wait) * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
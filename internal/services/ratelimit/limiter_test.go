@@ -0,0 +1,223 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestLimiter(t *testing.T) (*Limiter, *miniredis.Miniredis) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return New(rdb, "test"), m
+}
+
+func TestAllow(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		allowed bool
+		wait    time.Duration
+	}
+	tests := []struct {
+		name  string
+		qps   float64
+		steps []step
+	}{
+		{
+			name: "unlimited",
+			qps:  0,
+			steps: []step{
+				{allowed: true}, {allowed: true}, {allowed: true},
+			},
+		},
+		{
+			name: "burst then refill",
+			qps:  2,
+			steps: []step{
+				{allowed: true},
+				{allowed: true},
+				{allowed: false, wait: 500 * time.Millisecond},
+				{advance: 499 * time.Millisecond, allowed: false, wait: time.Millisecond},
+				{advance: time.Millisecond, allowed: true},
+				{allowed: false, wait: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "fractional qps",
+			qps:  0.5,
+			steps: []step{
+				{allowed: true},
+				{allowed: false, wait: 2 * time.Second},
+				{advance: time.Second, allowed: false, wait: time.Second},
+				{advance: time.Second, allowed: true},
+			},
+		},
+		{
+			name: "idle time does not bank more than the burst",
+			qps:  2,
+			steps: []step{
+				{allowed: true},
+				{advance: time.Minute, allowed: true},
+				{allowed: true},
+				{allowed: false, wait: 500 * time.Millisecond},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, m := newTestLimiter(t)
+			now := time.Now()
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				m.SetTime(now)
+				allowed, wait, err := l.Allow(context.Background(), "p", tt.qps)
+				if err != nil {
+					t.Fatalf("step %d: Allow: %v", i, err)
+				}
+				if allowed != s.allowed || wait != s.wait {
+					t.Fatalf("step %d: got allowed=%v wait=%s, want allowed=%v wait=%s", i, allowed, wait, s.allowed, s.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowIsPerName(t *testing.T) {
+	l, m := newTestLimiter(t)
+	m.SetTime(time.Now())
+	ctx := context.Background()
+
+	if allowed, _, _ := l.Allow(ctx, "a", 1); !allowed {
+		t.Fatal("first call for a denied")
+	}
+	if allowed, _, _ := l.Allow(ctx, "a", 1); allowed {
+		t.Fatal("second call for a allowed")
+	}
+	if allowed, _, _ := l.Allow(ctx, "b", 1); !allowed {
+		t.Fatal("b is limited by a")
+	}
+}
+
+func TestSlots(t *testing.T) {
+	type step struct {
+		op      string // acquire / refresh / release
+		holder  string
+		advance time.Duration
+		want    bool
+	}
+	tests := []struct {
+		name  string
+		max   int
+		steps []step
+		inUse int64
+	}{
+		{
+			name: "cap",
+			max:  2,
+			steps: []step{
+				{op: "acquire", holder: "a", want: true},
+				{op: "acquire", holder: "b", want: true},
+				{op: "acquire", holder: "c", want: false},
+			},
+			inUse: 2,
+		},
+		{
+			name: "holder reacquires without taking another slot",
+			max:  2,
+			steps: []step{
+				{op: "acquire", holder: "a", want: true},
+				{op: "acquire", holder: "a", want: true},
+				{op: "acquire", holder: "b", want: true},
+				{op: "acquire", holder: "c", want: false},
+			},
+			inUse: 2,
+		},
+		{
+			name: "release frees a slot",
+			max:  1,
+			steps: []step{
+				{op: "acquire", holder: "a", want: true},
+				{op: "acquire", holder: "b", want: false},
+				{op: "release", holder: "a"},
+				{op: "acquire", holder: "b", want: true},
+			},
+			inUse: 1,
+		},
+		{
+			name: "stale slot is reclaimed",
+			max:  1,
+			steps: []step{
+				{op: "acquire", holder: "a", want: true},
+				{op: "acquire", holder: "b", advance: 59 * time.Second, want: false},
+				{op: "acquire", holder: "b", advance: time.Second, want: true},
+				{op: "refresh", holder: "a", want: false},
+			},
+			inUse: 1,
+		},
+		{
+			name: "refresh extends the lease",
+			max:  1,
+			steps: []step{
+				{op: "acquire", holder: "a", want: true},
+				{op: "refresh", holder: "a", advance: 50 * time.Second, want: true},
+				{op: "acquire", holder: "b", advance: 50 * time.Second, want: false},
+				{op: "acquire", holder: "b", advance: 10 * time.Second, want: true},
+			},
+			inUse: 1,
+		},
+		{
+			name: "unlimited",
+			max:  0,
+			steps: []step{
+				{op: "acquire", holder: "a", want: true},
+				{op: "acquire", holder: "b", want: true},
+			},
+			inUse: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, m := newTestLimiter(t)
+			ctx := context.Background()
+			now := time.Now()
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				m.SetTime(now)
+
+				var got bool
+				var err error
+				switch s.op {
+				case "acquire":
+					got, err = l.AcquireSlot(ctx, "p", tt.max, s.holder, time.Minute)
+				case "refresh":
+					got, err = l.RefreshSlot(ctx, "p", s.holder, time.Minute)
+				case "release":
+					err = l.ReleaseSlot(ctx, "p", s.holder)
+					got = s.want
+				}
+				if err != nil {
+					t.Fatalf("step %d: %s: %v", i, s.op, err)
+				}
+				if got != s.want {
+					t.Fatalf("step %d: %s %s = %v, want %v", i, s.op, s.holder, got, s.want)
+				}
+			}
+
+			// InUse 按本机时间统计，最后占用的槽位租约都还未到期
+			inUse, err := l.InUse(ctx, "p")
+			if err != nil {
+				t.Fatalf("InUse: %v", err)
+			}
+			if inUse != tt.inUse {
+				t.Fatalf("InUse = %d, want %d", inUse, tt.inUse)
+			}
+		})
+	}
+}
@@ -3,13 +3,26 @@ package xhsconnector
 import (
 	"context"
 	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
+// Comment 为平台评论。UserID 为平台用户的稳定 ID，UserName 为可能变化、重名的昵称；
+// 楼中楼回复的 ParentCommentID 为被回复的评论，Depth 为回复层级（一级评论为 0），
+// SubCommentCount 为一级评论下的回复数。
 type Comment struct {
 	CommentID        string    `json:"comment_id"`
+	UserID           string    `json:"user_id,omitempty"`
 	UserName         string    `json:"user_name"`
+	UserAvatar       string    `json:"user_avatar,omitempty"`
 	Content          string    `json:"content"`
 	CommentCreatedAt time.Time `json:"comment_created_at"`
+	// ImageURLs 为评论附带的图片地址，可能带防盗链或过期，需要转存后再使用
+	ImageURLs       []string `json:"image_urls,omitempty"`
+	ParentCommentID string   `json:"parent_comment_id,omitempty"`
+	Depth           int      `json:"depth,omitempty"`
+	SubCommentCount int      `json:"sub_comment_count,omitempty"`
 }
 
 type ListCommentsResult struct {
@@ -18,8 +31,39 @@ type ListCommentsResult struct {
 	HasMore    bool      `json:"has_more"`
 }
 
+// UserNote 为账号发布的笔记，NoteURL 为空时使用 NoteID 拉取评论
+type UserNote struct {
+	NoteID      string    `json:"note_id"`
+	NoteURL     string    `json:"note_url,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+type ListUserNotesResult struct {
+	Notes      []UserNote `json:"notes"`
+	NextCursor string     `json:"next_cursor"`
+	HasMore    bool       `json:"has_more"`
+}
+
+type ReplyCommentResult struct {
+	ReplyID string `json:"reply_id"`
+}
+
 type Connector interface {
 	ListComments(ctx context.Context, noteIDOrURL string, cursor string) (*ListCommentsResult, error)
+	// ListSubComments 列出一级评论下的全部回复（含回复的回复），每个一级评论使用独立的游标
+	ListSubComments(ctx context.Context, noteIDOrURL string, rootCommentID string, cursor string) (*ListCommentsResult, error)
+	// ListUserNotes 按发布时间倒序列出账号的笔记
+	ListUserNotes(ctx context.Context, userIDOrURL string, cursor string) (*ListUserNotesResult, error)
+	// ReplyComment 在笔记下回复指定评论，commentID 为平台评论 ID
+	ReplyComment(ctx context.Context, noteIDOrURL string, commentID string, content string) (*ReplyCommentResult, error)
+	// Close 释放连接，stdio 模式下会停止 MCP 服务端子进程
+	Close() error
+}
+
+// SelfIdentifier 为能返回当前登录账号平台用户 ID 的连接器，用于识别本账号发出的回复
+type SelfIdentifier interface {
+	SelfUserID() string
 }
 
 type ConnectorConfig struct {
@@ -27,11 +71,50 @@ type ConnectorConfig struct {
 	MCPServerCmd *string
 	MCPServerURL *string
 	MCPAuth      *string
+	// Logger 用于记录 MCP 子进程的启停与 stderr 输出，为 nil 时不记录
+	Logger *zap.Logger
+	// MockScenarioFile 为 Mock 模式回放的场景文件，MockSpeed 大于 0 时覆盖场景倍速
+	MockScenarioFile string
+	MockSpeed        float64
+	// RDB 用于在进程间共享 Mock 模式注入的评论与场景时钟，以及 replay 模式的回放进度
+	RDB *redis.Client
+	// RecordDir 非空时把 ListComments 的请求与脱敏后的响应录制到该目录；ReplayDir 为 replay 模式回放的录制目录
+	RecordDir string
+	ReplayDir string
 }
 
 func NewConnector(cfg *ConnectorConfig) (Connector, error) {
+	if cfg.Mode == "replay" {
+		return NewReplayConnector(cfg.ReplayDir, cfg.RDB)
+	}
+
+	connector, err := newLiveConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RecordDir == "" {
+		return connector, nil
+	}
+	recorder, err := NewRecordingConnector(connector, cfg.RecordDir)
+	if err != nil {
+		connector.Close()
+		return nil, err
+	}
+	return recorder, nil
+}
+
+func newLiveConnector(cfg *ConnectorConfig) (Connector, error) {
 	if cfg.Mode == "mcp" {
 		return NewMCPConnector(cfg)
 	}
-	return NewMockConnector(), nil
+
+	opts := MockOptions{RDB: cfg.RDB, Speed: cfg.MockSpeed}
+	if cfg.MockScenarioFile != "" {
+		scenario, err := LoadScenario(cfg.MockScenarioFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.Scenario = scenario
+	}
+	return NewMockConnectorWithOptions(opts), nil
 }
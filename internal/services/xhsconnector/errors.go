@@ -0,0 +1,127 @@
+package xhsconnector
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported 表示连接器不支持该操作（如 MCP 服务端未提供可选工具）
+var ErrUnsupported = errors.New("operation not supported by connector")
+
+// ErrorKind 为平台侧失败的类型，轮询时按类型分别处理
+type ErrorKind string
+
+const (
+	ErrorKindLoginExpired ErrorKind = "login_expired"
+	ErrorKindCaptcha      ErrorKind = "captcha"
+	ErrorKindRateLimited  ErrorKind = "rate_limited"
+	ErrorKindNoteNotFound ErrorKind = "note_not_found"
+)
+
+// ConnectorError 为带类型的平台错误，errors.Is 按类型与 ErrLoginExpired 等哨兵错误匹配。
+// RetryAfter 为平台建议的重试间隔，0 表示未知。
+type ConnectorError struct {
+	Kind       ErrorKind
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *ConnectorError) Error() string {
+	if e.Message == "" {
+		return string(e.Kind)
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Message)
+}
+
+func (e *ConnectorError) Is(target error) bool {
+	t, ok := target.(*ConnectorError)
+	return ok && t.Kind == e.Kind
+}
+
+var (
+	ErrLoginExpired = &ConnectorError{Kind: ErrorKindLoginExpired, Message: "login session expired"}
+	ErrCaptcha      = &ConnectorError{Kind: ErrorKindCaptcha, Message: "captcha verification required"}
+	ErrRateLimited  = &ConnectorError{Kind: ErrorKindRateLimited, Message: "rate limited"}
+	ErrNoteNotFound = &ConnectorError{Kind: ErrorKindNoteNotFound, Message: "note not found or deleted"}
+)
+
+// KindOf 返回错误的类型，非平台错误返回空字符串
+func KindOf(err error) ErrorKind {
+	var ce *ConnectorError
+	if errors.As(err, &ce) {
+		return ce.Kind
+	}
+	return ""
+}
+
+// RetryAfterOf 返回平台建议的重试间隔，未给出时为 0
+func RetryAfterOf(err error) time.Duration {
+	var ce *ConnectorError
+	if errors.As(err, &ce) {
+		return ce.RetryAfter
+	}
+	return 0
+}
+
+// toolErrorPayload 为工具返回 isError 时可选的结构化错误
+type toolErrorPayload struct {
+	ErrorCode     string `json:"error_code"`
+	Message       string `json:"message"`
+	RetryAfterSec int    `json:"retry_after_sec"`
+}
+
+// errorKindKeywords 用于识别未返回 error_code 的工具错误，按顺序匹配
+var errorKindKeywords = []struct {
+	kind     ErrorKind
+	keywords []string
+}{
+	{ErrorKindLoginExpired, []string{"login expired", "session expired", "not logged in", "登录已过期", "登录失效", "未登录", "请先登录"}},
+	{ErrorKindCaptcha, []string{"captcha", "verification required", "验证码", "滑块", "安全验证"}},
+	{ErrorKindRateLimited, []string{"rate limit", "too many requests", "访问频繁", "操作频繁", "请求过于频繁"}},
+	{ErrorKindNoteNotFound, []string{"note not found", "note deleted", "笔记不存在", "笔记已删除", "已被删除"}},
+}
+
+func knownErrorKind(kind ErrorKind) bool {
+	for _, k := range errorKindKeywords {
+		if k.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyToolError 将工具返回的错误结果转换为 ConnectorError：优先读取结构化的 error_code，
+// 其次按错误文本中的关键字识别，都无法识别时返回普通错误
+func classifyToolError(tool string, result *MCPToolResult) error {
+	msg := result.Text()
+	if msg == "" {
+		msg = "unknown error"
+	}
+
+	var payload toolErrorPayload
+	if result.Decode(&payload) == nil && payload.ErrorCode != "" {
+		kind := ErrorKind(strings.ToLower(payload.ErrorCode))
+		if knownErrorKind(kind) {
+			if payload.Message != "" {
+				msg = payload.Message
+			}
+			return &ConnectorError{
+				Kind:       kind,
+				Message:    fmt.Sprintf("tool %s failed: %s", tool, msg),
+				RetryAfter: time.Duration(payload.RetryAfterSec) * time.Second,
+			}
+		}
+	}
+
+	lower := strings.ToLower(msg)
+	for _, k := range errorKindKeywords {
+		for _, keyword := range k.keywords {
+			if strings.Contains(lower, keyword) {
+				return &ConnectorError{Kind: k.kind, Message: fmt.Sprintf("tool %s failed: %s", tool, msg)}
+			}
+		}
+	}
+	return fmt.Errorf("tool %s failed: %s", tool, msg)
+}
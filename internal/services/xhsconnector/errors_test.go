@@ -0,0 +1,95 @@
+package xhsconnector
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClassifyToolError(t *testing.T) {
+	text := func(s string) *MCPToolResult {
+		return &MCPToolResult{IsError: true, Content: []MCPContent{{Type: "text", Text: s}}}
+	}
+	structured := func(v interface{}, s string) *MCPToolResult {
+		raw, _ := json.Marshal(v)
+		result := text(s)
+		result.StructuredContent = raw
+		return result
+	}
+
+	tests := []struct {
+		name           string
+		result         *MCPToolResult
+		wantKind       ErrorKind
+		wantMsg        string
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:           "structured error code",
+			result:         structured(map[string]interface{}{"error_code": "RATE_LIMITED", "message": "slow down", "retry_after_sec": 90}, "busy"),
+			wantKind:       ErrorKindRateLimited,
+			wantMsg:        "rate_limited: tool get_feed_detail failed: slow down",
+			wantRetryAfter: 90 * time.Second,
+		},
+		{
+			name:     "structured code without message keeps text",
+			result:   structured(map[string]interface{}{"error_code": "note_not_found"}, "gone"),
+			wantKind: ErrorKindNoteNotFound,
+			wantMsg:  "note_not_found: tool get_feed_detail failed: gone",
+		},
+		{
+			name:     "unknown code falls back to keywords",
+			result:   structured(map[string]interface{}{"error_code": "E1001"}, "请先登录后再试"),
+			wantKind: ErrorKindLoginExpired,
+			wantMsg:  "login_expired: tool get_feed_detail failed: 请先登录后再试",
+		},
+		{
+			name:     "english keyword is case insensitive",
+			result:   text("Session Expired, please scan the QR code"),
+			wantKind: ErrorKindLoginExpired,
+		},
+		{name: "captcha keyword", result: text("触发滑块验证"), wantKind: ErrorKindCaptcha},
+		{name: "rate limit keyword", result: text("HTTP 429 Too Many Requests"), wantKind: ErrorKindRateLimited},
+		{name: "chinese rate limit keyword", result: text("访问频繁，请稍后再试"), wantKind: ErrorKindRateLimited},
+		{name: "deleted note keyword", result: text("该笔记已被删除"), wantKind: ErrorKindNoteNotFound},
+		{
+			name:    "unrecognised error",
+			result:  text("internal server error"),
+			wantMsg: "tool get_feed_detail failed: internal server error",
+		},
+		{
+			name:    "empty result",
+			result:  &MCPToolResult{IsError: true},
+			wantMsg: "tool get_feed_detail failed: unknown error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyToolError("get_feed_detail", tt.result)
+			if got := KindOf(err); got != tt.wantKind {
+				t.Fatalf("KindOf(%v) = %q, want %q", err, got, tt.wantKind)
+			}
+			if tt.wantMsg != "" && err.Error() != tt.wantMsg {
+				t.Fatalf("error = %q, want %q", err.Error(), tt.wantMsg)
+			}
+			if got := RetryAfterOf(err); got != tt.wantRetryAfter {
+				t.Fatalf("RetryAfterOf = %s, want %s", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestConnectorErrorIs(t *testing.T) {
+	err := &ConnectorError{Kind: ErrorKindCaptcha, Message: "tool x failed: 验证码"}
+	if !errors.Is(err, ErrCaptcha) {
+		t.Fatal("captcha error does not match ErrCaptcha")
+	}
+	if errors.Is(err, ErrRateLimited) {
+		t.Fatal("captcha error matches ErrRateLimited")
+	}
+	if KindOf(errors.New("plain")) != "" {
+		t.Fatal("plain error has a kind")
+	}
+}
@@ -1,141 +1,161 @@
 package xhsconnector
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"time"
-)
-
-type MCPConnector struct {
-	serverURL string
-	auth      *string
-	client    *http.Client
-}
-
-type MCPRequest struct {
-	JSONRPC string        `json:"jsonrpc"`
-	ID      int           `json:"id"`
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params,omitempty"`
-}
+	"strings"
 
-type MCPResponse struct {
-	JSONRPC string          `json:"jsonrpc"`
-	ID      int             `json:"id"`
-	Result  json.RawMessage `json:"result,omitempty"`
-	Error   *MCPError       `json:"error,omitempty"`
-}
+	"go.uber.org/zap"
+)
 
-type MCPError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
+const (
+	toolListComments    = "xhs_list_comments"
+	toolReplyComment    = "xhs_reply_comment"
+	toolListUserNotes   = "xhs_list_user_notes"
+	toolListSubComments = "xhs_list_sub_comments"
+)
 
-type MCPToolCallParams struct {
-	Name      string                 `json:"name"`
-	Arguments map[string]interface{} `json:"arguments"`
+// MCPConnector 通过 MCP 服务端的 xhs_list_comments 工具拉取评论，可选的 xhs_list_sub_comments、
+// xhs_reply_comment、xhs_list_user_notes 工具用于拉取楼中楼回复、回复评论与发现账号笔记
+type MCPConnector struct {
+	client *mcpClient
 }
 
+// NewMCPConnector 配置了 MCPServerCmd 时以子进程方式运行 MCP 服务端并通过 stdio 通信，
+// 否则连接 MCPServerURL
 func NewMCPConnector(cfg *ConnectorConfig) (*MCPConnector, error) {
+	if cfg.MCPServerCmd != nil && strings.TrimSpace(*cfg.MCPServerCmd) != "" {
+		args, err := splitCommand(*cfg.MCPServerCmd)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MCP server command: %w", err)
+		}
+		logger := cfg.Logger
+		if logger == nil {
+			logger = zap.NewNop()
+		}
+		return &MCPConnector{
+			client: newStdioMCPClient(args, logger.Named("mcp"), []string{toolListComments}),
+		}, nil
+	}
+
 	if cfg.MCPServerURL == nil || *cfg.MCPServerURL == "" {
-		return nil, fmt.Errorf("MCP server URL is required for MCP mode")
+		return nil, fmt.Errorf("MCP server command or URL is required for MCP mode")
 	}
 
 	return &MCPConnector{
-		serverURL: *cfg.MCPServerURL,
-		auth:      cfg.MCPAuth,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client: newMCPClient(*cfg.MCPServerURL, cfg.MCPAuth, []string{toolListComments}),
 	}, nil
 }
 
-func (m *MCPConnector) callTool(ctx context.Context, toolName string, args map[string]interface{}) (json.RawMessage, error) {
-	params := MCPToolCallParams{
-		Name:      toolName,
-		Arguments: args,
+func (m *MCPConnector) ListComments(ctx context.Context, noteIDOrURL string, cursor string) (*ListCommentsResult, error) {
+	args := map[string]interface{}{
+		"note_id_or_url": noteIDOrURL,
 	}
 
-	req := MCPRequest{
-		JSONRPC: "2.0",
-		ID:      1,
-		Method:  "tools/call",
-		Params:  []interface{}{params},
+	if cursor != "" {
+		args["cursor"] = cursor
 	}
 
-	reqBody, err := json.Marshal(req)
+	result, err := m.client.CallTool(ctx, toolListComments, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal MCP request: %w", err)
+		return nil, fmt.Errorf("failed to call %s: %w", toolListComments, err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", m.serverURL, bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	var commentsResult ListCommentsResult
+	if err := result.Decode(&commentsResult); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal comments result: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	if m.auth != nil && *m.auth != "" {
-		httpReq.Header.Set("Authorization", *m.auth)
-	}
+	return &commentsResult, nil
+}
 
-	resp, err := m.client.Do(httpReq)
+// requireTool 检查可选工具是否可用，服务端未提供时返回 ErrUnsupported
+func (m *MCPConnector) requireTool(ctx context.Context, name string) error {
+	ok, err := m.client.HasTool(ctx, name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call MCP server: %w", err)
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: MCP server does not provide tool %s", ErrUnsupported, name)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+// ListSubComments 调用 xhs_list_sub_comments；该工具为可选工具，服务端未提供时返回 ErrUnsupported
+func (m *MCPConnector) ListSubComments(ctx context.Context, noteIDOrURL string, rootCommentID string, cursor string) (*ListCommentsResult, error) {
+	if err := m.requireTool(ctx, toolListSubComments); err != nil {
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("MCP server returned status %d: %s", resp.StatusCode, string(body))
+	args := map[string]interface{}{
+		"note_id_or_url": noteIDOrURL,
+		"comment_id":     rootCommentID,
+	}
+	if cursor != "" {
+		args["cursor"] = cursor
 	}
 
-	var mcpResp MCPResponse
-	if err := json.Unmarshal(body, &mcpResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal MCP response: %w", err)
+	result, err := m.client.CallTool(ctx, toolListSubComments, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", toolListSubComments, err)
 	}
 
-	if mcpResp.Error != nil {
-		return nil, fmt.Errorf("MCP error: %s", mcpResp.Error.Message)
+	var commentsResult ListCommentsResult
+	if err := result.Decode(&commentsResult); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sub comments result: %w", err)
 	}
 
-	return mcpResp.Result, nil
+	return &commentsResult, nil
 }
 
-func (m *MCPConnector) ListComments(ctx context.Context, noteIDOrURL string, cursor string) (*ListCommentsResult, error) {
-	args := map[string]interface{}{
-		"note_id_or_url": noteIDOrURL,
+// ListUserNotes 调用 xhs_list_user_notes；该工具为可选工具，服务端未提供时返回 ErrUnsupported
+func (m *MCPConnector) ListUserNotes(ctx context.Context, userIDOrURL string, cursor string) (*ListUserNotesResult, error) {
+	if err := m.requireTool(ctx, toolListUserNotes); err != nil {
+		return nil, err
 	}
 
+	args := map[string]interface{}{
+		"user_id_or_url": userIDOrURL,
+	}
 	if cursor != "" {
 		args["cursor"] = cursor
 	}
 
-	result, err := m.callTool(ctx, "xhs_list_comments", args)
+	result, err := m.client.CallTool(ctx, toolListUserNotes, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call xhs_list_comments: %w", err)
+		return nil, fmt.Errorf("failed to call %s: %w", toolListUserNotes, err)
 	}
 
-	var commentsResult struct {
-		Comments   []Comment `json:"comments"`
-		NextCursor string    `json:"next_cursor"`
-		HasMore    bool      `json:"has_more"`
+	var notesResult ListUserNotesResult
+	if err := result.Decode(&notesResult); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user notes result: %w", err)
 	}
 
-	if err := json.Unmarshal(result, &commentsResult); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal comments result: %w", err)
+	return &notesResult, nil
+}
+
+// ReplyComment 调用 xhs_reply_comment；该工具为可选工具，服务端未提供时返回 ErrUnsupported
+func (m *MCPConnector) ReplyComment(ctx context.Context, noteIDOrURL string, commentID string, content string) (*ReplyCommentResult, error) {
+	if err := m.requireTool(ctx, toolReplyComment); err != nil {
+		return nil, err
 	}
 
-	return &ListCommentsResult{
-		Comments:   commentsResult.Comments,
-		NextCursor: commentsResult.NextCursor,
-		HasMore:    commentsResult.HasMore,
-	}, nil
+	result, err := m.client.CallTool(ctx, toolReplyComment, map[string]interface{}{
+		"note_id_or_url": noteIDOrURL,
+		"comment_id":     commentID,
+		"content":        content,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", toolReplyComment, err)
+	}
+
+	// 回复 ID 为可选返回值，工具只返回文本时视为成功
+	var replyResult ReplyCommentResult
+	result.Decode(&replyResult)
+	return &replyResult, nil
+}
+
+// Close 结束 MCP 会话，stdio 模式下停止子进程
+func (m *MCPConnector) Close() error {
+	return m.client.Close()
 }
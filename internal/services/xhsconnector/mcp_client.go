@@ -0,0 +1,449 @@
+package xhsconnector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// mcpProtocolVersion 为客户端请求的协议版本，服务端可协商为 mcpSupportedVersions 中的任一版本
+	mcpProtocolVersion = "2025-06-18"
+	mcpClientName      = "xiaohongshu-image"
+	mcpClientVersion   = "1.0.0"
+	mcpRequestTimeout  = 30 * time.Second
+
+	mcpMethodNotFound = -32601
+)
+
+var mcpSupportedVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+type MCPRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// MCPResponse 为收到的消息：对请求的响应，或服务端发起的请求/通知（Method 非空）
+type MCPResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
+}
+
+type MCPError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *MCPError) Error() string {
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
+}
+
+// mcpReply 为对服务端请求的响应
+type mcpReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
+}
+
+type MCPToolCallParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+type MCPImplementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type mcpInitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      MCPImplementation      `json:"serverInfo"`
+}
+
+type MCPTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+type mcpListToolsResult struct {
+	Tools      []MCPTool `json:"tools"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+// MCPContent 为工具结果中的内容块，type 为 text、image、audio、resource 或 resource_link
+type MCPContent struct {
+	Type     string       `json:"type"`
+	Text     string       `json:"text,omitempty"`
+	Data     string       `json:"data,omitempty"`
+	MimeType string       `json:"mimeType,omitempty"`
+	URI      string       `json:"uri,omitempty"`
+	Resource *MCPResource `json:"resource,omitempty"`
+}
+
+type MCPResource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+type MCPToolResult struct {
+	Content           []MCPContent    `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// Text 拼接所有文本内容块
+func (r *MCPToolResult) Text() string {
+	var parts []string
+	for _, c := range r.Content {
+		switch {
+		case c.Type == "text" && c.Text != "":
+			parts = append(parts, c.Text)
+		case c.Type == "resource" && c.Resource != nil && c.Resource.Text != "":
+			parts = append(parts, c.Resource.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Decode 将工具结果解码到 v：优先使用 structuredContent，
+// 其次是第一个内容为 JSON 的 text 或 resource 内容块
+func (r *MCPToolResult) Decode(v interface{}) error {
+	if len(r.StructuredContent) > 0 && string(r.StructuredContent) != "null" {
+		return json.Unmarshal(r.StructuredContent, v)
+	}
+
+	var lastErr error
+	for _, c := range r.Content {
+		text := c.Text
+		if c.Type == "resource" && c.Resource != nil {
+			text = c.Resource.Text
+		} else if c.Type != "text" {
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(text), v); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	if lastErr != nil {
+		return fmt.Errorf("no JSON content in tool result: %w", lastErr)
+	}
+	return fmt.Errorf("tool result has no content")
+}
+
+func mcpIDKey(id interface{}) string {
+	switch v := id.(type) {
+	case *int64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	case json.RawMessage:
+		return strings.Trim(string(v), `"`)
+	}
+	return ""
+}
+
+// mcpClient 按 MCP 规范与服务端通信：首次调用时完成 initialize / initialized 握手与 tools/list 发现，
+// 会话失效时自动重新握手。优先使用 Streamable HTTP，服务端不支持时回退到旧版 HTTP+SSE；
+// 设置 stdio 时改为与本地子进程通信。
+type mcpClient struct {
+	serverURL     string
+	auth          *string
+	httpClient    *http.Client
+	stdio         *stdioSupervisor
+	requiredTools []string
+	nextID        int64
+
+	mu        sync.Mutex
+	transport mcpTransport
+	server    MCPImplementation
+	tools     map[string]MCPTool
+}
+
+func newMCPClient(serverURL string, auth *string, requiredTools []string) *mcpClient {
+	return &mcpClient{
+		serverURL: serverURL,
+		auth:      auth,
+		// 超时由每次请求的 context 控制，SSE 事件流需要长连接
+		httpClient:    &http.Client{},
+		requiredTools: requiredTools,
+	}
+}
+
+// newStdioMCPClient 创建通过子进程 stdin/stdout 通信的客户端，子进程在首次调用时启动
+func newStdioMCPClient(args []string, logger *zap.Logger, requiredTools []string) *mcpClient {
+	c := &mcpClient{requiredTools: requiredTools}
+	c.stdio = newStdioSupervisor(args, logger, c.handleServerRequest)
+	return c
+}
+
+func (c *mcpClient) newRequest(method string, params interface{}) *MCPRequest {
+	id := atomic.AddInt64(&c.nextID, 1)
+	return &MCPRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params}
+}
+
+func (c *mcpClient) newNotification(method string) *MCPRequest {
+	return &MCPRequest{JSONRPC: "2.0", Method: method}
+}
+
+// handleServerRequest 响应服务端发起的请求，目前只支持 ping
+func (c *mcpClient) handleServerRequest(t mcpTransport, msg *MCPResponse) {
+	reply := &mcpReply{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		reply.Result = struct{}{}
+	} else {
+		reply.Error = &MCPError{Code: mcpMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpRequestTimeout)
+	defer cancel()
+	t.reply(ctx, reply)
+}
+
+// session 返回已完成握手的传输，必要时建立新会话
+func (c *mcpClient) session(ctx context.Context) (mcpTransport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.transport != nil {
+		return c.transport, nil
+	}
+
+	t, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tools, err := c.listTools(ctx, t)
+	if err != nil {
+		t.close()
+		return nil, fmt.Errorf("failed to list MCP tools: %w", err)
+	}
+	for _, name := range c.requiredTools {
+		if _, ok := tools[name]; !ok {
+			t.close()
+			return nil, fmt.Errorf("MCP server does not provide tool %s", name)
+		}
+	}
+
+	c.transport = t
+	c.tools = tools
+	return t, nil
+}
+
+func (c *mcpClient) connect(ctx context.Context) (mcpTransport, error) {
+	if c.stdio != nil {
+		t, err := c.stdio.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.initialize(ctx, t); err != nil {
+			t.close()
+			return nil, err
+		}
+		return t, nil
+	}
+
+	httpTransport := newStreamableHTTPTransport(c.serverURL, c.auth, c.httpClient, c.handleServerRequest)
+
+	err := c.initialize(ctx, httpTransport)
+	if err == nil {
+		return httpTransport, nil
+	}
+	if !errors.Is(err, errMCPTransportUnsupported) {
+		httpTransport.close()
+		return nil, err
+	}
+
+	sse, sseErr := dialSSETransport(ctx, c.serverURL, c.auth, c.httpClient, c.handleServerRequest)
+	if sseErr != nil {
+		return nil, fmt.Errorf("%v; SSE fallback failed: %w", err, sseErr)
+	}
+
+	if err := c.initialize(ctx, sse); err != nil {
+		sse.close()
+		return nil, err
+	}
+	return sse, nil
+}
+
+func (c *mcpClient) initialize(ctx context.Context, t mcpTransport) error {
+	resp, err := t.roundTrip(ctx, c.newRequest("initialize", map[string]interface{}{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      MCPImplementation{Name: mcpClientName, Version: mcpClientVersion},
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to initialize MCP session: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("failed to initialize MCP session: %w", resp.Error)
+	}
+
+	var result mcpInitializeResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal initialize result: %w", err)
+	}
+
+	supported := false
+	for _, v := range mcpSupportedVersions {
+		if v == result.ProtocolVersion {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("unsupported MCP protocol version: %s", result.ProtocolVersion)
+	}
+	t.setProtocolVersion(result.ProtocolVersion)
+	c.server = result.ServerInfo
+
+	if err := t.notify(ctx, c.newNotification("notifications/initialized")); err != nil {
+		return fmt.Errorf("failed to send initialized notification: %w", err)
+	}
+	return nil
+}
+
+func (c *mcpClient) listTools(ctx context.Context, t mcpTransport) (map[string]MCPTool, error) {
+	tools := make(map[string]MCPTool)
+	cursor := ""
+	for {
+		var params interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+
+		resp, err := t.roundTrip(ctx, c.newRequest("tools/list", params))
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+
+		var page mcpListToolsResult
+		if err := json.Unmarshal(resp.Result, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tools/list result: %w", err)
+		}
+		for _, tool := range page.Tools {
+			tools[tool.Name] = tool
+		}
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// reset 丢弃失效的会话，下次调用时重新握手
+func (c *mcpClient) reset(t mcpTransport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.transport == t {
+		c.transport = nil
+		c.tools = nil
+	}
+	t.close()
+}
+
+// call 发送请求，会话失效时重新握手并重试一次
+func (c *mcpClient) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, mcpRequestTimeout)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		t, err := c.session(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.roundTrip(ctx, c.newRequest(method, params))
+		if errors.Is(err, errMCPSessionLost) && attempt == 0 {
+			c.reset(t)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	}
+}
+
+// HasTool 报告服务端是否提供该工具，需要时先完成握手
+func (c *mcpClient) HasTool(ctx context.Context, name string) (bool, error) {
+	if _, err := c.session(ctx); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.tools[name]
+	return ok, nil
+}
+
+// CallTool 调用 tools/call；工具返回 isError 时以文本内容作为错误返回，可识别的平台错误返回 ConnectorError。
+// 旧版服务直接返回结果对象时，将其作为 structuredContent 处理。
+func (c *mcpClient) CallTool(ctx context.Context, name string, args map[string]interface{}) (*MCPToolResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	raw, err := c.call(ctx, "tools/call", MCPToolCallParams{Name: name, Arguments: args})
+	if err != nil {
+		return nil, err
+	}
+
+	var result MCPToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tool result: %w", err)
+	}
+	if result.Content == nil && len(result.StructuredContent) == 0 {
+		result.StructuredContent = raw
+	}
+	if result.IsError {
+		return nil, classifyToolError(name, &result)
+	}
+	return &result, nil
+}
+
+// Close 结束会话；stdio 模式下同时停止子进程且不再重启
+func (c *mcpClient) Close() error {
+	c.mu.Lock()
+	t := c.transport
+	c.transport = nil
+	c.tools = nil
+	c.mu.Unlock()
+	if c.stdio != nil {
+		return c.stdio.close()
+	}
+	if t == nil {
+		return nil
+	}
+	return t.close()
+}
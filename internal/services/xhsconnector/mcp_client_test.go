@@ -0,0 +1,383 @@
+package xhsconnector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMCPServer 是一个最小的 Streamable HTTP MCP 服务端
+type fakeMCPServer struct {
+	mu         sync.Mutex
+	sessionID  string
+	sessions   int
+	methods    []string
+	headers    []http.Header
+	pingReply  *MCPResponse
+	deleted    bool
+	sseCall    bool
+	expireCall bool
+	toolResult string
+}
+
+func (s *fakeMCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method == http.MethodDelete {
+		s.deleted = r.Header.Get("Mcp-Session-Id") == s.sessionID
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	var msg MCPResponse
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.headers = append(s.headers, r.Header.Clone())
+
+	// 客户端对服务端请求的响应
+	if msg.Method == "" {
+		s.pingReply = &msg
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	s.methods = append(s.methods, msg.Method)
+	if len(msg.ID) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if msg.Method == "initialize" {
+		s.sessions++
+		s.sessionID = fmt.Sprintf("session-%d", s.sessions)
+		w.Header().Set("Mcp-Session-Id", s.sessionID)
+		writeFakeResult(w, msg.ID, map[string]interface{}{
+			"protocolVersion": "2025-03-26",
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "0.1"},
+		})
+		return
+	}
+	if r.Header.Get("Mcp-Session-Id") != s.sessionID {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	switch msg.Method {
+	case "tools/list":
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		if params.Cursor == "" {
+			writeFakeResult(w, msg.ID, map[string]interface{}{
+				"tools":      []MCPTool{{Name: "list_comments"}},
+				"nextCursor": "page-2",
+			})
+			return
+		}
+		writeFakeResult(w, msg.ID, map[string]interface{}{"tools": []MCPTool{{Name: "reply_comment"}}})
+	case "tools/call":
+		if s.expireCall {
+			s.expireCall = false
+			s.sessionID = ""
+			http.Error(w, "session expired", http.StatusNotFound)
+			return
+		}
+		result := json.RawMessage(s.toolResult)
+		if !s.sseCall {
+			writeFakeResult(w, msg.ID, result)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		events := []string{
+			": keep-alive\n\n",
+			"event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":\"srv-1\",\"method\":\"ping\"}\n\n",
+			"data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progress\":1}}\n\n",
+			"event: other\ndata: ignored\n\n",
+			"data: {\"jsonrpc\":\"2.0\",\"id\":999,\"result\":{}}\n\n",
+			fmt.Sprintf("id: 7\ndata: {\"jsonrpc\":\"2.0\",\ndata: \"id\":%s,\"result\":%s}\n\n", msg.ID, result),
+		}
+		for _, ev := range events {
+			io.WriteString(w, ev)
+			w.(http.Flusher).Flush()
+		}
+	default:
+		writeFakeError(w, msg.ID, mcpMethodNotFound, "method not found")
+	}
+}
+
+func writeFakeResult(w http.ResponseWriter, id json.RawMessage, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mcpReply{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func writeFakeError(w http.ResponseWriter, id json.RawMessage, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mcpReply{JSONRPC: "2.0", ID: id, Error: &MCPError{Code: code, Message: message}})
+}
+
+const fakeToolResult = `{"content":[{"type":"text","text":"{\"comments\":[{\"comment_id\":\"c1\",\"content\":\"画一只猫\"}]}"}]}`
+
+func newFakeMCP(t *testing.T) (*fakeMCPServer, *mcpClient) {
+	t.Helper()
+	fake := &fakeMCPServer{toolResult: fakeToolResult}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	auth := "Bearer token"
+	return fake, newMCPClient(srv.URL, &auth, []string{"list_comments", "reply_comment"})
+}
+
+func TestMCPClientStreamableHTTP(t *testing.T) {
+	for _, sse := range []bool{false, true} {
+		name := "json"
+		if sse {
+			name = "sse"
+		}
+		t.Run(name, func(t *testing.T) {
+			fake, client := newFakeMCP(t)
+			fake.sseCall = sse
+			ctx := context.Background()
+
+			result, err := client.CallTool(ctx, "list_comments", map[string]interface{}{"note_id": "n1"})
+			if err != nil {
+				t.Fatalf("CallTool: %v", err)
+			}
+			var page ListCommentsResult
+			if err := result.Decode(&page); err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if len(page.Comments) != 1 || page.Comments[0].Content != "画一只猫" {
+				t.Fatalf("unexpected comments %+v", page.Comments)
+			}
+			if client.server.Name != "fake" {
+				t.Fatalf("server info not recorded: %+v", client.server)
+			}
+			if ok, _ := client.HasTool(ctx, "reply_comment"); !ok {
+				t.Fatal("second tools/list page was not read")
+			}
+
+			fake.mu.Lock()
+			want := []string{"initialize", "notifications/initialized", "tools/list", "tools/list", "tools/call"}
+			if !reflect.DeepEqual(fake.methods, want) {
+				t.Fatalf("methods %v, want %v", fake.methods, want)
+			}
+			for i, h := range fake.headers {
+				if h.Get("Authorization") != "Bearer token" {
+					t.Fatalf("request %d without auth header", i)
+				}
+				if i == 0 {
+					continue
+				}
+				if h.Get("Mcp-Session-Id") != "session-1" || h.Get("MCP-Protocol-Version") != "2025-03-26" {
+					t.Fatalf("request %d headers: session=%q version=%q", i, h.Get("Mcp-Session-Id"), h.Get("MCP-Protocol-Version"))
+				}
+			}
+			if sse {
+				// 事件流中的 ping 请求在等待响应期间得到答复
+				if fake.pingReply == nil || string(fake.pingReply.ID) != `"srv-1"` || string(fake.pingReply.Result) != "{}" {
+					t.Fatalf("ping not answered: %+v", fake.pingReply)
+				}
+			}
+			fake.mu.Unlock()
+
+			if err := client.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			if !fake.deleted {
+				t.Fatal("session was not terminated with DELETE")
+			}
+		})
+	}
+}
+
+func TestMCPClientReinitializesLostSession(t *testing.T) {
+	fake, client := newFakeMCP(t)
+	ctx := context.Background()
+
+	if _, err := client.CallTool(ctx, "list_comments", nil); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	fake.mu.Lock()
+	fake.expireCall = true
+	fake.mu.Unlock()
+
+	if _, err := client.CallTool(ctx, "list_comments", nil); err != nil {
+		t.Fatalf("CallTool after session expiry: %v", err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.sessions != 2 {
+		t.Fatalf("got %d sessions, want 2", fake.sessions)
+	}
+}
+
+func TestMCPClientRPCError(t *testing.T) {
+	_, client := newFakeMCP(t)
+
+	_, err := client.call(context.Background(), "resources/list", nil)
+	var rpcErr *MCPError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != mcpMethodNotFound {
+		t.Fatalf("got %v, want method not found", err)
+	}
+}
+
+func TestMCPClientMissingRequiredTool(t *testing.T) {
+	fake := &fakeMCPServer{toolResult: fakeToolResult}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := newMCPClient(srv.URL, nil, []string{"search_notes"})
+	_, err := client.CallTool(context.Background(), "list_comments", nil)
+	if err == nil || !strings.Contains(err.Error(), "does not provide tool search_notes") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+// legacySSEServer 只支持旧版 HTTP+SSE 传输
+type legacySSEServer struct {
+	events chan string
+	posts  chan *MCPResponse
+}
+
+func (s *legacySSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/sse":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	case r.Method == http.MethodGet && r.URL.Path == "/sse":
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "event: endpoint\ndata: /messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case ev := <-s.events:
+				io.WriteString(w, ev)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/messages" && r.URL.Query().Get("session") == "1":
+		var msg MCPResponse
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		s.posts <- &msg
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// respond 在事件流中返回对 msg 的响应
+func (s *legacySSEServer) respond(msg *MCPResponse) {
+	var result interface{}
+	switch msg.Method {
+	case "initialize":
+		result = map[string]interface{}{"protocolVersion": "2024-11-05", "capabilities": map[string]interface{}{}, "serverInfo": map[string]interface{}{"name": "legacy"}}
+	case "tools/list":
+		result = map[string]interface{}{"tools": []MCPTool{{Name: "list_comments"}}}
+	case "tools/call":
+		result = json.RawMessage(fakeToolResult)
+	}
+	data, _ := json.Marshal(mcpReply{JSONRPC: "2.0", ID: msg.ID, Result: result})
+	s.events <- "event: message\ndata: " + string(data) + "\n\n"
+}
+
+func TestMCPClientFallsBackToLegacySSE(t *testing.T) {
+	legacy := &legacySSEServer{events: make(chan string, 8), posts: make(chan *MCPResponse, 8)}
+	srv := httptest.NewServer(legacy)
+	defer srv.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case msg := <-legacy.posts:
+				if len(msg.ID) > 0 {
+					legacy.respond(msg)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	client := newMCPClient(srv.URL+"/sse", nil, []string{"list_comments"})
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := client.CallTool(ctx, "list_comments", nil)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !strings.Contains(result.Text(), "画一只猫") {
+		t.Fatalf("unexpected result %q", result.Text())
+	}
+	if _, ok := client.transport.(*sseTransport); !ok {
+		t.Fatalf("transport is %T, want legacy SSE", client.transport)
+	}
+	if client.server.Name != "legacy" {
+		t.Fatalf("server info %+v", client.server)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	stream := ": comment\n" +
+		"event: endpoint\ndata: /messages\n\n" +
+		"data: line one\ndata:line two\nid: 3\n\n" +
+		"\n\n" +
+		"event: ping\n\n" +
+		"data: unterminated"
+
+	var got []sseEvent
+	err := readSSE(strings.NewReader(stream), func(ev sseEvent) (bool, error) {
+		got = append(got, ev)
+		return false, nil
+	})
+	if err != io.EOF {
+		t.Fatalf("readSSE returned %v, want io.EOF", err)
+	}
+	want := []sseEvent{
+		{Event: "endpoint", Data: "/messages"},
+		{Data: "line one\nline two", ID: "3"},
+		{Event: "ping"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeMCPMessages(t *testing.T) {
+	msgs, err := decodeMCPMessages([]byte(` [{"jsonrpc":"2.0","id":1,"result":{}},{"jsonrpc":"2.0","method":"ping","id":"a"}]`))
+	if err != nil || len(msgs) != 2 || mcpIDKey(msgs[0].ID) != "1" || mcpIDKey(msgs[1].ID) != "a" || msgs[1].Method != "ping" {
+		t.Fatalf("batch decoded to %+v, %v", msgs, err)
+	}
+	if msgs, err := decodeMCPMessages([]byte("  ")); err != nil || msgs != nil {
+		t.Fatalf("empty body decoded to %+v, %v", msgs, err)
+	}
+	if _, err := decodeMCPMessages([]byte("not json")); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,411 @@
+package xhsconnector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	stdioShutdownTimeout = 5 * time.Second
+	stdioRestartMinDelay = time.Second
+	stdioRestartMaxDelay = time.Minute
+	// stdioStableAfter 子进程运行超过该时长后退出，重启退避从最小值重新计算
+	stdioStableAfter = time.Minute
+)
+
+var errMCPSupervisorClosed = errors.New("MCP server supervisor closed")
+
+// splitCommand 按 shell 规则拆分命令行，支持单双引号与反斜杠转义，不做变量展开
+func splitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("unterminated quote or escape in command: %q", s)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
+
+// stdioTransport 实现 stdio 传输：子进程从 stdin 读取、向 stdout 写出以换行分隔的 JSON-RPC 消息，
+// stderr 仅用于日志。子进程退出后所有调用返回 errMCPSessionLost。
+type stdioTransport struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	logger    *zap.Logger
+	onRequest mcpRequestHandler
+
+	writeMu   sync.Mutex
+	mu        sync.Mutex
+	pending   map[string]chan *MCPResponse
+	done      chan struct{}
+	err       error
+	closeOnce sync.Once
+}
+
+func startStdioTransport(args []string, logger *zap.Logger, onRequest mcpRequestHandler) (*stdioTransport, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	t := &stdioTransport{
+		cmd:       cmd,
+		stdin:     stdin,
+		logger:    logger.With(zap.Int("pid", cmd.Process.Pid)),
+		onRequest: onRequest,
+		pending:   make(map[string]chan *MCPResponse),
+		done:      make(chan struct{}),
+	}
+
+	var stderrDone sync.WaitGroup
+	stderrDone.Add(1)
+	go func() {
+		defer stderrDone.Done()
+		t.logStderr(stderr)
+	}()
+	go t.readLoop(stdout, &stderrDone)
+	return t, nil
+}
+
+func (t *stdioTransport) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			t.logger.Info(line, zap.String("stream", "stderr"))
+		}
+	}
+}
+
+func (t *stdioTransport) readLoop(stdout io.Reader, stderrDone *sync.WaitGroup) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		msgs, err := decodeMCPMessages([]byte(line))
+		if err != nil {
+			t.logger.Warn("ignoring non JSON-RPC output from MCP server", zap.String("line", line))
+			continue
+		}
+		for _, msg := range msgs {
+			t.dispatch(msg)
+		}
+	}
+	// 读完 stdout 后仍可能有输出残留在 stderr，Wait 会关闭管道，需等日志读取结束
+	io.Copy(io.Discard, stdout)
+	stderrDone.Wait()
+	err := t.cmd.Wait()
+
+	t.mu.Lock()
+	if err != nil {
+		t.err = fmt.Errorf("MCP server exited: %w", err)
+	} else {
+		t.err = errors.New("MCP server exited")
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) dispatch(msg *MCPResponse) {
+	if msg.Method != "" {
+		if len(msg.ID) > 0 && t.onRequest != nil {
+			go t.onRequest(t, msg)
+		}
+		return
+	}
+
+	id := mcpIDKey(msg.ID)
+	t.mu.Lock()
+	ch, ok := t.pending[id]
+	delete(t.pending, id)
+	t.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (t *stdioTransport) write(msg interface{}) error {
+	if t.exited() {
+		return errMCPSessionLost
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal MCP message: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(body, '\n')); err != nil {
+		if t.exited() {
+			return errMCPSessionLost
+		}
+		return fmt.Errorf("failed to write to MCP server: %w", err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) roundTrip(ctx context.Context, req *MCPRequest) (*MCPResponse, error) {
+	id := mcpIDKey(req.ID)
+	ch := make(chan *MCPResponse, 1)
+	t.mu.Lock()
+	t.pending[id] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		return nil, errMCPSessionLost
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, req *MCPRequest) error {
+	return t.write(req)
+}
+
+func (t *stdioTransport) reply(ctx context.Context, reply *mcpReply) error {
+	return t.write(reply)
+}
+
+// setProtocolVersion stdio 传输不使用协议版本头
+func (t *stdioTransport) setProtocolVersion(version string) {}
+
+func (t *stdioTransport) exited() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// exitErr 返回子进程的退出原因，子进程仍在运行时为 nil
+func (t *stdioTransport) exitErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// close 按规范先关闭 stdin 等待子进程自行退出，超时后依次发送 SIGTERM 与 SIGKILL
+func (t *stdioTransport) close() error {
+	t.closeOnce.Do(func() {
+		t.writeMu.Lock()
+		t.stdin.Close()
+		t.writeMu.Unlock()
+
+		select {
+		case <-t.done:
+			return
+		case <-time.After(stdioShutdownTimeout):
+		}
+		t.cmd.Process.Signal(syscall.SIGTERM)
+
+		select {
+		case <-t.done:
+			return
+		case <-time.After(stdioShutdownTimeout):
+		}
+		t.cmd.Process.Kill()
+		<-t.done
+	})
+	return nil
+}
+
+// stdioSupervisor 管理 MCP 服务端子进程：首次使用时启动，意外退出后按指数退避重启
+type stdioSupervisor struct {
+	args      []string
+	logger    *zap.Logger
+	onRequest mcpRequestHandler
+
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	current *stdioTransport
+	lastErr error
+	// ready 在每次启动尝试结束后关闭并替换，用于唤醒等待中的 dial
+	ready chan struct{}
+	stop  chan struct{}
+}
+
+func newStdioSupervisor(args []string, logger *zap.Logger, onRequest mcpRequestHandler) *stdioSupervisor {
+	return &stdioSupervisor{
+		args:      args,
+		logger:    logger,
+		onRequest: onRequest,
+		ready:     make(chan struct{}),
+		stop:      make(chan struct{}),
+	}
+}
+
+// dial 返回当前运行中子进程的传输；子进程正在重启时等待其启动完成
+func (s *stdioSupervisor) dial(ctx context.Context) (mcpTransport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started && !s.closed {
+		s.started = true
+		go s.run()
+	}
+
+	for {
+		if s.closed {
+			return nil, errMCPSupervisorClosed
+		}
+		if s.current != nil && !s.current.exited() {
+			return s.current, nil
+		}
+		if s.current == nil && s.lastErr != nil {
+			return nil, fmt.Errorf("failed to start MCP server: %w", s.lastErr)
+		}
+
+		ready := s.ready
+		s.mu.Unlock()
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			s.mu.Lock()
+			return nil, fmt.Errorf("MCP server is not running: %w", ctx.Err())
+		}
+		s.mu.Lock()
+	}
+}
+
+func (s *stdioSupervisor) run() {
+	delay := stdioRestartMinDelay
+	for {
+		startedAt := time.Now()
+		t, err := startStdioTransport(s.args, s.logger, s.onRequest)
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			if t != nil {
+				t.close()
+			}
+			return
+		}
+		s.current, s.lastErr = t, err
+		close(s.ready)
+		s.ready = make(chan struct{})
+		s.mu.Unlock()
+
+		if err != nil {
+			s.logger.Error("failed to start MCP server", zap.Error(err), zap.Duration("retry_in", delay))
+		} else {
+			s.logger.Info("MCP server started", zap.Int("pid", t.cmd.Process.Pid), zap.String("command", s.args[0]))
+			<-t.done
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			if time.Since(startedAt) >= stdioStableAfter {
+				delay = stdioRestartMinDelay
+			}
+			s.logger.Warn("MCP server exited, restarting", zap.Error(t.exitErr()), zap.Duration("retry_in", delay))
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > stdioRestartMaxDelay {
+			delay = stdioRestartMaxDelay
+		}
+	}
+}
+
+// close 停止重启并关闭当前子进程
+func (s *stdioSupervisor) close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stop)
+	close(s.ready)
+	t := s.current
+	s.mu.Unlock()
+
+	if t != nil {
+		return t.close()
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package xhsconnector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const stdioHelperEnv = "XHS_MCP_STDIO_HELPER"
+
+// TestStdioHelperServer 不是测试：设置 stdioHelperEnv 后由 stdio 测试作为 MCP 服务端子进程启动。
+// 提供 echo（返回进程 ID）与 crash（立即以状态码 3 退出）两个工具，stdin 关闭后正常退出。
+func TestStdioHelperServer(t *testing.T) {
+	if os.Getenv(stdioHelperEnv) != "1" {
+		t.Skip("helper process for stdio tests")
+	}
+
+	fmt.Fprintln(os.Stderr, "helper MCP server ready")
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var msg MCPResponse
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || len(msg.ID) == 0 || msg.Method == "" {
+			continue
+		}
+
+		var result interface{}
+		switch msg.Method {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": mcpProtocolVersion,
+				"capabilities":    map[string]interface{}{},
+				"serverInfo":      MCPImplementation{Name: "helper", Version: "0.1"},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []MCPTool{{Name: "echo"}, {Name: "crash"}}}
+		case "tools/call":
+			var params MCPToolCallParams
+			json.Unmarshal(msg.Params, &params)
+			if params.Name == "crash" {
+				fmt.Fprintln(os.Stderr, "crashing on purpose")
+				os.Exit(3)
+			}
+			result = map[string]interface{}{"structuredContent": map[string]interface{}{"pid": os.Getpid(), "args": params.Arguments}}
+		}
+		encoder.Encode(mcpReply{JSONRPC: "2.0", ID: msg.ID, Result: result})
+	}
+	os.Exit(0)
+}
+
+func newHelperStdioClient(t *testing.T) *mcpClient {
+	t.Helper()
+	t.Setenv(stdioHelperEnv, "1")
+	return newStdioMCPClient([]string{os.Args[0], "-test.run=^TestStdioHelperServer$"}, zap.NewNop(), []string{"echo"})
+}
+
+func helperPID(t *testing.T, ctx context.Context, client *mcpClient) int {
+	t.Helper()
+	result, err := client.CallTool(ctx, "echo", map[string]interface{}{"x": "y"})
+	if err != nil {
+		t.Fatalf("CallTool echo: %v", err)
+	}
+	var echo struct {
+		PID  int               `json:"pid"`
+		Args map[string]string `json:"args"`
+	}
+	if err := result.Decode(&echo); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if echo.PID == 0 || echo.Args["x"] != "y" {
+		t.Fatalf("unexpected echo %+v", echo)
+	}
+	return echo.PID
+}
+
+func TestStdioCleanShutdown(t *testing.T) {
+	client := newHelperStdioClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pid := helperPID(t, ctx, client)
+	if again := helperPID(t, ctx, client); again != pid {
+		t.Fatalf("second call ran in process %d, want %d", again, pid)
+	}
+	transport := client.stdio.current
+
+	start := time.Now()
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	// 关闭 stdin 后子进程自行退出，不需要等到 SIGTERM
+	if elapsed := time.Since(start); elapsed >= stdioShutdownTimeout {
+		t.Fatalf("Close took %s, child did not exit on stdin EOF", elapsed)
+	}
+	if !transport.exited() || transport.cmd.ProcessState.ExitCode() != 0 {
+		t.Fatalf("child state %v, want clean exit", transport.cmd.ProcessState)
+	}
+
+	if _, err := client.CallTool(ctx, "echo", nil); !errors.Is(err, errMCPSupervisorClosed) {
+		t.Fatalf("CallTool after Close: %v, want %v", err, errMCPSupervisorClosed)
+	}
+}
+
+func TestStdioCrashedChildRestarts(t *testing.T) {
+	client := newHelperStdioClient(t)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	pid := helperPID(t, ctx, client)
+	crashed := client.stdio.current
+
+	// 子进程崩溃时调用返回会话失效，重新握手后的重试同样崩溃
+	if _, err := client.CallTool(ctx, "crash", nil); !errors.Is(err, errMCPSessionLost) {
+		t.Fatalf("CallTool crash: %v, want %v", err, errMCPSessionLost)
+	}
+	if err := crashed.exitErr(); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Fatalf("exit error %v, want exit status 3", err)
+	}
+
+	// 监督进程按退避重启子进程，之后的调用使用新的进程
+	restarted := helperPID(t, ctx, client)
+	if restarted == pid {
+		t.Fatalf("call still served by crashed process %d", pid)
+	}
+}
+
+func TestStdioStartFailure(t *testing.T) {
+	client := newStdioMCPClient([]string{"/nonexistent/mcp-server"}, zap.NewNop(), nil)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.CallTool(ctx, "echo", nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to start MCP server") {
+		t.Fatalf("got %v, want start failure", err)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "npx -y xhs-mcp", want: []string{"npx", "-y", "xhs-mcp"}},
+		{in: "  node\tserver.js\n--port 1 ", want: []string{"node", "server.js", "--port", "1"}},
+		{in: `python "my server.py" --name 'a b'`, want: []string{"python", "my server.py", "--name", "a b"}},
+		{in: `echo a\ b "c\"d" 'e\f'`, want: []string{"echo", "a b", `c"d`, `e\f`}},
+		{in: `run "" x`, want: []string{"run", "", "x"}},
+		{in: `run "unterminated`, wantErr: true},
+		{in: `run trailing\`, wantErr: true},
+		{in: "   ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitCommand(%q) = %q, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
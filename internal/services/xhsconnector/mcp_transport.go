@@ -0,0 +1,500 @@
+package xhsconnector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const maxSSELineSize = 10 << 20
+
+var (
+	// errMCPSessionLost 表示会话已失效（会话过期或 SSE 连接断开），需要重新 initialize
+	errMCPSessionLost = errors.New("MCP session lost")
+	// errMCPTransportUnsupported 表示服务端不支持 Streamable HTTP，应回退到 HTTP+SSE
+	errMCPTransportUnsupported = errors.New("MCP server does not support streamable HTTP")
+)
+
+// mcpTransport 负责收发 JSON-RPC 消息。
+// roundTrip 等待与请求 ID 相同的响应，等待期间服务端发起的请求交给 onRequest 处理。
+type mcpTransport interface {
+	roundTrip(ctx context.Context, req *MCPRequest) (*MCPResponse, error)
+	notify(ctx context.Context, req *MCPRequest) error
+	reply(ctx context.Context, reply *mcpReply) error
+	setProtocolVersion(version string)
+	close() error
+}
+
+type mcpRequestHandler func(t mcpTransport, msg *MCPResponse)
+
+type sseEvent struct {
+	Event string
+	Data  string
+	ID    string
+}
+
+// readSSE 逐个解析 text/event-stream 事件，fn 返回 true 时停止读取
+func readSSE(r io.Reader, fn func(ev sseEvent) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+
+	var ev sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) == 0 && ev.Event == "" {
+				continue
+			}
+			ev.Data = strings.Join(data, "\n")
+			done, err := fn(ev)
+			if err != nil || done {
+				return err
+			}
+			ev, data = sseEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			ev.ID = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// decodeMCPMessages 解码单条消息或批量消息
+func decodeMCPMessages(data []byte) ([]*MCPResponse, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if data[0] == '[' {
+		var batch []*MCPResponse
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return nil, err
+		}
+		return batch, nil
+	}
+	var msg MCPResponse
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return []*MCPResponse{&msg}, nil
+}
+
+func readErrorBody(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return string(body)
+}
+
+// streamableHTTPTransport 实现 Streamable HTTP 传输：每条消息一个 POST，
+// 响应为 application/json 或 text/event-stream，会话 ID 通过 Mcp-Session-Id 头传递。
+type streamableHTTPTransport struct {
+	endpoint  string
+	auth      *string
+	client    *http.Client
+	onRequest mcpRequestHandler
+
+	mu              sync.RWMutex
+	sessionID       string
+	protocolVersion string
+}
+
+func newStreamableHTTPTransport(endpoint string, auth *string, client *http.Client, onRequest mcpRequestHandler) *streamableHTTPTransport {
+	return &streamableHTTPTransport{
+		endpoint:  endpoint,
+		auth:      auth,
+		client:    client,
+		onRequest: onRequest,
+	}
+}
+
+func (t *streamableHTTPTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
+func (t *streamableHTTPTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, t.endpoint, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	if t.auth != nil && *t.auth != "" {
+		httpReq.Header.Set("Authorization", *t.auth)
+	}
+
+	t.mu.RLock()
+	if t.sessionID != "" {
+		httpReq.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		httpReq.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+	t.mu.RUnlock()
+	return httpReq, nil
+}
+
+func (t *streamableHTTPTransport) post(ctx context.Context, msg interface{}) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal MCP message: %w", err)
+	}
+	httpReq, err := t.newRequest(ctx, http.MethodPost, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call MCP server: %w", err)
+	}
+
+	t.mu.RLock()
+	hasSession := t.sessionID != ""
+	t.mu.RUnlock()
+	if resp.StatusCode == http.StatusNotFound && hasSession {
+		resp.Body.Close()
+		return nil, errMCPSessionLost
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		err := fmt.Errorf("MCP server returned status %d: %s", resp.StatusCode, readErrorBody(resp))
+		// 按规范，initialize 返回 4xx 说明服务端可能只支持旧版 HTTP+SSE
+		if req, ok := msg.(*MCPRequest); ok && req.Method == "initialize" && resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+			return nil, fmt.Errorf("%w: %v", errMCPTransportUnsupported, err)
+		}
+		return nil, err
+	}
+
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *streamableHTTPTransport) roundTrip(ctx context.Context, req *MCPRequest) (*MCPResponse, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	id := mcpIDKey(req.ID)
+	var result *MCPResponse
+	handle := func(data []byte) (bool, error) {
+		msgs, err := decodeMCPMessages(data)
+		if err != nil {
+			return false, fmt.Errorf("failed to unmarshal MCP response: %w", err)
+		}
+		for _, msg := range msgs {
+			if msg.Method != "" {
+				if len(msg.ID) > 0 && t.onRequest != nil {
+					t.onRequest(t, msg)
+				}
+				continue
+			}
+			if mcpIDKey(msg.ID) == id {
+				result = msg
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		err := readSSE(resp.Body, func(ev sseEvent) (bool, error) {
+			if ev.Event != "" && ev.Event != "message" {
+				return false, nil
+			}
+			return handle([]byte(ev.Data))
+		})
+		if result != nil {
+			return result, nil
+		}
+		if err == io.EOF {
+			return nil, fmt.Errorf("MCP event stream closed before response")
+		}
+		return nil, fmt.Errorf("failed to read MCP event stream: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if _, err := handle(body); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("MCP server returned no response for request %s", id)
+	}
+	return result, nil
+}
+
+func (t *streamableHTTPTransport) notify(ctx context.Context, req *MCPRequest) error {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *streamableHTTPTransport) reply(ctx context.Context, reply *mcpReply) error {
+	resp, err := t.post(ctx, reply)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close 通过 DELETE 结束会话，服务端不支持时忽略
+func (t *streamableHTTPTransport) close() error {
+	t.mu.RLock()
+	hasSession := t.sessionID != ""
+	t.mu.RUnlock()
+	if !hasSession {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpRequestTimeout)
+	defer cancel()
+	httpReq, err := t.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to terminate MCP session: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// sseTransport 实现旧版 HTTP+SSE 传输：GET 建立事件流，服务端先通过 endpoint 事件告知消息地址，
+// 之后客户端 POST 消息到该地址，响应从事件流返回。
+type sseTransport struct {
+	endpoint  string
+	auth      *string
+	client    *http.Client
+	onRequest mcpRequestHandler
+	cancel    context.CancelFunc
+
+	mu      sync.Mutex
+	pending map[string]chan *MCPResponse
+	done    chan struct{}
+	err     error
+}
+
+func dialSSETransport(ctx context.Context, serverURL string, auth *string, client *http.Client, onRequest mcpRequestHandler) (*sseTransport, error) {
+	base, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MCP server URL: %w", err)
+	}
+
+	// 事件流在会话期间一直保持，不受单次请求的超时控制
+	streamCtx, cancel := context.WithCancel(context.Background())
+	httpReq, err := http.NewRequestWithContext(streamCtx, http.MethodGet, serverURL, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if auth != nil && *auth != "" {
+		httpReq.Header.Set("Authorization", *auth)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open MCP event stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		defer cancel()
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("MCP server returned status %d (%s) for event stream: %s", resp.StatusCode, resp.Header.Get("Content-Type"), readErrorBody(resp))
+	}
+
+	t := &sseTransport{
+		auth:      auth,
+		client:    client,
+		onRequest: onRequest,
+		cancel:    cancel,
+		pending:   make(map[string]chan *MCPResponse),
+		done:      make(chan struct{}),
+	}
+
+	endpoints := make(chan string, 1)
+	go t.readLoop(resp.Body, endpoints)
+
+	select {
+	case endpoint := <-endpoints:
+		ref, err := url.Parse(endpoint)
+		if err != nil {
+			t.close()
+			return nil, fmt.Errorf("invalid MCP endpoint %q: %w", endpoint, err)
+		}
+		t.endpoint = base.ResolveReference(ref).String()
+		return t, nil
+	case <-t.done:
+		return nil, fmt.Errorf("MCP event stream closed before endpoint event: %w", t.err)
+	case <-ctx.Done():
+		t.close()
+		return nil, ctx.Err()
+	}
+}
+
+func (t *sseTransport) readLoop(body io.ReadCloser, endpoints chan<- string) {
+	defer body.Close()
+
+	err := readSSE(body, func(ev sseEvent) (bool, error) {
+		switch ev.Event {
+		case "endpoint":
+			select {
+			case endpoints <- strings.TrimSpace(ev.Data):
+			default:
+			}
+		case "", "message":
+			msgs, err := decodeMCPMessages([]byte(ev.Data))
+			if err != nil {
+				return false, nil
+			}
+			for _, msg := range msgs {
+				t.dispatch(msg)
+			}
+		}
+		return false, nil
+	})
+
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *sseTransport) dispatch(msg *MCPResponse) {
+	if msg.Method != "" {
+		if len(msg.ID) > 0 && t.onRequest != nil {
+			go t.onRequest(t, msg)
+		}
+		return
+	}
+
+	id := mcpIDKey(msg.ID)
+	t.mu.Lock()
+	ch, ok := t.pending[id]
+	delete(t.pending, id)
+	t.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (t *sseTransport) post(ctx context.Context, msg interface{}) error {
+	select {
+	case <-t.done:
+		return errMCPSessionLost
+	default:
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal MCP message: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if t.auth != nil && *t.auth != "" {
+		httpReq.Header.Set("Authorization", *t.auth)
+	}
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call MCP server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errMCPSessionLost
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("MCP server returned status %d: %s", resp.StatusCode, readErrorBody(resp))
+	}
+	return nil
+}
+
+func (t *sseTransport) roundTrip(ctx context.Context, req *MCPRequest) (*MCPResponse, error) {
+	id := mcpIDKey(req.ID)
+	ch := make(chan *MCPResponse, 1)
+	t.mu.Lock()
+	t.pending[id] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := t.post(ctx, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		return nil, errMCPSessionLost
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *sseTransport) notify(ctx context.Context, req *MCPRequest) error {
+	return t.post(ctx, req)
+}
+
+func (t *sseTransport) reply(ctx context.Context, reply *mcpReply) error {
+	return t.post(ctx, reply)
+}
+
+// setProtocolVersion 旧版传输不使用协议版本头
+func (t *sseTransport) setProtocolVersion(version string) {}
+
+func (t *sseTransport) close() error {
+	t.cancel()
+	return nil
+}
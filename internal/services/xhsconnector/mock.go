@@ -2,20 +2,75 @@ package xhsconnector
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
+// mockInjectedTTL 为注入评论在 Redis 中的保留时长
+const mockInjectedTTL = 7 * 24 * time.Hour
+
+// MockSelfUserID 为 Mock 模式下本账号的用户 ID，ReplyComment 发出的回复以该用户出现在楼中楼中
+const (
+	MockSelfUserID   = "mock_self"
+	mockSelfUserName = "小红书生图助手"
+)
+
+// CommentInjector 为支持即时注入评论的连接器。ParentCommentID 非空时注入为该一级评论下的回复，
+// 未指定的评论 ID 与时间会自动生成。
+type CommentInjector interface {
+	InjectComment(ctx context.Context, noteIDOrURL string, comment Comment) (*Comment, error)
+}
+
+// MockOptions 配置 MockConnector。RDB 非空时注入的评论与场景时钟保存在 Redis 中，
+// API 与 Worker 进程看到同一份数据；Scenario 非空时按场景回放评论与故障，Speed 大于 0 时覆盖场景倍速。
+type MockOptions struct {
+	RDB      *redis.Client
+	Scenario *Scenario
+	Speed    float64
+}
+
 type MockConnector struct {
 	mu       sync.RWMutex
 	comments map[string][]Comment
+	// subComments 按一级评论 ID 存放楼中楼回复
+	subComments map[string][]Comment
+	replies     map[string][]MockReply
+	// errors 按笔记设置拉取评论时返回的错误，用于模拟平台失败
+	errors map[string]error
+
+	rdb      *redis.Client
+	scenario *scenarioPlayer
+}
+
+// MockReply 为 MockConnector 收到的一次回复
+type MockReply struct {
+	ReplyID   string    `json:"reply_id"`
+	CommentID string    `json:"comment_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 func NewMockConnector() *MockConnector {
+	return NewMockConnectorWithOptions(MockOptions{})
+}
+
+func NewMockConnectorWithOptions(opts MockOptions) *MockConnector {
 	m := &MockConnector{
-		comments: make(map[string][]Comment),
+		comments:    make(map[string][]Comment),
+		subComments: make(map[string][]Comment),
+		replies:     make(map[string][]MockReply),
+		errors:      make(map[string]error),
+		rdb:         opts.RDB,
+	}
+	if opts.Scenario != nil {
+		m.scenario = newScenarioPlayer(opts.Scenario, opts.Speed, opts.RDB)
 	}
 	m.initMockComments()
 	return m
@@ -61,20 +116,58 @@ func (m *MockConnector) initMockComments() {
 			Content:          "出图！风景画，风格是油画，art@studio.com",
 			CommentCreatedAt: now,
 		},
+		{
+			CommentID:        "mock_007",
+			UserName:         "测试用户7",
+			Content:          "把这张图改成动漫风，邮箱：edit@example.com",
+			CommentCreatedAt: now,
+			ImageURLs:        []string{"https://picsum.photos/seed/xhs-mock-007/768/1024"},
+		},
 	}
 
 	m.comments["default"] = mockComments
-}
 
-func (m *MockConnector) ListComments(ctx context.Context, noteIDOrURL string, cursor string) (*ListCommentsResult, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.subComments["mock_002"] = []Comment{
+		{
+			CommentID:        "mock_002_r1",
+			UserName:         "测试用户8",
+			Content:          "同求！我也想要一个，主题是雪山日出，snow@example.com",
+			CommentCreatedAt: now.Add(-50 * time.Minute),
+			ParentCommentID:  "mock_002",
+			Depth:            1,
+		},
+		{
+			CommentID:        "mock_002_r2",
+			UserName:         "测试用户2",
+			Content:          "楼上的也好看",
+			CommentCreatedAt: now.Add(-40 * time.Minute),
+			ParentCommentID:  "mock_002_r1",
+			Depth:            2,
+		},
+	}
 
-	comments, exists := m.comments[noteIDOrURL]
-	if !exists {
-		comments = m.comments["default"]
+	for i := range mockComments {
+		withMockUser(&mockComments[i])
 	}
+	for i := range m.subComments["mock_002"] {
+		withMockUser(&m.subComments["mock_002"][i])
+	}
+}
 
+// withMockUser 按昵称为评论补全稳定的用户 ID 与头像，同名用户视为同一人
+func withMockUser(comment *Comment) {
+	if comment.UserID == "" && comment.UserName != "" {
+		h := fnv.New32a()
+		h.Write([]byte(comment.UserName))
+		comment.UserID = fmt.Sprintf("mock_user_%x", h.Sum32())
+	}
+	if comment.UserAvatar == "" && comment.UserID != "" {
+		comment.UserAvatar = fmt.Sprintf("https://picsum.photos/seed/%s/96/96", comment.UserID)
+	}
+}
+
+// paginateComments 以评论 ID 作为游标分页，每页最多 50 条
+func paginateComments(comments []Comment, cursor string) *ListCommentsResult {
 	var startIndex int
 	if cursor != "" {
 		for i, c := range comments {
@@ -90,8 +183,6 @@ func (m *MockConnector) ListComments(ctx context.Context, noteIDOrURL string, cu
 		endIndex = len(comments)
 	}
 
-	resultComments := comments[startIndex:endIndex]
-
 	var nextCursor string
 	var hasMore bool
 	if endIndex < len(comments) {
@@ -99,13 +190,288 @@ func (m *MockConnector) ListComments(ctx context.Context, noteIDOrURL string, cu
 		hasMore = true
 	}
 
-	time.Sleep(100*time.Millisecond + time.Duration(rand.Intn(200))*time.Millisecond)
-
 	return &ListCommentsResult{
-		Comments:   resultComments,
+		Comments:   append([]Comment(nil), comments[startIndex:endIndex]...),
 		NextCursor: nextCursor,
 		HasMore:    hasMore,
-	}, nil
+	}
+}
+
+func (m *MockConnector) ListComments(ctx context.Context, noteIDOrURL string, cursor string) (*ListCommentsResult, error) {
+	if err := m.failure(ctx, noteIDOrURL); err != nil {
+		return nil, err
+	}
+
+	comments, err := m.noteComments(ctx, noteIDOrURL)
+	if err != nil {
+		return nil, err
+	}
+
+	result := paginateComments(comments, cursor)
+	for i := range result.Comments {
+		subComments, err := m.rootSubComments(ctx, result.Comments[i].CommentID)
+		if err != nil {
+			return nil, err
+		}
+		result.Comments[i].SubCommentCount = len(subComments)
+	}
+
+	time.Sleep(100*time.Millisecond + time.Duration(rand.Intn(200))*time.Millisecond)
+
+	return result, nil
+}
+
+// ListSubComments 返回一级评论下的楼中楼回复
+func (m *MockConnector) ListSubComments(ctx context.Context, noteIDOrURL string, rootCommentID string, cursor string) (*ListCommentsResult, error) {
+	if err := m.failure(ctx, noteIDOrURL); err != nil {
+		return nil, err
+	}
+
+	comments, err := m.rootSubComments(ctx, rootCommentID)
+	if err != nil {
+		return nil, err
+	}
+	return paginateComments(comments, cursor), nil
+}
+
+// failure 返回 SetError 设置的错误或场景中当前生效的故障
+func (m *MockConnector) failure(ctx context.Context, noteIDOrURL string) error {
+	m.mu.RLock()
+	err := m.errors[noteIDOrURL]
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if m.scenario != nil {
+		return m.scenario.failure(ctx, noteIDOrURL)
+	}
+	return nil
+}
+
+// noteComments 合并场景（或内置）评论、AddComment 添加的评论与注入的评论，按评论时间排序
+func (m *MockConnector) noteComments(ctx context.Context, noteIDOrURL string) ([]Comment, error) {
+	var comments []Comment
+	covered := false
+	if m.scenario != nil {
+		var err error
+		comments, covered, err = m.scenario.comments(ctx, noteIDOrURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.RLock()
+	added, exists := m.comments[noteIDOrURL]
+	switch {
+	case covered || exists:
+		comments = append(comments, added...)
+	default:
+		comments = append(comments, m.comments["default"]...)
+	}
+	m.mu.RUnlock()
+
+	injected, err := m.loadInjected(ctx, m.injectedCommentsKey(noteIDOrURL))
+	if err != nil {
+		return nil, err
+	}
+	comments = append(comments, injected...)
+
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CommentCreatedAt.Before(comments[j].CommentCreatedAt)
+	})
+	return comments, nil
+}
+
+// rootSubComments 合并一级评论下的内置、场景与注入的回复，按评论时间排序
+func (m *MockConnector) rootSubComments(ctx context.Context, rootCommentID string) ([]Comment, error) {
+	m.mu.RLock()
+	comments := append([]Comment(nil), m.subComments[rootCommentID]...)
+	m.mu.RUnlock()
+
+	if m.scenario != nil {
+		replies, err := m.scenario.subComments(ctx, rootCommentID)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, replies...)
+	}
+
+	injected, err := m.loadInjected(ctx, m.injectedSubCommentsKey(rootCommentID))
+	if err != nil {
+		return nil, err
+	}
+	comments = append(comments, injected...)
+
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CommentCreatedAt.Before(comments[j].CommentCreatedAt)
+	})
+	return comments, nil
+}
+
+func (m *MockConnector) injectedCommentsKey(noteIDOrURL string) string {
+	return fmt.Sprintf("mock:xhs:comments:%s", noteIDOrURL)
+}
+
+func (m *MockConnector) injectedSubCommentsKey(rootCommentID string) string {
+	return fmt.Sprintf("mock:xhs:subcomments:%s", rootCommentID)
+}
+
+// loadInjected 读取 Redis 中注入的评论，未配置 Redis 时注入的评论直接保存在内存中
+func (m *MockConnector) loadInjected(ctx context.Context, key string) ([]Comment, error) {
+	if m.rdb == nil {
+		return nil, nil
+	}
+	items, err := m.rdb.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load injected comments: %w", err)
+	}
+	comments := make([]Comment, 0, len(items))
+	for _, item := range items {
+		var comment Comment
+		if err := json.Unmarshal([]byte(item), &comment); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal injected comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+// InjectComment 即时添加一条评论，下次拉取时返回；内容为空时生成一条随机的生成请求
+func (m *MockConnector) InjectComment(ctx context.Context, noteIDOrURL string, comment Comment) (*Comment, error) {
+	now := time.Now()
+	if comment.Content == "" {
+		generated := m.GenerateNewComment(noteIDOrURL)
+		comment.Content = generated.Content
+		if comment.UserName == "" {
+			comment.UserName = generated.UserName
+		}
+	}
+	withMockUser(&comment)
+	if comment.CommentID == "" {
+		comment.CommentID = fmt.Sprintf("mock_inj_%d", now.UnixNano())
+	}
+	if comment.CommentCreatedAt.IsZero() {
+		comment.CommentCreatedAt = now
+	}
+	comment.SubCommentCount = 0
+	comment.Depth = 0
+	key := m.injectedCommentsKey(noteIDOrURL)
+	if comment.ParentCommentID != "" {
+		comment.Depth = 1
+		key = m.injectedSubCommentsKey(comment.ParentCommentID)
+	}
+
+	if m.rdb == nil {
+		if comment.ParentCommentID != "" {
+			m.AddSubComment(comment.ParentCommentID, comment)
+		} else {
+			m.AddComment(noteIDOrURL, comment)
+		}
+		return &comment, nil
+	}
+
+	data, err := json.Marshal(comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal comment: %w", err)
+	}
+	pipe := m.rdb.TxPipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.Expire(ctx, key, mockInjectedTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to save injected comment: %w", err)
+	}
+	return &comment, nil
+}
+
+// ListUserNotes 返回场景中已发布的笔记；没有场景时返回固定的三篇笔记，分别发布于 1、10、60 天前
+func (m *MockConnector) ListUserNotes(ctx context.Context, userIDOrURL string, cursor string) (*ListUserNotesResult, error) {
+	if m.scenario != nil && len(m.scenario.published) > 0 {
+		notes, err := m.scenario.userNotes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &ListUserNotesResult{Notes: notes}, nil
+	}
+
+	now := time.Now()
+	notes := []UserNote{
+		{NoteID: "mock_note_001", Title: "Mock 笔记 1", PublishedAt: now.AddDate(0, 0, -1)},
+		{NoteID: "mock_note_002", Title: "Mock 笔记 2", PublishedAt: now.AddDate(0, 0, -10)},
+		{NoteID: "mock_note_003", Title: "Mock 笔记 3", PublishedAt: now.AddDate(0, 0, -60)},
+	}
+	return &ListUserNotesResult{Notes: notes}, nil
+}
+
+// ReplyComment 记录回复，并与真实平台一样以本账号（MockSelfUserID）的楼中楼回复出现在被回复评论所在的一级评论下
+func (m *MockConnector) ReplyComment(ctx context.Context, noteIDOrURL string, commentID string, content string) (*ReplyCommentResult, error) {
+	if m.scenario != nil {
+		if err := m.scenario.failure(ctx, noteIDOrURL); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reply := MockReply{
+		ReplyID:   fmt.Sprintf("mock_reply_%d", time.Now().UnixNano()),
+		CommentID: commentID,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+	m.replies[noteIDOrURL] = append(m.replies[noteIDOrURL], reply)
+
+	rootID, depth := commentID, 1
+	for id, subComments := range m.subComments {
+		for _, sub := range subComments {
+			if sub.CommentID == commentID {
+				rootID, depth = id, sub.Depth+1
+			}
+		}
+	}
+	m.subComments[rootID] = append(m.subComments[rootID], Comment{
+		CommentID:        reply.ReplyID,
+		UserID:           MockSelfUserID,
+		UserName:         mockSelfUserName,
+		Content:          content,
+		CommentCreatedAt: reply.CreatedAt,
+		ParentCommentID:  commentID,
+		Depth:            depth,
+	})
+
+	return &ReplyCommentResult{ReplyID: reply.ReplyID}, nil
+}
+
+// SelfUserID 返回 Mock 模式下本账号的用户 ID
+func (m *MockConnector) SelfUserID() string {
+	return MockSelfUserID
+}
+
+// Replies 返回笔记下已记录的回复
+func (m *MockConnector) Replies(noteIDOrURL string) []MockReply {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]MockReply(nil), m.replies[noteIDOrURL]...)
+}
+
+// SetError 设置笔记拉取评论与回复时返回的错误，如 ErrRateLimited；err 为 nil 时恢复正常
+func (m *MockConnector) SetError(noteIDOrURL string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		delete(m.errors, noteIDOrURL)
+		return
+	}
+	m.errors[noteIDOrURL] = err
+}
+
+// AddSubComment 在一级评论下添加一条楼中楼回复
+func (m *MockConnector) AddSubComment(rootCommentID string, comment Comment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subComments[rootCommentID] = append(m.subComments[rootCommentID], comment)
 }
 
 func (m *MockConnector) AddComment(noteIDOrURL string, comment Comment) {
@@ -119,6 +485,7 @@ func (m *MockConnector) AddComment(noteIDOrURL string, comment Comment) {
 	m.comments[noteIDOrURL] = append(m.comments[noteIDOrURL], comment)
 }
 
+// GenerateNewComment 生成一条带生成请求与邮箱的随机评论
 func (m *MockConnector) GenerateNewComment(noteIDOrURL string) Comment {
 	prompts := []string{
 		"帮我生成一张美食图片，邮箱：",
@@ -141,10 +508,16 @@ func (m *MockConnector) GenerateNewComment(noteIDOrURL string) Comment {
 	prompt := prompts[rand.Intn(len(prompts))]
 	email := emails[rand.Intn(len(emails))]
 
-	return Comment{
+	comment := Comment{
 		CommentID:        fmt.Sprintf("mock_%d", time.Now().UnixNano()),
 		UserName:         fmt.Sprintf("随机用户%d", rand.Intn(1000)),
 		Content:          prompt + email,
 		CommentCreatedAt: time.Now(),
 	}
+	withMockUser(&comment)
+	return comment
+}
+
+func (m *MockConnector) Close() error {
+	return nil
 }
@@ -0,0 +1,280 @@
+package xhsconnector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Recording 为一次 ListComments 调用的录制，Response 与 Error 二选一
+type Recording struct {
+	RecordedAt time.Time           `json:"recorded_at"`
+	NoteTarget string              `json:"note_id_or_url"`
+	Cursor     string              `json:"cursor"`
+	Response   *ListCommentsResult `json:"response,omitempty"`
+	Error      *RecordedError      `json:"error,omitempty"`
+}
+
+// RecordedError 为录制的错误，Kind 非空时回放为对应的 ConnectorError
+type RecordedError struct {
+	Kind          ErrorKind `json:"kind,omitempty"`
+	Message       string    `json:"message"`
+	RetryAfterSec int       `json:"retry_after_sec,omitempty"`
+}
+
+var (
+	redactEmailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+	redactPhonePattern = regexp.MustCompile(`\b1[3-9]\d{9}\b`)
+)
+
+func redactHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// redactText 将邮箱替换为同一邮箱对应的固定占位邮箱，保留意图识别所需的格式；手机号替换为掩码
+func redactText(text string) string {
+	text = redactEmailPattern.ReplaceAllStringFunc(text, func(email string) string {
+		return fmt.Sprintf("redacted_%s@example.com", redactHash(strings.ToLower(email)))
+	})
+	return redactPhonePattern.ReplaceAllString(text, "1**********")
+}
+
+// redactComment 脱敏评论中的用户信息与联系方式，同一用户脱敏后的 ID 与昵称保持一致
+func redactComment(comment Comment) Comment {
+	comment.Content = redactText(comment.Content)
+	if comment.UserID != "" {
+		comment.UserID = "user_" + redactHash(comment.UserID)
+	}
+	if comment.UserName != "" {
+		comment.UserName = "用户" + redactHash(comment.UserName)[:6]
+	}
+	comment.UserAvatar = ""
+	return comment
+}
+
+// RecordingConnector 包装任意连接器，把每次 ListComments 的请求与脱敏后的响应写入 Dir，
+// 每次调用一个 JSON 文件，供 replay 模式回放；其余方法直接转发
+type RecordingConnector struct {
+	Connector
+	dir string
+	mu  sync.Mutex
+}
+
+func NewRecordingConnector(inner Connector, dir string) (*RecordingConnector, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create recording dir: %w", err)
+	}
+	return &RecordingConnector{Connector: inner, dir: dir}, nil
+}
+
+func (r *RecordingConnector) ListComments(ctx context.Context, noteIDOrURL string, cursor string) (*ListCommentsResult, error) {
+	result, err := r.Connector.ListComments(ctx, noteIDOrURL, cursor)
+
+	recording := &Recording{
+		RecordedAt: time.Now(),
+		NoteTarget: noteIDOrURL,
+		Cursor:     cursor,
+	}
+	if err != nil {
+		recording.Error = &RecordedError{
+			Kind:          KindOf(err),
+			Message:       redactText(err.Error()),
+			RetryAfterSec: int(RetryAfterOf(err) / time.Second),
+		}
+	} else if result != nil {
+		redacted := *result
+		redacted.Comments = make([]Comment, len(result.Comments))
+		for i, comment := range result.Comments {
+			redacted.Comments[i] = redactComment(comment)
+		}
+		recording.Response = &redacted
+	}
+	// 录制失败不影响轮询
+	_ = r.save(recording)
+
+	return result, err
+}
+
+// save 按笔记分目录保存录制，文件名为录制时间，目录内按文件名排序即为调用顺序
+func (r *RecordingConnector) save(recording *Recording) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	noteDir := filepath.Join(r.dir, redactHash(recording.NoteTarget))
+	if err := os.MkdirAll(noteDir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%019d.json", recording.RecordedAt.UnixNano())
+	return os.WriteFile(filepath.Join(noteDir, name), data, 0o644)
+}
+
+// InjectComment 转发给支持注入评论的连接器
+func (r *RecordingConnector) InjectComment(ctx context.Context, noteIDOrURL string, comment Comment) (*Comment, error) {
+	injector, ok := r.Connector.(CommentInjector)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return injector.InjectComment(ctx, noteIDOrURL, comment)
+}
+
+// SelfUserID 转发被包装连接器的登录账号 ID
+func (r *RecordingConnector) SelfUserID() string {
+	if self, ok := r.Connector.(SelfIdentifier); ok {
+		return self.SelfUserID()
+	}
+	return ""
+}
+
+// ReplayConnector 按录制顺序回放 ListComments：同一笔记与游标的调用依次返回下一条录制，
+// 录制用完后重复最后一条。有 Redis 时回放进度保存在 Redis 中，API 与 Worker 进程共用同一进度；
+// 否则进度只在进程内，此时只能有一个进程轮询。其余方法返回 ErrUnsupported。
+type ReplayConnector struct {
+	recordings map[string][]*Recording
+	rdb        *redis.Client
+	// id 为全部录制内容的哈希，录制变化后进度重新开始
+	id        string
+	positions map[string]int
+	mu        sync.Mutex
+}
+
+func replayKey(noteIDOrURL, cursor string) string {
+	return noteIDOrURL + "\x00" + cursor
+}
+
+// NewReplayConnector 加载 dir 下的全部录制，rdb 为 nil 时回放进度保存在进程内
+func NewReplayConnector(dir string, rdb *redis.Client) (*ReplayConnector, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("replay mode requires a recording dir")
+	}
+
+	var all []*Recording
+	h := fnv.New64a()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var recording Recording
+		if err := json.Unmarshal(data, &recording); err != nil {
+			return fmt.Errorf("invalid recording %s: %w", path, err)
+		}
+		h.Write(data)
+		all = append(all, &recording)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recordings: %w", err)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].RecordedAt.Before(all[j].RecordedAt)
+	})
+	recordings := make(map[string][]*Recording)
+	for _, recording := range all {
+		key := replayKey(recording.NoteTarget, recording.Cursor)
+		recordings[key] = append(recordings[key], recording)
+	}
+
+	return &ReplayConnector{
+		recordings: recordings,
+		rdb:        rdb,
+		id:         strconv.FormatUint(h.Sum64(), 16),
+		positions:  make(map[string]int),
+	}, nil
+}
+
+// next 返回本次调用应回放的录制序号并推进进度，超出后停在最后一条
+func (r *ReplayConnector) next(ctx context.Context, key string, count int) (int, error) {
+	if r.rdb == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		pos := r.positions[key]
+		if pos < count-1 {
+			r.positions[key] = pos + 1
+		}
+		return pos, nil
+	}
+
+	n, err := r.rdb.Incr(ctx, fmt.Sprintf("mock:xhs:replay:%s:%s", r.id, redactHash(key))).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance replay position: %w", err)
+	}
+	if pos := int(n - 1); pos < count-1 {
+		return pos, nil
+	}
+	return count - 1, nil
+}
+
+func (r *ReplayConnector) ListComments(ctx context.Context, noteIDOrURL string, cursor string) (*ListCommentsResult, error) {
+	key := replayKey(noteIDOrURL, cursor)
+
+	recordings := r.recordings[key]
+	if len(recordings) == 0 {
+		return nil, fmt.Errorf("no recording for note %s with cursor %q", noteIDOrURL, cursor)
+	}
+	pos, err := r.next(ctx, key, len(recordings))
+	if err != nil {
+		return nil, err
+	}
+	recording := recordings[pos]
+
+	if recording.Error != nil {
+		if recording.Error.Kind == "" {
+			return nil, errors.New(recording.Error.Message)
+		}
+		return nil, &ConnectorError{
+			Kind:       recording.Error.Kind,
+			Message:    recording.Error.Message,
+			RetryAfter: time.Duration(recording.Error.RetryAfterSec) * time.Second,
+		}
+	}
+
+	result := ListCommentsResult{}
+	if recording.Response != nil {
+		result = *recording.Response
+		result.Comments = append([]Comment(nil), recording.Response.Comments...)
+	}
+	return &result, nil
+}
+
+func (r *ReplayConnector) ListSubComments(ctx context.Context, noteIDOrURL string, rootCommentID string, cursor string) (*ListCommentsResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *ReplayConnector) ListUserNotes(ctx context.Context, userIDOrURL string, cursor string) (*ListUserNotesResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *ReplayConnector) ReplyComment(ctx context.Context, noteIDOrURL string, commentID string, content string) (*ReplyCommentResult, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *ReplayConnector) Close() error {
+	return nil
+}
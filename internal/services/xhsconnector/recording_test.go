@@ -0,0 +1,171 @@
+package xhsconnector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedConnector 按顺序返回预设的 ListComments 结果
+type scriptedConnector struct {
+	Connector
+	calls []scriptedCall
+	n     int
+}
+
+type scriptedCall struct {
+	result *ListCommentsResult
+	err    error
+}
+
+func (s *scriptedConnector) ListComments(ctx context.Context, noteIDOrURL string, cursor string) (*ListCommentsResult, error) {
+	call := s.calls[s.n]
+	s.n++
+	return call.result, call.err
+}
+
+func TestRedactComment(t *testing.T) {
+	comment := Comment{
+		CommentID:  "c1",
+		UserID:     "5f0a1b2c",
+		UserName:   "小明",
+		UserAvatar: "https://sns-avatar.example/a.jpg",
+		Content:    "帮我画一只猫 发到 Alice.Smith@Example.com 或打 13812345678",
+	}
+
+	redacted := redactComment(comment)
+	if redacted.CommentID != "c1" {
+		t.Errorf("comment ID changed: %s", redacted.CommentID)
+	}
+	for _, leaked := range []string{"Alice.Smith", "Example.com", "13812345678"} {
+		if strings.Contains(redacted.Content, leaked) {
+			t.Errorf("content still contains %q: %s", leaked, redacted.Content)
+		}
+	}
+	if !redactEmailPattern.MatchString(redacted.Content) {
+		t.Errorf("redacted content has no email for intent extraction: %s", redacted.Content)
+	}
+	if !strings.Contains(redacted.Content, "帮我画一只猫") || !strings.Contains(redacted.Content, "1**********") {
+		t.Errorf("unexpected content: %s", redacted.Content)
+	}
+	if redacted.UserID == comment.UserID || !strings.HasPrefix(redacted.UserID, "user_") {
+		t.Errorf("user ID not redacted: %s", redacted.UserID)
+	}
+	if redacted.UserName == comment.UserName {
+		t.Errorf("user name not redacted: %s", redacted.UserName)
+	}
+	if redacted.UserAvatar != "" {
+		t.Errorf("avatar not dropped: %s", redacted.UserAvatar)
+	}
+
+	// 同一用户与邮箱脱敏结果一致，大小写不同的邮箱视为同一个
+	again := redactComment(Comment{UserID: "5f0a1b2c", UserName: "小明", Content: "alice.smith@example.com"})
+	if again.UserID != redacted.UserID || again.UserName != redacted.UserName {
+		t.Errorf("redaction is not stable: %+v vs %+v", again, redacted)
+	}
+	if !strings.Contains(redacted.Content, again.Content) {
+		t.Errorf("email redaction is not stable: %q vs %q", again.Content, redacted.Content)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	first := &ListCommentsResult{Comments: []Comment{
+		{CommentID: "c1", UserID: "u1", UserName: "小红", Content: "画一只猫 a@example.com"},
+	}}
+	second := &ListCommentsResult{Comments: []Comment{
+		{CommentID: "c1", UserID: "u1", UserName: "小红", Content: "画一只猫 a@example.com"},
+		{CommentID: "c2", UserID: "u2", UserName: "小蓝", Content: "画一只狗 b@example.com"},
+	}}
+	inner := &scriptedConnector{calls: []scriptedCall{
+		{result: first},
+		{err: &ConnectorError{Kind: ErrorKindRateLimited, Message: "too many requests from b@example.com", RetryAfter: 30 * time.Second}},
+		{result: second},
+	}}
+
+	recorder, err := NewRecordingConnector(inner, dir)
+	if err != nil {
+		t.Fatalf("NewRecordingConnector: %v", err)
+	}
+	for i := range inner.calls {
+		result, err := recorder.ListComments(ctx, "note1", "")
+		// 录制不改变返回给调用方的结果
+		if result != inner.calls[i].result || err != inner.calls[i].err {
+			t.Fatalf("call %d: recording changed the result", i)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if len(files) != len(inner.calls) {
+		t.Fatalf("got %d recordings, want %d", len(files), len(inner.calls))
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, leaked := range []string{"a@example.com", "b@example.com", "小红", "\"u1\""} {
+			if strings.Contains(string(data), leaked) {
+				t.Errorf("%s contains %q", filepath.Base(file), leaked)
+			}
+		}
+	}
+
+	replay, err := NewReplayConnector(dir, nil)
+	if err != nil {
+		t.Fatalf("NewReplayConnector: %v", err)
+	}
+
+	result, err := replay.ListComments(ctx, "note1", "")
+	if err != nil || len(result.Comments) != 1 || result.Comments[0].CommentID != "c1" {
+		t.Fatalf("replay 1: %+v, %v", result, err)
+	}
+	if !redactEmailPattern.MatchString(result.Comments[0].Content) {
+		t.Errorf("replayed comment lost its email: %s", result.Comments[0].Content)
+	}
+
+	_, err = replay.ListComments(ctx, "note1", "")
+	if KindOf(err) != ErrorKindRateLimited || RetryAfterOf(err) != 30*time.Second {
+		t.Fatalf("replay 2: got %v, want rate limited with retry after", err)
+	}
+	if strings.Contains(err.Error(), "b@example.com") {
+		t.Errorf("replayed error is not redacted: %v", err)
+	}
+
+	// 录制用完后重复最后一条
+	for i := 0; i < 2; i++ {
+		result, err = replay.ListComments(ctx, "note1", "")
+		if err != nil || len(result.Comments) != 2 {
+			t.Fatalf("replay %d: %+v, %v", i+3, result, err)
+		}
+	}
+	// 回放结果互不共享底层数组
+	result.Comments[0].Content = "changed"
+	result, _ = replay.ListComments(ctx, "note1", "")
+	if result.Comments[0].Content == "changed" {
+		t.Error("replayed results share comments")
+	}
+
+	if _, err := replay.ListComments(ctx, "note1", "next"); err == nil {
+		t.Error("expected error for unrecorded cursor")
+	}
+	if _, err := replay.ListComments(ctx, "note2", ""); err == nil {
+		t.Error("expected error for unrecorded note")
+	}
+	if _, err := replay.ListSubComments(ctx, "note1", "c1", ""); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ListSubComments: got %v, want ErrUnsupported", err)
+	}
+}
+
+func TestReplayRequiresDir(t *testing.T) {
+	if _, err := NewReplayConnector("", nil); err == nil {
+		t.Fatal("expected error for empty dir")
+	}
+}
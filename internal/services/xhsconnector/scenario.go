@@ -0,0 +1,415 @@
+package xhsconnector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario 描述 MockConnector 回放的笔记、评论、楼中楼回复与注入的故障，文件可为 YAML 或 JSON。
+// 所有时间都是相对场景开始的偏移（如 "90s"、"5m"），Speed 为回放倍速，60 表示场景中的 1 分钟在 1 秒内发生。
+type Scenario struct {
+	Name  string         `yaml:"name" json:"name"`
+	Speed float64        `yaml:"speed" json:"speed"`
+	Notes []ScenarioNote `yaml:"notes" json:"notes"`
+
+	// id 由文件内容计算，场景文件修改后重新开始计时
+	id string
+}
+
+// ScenarioNote 为场景中的一篇笔记。Target 为拉取评论时使用的 note_id_or_url，
+// "default" 匹配场景中未列出的笔记；At 为笔记发布时间，发布后才出现在 ListUserNotes 中。
+type ScenarioNote struct {
+	Target   string             `yaml:"target" json:"target"`
+	Title    string             `yaml:"title" json:"title"`
+	At       time.Duration      `yaml:"at" json:"at"`
+	Comments []ScenarioComment  `yaml:"comments" json:"comments"`
+	Generate *ScenarioGenerator `yaml:"generate" json:"generate"`
+	Failures []ScenarioFailure  `yaml:"failures" json:"failures"`
+}
+
+// ScenarioComment 为在 At 时刻出现的评论。Replies 为其下的楼中楼回复，
+// 回复的 ReplyTo 为被回复的评论 ID，默认为所在的一级评论；UserID 为空时按 User 生成。
+type ScenarioComment struct {
+	ID        string            `yaml:"id" json:"id"`
+	UserID    string            `yaml:"user_id" json:"user_id"`
+	User      string            `yaml:"user" json:"user"`
+	Content   string            `yaml:"content" json:"content"`
+	At        time.Duration     `yaml:"at" json:"at"`
+	ImageURLs []string          `yaml:"image_urls" json:"image_urls"`
+	ReplyTo   string            `yaml:"reply_to" json:"reply_to"`
+	Replies   []ScenarioComment `yaml:"replies" json:"replies"`
+}
+
+// ScenarioGenerator 从 Start 开始每隔 Every 生成一条评论，共 Count 条，用于压测。
+// User 与 Content 中的 {n} 替换为从 1 开始的序号。
+type ScenarioGenerator struct {
+	Count   int           `yaml:"count" json:"count"`
+	Start   time.Duration `yaml:"start" json:"start"`
+	Every   time.Duration `yaml:"every" json:"every"`
+	User    string        `yaml:"user" json:"user"`
+	Content string        `yaml:"content" json:"content"`
+}
+
+// ScenarioFailure 在 [From, Until) 内让该笔记的拉取评论、拉取回复与回复评论返回 Kind 对应的错误，
+// Until 为 0 表示持续到场景结束；Kind 为 "error" 时返回普通错误。
+type ScenarioFailure struct {
+	Kind       ErrorKind     `yaml:"kind" json:"kind"`
+	From       time.Duration `yaml:"from" json:"from"`
+	Until      time.Duration `yaml:"until" json:"until"`
+	RetryAfter time.Duration `yaml:"retry_after" json:"retry_after"`
+	Message    string        `yaml:"message" json:"message"`
+}
+
+const scenarioGenericFailure ErrorKind = "error"
+
+// LoadScenario 读取并校验场景文件
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+	return ParseScenario(data)
+}
+
+// ParseScenario 解析 YAML 或 JSON 格式的场景，未知字段视为错误
+func ParseScenario(data []byte) (*Scenario, error) {
+	var scenario Scenario
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	if err := scenario.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+
+	h := fnv.New64a()
+	h.Write(data)
+	scenario.id = strconv.FormatUint(h.Sum64(), 16)
+	return &scenario, nil
+}
+
+func (s *Scenario) validate() error {
+	if s.Speed < 0 {
+		return errors.New("speed must not be negative")
+	}
+	targets := make(map[string]bool)
+	ids := make(map[string]bool)
+	for i, note := range s.Notes {
+		if note.Target == "" {
+			return fmt.Errorf("notes[%d]: target is required", i)
+		}
+		if targets[note.Target] {
+			return fmt.Errorf("notes[%d]: duplicate target %s", i, note.Target)
+		}
+		targets[note.Target] = true
+
+		for _, comment := range note.Comments {
+			if err := validateScenarioComment(comment, ids); err != nil {
+				return fmt.Errorf("notes[%d]: %w", i, err)
+			}
+		}
+		if g := note.Generate; g != nil {
+			if g.Count <= 0 || g.Content == "" {
+				return fmt.Errorf("notes[%d]: generate needs count and content", i)
+			}
+			if g.Count > 1 && g.Every <= 0 {
+				return fmt.Errorf("notes[%d]: generate needs every", i)
+			}
+		}
+		for j, failure := range note.Failures {
+			if failure.Kind != scenarioGenericFailure && !knownErrorKind(failure.Kind) {
+				return fmt.Errorf("notes[%d].failures[%d]: unknown kind %s", i, j, failure.Kind)
+			}
+			if failure.Until != 0 && failure.Until <= failure.From {
+				return fmt.Errorf("notes[%d].failures[%d]: until must be after from", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+func validateScenarioComment(comment ScenarioComment, ids map[string]bool) error {
+	if comment.ID == "" {
+		return errors.New("comment id is required")
+	}
+	if ids[comment.ID] {
+		return fmt.Errorf("duplicate comment id %s", comment.ID)
+	}
+	ids[comment.ID] = true
+
+	// reply_to 只能指向一级评论或排在前面的回复
+	thread := map[string]bool{comment.ID: true}
+	for _, reply := range comment.Replies {
+		if len(reply.Replies) > 0 {
+			return fmt.Errorf("comment %s: replies must be listed under the top-level comment", reply.ID)
+		}
+		if err := validateScenarioComment(reply, ids); err != nil {
+			return err
+		}
+		if reply.ReplyTo != "" && !thread[reply.ReplyTo] {
+			return fmt.Errorf("comment %s: reply_to %s must be the top-level comment or an earlier reply", reply.ID, reply.ReplyTo)
+		}
+		thread[reply.ID] = true
+	}
+	return nil
+}
+
+// scenarioClock 计算场景经过的时间。有 Redis 时开始时间与当前时间都取自 Redis，
+// API 与 Worker 进程看到同一时间线；否则以进程内首次使用的时间为开始。
+type scenarioClock struct {
+	rdb   *redis.Client
+	key   string
+	speed float64
+
+	mu    sync.Mutex
+	start time.Time
+}
+
+func (c *scenarioClock) now(ctx context.Context) (time.Time, error) {
+	if c.rdb == nil {
+		return time.Now(), nil
+	}
+	now, err := c.rdb.Time(ctx).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get redis time: %w", err)
+	}
+	return now, nil
+}
+
+// elapsed 返回场景开始时间与按倍速换算后的场景时间
+func (c *scenarioClock) elapsed(ctx context.Context) (time.Time, time.Duration, error) {
+	now, err := c.now(ctx)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.start.IsZero() {
+		start := now
+		if c.rdb != nil {
+			if err := c.rdb.SetNX(ctx, c.key, now.UnixMilli(), 0).Err(); err != nil {
+				return time.Time{}, 0, fmt.Errorf("failed to save scenario start: %w", err)
+			}
+			ms, err := c.rdb.Get(ctx, c.key).Int64()
+			if err != nil {
+				return time.Time{}, 0, fmt.Errorf("failed to get scenario start: %w", err)
+			}
+			start = time.UnixMilli(ms)
+		}
+		c.start = start
+	}
+
+	return c.start, time.Duration(float64(now.Sub(c.start)) * c.speed), nil
+}
+
+// realTime 将场景偏移换算为现实时间
+func (c *scenarioClock) realTime(start time.Time, offset time.Duration) time.Time {
+	return start.Add(time.Duration(float64(offset) / c.speed))
+}
+
+// scenarioReply 为展开后的楼中楼回复
+type scenarioReply struct {
+	comment ScenarioComment
+	parent  string
+	depth   int
+}
+
+// scenarioPlayer 按场景时间给出已出现的评论与当前生效的故障
+type scenarioPlayer struct {
+	clock *scenarioClock
+	notes map[string]*ScenarioNote
+	// replies 按一级评论 ID 存放展开后的回复
+	replies map[string][]scenarioReply
+	// published 为除 default 外的笔记，用于 ListUserNotes
+	published []*ScenarioNote
+}
+
+func newScenarioPlayer(scenario *Scenario, speed float64, rdb *redis.Client) *scenarioPlayer {
+	if speed <= 0 {
+		speed = scenario.Speed
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+
+	p := &scenarioPlayer{
+		clock: &scenarioClock{
+			rdb:   rdb,
+			key:   fmt.Sprintf("mock:xhs:scenario:%s:start", scenario.id),
+			speed: speed,
+		},
+		notes:   make(map[string]*ScenarioNote),
+		replies: make(map[string][]scenarioReply),
+	}
+	for i := range scenario.Notes {
+		note := &scenario.Notes[i]
+		p.notes[note.Target] = note
+		if note.Target != "default" {
+			p.published = append(p.published, note)
+		}
+		for _, comment := range note.Comments {
+			depths := map[string]int{comment.ID: 0}
+			for _, reply := range comment.Replies {
+				parent := reply.ReplyTo
+				if parent == "" {
+					parent = comment.ID
+				}
+				depth := depths[parent] + 1
+				depths[reply.ID] = depth
+				p.replies[comment.ID] = append(p.replies[comment.ID], scenarioReply{comment: reply, parent: parent, depth: depth})
+			}
+		}
+	}
+	// ListUserNotes 按发布时间倒序返回
+	sort.SliceStable(p.published, func(i, j int) bool {
+		return p.published[i].At > p.published[j].At
+	})
+	return p
+}
+
+func (p *scenarioPlayer) note(target string) *ScenarioNote {
+	if note, ok := p.notes[target]; ok {
+		return note
+	}
+	return p.notes["default"]
+}
+
+// comments 返回笔记下已出现的一级评论；场景未覆盖该笔记时 ok 为 false
+func (p *scenarioPlayer) comments(ctx context.Context, target string) ([]Comment, bool, error) {
+	note := p.note(target)
+	if note == nil {
+		return nil, false, nil
+	}
+	start, elapsed, err := p.clock.elapsed(ctx)
+	if err != nil {
+		return nil, true, err
+	}
+
+	var comments []Comment
+	for _, c := range note.Comments {
+		if c.At > elapsed {
+			continue
+		}
+		comments = append(comments, p.toComment(start, c))
+	}
+
+	if g := note.Generate; g != nil {
+		h := fnv.New32a()
+		h.Write([]byte(note.Target))
+		for n := 1; n <= g.Count; n++ {
+			at := g.Start + time.Duration(n-1)*g.Every
+			if at > elapsed {
+				break
+			}
+			index := strconv.Itoa(n)
+			comments = append(comments, p.toComment(start, ScenarioComment{
+				ID:      fmt.Sprintf("gen_%x_%d", h.Sum32(), n),
+				User:    strings.ReplaceAll(g.User, "{n}", index),
+				Content: strings.ReplaceAll(g.Content, "{n}", index),
+				At:      at,
+			}))
+		}
+	}
+	return comments, true, nil
+}
+
+// subComments 返回一级评论下已出现的回复
+func (p *scenarioPlayer) subComments(ctx context.Context, rootCommentID string) ([]Comment, error) {
+	replies := p.replies[rootCommentID]
+	if len(replies) == 0 {
+		return nil, nil
+	}
+	start, elapsed, err := p.clock.elapsed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []Comment
+	for _, reply := range replies {
+		if reply.comment.At > elapsed {
+			continue
+		}
+		comment := p.toComment(start, reply.comment)
+		comment.ParentCommentID = reply.parent
+		comment.Depth = reply.depth
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+// userNotes 返回已发布的笔记，最新的在前
+func (p *scenarioPlayer) userNotes(ctx context.Context) ([]UserNote, error) {
+	start, elapsed, err := p.clock.elapsed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []UserNote
+	for _, note := range p.published {
+		if note.At > elapsed {
+			continue
+		}
+		userNote := UserNote{Title: note.Title, PublishedAt: p.clock.realTime(start, note.At)}
+		if strings.HasPrefix(note.Target, "http://") || strings.HasPrefix(note.Target, "https://") {
+			userNote.NoteURL = note.Target
+		} else {
+			userNote.NoteID = note.Target
+		}
+		notes = append(notes, userNote)
+	}
+	return notes, nil
+}
+
+// failure 返回笔记当前生效的故障，没有故障时返回 nil
+func (p *scenarioPlayer) failure(ctx context.Context, target string) error {
+	note := p.note(target)
+	if note == nil || len(note.Failures) == 0 {
+		return nil
+	}
+	_, elapsed, err := p.clock.elapsed(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range note.Failures {
+		if elapsed < f.From || (f.Until != 0 && elapsed >= f.Until) {
+			continue
+		}
+		message := f.Message
+		if message == "" {
+			message = "injected by scenario"
+		}
+		if f.Kind == scenarioGenericFailure {
+			return errors.New(message)
+		}
+		return &ConnectorError{Kind: f.Kind, Message: message, RetryAfter: f.RetryAfter}
+	}
+	return nil
+}
+
+func (p *scenarioPlayer) toComment(start time.Time, c ScenarioComment) Comment {
+	comment := Comment{
+		CommentID:        c.ID,
+		UserID:           c.UserID,
+		UserName:         c.User,
+		Content:          c.Content,
+		CommentCreatedAt: p.clock.realTime(start, c.At),
+		ImageURLs:        c.ImageURLs,
+	}
+	withMockUser(&comment)
+	return comment
+}
@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/mailer"
+	"github.com/xiaohongshu-image/internal/services/provider"
+)
+
+// buildArtifacts 将供应商返回的产物转换为数据库记录，未标明类型的产物沿用任务的请求类型
+func buildArtifacts(task *models.Task, artifacts []provider.Artifact) []models.Artifact {
+	if len(artifacts) == 0 && task.ResultURL != nil {
+		artifacts = []provider.Artifact{{URL: *task.ResultURL}}
+	}
+
+	result := make([]models.Artifact, 0, len(artifacts))
+	for i, a := range artifacts {
+		artifact := models.Artifact{
+			TaskID:    task.ID,
+			Kind:      a.Kind,
+			URL:       a.URL,
+			SortOrder: i,
+		}
+		if artifact.Kind == "" {
+			artifact.Kind = string(task.RequestType)
+			if task.RequestType == models.RequestTypeEdit {
+				artifact.Kind = string(models.RequestTypeImage)
+			}
+		}
+		if a.ObjectKey != "" {
+			objectKey := a.ObjectKey
+			artifact.ObjectKey = &objectKey
+		}
+		if a.MimeType != "" {
+			mimeType := a.MimeType
+			artifact.MimeType = &mimeType
+		}
+		if a.Size > 0 {
+			size := a.Size
+			artifact.SizeBytes = &size
+		}
+		if a.Width > 0 {
+			width := a.Width
+			artifact.Width = &width
+		}
+		if a.Height > 0 {
+			height := a.Height
+			artifact.Height = &height
+		}
+		result = append(result, artifact)
+	}
+
+	return result
+}
+
+func resultLinks(task *models.Task) []mailer.ResultLink {
+	var links []mailer.ResultLink
+	for _, a := range task.Artifacts {
+		links = append(links, mailer.ResultLink{Kind: a.Kind, URL: a.URL, Hosted: a.ObjectKey != nil})
+	}
+	if len(links) == 0 && task.ResultURL != nil {
+		links = append(links, mailer.ResultLink{Kind: string(task.RequestType), URL: *task.ResultURL})
+	}
+	return links
+}
+
+// previewURL 返回邮件中展示的预览图：优先使用缩略图或封面，其次为第一张图片，视频没有缩略图时为空
+func previewURL(task *models.Task) string {
+	for _, a := range task.Artifacts {
+		if a.Kind == "thumbnail" || a.Kind == "cover" {
+			return a.URL
+		}
+	}
+	for _, a := range task.Artifacts {
+		if a.Kind == string(models.RequestTypeImage) {
+			return a.URL
+		}
+	}
+	if len(task.Artifacts) == 0 && task.ResultURL != nil && task.RequestType != models.RequestTypeVideo {
+		return *task.ResultURL
+	}
+	return ""
+}
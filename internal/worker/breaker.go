@@ -0,0 +1,183 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/services/breaker"
+	"github.com/xiaohongshu-image/internal/services/provider"
+	"go.uber.org/zap"
+)
+
+// healthCheckTick 为健康检查调度的检查间隔，每个供应商按自己的 interval_sec 执行
+const healthCheckTick = 5 * time.Second
+
+// providerChoice 是 selectProvider 选中的供应商。probe 非 0 表示本次提交是熔断器 half_open 状态下的探测请求
+type providerChoice struct {
+	cfg   *provider.ProviderConfig
+	lease *provider.Lease
+	probe int64
+}
+
+// selectProvider 按配置顺序选择第一个支持该请求且熔断器放行的已注册供应商。
+// 所有可用供应商都被熔断时返回 nil 与最短的等待时长；返回的实例使用完毕后需要 Release。
+func (w *Worker) selectProvider(ctx context.Context, configs []provider.ProviderConfig, req provider.UnifiedGenRequest) (*providerChoice, time.Duration, error) {
+	var wait time.Duration
+	registered, eligible := 0, 0
+
+	for i := range configs {
+		cfg := &configs[i]
+		lease, exists := w.acquireProvider(cfg.ProviderName)
+		if !exists {
+			continue
+		}
+		registered++
+		if !cfg.Supports(req) {
+			lease.Release()
+			continue
+		}
+		eligible++
+
+		if w.breaker == nil {
+			return &providerChoice{cfg: cfg, lease: lease}, 0, nil
+		}
+
+		permit, err := w.breaker.Allow(ctx, cfg.ProviderName, cfg.BreakerSettings())
+		if err != nil {
+			// 熔断器不可用时放行，避免 Redis 故障阻塞所有任务
+			w.logger.Warn("failed to check provider breaker", zap.Error(err), zap.String("provider", cfg.ProviderName))
+			return &providerChoice{cfg: cfg, lease: lease}, 0, nil
+		}
+		if permit.Allowed {
+			if eligible > 1 {
+				w.logger.Info("rerouting to fallback provider", zap.String("provider", cfg.ProviderName))
+			}
+			return &providerChoice{cfg: cfg, lease: lease, probe: permit.Probe}, 0, nil
+		}
+		lease.Release()
+
+		w.logger.Info("provider circuit open", zap.String("provider", cfg.ProviderName), zap.Duration("retry_after", permit.RetryAfter))
+		if wait == 0 || permit.RetryAfter < wait {
+			wait = permit.RetryAfter
+		}
+	}
+
+	if registered == 0 {
+		return nil, 0, fmt.Errorf("provider not found: %s", configs[0].ProviderName)
+	}
+	if eligible == 0 {
+		if len(req.ReferenceImages) > 0 {
+			return nil, 0, fmt.Errorf("no provider supports %s requests with reference images", req.Type)
+		}
+		return nil, 0, fmt.Errorf("no provider supports %s requests", req.Type)
+	}
+	if wait < minRateRetryDelay {
+		wait = minRateRetryDelay
+	}
+	return nil, wait, nil
+}
+
+// releaseProviderProbe 归还 selectProvider 占用但最终没有发出的探测请求，
+// 否则 half_open 状态下其他任务要等探测租约过期才能再次探测
+func (w *Worker) releaseProviderProbe(ctx context.Context, choice *providerChoice) {
+	if w.breaker == nil || choice.probe == 0 {
+		return
+	}
+	if err := w.breaker.ReleaseProbe(ctx, choice.cfg.ProviderName, choice.probe); err != nil {
+		w.logger.Warn("failed to release provider probe", zap.Error(err), zap.String("provider", choice.cfg.ProviderName))
+	}
+}
+
+// recordProviderCall 将一次供应商调用的结果计入熔断器。
+// probe 为 selectProvider 分配的探测编号，half_open 状态下只有探测请求的结果会改变熔断器状态
+func (w *Worker) recordProviderCall(ctx context.Context, cfg *provider.ProviderConfig, probe int64, timeout time.Duration, latency time.Duration, callErr error) {
+	if w.breaker == nil || cfg == nil {
+		return
+	}
+
+	outcome := breaker.OutcomeSuccess
+	if callErr != nil {
+		outcome = breaker.OutcomeFailure
+	} else if latency >= cfg.SlowCallThreshold(timeout) {
+		outcome = breaker.OutcomeSlow
+	}
+
+	state, err := w.breaker.Record(ctx, cfg.ProviderName, cfg.BreakerSettings(), probe, outcome)
+	if err != nil {
+		w.logger.Warn("failed to record provider call", zap.Error(err), zap.String("provider", cfg.ProviderName))
+		return
+	}
+	if state == breaker.StateOpen && outcome != breaker.OutcomeSuccess {
+		w.logger.Warn("provider circuit opened", zap.String("provider", cfg.ProviderName), zap.String("outcome", string(outcome)))
+	}
+}
+
+// RunHealthChecks 周期性探测配置了 health_check 的供应商，结果写入熔断器。
+// 多个进程同时运行时，每个供应商在一个检查周期内只会被探测一次。
+func (w *Worker) RunHealthChecks(ctx context.Context) {
+	if w.breaker == nil {
+		return
+	}
+
+	ticker := time.NewTicker(healthCheckTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.checkProvidersHealth(ctx)
+		}
+	}
+}
+
+func (w *Worker) checkProvidersHealth(ctx context.Context) {
+	configs := w.providers.Configs()
+	for i := range configs {
+		cfg := &configs[i]
+		if cfg.HealthCheck != nil {
+			w.checkProviderHealth(ctx, cfg)
+		}
+	}
+}
+
+func (w *Worker) checkProviderHealth(ctx context.Context, cfg *provider.ProviderConfig) {
+	lease, exists := w.providers.Acquire(cfg.ProviderName)
+	if !exists {
+		return
+	}
+	defer lease.Release()
+
+	checker, ok := lease.Provider.(provider.HealthChecker)
+	if !ok {
+		return
+	}
+
+	claimed, err := w.breaker.ClaimHealthCheck(ctx, cfg.ProviderName, cfg.HealthCheck.Interval())
+	if err != nil {
+		w.logger.Warn("failed to claim health check", zap.Error(err), zap.String("provider", cfg.ProviderName))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	start := time.Now()
+	checkErr := checker.CheckHealth(ctx)
+	latency := time.Since(start)
+
+	state, err := w.breaker.RecordHealth(ctx, cfg.ProviderName, cfg.BreakerSettings(), latency, checkErr)
+	if err != nil {
+		w.logger.Warn("failed to record health check", zap.Error(err), zap.String("provider", cfg.ProviderName))
+		return
+	}
+	if checkErr != nil {
+		w.logger.Warn("provider health check failed",
+			zap.String("provider", cfg.ProviderName),
+			zap.String("state", state),
+			zap.Error(checkErr),
+		)
+	}
+}
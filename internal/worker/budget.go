@@ -0,0 +1,234 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/provider"
+	"go.uber.org/zap"
+)
+
+const (
+	BudgetWindowDaily   = "daily"
+	BudgetWindowMonthly = "monthly"
+
+	// BudgetScopeGlobal 为全部供应商合计的预算
+	BudgetScopeGlobal   = "global"
+	BudgetScopeProvider = "provider"
+)
+
+// BudgetUsage 为一个预算窗口的用量，Provider 为空表示全局预算
+type BudgetUsage struct {
+	Scope    string    `json:"scope"`
+	Provider string    `json:"provider,omitempty"`
+	Window   string    `json:"window"`
+	Limit    float64   `json:"limit"`
+	Spent    float64   `json:"spent"`
+	ResetAt  time.Time `json:"reset_at"`
+	start    time.Time
+}
+
+// budgetWindowBounds 返回 now 所在自然日或自然月的起止时间（本地时区）
+func budgetWindowBounds(window string, now time.Time) (time.Time, time.Time) {
+	if window == BudgetWindowMonthly {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+func newBudgetUsage(scope, providerName, window string, limit float64, now time.Time) BudgetUsage {
+	start, end := budgetWindowBounds(window, now)
+	return BudgetUsage{
+		Scope:    scope,
+		Provider: providerName,
+		Window:   window,
+		Limit:    limit,
+		ResetAt:  end,
+		start:    start,
+	}
+}
+
+// budgetLimits 返回适用于 cfg 的全部预算窗口；cfg 为 nil 时只返回全局预算。上限不大于 0 表示不限制。
+func budgetLimits(setting *models.Setting, cfg *provider.ProviderConfig, now time.Time) []BudgetUsage {
+	var limits []BudgetUsage
+	if setting.DailyBudget != nil && *setting.DailyBudget > 0 {
+		limits = append(limits, newBudgetUsage(BudgetScopeGlobal, "", BudgetWindowDaily, *setting.DailyBudget, now))
+	}
+	if setting.MonthlyBudget != nil && *setting.MonthlyBudget > 0 {
+		limits = append(limits, newBudgetUsage(BudgetScopeGlobal, "", BudgetWindowMonthly, *setting.MonthlyBudget, now))
+	}
+	if cfg == nil || cfg.Budget == nil {
+		return limits
+	}
+	if cfg.Budget.DailyLimit > 0 {
+		limits = append(limits, newBudgetUsage(BudgetScopeProvider, cfg.ProviderName, BudgetWindowDaily, cfg.Budget.DailyLimit, now))
+	}
+	if cfg.Budget.MonthlyLimit > 0 {
+		limits = append(limits, newBudgetUsage(BudgetScopeProvider, cfg.ProviderName, BudgetWindowMonthly, cfg.Budget.MonthlyLimit, now))
+	}
+	return limits
+}
+
+func (w *Worker) fillBudgetSpent(usage *BudgetUsage) error {
+	spent, err := w.db.SumSubmittedCost(usage.Provider, usage.start)
+	if err != nil {
+		return fmt.Errorf("failed to sum submitted cost: %w", err)
+	}
+	usage.Spent = spent
+	return nil
+}
+
+// checkBudget 检查提交费用为 cost 的任务后是否超出全局或供应商预算。
+// 超出时返回说明与最晚的窗口重置时间；已提交的任务按预估费用计入，并发提交时可能少量超出上限。
+func (w *Worker) checkBudget(cfg *provider.ProviderConfig, cost float64) (string, time.Time, error) {
+	setting, err := w.db.GetSetting()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	usages := budgetLimits(setting, cfg, time.Now())
+	for i := range usages {
+		if err := w.fillBudgetSpent(&usages[i]); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	reason, resetAt := exceededBudget(usages, cost)
+	return reason, resetAt, nil
+}
+
+// budgetCheckRequired 返回提交前是否需要检查预算：没有预估费用或已批准超出预算的任务直接提交
+func budgetCheckRequired(task *models.Task, cost float64) bool {
+	return cost > 0 && !task.BudgetApproved
+}
+
+// exceededBudget 返回加上 cost 后超出上限的第一个窗口的说明，以及超出窗口中最晚的重置时间。
+// 恰好达到上限不算超出；均未超出时返回空说明。
+func exceededBudget(usages []BudgetUsage, cost float64) (string, time.Time) {
+	var reason string
+	var resetAt time.Time
+	for _, usage := range usages {
+		if usage.Spent+cost <= usage.Limit {
+			continue
+		}
+
+		if reason == "" {
+			scope := usage.Scope
+			if usage.Provider != "" {
+				scope = "provider " + usage.Provider
+			}
+			reason = fmt.Sprintf("%s %s budget exceeded: spent %.4f + estimated %.4f > limit %.4f", scope, usage.Window, usage.Spent, cost, usage.Limit)
+		}
+		if usage.ResetAt.After(resetAt) {
+			resetAt = usage.ResetAt
+		}
+	}
+	return reason, resetAt
+}
+
+// BudgetUsages 返回全局与各供应商预算的当前用量
+func (w *Worker) BudgetUsages() ([]BudgetUsage, error) {
+	setting, err := w.db.GetSetting()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	now := time.Now()
+	usages := append([]BudgetUsage{}, budgetLimits(setting, nil, now)...)
+	configs := w.providers.Configs()
+	for i := range configs {
+		usages = append(usages, budgetLimits(&models.Setting{}, &configs[i], now)...)
+	}
+
+	for i := range usages {
+		if err := w.fillBudgetSpent(&usages[i]); err != nil {
+			return nil, err
+		}
+	}
+	return usages, nil
+}
+
+// holdForBudget 将任务置为 QUEUED_BUDGET，并在预算窗口重置时重新投递提交任务
+func (w *Worker) holdForBudget(payload SubmitJobPayload, cost float64, reason string, resetAt time.Time) error {
+	queued, err := w.db.QueueTaskForBudget(payload.TaskID, cost, reason)
+	if err != nil {
+		w.logger.Error("failed to queue task for budget", zap.Error(err), zap.Uint("task_id", payload.TaskID))
+		return err
+	}
+	if !queued {
+		w.logger.Info("task no longer waiting for submit, skipping budget hold", zap.Uint("task_id", payload.TaskID))
+		return nil
+	}
+
+	// 预算窗口重置后重新计算容量等待时间
+	payload.CapacityWaitSince = 0
+	data, _ := json.Marshal(payload)
+	_, err = w.redis.Enqueue(
+		asynq.NewTask(TypeSubmitJob, data, asynq.ProcessAt(resetAt), asynq.Queue("critical")),
+	)
+	if err != nil {
+		w.logger.Error("failed to schedule submit job task", zap.Error(err), zap.Uint("task_id", payload.TaskID))
+		return err
+	}
+
+	auditPayload, _ := json.Marshal(map[string]interface{}{
+		"task_id":        payload.TaskID,
+		"estimated_cost": cost,
+		"reason":         reason,
+		"reset_at":       resetAt,
+	})
+	w.db.CreateAuditLog(&models.AuditLog{
+		Level:       "WARN",
+		Event:       "task_queued_budget",
+		PayloadJSON: string(auditPayload),
+	})
+
+	w.logger.Info("task queued for budget",
+		zap.Uint("task_id", payload.TaskID),
+		zap.Float64("estimated_cost", cost),
+		zap.String("reason", reason),
+		zap.Time("reset_at", resetAt),
+	)
+	return nil
+}
+
+// ApproveBudget 允许 QUEUED_BUDGET 任务超出预算并立即重新提交。
+// 任务不处于 QUEUED_BUDGET 时返回 false。
+func (w *Worker) ApproveBudget(taskID uint) (bool, error) {
+	approved, err := w.db.ApproveTaskBudget(taskID)
+	if err != nil {
+		return false, fmt.Errorf("failed to approve task budget: %w", err)
+	}
+	if !approved {
+		return false, nil
+	}
+
+	task, err := w.db.GetTaskByID(taskID)
+	if err != nil {
+		return true, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	prompt := ""
+	if task.Prompt != nil {
+		prompt = *task.Prompt
+	}
+	data, _ := json.Marshal(SubmitJobPayload{
+		TaskID:      task.ID,
+		RequestType: string(task.RequestType),
+		Prompt:      prompt,
+	})
+	_, err = w.redis.Enqueue(
+		asynq.NewTask(TypeSubmitJob, data, asynq.Queue("critical")),
+	)
+	if err != nil {
+		w.logger.Error("failed to enqueue submit job task", zap.Error(err), zap.Uint("task_id", taskID))
+		return true, err
+	}
+
+	w.logger.Info("task budget approved", zap.Uint("task_id", taskID))
+	return true, nil
+}
@@ -0,0 +1,209 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/provider"
+)
+
+func TestBudgetWindowBounds(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+
+	tests := []struct {
+		name      string
+		window    string
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "daily mid day",
+			window:    BudgetWindowDaily,
+			now:       time.Date(2024, 5, 1, 15, 30, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "daily at midnight starts a new window",
+			window:    BudgetWindowDaily,
+			now:       time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "daily month end",
+			window:    BudgetWindowDaily,
+			now:       time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC),
+			wantStart: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "daily in local time zone",
+			window:    BudgetWindowDaily,
+			now:       time.Date(2024, 5, 1, 7, 0, 0, 0, shanghai),
+			wantStart: time.Date(2024, 5, 1, 0, 0, 0, 0, shanghai),
+			wantEnd:   time.Date(2024, 5, 2, 0, 0, 0, 0, shanghai),
+		},
+		{
+			name:      "monthly on the 31st",
+			window:    BudgetWindowMonthly,
+			now:       time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "monthly year end",
+			window:    BudgetWindowMonthly,
+			now:       time.Date(2024, 12, 15, 8, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := budgetWindowBounds(tt.window, tt.now)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Fatalf("budgetWindowBounds = [%s, %s), want [%s, %s)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestBudgetLimits(t *testing.T) {
+	now := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+	amount := func(v float64) *float64 { return &v }
+	cfg := &provider.ProviderConfig{
+		ProviderName: "seedream",
+		Budget:       &provider.BudgetConfig{DailyLimit: 5, MonthlyLimit: 50},
+	}
+
+	tests := []struct {
+		name    string
+		setting *models.Setting
+		cfg     *provider.ProviderConfig
+		want    []string
+	}{
+		{name: "no budgets", setting: &models.Setting{}},
+		{name: "zero means unlimited", setting: &models.Setting{DailyBudget: amount(0), MonthlyBudget: amount(0)}},
+		{
+			name:    "global only",
+			setting: &models.Setting{DailyBudget: amount(10), MonthlyBudget: amount(100)},
+			want:    []string{"global//daily/10", "global//monthly/100"},
+		},
+		{
+			name:    "provider without budget",
+			setting: &models.Setting{MonthlyBudget: amount(100)},
+			cfg:     &provider.ProviderConfig{ProviderName: "other"},
+			want:    []string{"global//monthly/100"},
+		},
+		{
+			name:    "global and provider",
+			setting: &models.Setting{DailyBudget: amount(10)},
+			cfg:     cfg,
+			want:    []string{"global//daily/10", "provider/seedream/daily/5", "provider/seedream/monthly/50"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, usage := range budgetLimits(tt.setting, tt.cfg, now) {
+				got = append(got, fmt.Sprintf("%s/%s/%s/%g", usage.Scope, usage.Provider, usage.Window, usage.Limit))
+				_, wantReset := budgetWindowBounds(usage.Window, now)
+				if !usage.ResetAt.Equal(wantReset) {
+					t.Fatalf("%s %s ResetAt = %s, want %s", usage.Scope, usage.Window, usage.ResetAt, wantReset)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("budgetLimits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExceededBudget(t *testing.T) {
+	dayEnd := time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC)
+	monthEnd := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	daily := func(spent, limit float64) BudgetUsage {
+		return BudgetUsage{Scope: BudgetScopeGlobal, Window: BudgetWindowDaily, Spent: spent, Limit: limit, ResetAt: dayEnd}
+	}
+	monthly := func(provider string, spent, limit float64) BudgetUsage {
+		return BudgetUsage{Scope: BudgetScopeProvider, Provider: provider, Window: BudgetWindowMonthly, Spent: spent, Limit: limit, ResetAt: monthEnd}
+	}
+
+	tests := []struct {
+		name        string
+		usages      []BudgetUsage
+		cost        float64
+		wantReason  string
+		wantResetAt time.Time
+	}{
+		{name: "no budgets", cost: 1},
+		{name: "under limit", usages: []BudgetUsage{daily(2, 10)}, cost: 1},
+		{name: "exactly reaches limit", usages: []BudgetUsage{daily(7.5, 10)}, cost: 2.5},
+		{
+			name:        "just over limit",
+			usages:      []BudgetUsage{daily(7.5, 10)},
+			cost:        2.5001,
+			wantReason:  "global daily budget exceeded: spent 7.5000 + estimated 2.5001 > limit 10.0000",
+			wantResetAt: dayEnd,
+		},
+		{
+			name:        "already over limit",
+			usages:      []BudgetUsage{daily(12, 10)},
+			cost:        0.1,
+			wantReason:  "global daily budget exceeded: spent 12.0000 + estimated 0.1000 > limit 10.0000",
+			wantResetAt: dayEnd,
+		},
+		{
+			name:        "monthly provider budget",
+			usages:      []BudgetUsage{daily(0, 10), monthly("seedream", 49, 50)},
+			cost:        2,
+			wantReason:  "provider seedream monthly budget exceeded: spent 49.0000 + estimated 2.0000 > limit 50.0000",
+			wantResetAt: monthEnd,
+		},
+		{
+			name:        "waits for the latest reset",
+			usages:      []BudgetUsage{daily(9, 10), monthly("seedream", 49, 50)},
+			cost:        2,
+			wantReason:  "global daily budget exceeded: spent 9.0000 + estimated 2.0000 > limit 10.0000",
+			wantResetAt: monthEnd,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, resetAt := exceededBudget(tt.usages, tt.cost)
+			if reason != tt.wantReason || !resetAt.Equal(tt.wantResetAt) {
+				t.Fatalf("exceededBudget = %q, %s, want %q, %s", reason, resetAt, tt.wantReason, tt.wantResetAt)
+			}
+		})
+	}
+}
+
+func TestBudgetCheckRequired(t *testing.T) {
+	tests := []struct {
+		name     string
+		cost     float64
+		approved bool
+		want     bool
+	}{
+		{name: "priced task", cost: 0.2, want: true},
+		{name: "free task", cost: 0, want: false},
+		{name: "approved over budget", cost: 0.2, approved: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{BudgetApproved: tt.approved}
+			if got := budgetCheckRequired(task, tt.cost); got != tt.want {
+				t.Fatalf("budgetCheckRequired = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/provider"
+	"go.uber.org/zap"
+)
+
+const TypeCancelJob = "cancel:job"
+
+type CancelJobPayload struct {
+	TaskID uint   `json:"task_id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// CancelTask 将任务标记为 CANCELLED 并投递 cancel:job 取消供应商侧的任务。
+// 任务已结束时返回 false。笔记被删除时会取消其下的全部任务；评论按游标增量拉取，
+// 无法得知单条已拉取的评论被删除，此时需要运营人员通过 API 取消。
+func (w *Worker) CancelTask(taskID uint, reason string) (bool, error) {
+	if reason == "" {
+		reason = "cancelled"
+	}
+
+	cancelled, err := w.db.CancelTask(taskID, reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel task: %w", err)
+	}
+	if !cancelled {
+		return false, nil
+	}
+
+	if err := w.enqueueCancelJob(taskID, reason); err != nil {
+		return true, err
+	}
+
+	w.logger.Info("task cancelled", zap.Uint("task_id", taskID), zap.String("reason", reason))
+	return true, nil
+}
+
+func (w *Worker) enqueueCancelJob(taskID uint, reason string) error {
+	payload, _ := json.Marshal(CancelJobPayload{
+		TaskID: taskID,
+		Reason: reason,
+	})
+
+	_, err := w.redis.Enqueue(
+		asynq.NewTask(TypeCancelJob, payload, asynq.Queue("critical"), asynq.MaxRetry(5)),
+	)
+	if err != nil {
+		w.logger.Error("failed to enqueue cancel job task", zap.Error(err), zap.Uint("task_id", taskID))
+		return err
+	}
+	return nil
+}
+
+// HandleCancelJob 取消供应商侧的任务并释放并发槽位。
+// 供应商不支持取消时只释放槽位，供应商任务会自然结束。
+func (w *Worker) HandleCancelJob(ctx context.Context, t *asynq.Task) error {
+	var payload CancelJobPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		w.logger.Error("failed to unmarshal cancel job payload", zap.Error(err))
+		return err
+	}
+
+	task, err := w.db.GetTaskByID(payload.TaskID)
+	if err != nil {
+		w.logger.Error("failed to get task", zap.Error(err), zap.Uint("task_id", payload.TaskID))
+		return err
+	}
+
+	if task.Status != models.TaskStatusCancelled {
+		w.logger.Info("task no longer cancelled, skipping", zap.Uint("task_id", payload.TaskID), zap.String("status", string(task.Status)))
+		return nil
+	}
+
+	if task.ProviderName == nil || task.ProviderJobID == nil || *task.ProviderJobID == "" {
+		// 尚未提交到供应商
+		return nil
+	}
+	providerName := *task.ProviderName
+
+	providerCancelled := false
+	if lease, exists := w.acquireProvider(providerName); exists {
+		defer lease.Release()
+		if canceler, ok := lease.Provider.(provider.Canceler); ok {
+			err := canceler.Cancel(ctx, *task.ProviderJobID)
+			switch {
+			case err == nil:
+				providerCancelled = true
+			case errors.Is(err, provider.ErrCancelNotSupported):
+			default:
+				w.logger.Error("failed to cancel provider job", zap.Error(err),
+					zap.Uint("task_id", payload.TaskID),
+					zap.String("provider", providerName),
+				)
+				return err
+			}
+		}
+	}
+
+	w.releaseProviderCapacity(ctx, providerName, payload.TaskID)
+
+	auditPayload, _ := json.Marshal(map[string]interface{}{
+		"task_id":            payload.TaskID,
+		"provider":           providerName,
+		"provider_job_id":    *task.ProviderJobID,
+		"provider_cancelled": providerCancelled,
+		"reason":             payload.Reason,
+	})
+	w.db.CreateAuditLog(&models.AuditLog{
+		Level:       "INFO",
+		Event:       "task_cancelled",
+		PayloadJSON: string(auditPayload),
+	})
+
+	w.logger.Info("provider job cancelled",
+		zap.Uint("task_id", payload.TaskID),
+		zap.String("provider", providerName),
+		zap.Bool("provider_cancelled", providerCancelled),
+	)
+	return nil
+}
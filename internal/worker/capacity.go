@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/provider"
+	"go.uber.org/zap"
+)
+
+const (
+	// providerSlotTTL 需覆盖状态轮询的最长间隔，每次轮询都会续约
+	providerSlotTTL = 30 * time.Minute
+	// capacityRetryDelay 为并发槽位已满时重新入队的延迟
+	capacityRetryDelay = 15 * time.Second
+	minRateRetryDelay  = time.Second
+	// maxCapacityWait 为从第一次等待供应商容量起的最长等待时间，超过后任务标记为失败
+	maxCapacityWait = time.Hour
+)
+
+func providerSlotHolder(taskID uint) string {
+	return fmt.Sprintf("task_%d", taskID)
+}
+
+// reserveProviderCapacity 占用供应商的并发槽位并检查提交速率。
+// 返回值大于 0 表示暂时没有容量，任务应在该时长后重试。
+func (w *Worker) reserveProviderCapacity(ctx context.Context, cfg *provider.ProviderConfig, taskID uint) (time.Duration, error) {
+	if w.limiter == nil {
+		return 0, nil
+	}
+
+	holder := providerSlotHolder(taskID)
+	acquired, err := w.limiter.AcquireSlot(ctx, cfg.ProviderName, cfg.MaxConcurrentJobs, holder, providerSlotTTL)
+	if err != nil {
+		return 0, err
+	}
+	if !acquired {
+		w.logger.Info("provider concurrency limit reached",
+			zap.String("provider", cfg.ProviderName),
+			zap.Int("max_concurrent_jobs", cfg.MaxConcurrentJobs),
+			zap.Uint("task_id", taskID),
+		)
+		return capacityRetryDelay, nil
+	}
+
+	allowed, wait, err := w.limiter.Allow(ctx, cfg.ProviderName, cfg.SubmitQPS)
+	if err != nil {
+		w.releaseProviderCapacity(ctx, cfg.ProviderName, taskID)
+		return 0, err
+	}
+	if !allowed {
+		w.releaseProviderCapacity(ctx, cfg.ProviderName, taskID)
+		w.logger.Info("provider submit rate limited",
+			zap.String("provider", cfg.ProviderName),
+			zap.Float64("submit_qps", cfg.SubmitQPS),
+			zap.Uint("task_id", taskID),
+		)
+		if wait < minRateRetryDelay {
+			wait = minRateRetryDelay
+		}
+		return wait, nil
+	}
+
+	return 0, nil
+}
+
+func (w *Worker) refreshProviderCapacity(ctx context.Context, providerName string, taskID uint) {
+	if w.limiter == nil {
+		return
+	}
+	if _, err := w.limiter.RefreshSlot(ctx, providerName, providerSlotHolder(taskID), providerSlotTTL); err != nil {
+		w.logger.Warn("failed to refresh provider slot", zap.Error(err), zap.String("provider", providerName), zap.Uint("task_id", taskID))
+	}
+}
+
+func (w *Worker) releaseProviderCapacity(ctx context.Context, providerName string, taskID uint) {
+	if w.limiter == nil {
+		return
+	}
+	if err := w.limiter.ReleaseSlot(ctx, providerName, providerSlotHolder(taskID)); err != nil {
+		w.logger.Warn("failed to release provider slot", zap.Error(err), zap.String("provider", providerName), zap.Uint("task_id", taskID))
+	}
+}
+
+// capacityWaitExceeded 返回任务从第一次等待容量起是否已超过 maxCapacityWait
+func capacityWaitExceeded(payload SubmitJobPayload, now time.Time) bool {
+	if payload.CapacityWaitSince == 0 {
+		return false
+	}
+	return now.Sub(time.Unix(payload.CapacityWaitSince, 0)) >= maxCapacityWait
+}
+
+// requeueSubmitJob 在供应商没有容量时延迟重新入队。
+// 并发、限速与熔断的等待共用同一个起始时间，累计超过 maxCapacityWait 后将任务标记为失败。
+func (w *Worker) requeueSubmitJob(task *models.Task, payload SubmitJobPayload, providerName string, delay time.Duration) error {
+	now := time.Now()
+	if payload.CapacityWaitSince == 0 {
+		payload.CapacityWaitSince = now.Unix()
+	}
+	if capacityWaitExceeded(payload, now) {
+		waited := now.Sub(time.Unix(payload.CapacityWaitSince, 0)).Round(time.Second)
+		w.logger.Warn("gave up waiting for provider capacity",
+			zap.Uint("task_id", payload.TaskID),
+			zap.String("provider", providerName),
+			zap.Duration("waited", waited),
+		)
+		task.Error = new(string)
+		*task.Error = fmt.Sprintf("no capacity at provider %s after waiting %s", providerName, waited)
+		w.failTask(task)
+		return nil
+	}
+
+	data, _ := json.Marshal(payload)
+
+	_, err := w.redis.Enqueue(
+		asynq.NewTask(TypeSubmitJob, data, asynq.ProcessIn(delay), asynq.Queue("critical")),
+	)
+	if err != nil {
+		w.logger.Error("failed to requeue submit job task", zap.Error(err), zap.Uint("task_id", payload.TaskID))
+		return err
+	}
+
+	w.logger.Info("submit job deferred - waiting for provider capacity",
+		zap.Uint("task_id", payload.TaskID),
+		zap.String("provider", providerName),
+		zap.Duration("delay", delay),
+		zap.Time("waiting_since", time.Unix(payload.CapacityWaitSince, 0)),
+	)
+	return nil
+}
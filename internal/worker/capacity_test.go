@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCapacityWaitExceeded(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  bool
+	}{
+		{name: "first wait", want: false},
+		{name: "just started", since: now, want: false},
+		{name: "many short rate waits", since: now.Add(-5 * time.Minute), want: false},
+		{name: "just under deadline", since: now.Add(-maxCapacityWait + time.Second), want: false},
+		{name: "at deadline", since: now.Add(-maxCapacityWait), want: true},
+		{name: "past deadline", since: now.Add(-2 * maxCapacityWait), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := SubmitJobPayload{TaskID: 1}
+			if !tt.since.IsZero() {
+				payload.CapacityWaitSince = tt.since.Unix()
+			}
+			if got := capacityWaitExceeded(payload, now); got != tt.want {
+				t.Fatalf("capacityWaitExceeded = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
+	"go.uber.org/zap"
+)
+
+const TypeDiscoverNotes = "discover:notes"
+
+// discoveryMaxPages 限制每次发现最多翻页数，笔记按发布时间倒序返回，新笔记都在前几页
+const discoveryMaxPages = 10
+
+// EnqueuePolls 为 NoteTarget 与所有定时轮询的笔记投递 poll:comments 任务，返回投递数量；
+// 处于退避中的笔记由 HandlePollComments 跳过
+func (w *Worker) EnqueuePolls() (int, error) {
+	setting, err := w.db.GetSetting()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get settings: %w", err)
+	}
+	watched, err := w.db.ListWatchedNoteTargets()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list watched notes: %w", err)
+	}
+
+	var targets []string
+	seen := make(map[string]bool)
+	// NoteTarget 对应的笔记被停止轮询（如笔记已删除）时同样跳过
+	if note, err := w.db.GetNoteByTarget(setting.NoteTarget); err == nil && !note.Watched {
+		seen[setting.NoteTarget] = true
+	}
+	for _, target := range append([]string{setting.NoteTarget}, watched...) {
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+
+	enqueued := 0
+	for _, target := range targets {
+		payload, _ := json.Marshal(PollCommentsPayload{NoteTarget: target})
+		_, err := w.redis.Enqueue(
+			asynq.NewTask(TypePollComments, payload, asynq.Queue("critical")),
+		)
+		if err != nil {
+			return enqueued, fmt.Errorf("failed to enqueue poll task: %w", err)
+		}
+		enqueued++
+	}
+	return enqueued, nil
+}
+
+func userNoteTarget(note xhsconnector.UserNote) string {
+	if note.NoteURL != "" {
+		return note.NoteURL
+	}
+	return note.NoteID
+}
+
+// HandleDiscoverNotes 列出 WatchAccount 的笔记并登记新笔记，之后停止轮询过旧的自动发现笔记
+func (w *Worker) HandleDiscoverNotes(ctx context.Context, t *asynq.Task) error {
+	setting, err := w.db.GetSetting()
+	if err != nil {
+		w.logger.Error("failed to get settings", zap.Error(err))
+		return err
+	}
+	if setting.WatchAccount == nil || *setting.WatchAccount == "" {
+		return nil
+	}
+	account := *setting.WatchAccount
+
+	var cutoff time.Time
+	if setting.NoteMaxAgeDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -setting.NoteMaxAgeDays)
+	}
+
+	w.logger.Info("discovering notes", zap.String("account", account))
+
+	discovered := 0
+	cursor := ""
+	for page := 0; page < discoveryMaxPages; page++ {
+		result, err := w.connector.ListUserNotes(ctx, account, cursor)
+		if errors.Is(err, xhsconnector.ErrUnsupported) {
+			// 连接器不支持列出笔记（如 replay 模式），重试没有意义
+			w.logger.Warn("connector does not support note discovery", zap.String("account", account))
+			return nil
+		}
+		if err != nil {
+			w.logger.Error("failed to list user notes", zap.Error(err), zap.String("account", account))
+			// 平台错误等下个发现周期再试，立即重试只会加重风控
+			if xhsconnector.KindOf(err) != "" {
+				if errors.Is(err, xhsconnector.ErrLoginExpired) {
+					w.alertLoginExpired(account, err)
+				}
+				return nil
+			}
+			return err
+		}
+
+		reachedCutoff := false
+		for _, userNote := range result.Notes {
+			target := userNoteTarget(userNote)
+			if target == "" {
+				continue
+			}
+			if !cutoff.IsZero() && !userNote.PublishedAt.IsZero() && userNote.PublishedAt.Before(cutoff) {
+				reachedCutoff = true
+				continue
+			}
+
+			note := &models.Note{NoteTarget: target}
+			if userNote.Title != "" {
+				note.Title = &userNote.Title
+			}
+			if !userNote.PublishedAt.IsZero() {
+				publishedAt := userNote.PublishedAt
+				note.PublishedAt = &publishedAt
+			}
+			created, err := w.db.RegisterDiscoveredNote(note)
+			if err != nil {
+				w.logger.Error("failed to register note", zap.Error(err), zap.String("note_target", target))
+				continue
+			}
+			if created {
+				discovered++
+				w.logger.Info("note discovered", zap.String("note_target", target))
+			}
+		}
+
+		if reachedCutoff || !result.HasMore || result.NextCursor == "" || result.NextCursor == cursor {
+			break
+		}
+		cursor = result.NextCursor
+	}
+
+	var retired int64
+	if !cutoff.IsZero() {
+		retired, err = w.db.RetireDiscoveredNotes(cutoff)
+		if err != nil {
+			w.logger.Error("failed to retire notes", zap.Error(err))
+			return err
+		}
+	}
+
+	if discovered > 0 || retired > 0 {
+		auditPayload, _ := json.Marshal(map[string]interface{}{
+			"account":    account,
+			"discovered": discovered,
+			"retired":    retired,
+		})
+		w.db.CreateAuditLog(&models.AuditLog{
+			Level:       "INFO",
+			Event:       "notes_discovered",
+			PayloadJSON: string(auditPayload),
+		})
+	}
+
+	w.logger.Info("note discovery completed",
+		zap.String("account", account),
+		zap.Int("discovered", discovered),
+		zap.Int64("retired", retired),
+	)
+	return nil
+}
@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/mailer"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// emailTemplate 返回自定义的邮件模板，没有自定义或读取失败时返回 nil 使用内置模板
+func (w *Worker) emailTemplate(name, locale string) *mailer.Template {
+	tpl, err := w.db.GetEmailTemplate(name, locale)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			w.logger.Warn("failed to load email template, using default",
+				zap.Error(err), zap.String("name", name), zap.String("locale", locale))
+		}
+		return nil
+	}
+	return &mailer.Template{
+		Subject:  tpl.Subject,
+		HTMLBody: tpl.HTMLBody,
+		TextBody: tpl.TextBody,
+	}
+}
+
+// emailMessage 组装任务的邮件内容，按评论内容选择语言
+func (w *Worker) emailMessage(ctx context.Context, task *models.Task) mailer.Message {
+	view := *task
+	view.Artifacts = w.presignArtifacts(ctx, task.Artifacts)
+
+	msg := mailer.Message{
+		Locale:      mailer.LocaleZH,
+		RequestType: string(task.RequestType),
+		Links:       resultLinks(&view),
+		PreviewURL:  previewURL(&view),
+	}
+	if task.Prompt != nil {
+		msg.Prompt = *task.Prompt
+	}
+	if task.Error != nil {
+		msg.Error = *task.Error
+	}
+	if task.Comment != nil {
+		msg.Locale = mailer.DetectLocale(task.Comment.Content)
+		msg.NoteTarget = task.Comment.NoteTarget
+		note, err := w.db.GetNoteByTarget(task.Comment.NoteTarget)
+		if err == nil && note.Title != nil {
+			msg.NoteTitle = *note.Title
+		}
+	}
+	return msg
+}
+
+// presignArtifacts 按邮件链接有效期重新签发存储在 MinIO 中的产物地址，入库时的签名可能已接近过期；
+// 签发失败的产物保留原地址并不再视为自有链接
+func (w *Worker) presignArtifacts(ctx context.Context, artifacts []models.Artifact) []models.Artifact {
+	result := make([]models.Artifact, len(artifacts))
+	copy(result, artifacts)
+
+	expiry := int(w.mailer.LinkExpiry() / time.Second)
+	for i := range result {
+		if result[i].ObjectKey == nil {
+			continue
+		}
+		url, err := w.storage.GetPresignedURL(ctx, *result[i].ObjectKey, expiry)
+		if err != nil {
+			w.logger.Warn("failed to presign artifact", zap.Error(err), zap.String("object_key", *result[i].ObjectKey))
+			result[i].ObjectKey = nil
+			continue
+		}
+		result[i].URL = url
+	}
+	return result
+}
+
+// PreviewEmailTemplate 使用示例数据渲染邮件模板，tpl 为 nil 时渲染当前生效的模板（自定义或内置）
+func (w *Worker) PreviewEmailTemplate(name, locale string, tpl *mailer.Template) (*mailer.Rendered, error) {
+	if tpl == nil {
+		tpl = w.emailTemplate(name, locale)
+	}
+	return w.mailer.Preview(name, locale, tpl)
+}
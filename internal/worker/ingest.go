@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	IngestStatusCreated   = "created"
+	IngestStatusDuplicate = "duplicate"
+	IngestStatusFailed    = "failed"
+	// IngestStatusIgnored 为本账号发出的回复，不入库
+	IngestStatusIgnored = "ignored"
+)
+
+// IngestResult 为推送的单条评论的入库结果
+type IngestResult struct {
+	CommentUID string `json:"comment_uid"`
+	Status     string `json:"status"`
+	CommentID  uint   `json:"comment_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// IngestComments 入库合作方推送的一批评论，与轮询共用 ingestComment 去重并投递 process:comment 任务。
+// CommentID 作为 comment_uid；ParentCommentID 为被回复评论的 comment_uid，需已入库或在同一批中排在前面。
+func (w *Worker) IngestComments(noteTarget string, comments []xhsconnector.Comment) ([]IngestResult, error) {
+	if err := w.db.RegisterPushedNote(noteTarget); err != nil {
+		return nil, fmt.Errorf("failed to register note: %w", err)
+	}
+
+	results := make([]IngestResult, 0, len(comments))
+	for _, comment := range comments {
+		result := IngestResult{CommentUID: comment.CommentID}
+
+		if len(w.filterOwnReplies([]xhsconnector.Comment{comment})) == 0 {
+			result.Status = IngestStatusIgnored
+			results = append(results, result)
+			continue
+		}
+
+		var parent *models.Comment
+		if comment.ParentCommentID != "" {
+			p, err := w.db.GetCommentByUID(comment.ParentCommentID)
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				err = fmt.Errorf("parent comment %s not found", comment.ParentCommentID)
+			case err == nil && p.NoteTarget != noteTarget:
+				err = fmt.Errorf("parent comment %s belongs to another note", comment.ParentCommentID)
+			}
+			if err != nil {
+				result.Status = IngestStatusFailed
+				result.Error = err.Error()
+				results = append(results, result)
+				continue
+			}
+			parent = p
+		}
+
+		dbComment, created, err := w.ingestComment(noteTarget, comment, parent)
+		if err != nil {
+			w.logger.Error("failed to ingest pushed comment", zap.Error(err), zap.String("comment_uid", comment.CommentID))
+			result.Status = IngestStatusFailed
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.CommentID = dbComment.ID
+		result.Status = IngestStatusDuplicate
+		if created {
+			result.Status = IngestStatusCreated
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
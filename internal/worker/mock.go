@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
+)
+
+// ErrInjectionUnsupported 表示当前连接器不支持注入评论（非 Mock 模式）
+var ErrInjectionUnsupported = errors.New("connector does not support comment injection")
+
+// InjectComment 在 Mock 模式下即时为笔记添加一条评论，下次轮询时入库
+func (w *Worker) InjectComment(ctx context.Context, noteTarget string, comment xhsconnector.Comment) (*xhsconnector.Comment, error) {
+	injector, ok := w.connector.(xhsconnector.CommentInjector)
+	if !ok {
+		return nil, ErrInjectionUnsupported
+	}
+	injected, err := injector.InjectComment(ctx, noteTarget, comment)
+	if errors.Is(err, xhsconnector.ErrUnsupported) {
+		return nil, ErrInjectionUnsupported
+	}
+	return injected, err
+}
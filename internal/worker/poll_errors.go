@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/mailer"
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
+	"go.uber.org/zap"
+)
+
+const (
+	// pollBackoffMax 为单个笔记退避的上限
+	pollBackoffMax = time.Hour
+	// loginAlertInterval 登录过期告警的最小间隔，避免每个笔记每次轮询都告警
+	loginAlertInterval = time.Hour
+
+	eventLoginExpired = "connector_login_expired"
+	// loginAlertKey 在告警间隔内存在，多个 worker 通过 SETNX 争抢同一次告警
+	loginAlertKey = "alert:" + eventLoginExpired
+)
+
+// pollBackoff 返回连续失败 failures 次后的等待时长：平台给出 RetryAfter 时优先使用，
+// 否则从轮询间隔开始每次翻倍，不超过 pollBackoffMax
+func pollBackoff(interval time.Duration, failures int, retryAfter time.Duration) time.Duration {
+	backoff := retryAfter
+	if backoff <= 0 {
+		backoff = interval
+		for i := 1; i < failures && backoff < pollBackoffMax; i++ {
+			backoff *= 2
+		}
+	}
+	if backoff > pollBackoffMax {
+		backoff = pollBackoffMax
+	}
+	return backoff
+}
+
+// handlePollError 记录拉取失败并按平台错误类型处理：限流、验证码与登录过期时推迟该笔记的下次拉取，
+// 登录过期时告警，笔记被删除时停止轮询。已处理的错误返回 nil，避免 asynq 立即重试加重风控。
+func (w *Worker) handlePollError(note *models.Note, err error) error {
+	now := time.Now()
+	kind := xhsconnector.KindOf(err)
+
+	note.LastPolledAt = &now
+	note.LastError = new(string)
+	*note.LastError = err.Error()
+	note.LastErrorKind = nil
+	if kind != "" {
+		note.LastErrorKind = new(string)
+		*note.LastErrorKind = string(kind)
+	}
+	note.ConsecutiveFailures++
+
+	switch kind {
+	case xhsconnector.ErrorKindRateLimited, xhsconnector.ErrorKindCaptcha, xhsconnector.ErrorKindLoginExpired:
+		interval := 120 * time.Second
+		if setting, err := w.db.GetSetting(); err == nil && setting.PollingIntervalSec > 0 {
+			interval = time.Duration(setting.PollingIntervalSec) * time.Second
+		}
+		nextPollAt := now.Add(pollBackoff(interval, note.ConsecutiveFailures, xhsconnector.RetryAfterOf(err)))
+		note.NextPollAt = &nextPollAt
+		w.logger.Warn("backing off note",
+			zap.String("note_target", note.NoteTarget),
+			zap.String("kind", string(kind)),
+			zap.Int("consecutive_failures", note.ConsecutiveFailures),
+			zap.Time("next_poll_at", nextPollAt),
+		)
+	}
+
+	if err := w.db.UpdateNote(note); err != nil {
+		w.logger.Error("failed to update note", zap.Error(err))
+	}
+
+	switch kind {
+	case xhsconnector.ErrorKindNoteNotFound:
+		w.pauseNote(note, err)
+		return nil
+	case xhsconnector.ErrorKindLoginExpired:
+		w.alertLoginExpired(note.NoteTarget, err)
+		return nil
+	case xhsconnector.ErrorKindRateLimited, xhsconnector.ErrorKindCaptcha:
+		return nil
+	}
+	return err
+}
+
+// resetPollFailures 在拉取成功后清除失败计数与退避
+func resetPollFailures(note *models.Note) {
+	note.LastError = nil
+	note.LastErrorKind = nil
+	note.ConsecutiveFailures = 0
+	note.NextPollAt = nil
+}
+
+// pauseNote 停止轮询已删除的笔记并取消其评论产生的未结束任务，运营人员可在笔记列表中重新开启轮询
+func (w *Worker) pauseNote(note *models.Note, cause error) {
+	now := time.Now()
+	if err := w.db.UpdateNoteFields(note.ID, map[string]interface{}{
+		"watched":    false,
+		"retired_at": now,
+	}); err != nil {
+		w.logger.Error("failed to pause note", zap.Error(err), zap.String("note_target", note.NoteTarget))
+		return
+	}
+
+	w.logger.Warn("note deleted, polling paused", zap.String("note_target", note.NoteTarget))
+	auditPayload, _ := json.Marshal(map[string]interface{}{
+		"note_target": note.NoteTarget,
+		"error":       cause.Error(),
+	})
+	w.db.CreateAuditLog(&models.AuditLog{
+		Level:       "WARN",
+		Event:       "note_paused",
+		PayloadJSON: string(auditPayload),
+	})
+
+	w.cancelNoteTasks(note.NoteTarget)
+}
+
+// cancelNoteTasks 取消已删除笔记下的未结束任务，避免继续为已不存在的评论生成
+func (w *Worker) cancelNoteTasks(noteTarget string) {
+	taskIDs, err := w.db.ListUnfinishedTaskIDsByNote(noteTarget)
+	if err != nil {
+		w.logger.Error("failed to list tasks of deleted note", zap.Error(err), zap.String("note_target", noteTarget))
+		return
+	}
+	for _, taskID := range taskIDs {
+		if _, err := w.CancelTask(taskID, "source note deleted"); err != nil {
+			w.logger.Error("failed to cancel task of deleted note", zap.Error(err),
+				zap.Uint("task_id", taskID),
+				zap.String("note_target", noteTarget),
+			)
+		}
+	}
+}
+
+// claimLoginAlert 原子地占用本次告警，loginAlertInterval 内只有第一个调用方返回 true
+func (w *Worker) claimLoginAlert(ctx context.Context) (bool, error) {
+	claimed, err := w.rdb.SetNX(ctx, loginAlertKey, time.Now().Unix(), loginAlertInterval).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim login alert: %w", err)
+	}
+	return claimed, nil
+}
+
+// alertLoginExpired 在登录过期时写入审计日志并向 AlertEmail 发送告警，每 loginAlertInterval 最多一次
+func (w *Worker) alertLoginExpired(noteTarget string, cause error) {
+	claimed, err := w.claimLoginAlert(context.Background())
+	if err != nil {
+		w.logger.Error("failed to dedup login alert", zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	w.logger.Error("connector login expired, operator action required",
+		zap.String("note_target", noteTarget),
+		zap.Error(cause),
+	)
+	auditPayload, _ := json.Marshal(map[string]interface{}{
+		"note_target": noteTarget,
+		"error":       cause.Error(),
+	})
+	w.db.CreateAuditLog(&models.AuditLog{
+		Level:       "ERROR",
+		Event:       eventLoginExpired,
+		PayloadJSON: string(auditPayload),
+	})
+
+	setting, err := w.db.GetSetting()
+	if err != nil {
+		w.logger.Error("failed to get settings", zap.Error(err))
+		return
+	}
+	if setting.AlertEmail == nil || *setting.AlertEmail == "" {
+		return
+	}
+	err = w.mailer.Send(mailer.Email{
+		To:      *setting.AlertEmail,
+		Subject: "【告警】小红书登录已过期",
+		Body: fmt.Sprintf("拉取笔记 %s 时小红书登录已过期，评论轮询已暂停退避。请重新登录 MCP 服务端后等待轮询自动恢复。\n\n错误信息：%s\n时间：%s",
+			noteTarget, cause.Error(), time.Now().Format("2006-01-02 15:04:05")),
+	})
+	if err != nil {
+		w.logger.Error("failed to send login expired alert", zap.Error(err))
+	}
+}
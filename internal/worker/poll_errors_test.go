@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPollBackoff(t *testing.T) {
+	interval := 2 * time.Minute
+
+	tests := []struct {
+		name       string
+		failures   int
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{name: "first failure", failures: 1, want: interval},
+		{name: "doubles", failures: 2, want: 2 * interval},
+		{name: "keeps doubling", failures: 4, want: 8 * interval},
+		{name: "capped", failures: 6, want: pollBackoffMax},
+		{name: "many failures stay capped", failures: 1000, want: pollBackoffMax},
+		{name: "retry after wins", failures: 5, retryAfter: 45 * time.Second, want: 45 * time.Second},
+		{name: "retry after capped", failures: 1, retryAfter: 3 * time.Hour, want: pollBackoffMax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pollBackoff(interval, tt.failures, tt.retryAfter); got != tt.want {
+				t.Fatalf("pollBackoff = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimLoginAlert(t *testing.T) {
+	m := miniredis.RunT(t)
+	w := &Worker{rdb: redis.NewClient(&redis.Options{Addr: m.Addr()})}
+	ctx := context.Background()
+
+	// 多个 worker 同时遇到登录过期时只有一个发送告警
+	var claimed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := w.claimLoginAlert(ctx)
+			if err != nil {
+				t.Errorf("claimLoginAlert: %v", err)
+			}
+			if ok {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed.Load() != 1 {
+		t.Fatalf("%d concurrent claims succeeded, want 1", claimed.Load())
+	}
+
+	m.FastForward(loginAlertInterval - time.Second)
+	if ok, _ := w.claimLoginAlert(ctx); ok {
+		t.Fatal("claimed again within the alert interval")
+	}
+	m.FastForward(time.Second)
+	if ok, err := w.claimLoginAlert(ctx); err != nil || !ok {
+		t.Fatalf("claimLoginAlert after interval = %v, %v, want true", ok, err)
+	}
+
+	m.Close()
+	if _, err := w.claimLoginAlert(ctx); err == nil {
+		t.Fatal("claimLoginAlert succeeded without Redis")
+	}
+}
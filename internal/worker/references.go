@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	maxReferenceImageBytes = 20 << 20
+	// referenceURLExpiry 为提交给供应商的参考图地址有效期（秒）
+	referenceURLExpiry = 3600
+)
+
+var referenceHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// storeReferenceImages 将评论附图下载后转存到对象存储，返回可用的图片数量。
+// 平台图片地址通常带防盗链且会过期，供应商无法直接访问；已转存的图片会跳过。
+func (w *Worker) storeReferenceImages(ctx context.Context, comment *models.Comment) int {
+	stored := 0
+	for i := range comment.Images {
+		image := &comment.Images[i]
+		if image.ObjectKey != nil {
+			stored++
+			continue
+		}
+
+		data, contentType, err := downloadImage(ctx, image.SourceURL)
+		if err == nil {
+			objectKey := fmt.Sprintf("references/comment_%d_%d%s", comment.ID, image.SortOrder, imageExtension(contentType))
+			var url string
+			url, err = w.storage.Upload(ctx, objectKey, data, contentType)
+			if err == nil {
+				size := int64(len(data))
+				image.ObjectKey = &objectKey
+				image.URL = &url
+				image.MimeType = &contentType
+				image.SizeBytes = &size
+				image.Error = nil
+				stored++
+			}
+		}
+		if err != nil {
+			w.logger.Warn("failed to store reference image", zap.Error(err),
+				zap.Uint("comment_id", comment.ID),
+				zap.String("source_url", image.SourceURL),
+			)
+			errMsg := err.Error()
+			image.Error = &errMsg
+		}
+
+		if err := w.db.UpdateCommentImage(image); err != nil {
+			w.logger.Error("failed to update comment image", zap.Error(err), zap.Uint("comment_id", comment.ID))
+		}
+	}
+	return stored
+}
+
+// referenceImageURLs 为已转存的参考图生成供应商可访问的临时地址
+func (w *Worker) referenceImageURLs(ctx context.Context, images []models.CommentImage) []string {
+	var urls []string
+	for _, image := range images {
+		if image.ObjectKey == nil {
+			continue
+		}
+		url, err := w.storage.GetPresignedURL(ctx, *image.ObjectKey, referenceURLExpiry)
+		if err != nil {
+			w.logger.Warn("failed to presign reference image", zap.Error(err), zap.String("object_key", *image.ObjectKey))
+			continue
+		}
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+func downloadImage(ctx context.Context, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := referenceHTTPClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("image download returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReferenceImageBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > maxReferenceImageBytes {
+		return nil, "", fmt.Errorf("image exceeds %d bytes", maxReferenceImageBytes)
+	}
+
+	// 以内容嗅探为准，平台常返回 application/octet-stream
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("unsupported content type: %s", contentType)
+	}
+	return data, contentType, nil
+}
+
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ""
+	}
+}
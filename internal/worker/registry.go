@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xiaohongshu-image/internal/services/provider"
+	"go.uber.org/zap"
+)
+
+// providerResyncInterval 为与数据库对齐供应商配置的周期，用于兜底丢失的变更通知
+const providerResyncInterval = time.Minute
+
+// SyncProviders 从数据库加载供应商配置，配置变化时重建实例
+func (w *Worker) SyncProviders() error {
+	setting, err := w.db.GetSetting()
+	if err != nil {
+		return fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	reloaded, err := w.providers.Reload(setting.ProviderJSON)
+	if err != nil {
+		return fmt.Errorf("failed to reload providers: %w", err)
+	}
+	if reloaded {
+		w.logger.Info("providers reloaded", zap.Int("count", len(w.providers.Configs())))
+	}
+	return nil
+}
+
+// ValidateProviders 解析并试创建供应商配置中的所有供应商
+func (w *Worker) ValidateProviders(raw string) error {
+	return w.providers.Validate(raw)
+}
+
+// ReloadProviders 在设置保存后调用：重新加载本进程的供应商并通知其他进程
+func (w *Worker) ReloadProviders(ctx context.Context) error {
+	if err := w.SyncProviders(); err != nil {
+		return err
+	}
+	return w.providers.Publish(ctx)
+}
+
+// RunProviderReload 订阅供应商配置变更通知，并周期性与数据库对齐
+func (w *Worker) RunProviderReload(ctx context.Context) {
+	pubsub := w.providers.Subscribe(ctx)
+	defer pubsub.Close()
+
+	ticker := time.NewTicker(providerResyncInterval)
+	defer ticker.Stop()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-messages:
+		case <-ticker.C:
+		}
+
+		if err := w.SyncProviders(); err != nil {
+			w.logger.Error("failed to sync providers", zap.Error(err))
+		}
+	}
+}
+
+// acquireProvider 获取供应商实例；找不到时可能是本进程尚未收到变更通知，先同步一次再重试
+func (w *Worker) acquireProvider(name string) (*provider.Lease, bool) {
+	if lease, ok := w.providers.Acquire(name); ok {
+		return lease, true
+	}
+	if err := w.SyncProviders(); err != nil {
+		w.logger.Warn("failed to sync providers", zap.Error(err))
+	}
+	return w.providers.Acquire(name)
+}
@@ -0,0 +1,235 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const TypeReplyComment = "reply:comment"
+
+// replyMaxRetry 限制回复失败的重试次数，避免平台限流时反复回复
+const replyMaxRetry = 3
+
+var defaultReplyTemplates = map[models.ReplyStage]string{
+	models.ReplyStageReceived:     "收到～正在为你生成{{.RequestLabel}}，完成后会发送到 {{.Email}}",
+	models.ReplyStageMissingEmail: "想要生成的话，请在评论里留下邮箱，生成后会发送到你的邮箱哦",
+	models.ReplyStageSent:         "{{.RequestLabel}}已经发送到 {{.Email}} 啦，请查收～",
+}
+
+// ReplyTemplateData 为回复模板可用的字段，Email 已打码以免在公开评论区泄露
+type ReplyTemplateData struct {
+	UserName     string
+	RequestType  string
+	RequestLabel string
+	Email        string
+	TaskID       uint
+}
+
+type ReplyCommentPayload struct {
+	CommentID uint   `json:"comment_id"`
+	TaskID    uint   `json:"task_id,omitempty"`
+	Stage     string `json:"stage"`
+}
+
+// parseReplyTemplates 解析设置中的回复模板并与默认模板合并，模板为空字符串表示该阶段不回复
+func parseReplyTemplates(raw *string) (map[models.ReplyStage]*template.Template, error) {
+	texts := make(map[models.ReplyStage]string, len(defaultReplyTemplates))
+	for stage, text := range defaultReplyTemplates {
+		texts[stage] = text
+	}
+
+	if raw != nil && strings.TrimSpace(*raw) != "" {
+		var overrides map[string]string
+		if err := json.Unmarshal([]byte(*raw), &overrides); err != nil {
+			return nil, fmt.Errorf("invalid reply templates: %w", err)
+		}
+		for stage, text := range overrides {
+			if _, ok := defaultReplyTemplates[models.ReplyStage(stage)]; !ok {
+				return nil, fmt.Errorf("unknown reply stage: %s", stage)
+			}
+			texts[models.ReplyStage(stage)] = text
+		}
+	}
+
+	templates := make(map[models.ReplyStage]*template.Template, len(texts))
+	for stage, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		tmpl, err := template.New(string(stage)).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s reply template: %w", stage, err)
+		}
+		if err := tmpl.Execute(&bytes.Buffer{}, ReplyTemplateData{}); err != nil {
+			return nil, fmt.Errorf("invalid %s reply template: %w", stage, err)
+		}
+		templates[stage] = tmpl
+	}
+	return templates, nil
+}
+
+// ValidateReplyTemplates 校验回复模板 JSON
+func (w *Worker) ValidateReplyTemplates(raw string) error {
+	_, err := parseReplyTemplates(&raw)
+	return err
+}
+
+// maskEmail 保留邮箱首字符与域名，如 a***@example.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	local := []rune(email[:at])
+	return string(local[0]) + "***" + email[at:]
+}
+
+func requestLabel(requestType models.RequestType) string {
+	if requestType == models.RequestTypeVideo {
+		return "视频"
+	}
+	return "图片"
+}
+
+// enqueueReply 投递评论回复任务，是否回复由笔记的 ReplyEnabled 决定
+func (w *Worker) enqueueReply(commentID, taskID uint, stage models.ReplyStage) {
+	payload, _ := json.Marshal(ReplyCommentPayload{
+		CommentID: commentID,
+		TaskID:    taskID,
+		Stage:     string(stage),
+	})
+	_, err := w.redis.Enqueue(
+		asynq.NewTask(TypeReplyComment, payload, asynq.Queue("default"), asynq.MaxRetry(replyMaxRetry)),
+	)
+	if err != nil {
+		w.logger.Error("failed to enqueue reply comment task", zap.Error(err), zap.Uint("comment_id", commentID))
+	}
+}
+
+func (w *Worker) HandleReplyComment(ctx context.Context, t *asynq.Task) error {
+	var payload ReplyCommentPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		w.logger.Error("failed to unmarshal reply comment payload", zap.Error(err))
+		return err
+	}
+	stage := models.ReplyStage(payload.Stage)
+
+	comment, err := w.db.GetCommentByID(payload.CommentID)
+	if err != nil {
+		w.logger.Error("failed to get comment", zap.Error(err), zap.Uint("comment_id", payload.CommentID))
+		return err
+	}
+
+	note, err := w.db.GetNoteByTarget(comment.NoteTarget)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		w.logger.Error("failed to get note", zap.Error(err), zap.String("note_target", comment.NoteTarget))
+		return err
+	}
+	if note == nil || !note.ReplyEnabled {
+		w.logger.Info("replies disabled for note, skipping", zap.String("note_target", comment.NoteTarget))
+		return nil
+	}
+
+	reply, err := w.db.GetCommentReply(comment.ID, stage)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		w.logger.Error("failed to get comment reply", zap.Error(err), zap.Uint("comment_id", comment.ID))
+		return err
+	}
+	if reply != nil && reply.Status == models.DeliveryStatusSent {
+		w.logger.Info("comment already replied", zap.Uint("comment_id", comment.ID), zap.String("stage", payload.Stage))
+		return nil
+	}
+
+	setting, err := w.db.GetSetting()
+	if err != nil {
+		w.logger.Error("failed to get settings", zap.Error(err))
+		return err
+	}
+	templates, err := parseReplyTemplates(setting.ReplyTemplates)
+	if err != nil {
+		w.logger.Error("failed to parse reply templates", zap.Error(err))
+		return nil
+	}
+	tmpl, ok := templates[stage]
+	if !ok {
+		w.logger.Info("no reply template for stage, skipping", zap.String("stage", payload.Stage))
+		return nil
+	}
+
+	data := ReplyTemplateData{TaskID: payload.TaskID}
+	if comment.UserName != nil {
+		data.UserName = *comment.UserName
+	}
+	if payload.TaskID != 0 {
+		task, err := w.db.GetTaskByID(payload.TaskID)
+		if err != nil {
+			w.logger.Error("failed to get task", zap.Error(err), zap.Uint("task_id", payload.TaskID))
+			return err
+		}
+		data.RequestType = string(task.RequestType)
+		data.RequestLabel = requestLabel(task.RequestType)
+		if task.Email != nil {
+			data.Email = maskEmail(*task.Email)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		w.logger.Error("failed to render reply template", zap.Error(err), zap.String("stage", payload.Stage))
+		return nil
+	}
+	content := strings.TrimSpace(buf.String())
+	if content == "" {
+		return nil
+	}
+
+	if reply == nil {
+		reply = &models.CommentReply{CommentID: comment.ID, Stage: stage}
+	}
+	if payload.TaskID != 0 {
+		reply.TaskID = &payload.TaskID
+	}
+	reply.Content = content
+	reply.Attempts++
+
+	result, err := w.connector.ReplyComment(ctx, comment.NoteTarget, comment.CommentUID, content)
+	if err != nil {
+		w.logger.Error("failed to reply comment", zap.Error(err), zap.Uint("comment_id", comment.ID), zap.String("stage", payload.Stage))
+		reply.Status = models.DeliveryStatusFailed
+		reply.Error = new(string)
+		*reply.Error = err.Error()
+		w.db.SaveCommentReply(reply)
+		// 连接器不支持回复时重试没有意义
+		if errors.Is(err, xhsconnector.ErrUnsupported) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	reply.Status = models.DeliveryStatusSent
+	reply.Error = nil
+	reply.SentAt = &now
+	if result.ReplyID != "" {
+		reply.ReplyID = &result.ReplyID
+	}
+	if err := w.db.SaveCommentReply(reply); err != nil {
+		w.logger.Error("failed to save comment reply", zap.Error(err), zap.Uint("comment_id", comment.ID))
+		return nil
+	}
+
+	w.logger.Info("comment replied", zap.Uint("comment_id", comment.ID), zap.String("stage", payload.Stage))
+	return nil
+}
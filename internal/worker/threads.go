@@ -0,0 +1,214 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// threadPollWindow 一级评论入库后在该时长内持续拉取其回复
+	threadPollWindow = 48 * time.Hour
+	// threadsPerPoll 每次轮询额外拉取回复的一级评论数，按最久未拉取的优先
+	threadsPerPoll = 20
+	// subCommentMaxPages 限制每个一级评论每次最多翻页数，剩余回复留到下次轮询
+	subCommentMaxPages = 5
+)
+
+// ingestComment 入库一条评论并投递 process:comment 任务，评论已存在时返回已有记录。
+// parent 非空时为楼中楼回复，层级优先使用平台返回的值；带有平台用户 ID 的评论关联到评论用户。
+func (w *Worker) ingestComment(noteTarget string, comment xhsconnector.Comment, parent *models.Comment) (*models.Comment, bool, error) {
+	commentUID := comment.CommentID
+	if commentUID == "" {
+		commentUID = w.generateCommentUID(comment)
+	}
+
+	existing, err := w.db.GetCommentByUID(commentUID)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to check comment existence: %w", err)
+	}
+
+	dbComment := &models.Comment{
+		NoteTarget:       noteTarget,
+		CommentUID:       commentUID,
+		UserName:         &comment.UserName,
+		Content:          comment.Content,
+		CommentCreatedAt: &comment.CommentCreatedAt,
+		IngestedAt:       time.Now(),
+		SubCommentCount:  comment.SubCommentCount,
+	}
+	if parent != nil {
+		dbComment.ParentID = &parent.ID
+		dbComment.Depth = comment.Depth
+		if dbComment.Depth <= 0 {
+			dbComment.Depth = parent.Depth + 1
+		}
+	}
+	if comment.UserID != "" {
+		commenter, err := w.db.UpsertCommenter(comment.UserID, comment.UserName, comment.UserAvatar, comment.CommentCreatedAt)
+		if err != nil {
+			w.logger.Error("failed to upsert commenter", zap.Error(err), zap.String("user_id", comment.UserID))
+		} else {
+			dbComment.CommenterID = &commenter.ID
+		}
+	}
+	for i, imageURL := range comment.ImageURLs {
+		dbComment.Images = append(dbComment.Images, models.CommentImage{
+			SourceURL: imageURL,
+			SortOrder: i,
+		})
+	}
+
+	if err := w.db.CreateComment(dbComment); err != nil {
+		return nil, false, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	taskPayload, _ := json.Marshal(ProcessCommentPayload{
+		CommentID:  dbComment.ID,
+		CommentUID: commentUID,
+		Content:    comment.Content,
+		NoteTarget: noteTarget,
+	})
+	_, err = w.redis.Enqueue(
+		asynq.NewTask(TypeProcessComment, taskPayload, asynq.Queue("default")),
+	)
+	if err != nil {
+		w.logger.Error("failed to enqueue process comment task", zap.Error(err))
+	}
+
+	return dbComment, true, nil
+}
+
+// filterOwnReplies 去掉本账号发出的回复：评论 ID 为已记录的回复 ID，或评论用户为连接器登录的账号。
+// 回复内容含有生成关键词，不过滤会被当作缺少邮箱的生成请求再次回复。
+func filterOwnReplies(comments []xhsconnector.Comment, selfUserID string, isReplyID func(string) bool) []xhsconnector.Comment {
+	filtered := make([]xhsconnector.Comment, 0, len(comments))
+	for _, comment := range comments {
+		if selfUserID != "" && comment.UserID == selfUserID {
+			continue
+		}
+		if comment.CommentID != "" && isReplyID(comment.CommentID) {
+			continue
+		}
+		filtered = append(filtered, comment)
+	}
+	return filtered
+}
+
+func (w *Worker) filterOwnReplies(comments []xhsconnector.Comment) []xhsconnector.Comment {
+	selfUserID := ""
+	if self, ok := w.connector.(xhsconnector.SelfIdentifier); ok {
+		selfUserID = self.SelfUserID()
+	}
+	return filterOwnReplies(comments, selfUserID, func(commentID string) bool {
+		own, err := w.db.IsOwnReplyID(commentID)
+		if err != nil {
+			w.logger.Error("failed to check own reply", zap.Error(err), zap.String("comment_uid", commentID))
+		}
+		return own
+	})
+}
+
+// pollThreads 拉取本页有回复的一级评论及近期一级评论的楼中楼回复，返回新入库的回复数
+func (w *Worker) pollThreads(ctx context.Context, noteTarget string, pageThreads []*models.Comment) int {
+	recent, err := w.db.ListThreadsToPoll(noteTarget, time.Now().Add(-threadPollWindow), threadsPerPoll)
+	if err != nil {
+		w.logger.Error("failed to list threads to poll", zap.Error(err), zap.String("note_target", noteTarget))
+	}
+
+	threads := pageThreads
+	seen := make(map[uint]bool, len(pageThreads))
+	for _, thread := range pageThreads {
+		seen[thread.ID] = true
+	}
+	for i := range recent {
+		if !seen[recent[i].ID] {
+			seen[recent[i].ID] = true
+			threads = append(threads, &recent[i])
+		}
+	}
+
+	newReplies := 0
+	for _, root := range threads {
+		created, err := w.pollSubComments(ctx, noteTarget, root)
+		newReplies += created
+		if errors.Is(err, xhsconnector.ErrUnsupported) {
+			w.logger.Debug("connector does not support sub comments", zap.Error(err))
+			break
+		}
+		// 平台错误（限流、验证码等）时本次不再拉取其余回复，留到下次轮询
+		if xhsconnector.KindOf(err) != "" {
+			w.logger.Warn("stopped polling sub comments", zap.Error(err), zap.String("note_target", noteTarget))
+			if errors.Is(err, xhsconnector.ErrLoginExpired) {
+				w.alertLoginExpired(noteTarget, err)
+			}
+			break
+		}
+		if err != nil {
+			w.logger.Error("failed to poll sub comments", zap.Error(err), zap.Uint("comment_id", root.ID))
+		}
+	}
+	return newReplies
+}
+
+// pollSubComments 从一级评论保存的游标继续拉取回复并更新游标，返回新入库的回复数
+func (w *Worker) pollSubComments(ctx context.Context, noteTarget string, root *models.Comment) (int, error) {
+	cursor := ""
+	if root.SubCursor != nil {
+		cursor = *root.SubCursor
+	}
+
+	created := 0
+	for page := 0; page < subCommentMaxPages; page++ {
+		result, err := w.connector.ListSubComments(ctx, noteTarget, root.CommentUID, cursor)
+		if err != nil {
+			return created, err
+		}
+
+		for _, comment := range w.filterOwnReplies(result.Comments) {
+			parent := root
+			if comment.ParentCommentID != "" && comment.ParentCommentID != root.CommentUID {
+				if p, err := w.db.GetCommentByUID(comment.ParentCommentID); err == nil {
+					parent = p
+				}
+			}
+			_, isNew, err := w.ingestComment(noteTarget, comment, parent)
+			if err != nil {
+				w.logger.Error("failed to ingest sub comment", zap.Error(err), zap.Uint("root_id", root.ID))
+				continue
+			}
+			if isNew {
+				created++
+			}
+		}
+
+		if !result.HasMore || result.NextCursor == "" || result.NextCursor == cursor {
+			if result.NextCursor != "" {
+				cursor = result.NextCursor
+			}
+			break
+		}
+		cursor = result.NextCursor
+	}
+
+	now := time.Now()
+	root.SubPolledAt = &now
+	if cursor != "" {
+		root.SubCursor = &cursor
+	}
+	if err := w.db.UpdateCommentThread(root); err != nil {
+		return created, fmt.Errorf("failed to update comment thread: %w", err)
+	}
+	return created, nil
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xiaohongshu-image/internal/services/xhsconnector"
+)
+
+func TestFilterOwnRepliesDropsMockReplies(t *testing.T) {
+	ctx := context.Background()
+	connector := xhsconnector.NewMockConnector()
+
+	// 默认 received 模板渲染后的回复，含有生成关键词且邮箱已打码
+	content := "收到～正在为你生成图片，完成后会发送到 s***@example.com"
+	topReply, err := connector.ReplyComment(ctx, "note", "mock_001", content)
+	if err != nil {
+		t.Fatalf("ReplyComment: %v", err)
+	}
+	nestedReply, err := connector.ReplyComment(ctx, "note", "mock_002_r1", content)
+	if err != nil {
+		t.Fatalf("ReplyComment: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		root       string
+		replyID    string
+		parentID   string
+		depth      int
+		selfUserID string
+		replyIDs   map[string]bool
+	}{
+		{name: "top-level by reply id", root: "mock_001", replyID: topReply.ReplyID, parentID: "mock_001", depth: 1,
+			replyIDs: map[string]bool{topReply.ReplyID: true}},
+		{name: "top-level by self user", root: "mock_001", replyID: topReply.ReplyID, parentID: "mock_001", depth: 1,
+			selfUserID: connector.SelfUserID()},
+		{name: "nested by reply id", root: "mock_002", replyID: nestedReply.ReplyID, parentID: "mock_002_r1", depth: 2,
+			replyIDs: map[string]bool{nestedReply.ReplyID: true}},
+		{name: "nested by self user", root: "mock_002", replyID: nestedReply.ReplyID, parentID: "mock_002_r1", depth: 2,
+			selfUserID: connector.SelfUserID()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := connector.ListSubComments(ctx, "note", tt.root, "")
+			if err != nil {
+				t.Fatalf("ListSubComments: %v", err)
+			}
+
+			var found *xhsconnector.Comment
+			for i := range result.Comments {
+				if result.Comments[i].CommentID == tt.replyID {
+					found = &result.Comments[i]
+				}
+			}
+			if found == nil {
+				t.Fatalf("reply %s not listed under %s", tt.replyID, tt.root)
+			}
+			if found.UserID != xhsconnector.MockSelfUserID || found.ParentCommentID != tt.parentID || found.Depth != tt.depth {
+				t.Fatalf("unexpected reply %+v", *found)
+			}
+
+			filtered := filterOwnReplies(result.Comments, tt.selfUserID, func(id string) bool {
+				return tt.replyIDs[id]
+			})
+			if len(filtered) != len(result.Comments)-1 {
+				t.Fatalf("filtered %d of %d comments, want exactly the reply removed", len(result.Comments)-len(filtered), len(result.Comments))
+			}
+			for _, comment := range filtered {
+				if comment.CommentID == tt.replyID {
+					t.Fatalf("own reply %s was not filtered", tt.replyID)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterOwnRepliesKeepsOtherUsers(t *testing.T) {
+	comments := []xhsconnector.Comment{
+		{CommentID: "c1", UserID: "u1", Content: "帮我生成图片 a@example.com"},
+		{CommentID: "c2", Content: "没有用户 ID 的评论"},
+	}
+	filtered := filterOwnReplies(comments, xhsconnector.MockSelfUserID, func(string) bool { return false })
+	if len(filtered) != len(comments) {
+		t.Fatalf("got %d comments, want %d", len(filtered), len(comments))
+	}
+}
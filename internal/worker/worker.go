@@ -7,11 +7,14 @@ import (
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 	"github.com/xiaohongshu-image/internal/db"
 	"github.com/xiaohongshu-image/internal/models"
+	"github.com/xiaohongshu-image/internal/services/breaker"
 	"github.com/xiaohongshu-image/internal/services/intent"
 	"github.com/xiaohongshu-image/internal/services/mailer"
 	"github.com/xiaohongshu-image/internal/services/provider"
+	"github.com/xiaohongshu-image/internal/services/ratelimit"
 	"github.com/xiaohongshu-image/internal/services/xhsconnector"
 	"go.uber.org/zap"
 )
@@ -27,32 +30,41 @@ const (
 type Worker struct {
 	db        *db.Database
 	redis     *asynq.Client
+	rdb       *redis.Client
 	connector xhsconnector.Connector
 	intentSvc *intent.Service
-	providers map[string]provider.Provider
+	providers *provider.Registry
 	storage   provider.Storage
 	mailer    *mailer.Service
+	limiter   *ratelimit.Limiter
+	breaker   *breaker.Breaker
 	logger    *zap.Logger
 }
 
 func NewWorker(
 	db *db.Database,
 	redis *asynq.Client,
+	rdb *redis.Client,
 	connector xhsconnector.Connector,
 	intentSvc *intent.Service,
-	providers map[string]provider.Provider,
+	providers *provider.Registry,
 	storage provider.Storage,
 	mailer *mailer.Service,
+	limiter *ratelimit.Limiter,
+	breaker *breaker.Breaker,
 	logger *zap.Logger,
 ) *Worker {
 	return &Worker{
 		db:        db,
 		redis:     redis,
+		rdb:       rdb,
 		connector: connector,
 		intentSvc: intentSvc,
 		providers: providers,
 		storage:   storage,
 		mailer:    mailer,
+		limiter:   limiter,
+		breaker:   breaker,
 		logger:    logger,
 	}
 }
@@ -63,6 +75,9 @@ func (w *Worker) RegisterHandlers(mux *asynq.ServeMux) {
 	mux.HandleFunc(TypeSubmitJob, w.HandleSubmitJob)
 	mux.HandleFunc(TypeCheckStatus, w.HandleCheckStatus)
 	mux.HandleFunc(TypeSendEmail, w.HandleSendEmail)
+	mux.HandleFunc(TypeCancelJob, w.HandleCancelJob)
+	mux.HandleFunc(TypeReplyComment, w.HandleReplyComment)
+	mux.HandleFunc(TypeDiscoverNotes, w.HandleDiscoverNotes)
 }
 
 type PollCommentsPayload struct {
@@ -96,6 +111,14 @@ func (w *Worker) HandlePollComments(ctx context.Context, t *asynq.Task) error {
 	}
 	defer w.releaseLock(ctx, lockKey)
 
+	if note.NextPollAt != nil && time.Now().Before(*note.NextPollAt) {
+		w.logger.Info("note backing off, skipping poll",
+			zap.String("note_target", payload.NoteTarget),
+			zap.Time("next_poll_at", *note.NextPollAt),
+		)
+		return nil
+	}
+
 	cursor := ""
 	if note.LastCursor != nil {
 		cursor = *note.LastCursor
@@ -103,61 +126,24 @@ func (w *Worker) HandlePollComments(ctx context.Context, t *asynq.Task) error {
 
 	result, err := w.connector.ListComments(ctx, payload.NoteTarget, cursor)
 	if err != nil {
-		w.logger.Error("failed to list comments", zap.Error(err))
-		now := time.Now()
-		note.LastError = new(string)
-		*note.LastError = err.Error()
-		note.LastPolledAt = &now
-		w.db.UpdateNote(note)
-		return err
+		w.logger.Error("failed to list comments", zap.Error(err), zap.String("note_target", payload.NoteTarget))
+		return w.handlePollError(note, err)
 	}
 
 	newCommentsCount := 0
+	var threads []*models.Comment
 	for _, comment := range result.Comments {
-		commentUID := comment.CommentID
-		if commentUID == "" {
-			commentUID = w.generateCommentUID(comment)
-		}
-
-		exists, err := w.db.CommentExists(commentUID)
+		dbComment, created, err := w.ingestComment(payload.NoteTarget, comment, nil)
 		if err != nil {
-			w.logger.Error("failed to check comment existence", zap.Error(err))
+			w.logger.Error("failed to ingest comment", zap.Error(err))
 			continue
 		}
-
-		if exists {
-			continue
+		if created {
+			newCommentsCount++
 		}
-
-		now := time.Now()
-		dbComment := &models.Comment{
-			NoteTarget:       payload.NoteTarget,
-			CommentUID:       commentUID,
-			UserName:         &comment.UserName,
-			Content:          comment.Content,
-			CommentCreatedAt: &comment.CommentCreatedAt,
-			IngestedAt:       now,
-		}
-
-		if err := w.db.CreateComment(dbComment); err != nil {
-			w.logger.Error("failed to create comment", zap.Error(err))
-			continue
-		}
-
-		newCommentsCount++
-
-		taskPayload, _ := json.Marshal(ProcessCommentPayload{
-			CommentID:  dbComment.ID,
-			CommentUID: commentUID,
-			Content:    comment.Content,
-			NoteTarget: payload.NoteTarget,
-		})
-
-		_, err = w.redis.Enqueue(
-			asynq.NewTask(TypeProcessComment, taskPayload, asynq.Queue("default")),
-		)
-		if err != nil {
-			w.logger.Error("failed to enqueue process comment task", zap.Error(err))
+		if comment.SubCommentCount > 0 {
+			dbComment.SubCommentCount = comment.SubCommentCount
+			threads = append(threads, dbComment)
 		}
 	}
 
@@ -166,14 +152,17 @@ func (w *Worker) HandlePollComments(ctx context.Context, t *asynq.Task) error {
 	if result.NextCursor != "" {
 		note.LastCursor = &result.NextCursor
 	}
-	note.LastError = nil
+	resetPollFailures(note)
 	if err := w.db.UpdateNote(note); err != nil {
 		w.logger.Error("failed to update note", zap.Error(err))
 	}
 
+	newRepliesCount := w.pollThreads(ctx, payload.NoteTarget, threads)
+
 	w.logger.Info("poll completed",
 		zap.String("note_target", payload.NoteTarget),
 		zap.Int("new_comments", newCommentsCount),
+		zap.Int("new_replies", newRepliesCount),
 	)
 
 	return nil
@@ -211,7 +200,38 @@ func (w *Worker) HandleProcessComment(ctx context.Context, t *asynq.Task) error
 		return err
 	}
 
-	intentResult, err := w.intentSvc.ExtractIntent(ctx, payload.Content, setting.IntentThreshold)
+	comment, err := w.db.GetCommentByID(payload.CommentID)
+	if err != nil {
+		w.logger.Error("failed to get comment", zap.Error(err), zap.String("comment_uid", payload.CommentUID))
+		return err
+	}
+
+	if comment.CommenterID != nil {
+		commenter, err := w.db.GetCommenterByID(*comment.CommenterID)
+		if err != nil {
+			w.logger.Error("failed to get commenter", zap.Error(err), zap.String("comment_uid", payload.CommentUID))
+			return err
+		}
+		if commenter.Blocked {
+			w.logger.Info("comment skipped - commenter blocked",
+				zap.String("comment_uid", payload.CommentUID),
+				zap.String("user_id", commenter.UserID),
+			)
+			auditPayload, _ := json.Marshal(map[string]interface{}{
+				"comment_uid":  payload.CommentUID,
+				"commenter_id": commenter.ID,
+				"user_id":      commenter.UserID,
+			})
+			w.db.CreateAuditLog(&models.AuditLog{
+				Level:       "INFO",
+				Event:       "commenter_blocked",
+				PayloadJSON: string(auditPayload),
+			})
+			return nil
+		}
+	}
+
+	intentResult, err := w.intentSvc.ExtractIntent(ctx, payload.Content, len(comment.Images), setting.IntentThreshold)
 	if err != nil {
 		w.logger.Error("failed to extract intent", zap.Error(err), zap.String("comment_uid", payload.CommentUID))
 
@@ -226,6 +246,9 @@ func (w *Worker) HandleProcessComment(ctx context.Context, t *asynq.Task) error
 
 	if !intentResult.HasRequest {
 		w.logger.Info("comment skipped - no clear intent", zap.String("comment_uid", payload.CommentUID), zap.String("reason", intentResult.Reason))
+		if intentResult.MissingEmail {
+			w.enqueueReply(payload.CommentID, 0, models.ReplyStageMissingEmail)
+		}
 		return nil
 	}
 
@@ -245,6 +268,25 @@ func (w *Worker) HandleProcessComment(ctx context.Context, t *asynq.Task) error
 
 	w.logger.Info("task created", zap.Uint("task_id", task.ID), zap.String("comment_uid", payload.CommentUID))
 
+	if comment.CommenterID != nil {
+		if err := w.db.RecordCommenterRequest(*comment.CommenterID, task.Email, task.CreatedAt); err != nil {
+			w.logger.Error("failed to record commenter request", zap.Error(err), zap.Uint("task_id", task.ID))
+		}
+	}
+
+	if task.RequestType == models.RequestTypeEdit {
+		if stored := w.storeReferenceImages(ctx, comment); stored == 0 {
+			task.Status = models.TaskStatusFailed
+			task.Error = new(string)
+			*task.Error = "failed to store reference images"
+			w.failTask(task)
+			w.logger.Warn("no reference image stored", zap.Uint("task_id", task.ID))
+			return nil
+		}
+	}
+
+	w.enqueueReply(payload.CommentID, task.ID, models.ReplyStageReceived)
+
 	submitPayload, _ := json.Marshal(SubmitJobPayload{
 		TaskID:      task.ID,
 		RequestType: string(task.RequestType),
@@ -265,6 +307,8 @@ type SubmitJobPayload struct {
 	TaskID      uint   `json:"task_id"`
 	RequestType string `json:"request_type"`
 	Prompt      string `json:"prompt"`
+	// CapacityWaitSince 为第一次等待供应商容量的 Unix 时间（秒）
+	CapacityWaitSince int64 `json:"capacity_wait_since,omitempty"`
 }
 
 func (w *Worker) HandleSubmitJob(ctx context.Context, t *asynq.Task) error {
@@ -282,59 +326,157 @@ func (w *Worker) HandleSubmitJob(ctx context.Context, t *asynq.Task) error {
 		return err
 	}
 
-	setting, err := w.db.GetSetting()
-	if err != nil {
-		w.logger.Error("failed to get settings", zap.Error(err))
-		return err
-	}
-
-	var providers []provider.ProviderConfig
-	if err := json.Unmarshal([]byte(setting.ProviderJSON), &providers); err != nil {
-		w.logger.Error("failed to unmarshal provider configs", zap.Error(err))
-		return err
+	switch task.Status {
+	case models.TaskStatusCancelled:
+		w.logger.Info("task cancelled, skipping submit", zap.Uint("task_id", payload.TaskID))
+		return nil
+	case models.TaskStatusSubmitted, models.TaskStatusRunning, models.TaskStatusSucceeded, models.TaskStatusEmailed:
+		// 预算批准与窗口重置可能各投递一次提交任务
+		w.logger.Info("task already submitted, skipping submit", zap.Uint("task_id", payload.TaskID))
+		return nil
+	case models.TaskStatusFailed:
+		w.logger.Info("task already failed, skipping submit", zap.Uint("task_id", payload.TaskID))
+		return nil
 	}
 
+	providers := w.providers.Configs()
 	if len(providers) == 0 {
 		err := fmt.Errorf("no providers configured")
 		w.logger.Error("no providers configured", zap.Error(err))
 		task.Status = models.TaskStatusFailed
 		task.Error = new(string)
 		*task.Error = err.Error()
-		w.db.UpdateTask(task)
-		return err
+		w.failTask(task)
+		// 任务已标记为失败，重试只会重复失败
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
 	}
 
-	providerName := providers[0].ProviderName
-	prov, exists := w.providers[providerName]
-	if !exists {
-		err := fmt.Errorf("provider not found: %s", providerName)
-		w.logger.Error("provider not found", zap.String("provider", providerName))
+	req := provider.UnifiedGenRequest{
+		RequestID: fmt.Sprintf("task_%d", payload.TaskID),
+		Type:      provider.RequestType(payload.RequestType),
+		Prompt:    payload.Prompt,
+		Task: &provider.TaskContext{
+			ID:        task.ID,
+			CommentID: task.CommentID,
+		},
+	}
+	if task.Comment != nil {
+		req.Task.NoteTarget = task.Comment.NoteTarget
+		if task.RequestType == models.RequestTypeEdit {
+			req.ReferenceImages = w.referenceImageURLs(ctx, task.Comment.Images)
+		}
+	}
+	if task.Email != nil {
+		req.Task.Email = *task.Email
+	}
+	if task.RequestType == models.RequestTypeEdit && len(req.ReferenceImages) == 0 {
+		err := fmt.Errorf("no reference images available")
+		w.logger.Error("no reference images available", zap.Uint("task_id", payload.TaskID))
+		task.Status = models.TaskStatusFailed
+		task.Error = new(string)
+		*task.Error = err.Error()
+		w.failTask(task)
+		return nil
+	}
+
+	choice, wait, err := w.selectProvider(ctx, providers, req)
+	if err != nil {
+		w.logger.Error("no available provider", zap.Error(err))
 		task.Status = models.TaskStatusFailed
 		task.Error = new(string)
 		*task.Error = err.Error()
-		w.db.UpdateTask(task)
+		w.failTask(task)
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+	if choice == nil {
+		// 所有供应商都处于熔断状态，延迟到最早可能恢复的时间再提交
+		return w.requeueSubmitJob(task, payload, providers[0].ProviderName, wait)
+	}
+	providerCfg, lease := choice.cfg, choice.lease
+	defer lease.Release()
+	providerName := providerCfg.ProviderName
+
+	// 因预算、容量或错误没有提交时归还熔断器的探测名额
+	submitted := false
+	defer func() {
+		if !submitted {
+			w.releaseProviderProbe(ctx, choice)
+		}
+	}()
+
+	cost := providerCfg.EstimateCost(req)
+	if budgetCheckRequired(task, cost) {
+		reason, resetAt, err := w.checkBudget(providerCfg, cost)
+		if err != nil {
+			w.logger.Error("failed to check budget", zap.Error(err), zap.Uint("task_id", payload.TaskID))
+			return err
+		}
+		if reason != "" {
+			return w.holdForBudget(payload, cost, reason, resetAt)
+		}
+	}
+	if err := w.db.SetTaskEstimatedCost(payload.TaskID, cost); err != nil {
+		w.logger.Error("failed to record estimated cost", zap.Error(err), zap.Uint("task_id", payload.TaskID))
 		return err
 	}
+	task.EstimatedCost = &cost
 
-	req := provider.UnifiedGenRequest{
-		RequestID: fmt.Sprintf("task_%d", payload.TaskID),
-		Type:      provider.RequestType(payload.RequestType),
-		Prompt:    payload.Prompt,
+	wait, err = w.reserveProviderCapacity(ctx, providerCfg, payload.TaskID)
+	if err != nil {
+		w.logger.Error("failed to reserve provider capacity", zap.Error(err), zap.Uint("task_id", payload.TaskID))
+		return err
+	}
+	if wait > 0 {
+		return w.requeueSubmitJob(task, payload, providerName, wait)
 	}
 
-	result, err := prov.Submit(ctx, req)
+	submitted = true
+	start := time.Now()
+	result, err := lease.Submit(ctx, req)
+	w.recordProviderCall(ctx, providerCfg, choice.probe, providerCfg.SubmitTimeout(), time.Since(start), err)
 	if err != nil {
 		w.logger.Error("failed to submit job", zap.Error(err), zap.Uint("task_id", payload.TaskID))
+		w.releaseProviderCapacity(ctx, providerName, payload.TaskID)
 		task.Status = models.TaskStatusFailed
 		task.Error = new(string)
 		*task.Error = err.Error()
-		w.db.UpdateTask(task)
-		return err
+		w.failTask(task)
+		return fmt.Errorf("failed to submit job: %w: %w", err, asynq.SkipRetry)
 	}
 
-	task.Status = models.TaskStatusSubmitted
+	submittedAt := time.Now()
 	task.ProviderName = &providerName
-	task.ProviderJobID = &result.ProviderJobID
+	task.SubmittedAt = &submittedAt
+	task.Error = nil
+	if result.ProviderJobID != "" {
+		task.ProviderJobID = &result.ProviderJobID
+	}
+
+	// 提交期间任务可能已被取消，此时记录供应商任务 ID 后交给 cancel:job 处理
+	if current, err := w.db.GetTaskByID(payload.TaskID); err == nil && current.Status == models.TaskStatusCancelled {
+		current.ProviderName = task.ProviderName
+		current.ProviderJobID = task.ProviderJobID
+		current.SubmittedAt = task.SubmittedAt
+		if err := w.db.UpdateTask(current); err != nil {
+			w.logger.Error("failed to update task", zap.Error(err))
+			return err
+		}
+		return w.enqueueCancelJob(payload.TaskID, "cancelled during submit")
+	}
+
+	if result.Result != nil {
+		// 同步供应商在提交响应中直接返回结果，无需轮询状态
+		w.releaseProviderCapacity(ctx, providerName, payload.TaskID)
+		if result.Result.Status == provider.JobStatusFailed {
+			task.Status = models.TaskStatusFailed
+			task.Error = result.Result.Error
+			w.failTask(task)
+			return nil
+		}
+		return w.completeTask(task, result.Result)
+	}
+
+	task.Status = models.TaskStatusSubmitted
 	if err := w.db.UpdateTask(task); err != nil {
 		w.logger.Error("failed to update task", zap.Error(err))
 		return err
@@ -383,66 +525,85 @@ func (w *Worker) HandleCheckStatus(ctx context.Context, t *asynq.Task) error {
 
 	if task.Status == models.TaskStatusSucceeded || task.Status == models.TaskStatusEmailed {
 		w.logger.Info("task already completed", zap.Uint("task_id", payload.TaskID))
+		w.releaseProviderCapacity(ctx, payload.ProviderName, payload.TaskID)
 		return nil
 	}
 
-	prov, exists := w.providers[payload.ProviderName]
+	if task.Status == models.TaskStatusCancelled {
+		w.logger.Info("task cancelled, stopping status polling", zap.Uint("task_id", payload.TaskID))
+		w.releaseProviderCapacity(ctx, payload.ProviderName, payload.TaskID)
+		return nil
+	}
+
+	lease, exists := w.acquireProvider(payload.ProviderName)
 	if !exists {
 		err := fmt.Errorf("provider not found: %s", payload.ProviderName)
 		w.logger.Error("provider not found", zap.String("provider", payload.ProviderName))
 		return err
 	}
+	defer lease.Release()
 
-	status, err := prov.Status(ctx, payload.ProviderJobID)
+	providerCfg := w.providers.Config(payload.ProviderName)
+	start := time.Now()
+	status, err := lease.Status(ctx, payload.ProviderJobID)
+	if providerCfg != nil {
+		// 状态查询不是探测请求，half_open 状态下不会改变熔断器状态
+		w.recordProviderCall(ctx, providerCfg, 0, providerCfg.StatusTimeout(), time.Since(start), err)
+	}
 	if err != nil {
 		w.logger.Error("failed to check status", zap.Error(err), zap.Uint("task_id", payload.TaskID))
 		return err
 	}
 
 	if status.Status == provider.JobStatusSucceeded {
-		task.Status = models.TaskStatusSucceeded
-		if status.ResultURL != nil {
-			task.ResultURL = status.ResultURL
-		}
-		if err := w.db.UpdateTask(task); err != nil {
-			w.logger.Error("failed to update task", zap.Error(err))
-			return err
-		}
-
-		emailPayload, _ := json.Marshal(SendEmailPayload{
-			TaskID: payload.TaskID,
-		})
-
-		_, err = w.redis.Enqueue(
-			asynq.NewTask(TypeSendEmail, emailPayload, asynq.Queue("critical")),
-		)
-		if err != nil {
-			w.logger.Error("failed to enqueue send email task", zap.Error(err))
-		}
-
-		w.logger.Info("task succeeded", zap.Uint("task_id", payload.TaskID))
-		return nil
+		w.releaseProviderCapacity(ctx, payload.ProviderName, payload.TaskID)
+		return w.completeTask(task, status)
 	}
 
 	if status.Status == provider.JobStatusFailed {
+		w.releaseProviderCapacity(ctx, payload.ProviderName, payload.TaskID)
 		task.Status = models.TaskStatusFailed
 		if status.Error != nil {
 			task.Error = status.Error
 		}
-		w.db.UpdateTask(task)
-		w.logger.Info("task failed", zap.Uint("task_id", payload.TaskID))
+		w.failTask(task)
 		return nil
 	}
 
+	if status.Status == provider.JobStatusUnknown {
+		w.logger.Warn("unknown provider status",
+			zap.Uint("task_id", payload.TaskID),
+			zap.String("provider", payload.ProviderName),
+			zap.String("raw_status", status.RawStatus),
+		)
+
+		auditPayload, _ := json.Marshal(map[string]interface{}{
+			"task_id":         payload.TaskID,
+			"provider":        payload.ProviderName,
+			"provider_job_id": payload.ProviderJobID,
+			"raw_status":      status.RawStatus,
+		})
+		w.db.CreateAuditLog(&models.AuditLog{
+			Level:       "WARN",
+			Event:       "provider_status_unknown",
+			PayloadJSON: string(auditPayload),
+		})
+	}
+
 	if payload.RetryCount >= 20 {
+		w.releaseProviderCapacity(ctx, payload.ProviderName, payload.TaskID)
 		task.Status = models.TaskStatusFailed
 		task.Error = new(string)
 		*task.Error = "max retries exceeded"
-		w.db.UpdateTask(task)
-		w.logger.Info("task failed - max retries", zap.Uint("task_id", payload.TaskID))
+		if status.Status == provider.JobStatusUnknown {
+			*task.Error = fmt.Sprintf("max retries exceeded, last unknown provider status: %s", status.RawStatus)
+		}
+		w.failTask(task)
 		return nil
 	}
 
+	w.refreshProviderCapacity(ctx, payload.ProviderName, payload.TaskID)
+
 	backoff := 15 * time.Second
 	for i := 0; i < payload.RetryCount; i++ {
 		backoff *= 2
@@ -468,6 +629,64 @@ func (w *Worker) HandleCheckStatus(ctx context.Context, t *asynq.Task) error {
 	return nil
 }
 
+// completeTask 保存结果与产物，将任务标记为成功并投递邮件任务；任务在此期间已被取消时不覆盖、不发送邮件
+func (w *Worker) completeTask(task *models.Task, status *provider.StatusResult) error {
+	task.Status = models.TaskStatusSucceeded
+	if status.ResultURL != nil {
+		task.ResultURL = status.ResultURL
+	}
+
+	artifacts := buildArtifacts(task, status.Artifacts)
+	if len(artifacts) > 0 {
+		task.ResultURL = &artifacts[0].URL
+		task.ResultObjectKey = artifacts[0].ObjectKey
+	}
+
+	updated, err := w.db.FinishTask(task, artifacts)
+	if err != nil {
+		w.logger.Error("failed to update task", zap.Error(err), zap.Uint("task_id", task.ID))
+		return err
+	}
+	if !updated {
+		w.logger.Info("task already finished or cancelled, result discarded", zap.Uint("task_id", task.ID))
+		return nil
+	}
+	task.Artifacts = artifacts
+
+	if err := w.db.IncrementCommenterSuccess(task.CommentID); err != nil {
+		w.logger.Error("failed to record commenter success", zap.Error(err), zap.Uint("task_id", task.ID))
+	}
+
+	emailPayload, _ := json.Marshal(SendEmailPayload{
+		TaskID: task.ID,
+	})
+
+	_, err = w.redis.Enqueue(
+		asynq.NewTask(TypeSendEmail, emailPayload, asynq.Queue("critical")),
+	)
+	if err != nil {
+		w.logger.Error("failed to enqueue send email task", zap.Error(err))
+	}
+
+	w.logger.Info("task succeeded", zap.Uint("task_id", task.ID))
+	return nil
+}
+
+// failTask 将任务标记为失败，任务在此期间已被取消或已结束时不覆盖
+func (w *Worker) failTask(task *models.Task) {
+	task.Status = models.TaskStatusFailed
+	updated, err := w.db.FinishTask(task, nil)
+	if err != nil {
+		w.logger.Error("failed to update task", zap.Error(err), zap.Uint("task_id", task.ID))
+		return
+	}
+	if !updated {
+		w.logger.Info("task already finished or cancelled, failure discarded", zap.Uint("task_id", task.ID))
+		return
+	}
+	w.logger.Info("task failed", zap.Uint("task_id", task.ID))
+}
+
 type SendEmailPayload struct {
 	TaskID uint `json:"task_id"`
 }
@@ -492,12 +711,18 @@ func (w *Worker) HandleSendEmail(ctx context.Context, t *asynq.Task) error {
 		return nil
 	}
 
+	if task.Status != models.TaskStatusSucceeded {
+		w.logger.Info("task not succeeded, skipping email", zap.Uint("task_id", payload.TaskID), zap.String("status", string(task.Status)))
+		return nil
+	}
+
 	if task.Email == nil {
 		w.logger.Error("no email address", zap.Uint("task_id", payload.TaskID))
 		return nil
 	}
 
-	if task.ResultURL == nil {
+	links := resultLinks(task)
+	if len(links) == 0 {
 		w.logger.Error("no result URL", zap.Uint("task_id", payload.TaskID))
 		return nil
 	}
@@ -507,7 +732,8 @@ func (w *Worker) HandleSendEmail(ctx context.Context, t *asynq.Task) error {
 		return nil
 	}
 
-	err = w.mailer.SendResultEmail(*task.Email, string(task.RequestType), *task.Prompt, *task.ResultURL)
+	msg := w.emailMessage(ctx, task)
+	err = w.mailer.SendResultEmail(*task.Email, w.emailTemplate(mailer.TemplateResult, msg.Locale), msg)
 	if err != nil {
 		w.logger.Error("failed to send email", zap.Error(err), zap.Uint("task_id", payload.TaskID))
 
@@ -535,6 +761,8 @@ func (w *Worker) HandleSendEmail(ctx context.Context, t *asynq.Task) error {
 	task.Status = models.TaskStatusEmailed
 	w.db.UpdateTask(task)
 
+	w.enqueueReply(task.CommentID, task.ID, models.ReplyStageSent)
+
 	w.logger.Info("email sent", zap.Uint("task_id", payload.TaskID), zap.String("email", *task.Email))
 
 	return nil
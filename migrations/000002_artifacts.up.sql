@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS artifacts (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    task_id BIGINT UNSIGNED NOT NULL,
+    kind VARCHAR(50) NOT NULL,
+    object_key VARCHAR(500),
+    url VARCHAR(1000) NOT NULL,
+    mime_type VARCHAR(100),
+    size_bytes BIGINT,
+    width INT,
+    height INT,
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_task_order (task_id, sort_order),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
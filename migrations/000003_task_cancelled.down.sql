@@ -0,0 +1,4 @@
+UPDATE tasks SET status = 'FAILED' WHERE status = 'CANCELLED';
+
+ALTER TABLE tasks
+    MODIFY status ENUM('PENDING', 'EXTRACTED', 'SUBMITTED', 'RUNNING', 'SUCCEEDED', 'EMAILED', 'FAILED') NOT NULL DEFAULT 'PENDING';
@@ -0,0 +1,2 @@
+ALTER TABLE tasks
+    MODIFY status ENUM('PENDING', 'EXTRACTED', 'SUBMITTED', 'RUNNING', 'SUCCEEDED', 'EMAILED', 'FAILED', 'CANCELLED') NOT NULL DEFAULT 'PENDING';
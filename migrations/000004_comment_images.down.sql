@@ -0,0 +1,6 @@
+UPDATE tasks SET request_type = 'image' WHERE request_type = 'edit';
+
+ALTER TABLE tasks
+    MODIFY request_type ENUM('image', 'video') NOT NULL;
+
+DROP TABLE IF EXISTS comment_images;
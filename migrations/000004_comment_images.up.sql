@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS comment_images (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    comment_id BIGINT UNSIGNED NOT NULL,
+    source_url VARCHAR(1000) NOT NULL,
+    object_key VARCHAR(500),
+    url VARCHAR(1000),
+    mime_type VARCHAR(100),
+    size_bytes BIGINT,
+    error TEXT,
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_comment_order (comment_id, sort_order),
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE tasks
+    MODIFY request_type ENUM('image', 'video', 'edit') NOT NULL;
@@ -0,0 +1,12 @@
+UPDATE tasks SET status = 'EXTRACTED' WHERE status = 'QUEUED_BUDGET';
+
+ALTER TABLE tasks
+    DROP KEY idx_submitted_at,
+    DROP COLUMN submitted_at,
+    DROP COLUMN budget_approved,
+    DROP COLUMN estimated_cost,
+    MODIFY status ENUM('PENDING', 'EXTRACTED', 'SUBMITTED', 'RUNNING', 'SUCCEEDED', 'EMAILED', 'FAILED', 'CANCELLED') NOT NULL DEFAULT 'PENDING';
+
+ALTER TABLE settings
+    DROP COLUMN monthly_budget,
+    DROP COLUMN daily_budget;
@@ -0,0 +1,12 @@
+ALTER TABLE tasks
+    MODIFY status ENUM('PENDING', 'EXTRACTED', 'QUEUED_BUDGET', 'SUBMITTED', 'RUNNING', 'SUCCEEDED', 'EMAILED', 'FAILED', 'CANCELLED') NOT NULL DEFAULT 'PENDING',
+    ADD COLUMN estimated_cost DECIMAL(12,4) AFTER retry_count,
+    ADD COLUMN budget_approved TINYINT(1) NOT NULL DEFAULT 0 AFTER estimated_cost,
+    ADD COLUMN submitted_at TIMESTAMP NULL AFTER budget_approved,
+    ADD KEY idx_submitted_at (submitted_at);
+
+UPDATE tasks SET submitted_at = updated_at WHERE provider_job_id IS NOT NULL;
+
+ALTER TABLE settings
+    ADD COLUMN daily_budget DECIMAL(12,4) AFTER provider_json,
+    ADD COLUMN monthly_budget DECIMAL(12,4) AFTER daily_budget;
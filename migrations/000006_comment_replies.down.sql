@@ -0,0 +1,7 @@
+ALTER TABLE settings
+    DROP COLUMN reply_templates;
+
+ALTER TABLE notes
+    DROP COLUMN reply_enabled;
+
+DROP TABLE IF EXISTS comment_replies;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS comment_replies (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    comment_id BIGINT UNSIGNED NOT NULL,
+    task_id BIGINT UNSIGNED,
+    stage VARCHAR(50) NOT NULL,
+    content TEXT NOT NULL,
+    reply_id VARCHAR(200),
+    status ENUM('SENT', 'FAILED') NOT NULL,
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    sent_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_comment_stage (comment_id, stage),
+    KEY idx_task_id (task_id),
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE notes
+    ADD COLUMN reply_enabled TINYINT(1) NOT NULL DEFAULT 0 AFTER last_error;
+
+ALTER TABLE settings
+    ADD COLUMN reply_templates JSON AFTER monthly_budget;
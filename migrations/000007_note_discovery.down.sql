@@ -0,0 +1,12 @@
+ALTER TABLE settings
+    DROP COLUMN note_max_age_days,
+    DROP COLUMN discovery_interval_sec,
+    DROP COLUMN watch_account;
+
+ALTER TABLE notes
+    DROP KEY idx_watched,
+    DROP COLUMN retired_at,
+    DROP COLUMN published_at,
+    DROP COLUMN title,
+    DROP COLUMN source,
+    DROP COLUMN watched;
@@ -0,0 +1,12 @@
+ALTER TABLE notes
+    ADD COLUMN watched TINYINT(1) NOT NULL DEFAULT 1 AFTER reply_enabled,
+    ADD COLUMN source VARCHAR(20) NOT NULL DEFAULT 'manual' AFTER watched,
+    ADD COLUMN title VARCHAR(500) AFTER source,
+    ADD COLUMN published_at TIMESTAMP NULL AFTER title,
+    ADD COLUMN retired_at TIMESTAMP NULL AFTER published_at,
+    ADD KEY idx_watched (watched);
+
+ALTER TABLE settings
+    ADD COLUMN watch_account VARCHAR(500) AFTER reply_templates,
+    ADD COLUMN discovery_interval_sec INT NOT NULL DEFAULT 3600 AFTER watch_account,
+    ADD COLUMN note_max_age_days INT NOT NULL DEFAULT 30 AFTER discovery_interval_sec;
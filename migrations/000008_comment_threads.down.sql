@@ -0,0 +1,13 @@
+ALTER TABLE comment_replies
+    DROP KEY idx_reply_id;
+
+ALTER TABLE comments
+    DROP FOREIGN KEY fk_comments_children;
+
+ALTER TABLE comments
+    DROP KEY idx_parent_id,
+    DROP COLUMN sub_polled_at,
+    DROP COLUMN sub_cursor,
+    DROP COLUMN sub_comment_count,
+    DROP COLUMN depth,
+    DROP COLUMN parent_id;
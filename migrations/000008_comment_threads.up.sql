@@ -0,0 +1,11 @@
+ALTER TABLE comments
+    ADD COLUMN parent_id BIGINT UNSIGNED NULL AFTER comment_uid,
+    ADD COLUMN depth INT NOT NULL DEFAULT 0 AFTER parent_id,
+    ADD COLUMN sub_comment_count INT NOT NULL DEFAULT 0 AFTER ingested_at,
+    ADD COLUMN sub_cursor VARCHAR(200) AFTER sub_comment_count,
+    ADD COLUMN sub_polled_at TIMESTAMP NULL AFTER sub_cursor,
+    ADD KEY idx_parent_id (parent_id),
+    ADD CONSTRAINT fk_comments_children FOREIGN KEY (parent_id) REFERENCES comments(id);
+
+ALTER TABLE comment_replies
+    ADD KEY idx_reply_id (reply_id);
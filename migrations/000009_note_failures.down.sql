@@ -0,0 +1,7 @@
+ALTER TABLE settings
+    DROP COLUMN alert_email;
+
+ALTER TABLE notes
+    DROP COLUMN next_poll_at,
+    DROP COLUMN consecutive_failures,
+    DROP COLUMN last_error_kind;
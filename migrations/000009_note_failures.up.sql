@@ -0,0 +1,7 @@
+ALTER TABLE notes
+    ADD COLUMN last_error_kind VARCHAR(30) AFTER last_error,
+    ADD COLUMN consecutive_failures INT NOT NULL DEFAULT 0 AFTER last_error_kind,
+    ADD COLUMN next_poll_at TIMESTAMP NULL AFTER consecutive_failures;
+
+ALTER TABLE settings
+    ADD COLUMN alert_email VARCHAR(200) AFTER note_max_age_days;
@@ -0,0 +1,9 @@
+ALTER TABLE comments
+    DROP FOREIGN KEY fk_comments_commenter;
+
+ALTER TABLE comments
+    DROP KEY idx_commenter_id,
+    DROP COLUMN commenter_id;
+
+DROP TABLE IF EXISTS commenter_emails;
+DROP TABLE IF EXISTS commenters;
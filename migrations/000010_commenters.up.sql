@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS commenters (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id VARCHAR(100) NOT NULL,
+    user_name VARCHAR(200),
+    avatar_url VARCHAR(1000),
+    comment_count INT NOT NULL DEFAULT 0,
+    request_count INT NOT NULL DEFAULT 0,
+    success_count INT NOT NULL DEFAULT 0,
+    blocked TINYINT(1) NOT NULL DEFAULT 0,
+    blocked_reason VARCHAR(500),
+    blocked_at TIMESTAMP NULL,
+    first_seen_at TIMESTAMP NULL,
+    last_seen_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_user_id (user_id),
+    KEY idx_blocked (blocked),
+    KEY idx_last_seen_at (last_seen_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS commenter_emails (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    commenter_id BIGINT UNSIGNED NOT NULL,
+    email VARCHAR(200) NOT NULL,
+    use_count INT NOT NULL DEFAULT 0,
+    first_used_at TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL,
+    UNIQUE KEY uk_commenter_email (commenter_id, email),
+    FOREIGN KEY (commenter_id) REFERENCES commenters(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE comments
+    ADD COLUMN commenter_id BIGINT UNSIGNED NULL AFTER depth,
+    ADD KEY idx_commenter_id (commenter_id),
+    ADD CONSTRAINT fk_comments_commenter FOREIGN KEY (commenter_id) REFERENCES commenters(id);
@@ -0,0 +1,196 @@
+'use client';
+
+import { useState, useEffect } from 'react';
+import { apiClient, Commenter, Comment } from '@/src/lib/api';
+import Link from 'next/link';
+
+export default function CommenterDetailPage({ params }: { params: { id: string } }) {
+  const [commenter, setCommenter] = useState<Commenter | null>(null);
+  const [comments, setComments] = useState<Comment[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [saving, setSaving] = useState(false);
+
+  useEffect(() => {
+    loadCommenter();
+  }, [params.id]);
+
+  const loadCommenter = async () => {
+    try {
+      setLoading(true);
+      const data = await apiClient.getCommenter(parseInt(params.id));
+      setCommenter(data.commenter);
+      setComments(data.comments);
+    } catch (error) {
+      console.error('Failed to load commenter:', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const toggleBlocked = async () => {
+    if (!commenter) {
+      return;
+    }
+    let reason: string | undefined;
+    if (!commenter.blocked) {
+      const input = prompt('Block this user? New comments will not create tasks. Reason (optional):');
+      if (input === null) {
+        return;
+      }
+      reason = input || undefined;
+    }
+    try {
+      setSaving(true);
+      const data = await apiClient.updateCommenter(commenter.id, {
+        blocked: !commenter.blocked,
+        blocked_reason: reason,
+      });
+      setCommenter(data);
+    } catch (error) {
+      console.error('Failed to update commenter:', error);
+      alert('Failed to update commenter');
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  if (loading && !commenter) {
+    return (
+      <div className="min-h-screen bg-gray-50 py-8">
+        <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8 text-gray-500">Loading...</div>
+      </div>
+    );
+  }
+
+  if (!commenter) {
+    return (
+      <div className="min-h-screen bg-gray-50 py-8">
+        <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8 text-gray-500">Commenter not found.</div>
+      </div>
+    );
+  }
+
+  return (
+    <div className="min-h-screen bg-gray-50 py-8">
+      <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8">
+        <div className="mb-8">
+          <Link href="/commenters" className="text-blue-600 hover:text-blue-900">
+            ← Back to Commenters
+          </Link>
+        </div>
+
+        <div className="space-y-6">
+          <div className="bg-white shadow rounded-lg p-6">
+            <div className="flex justify-between items-start mb-4">
+              <div className="flex items-center gap-4">
+                {commenter.avatar_url && (
+                  <img src={commenter.avatar_url} alt="" className="w-12 h-12 rounded-full" />
+                )}
+                <div>
+                  <h1 className="text-2xl font-bold text-gray-900">
+                    {commenter.user_name || commenter.user_id}
+                    {commenter.blocked && (
+                      <span className="ml-3 px-2 inline-flex text-xs leading-5 font-semibold rounded-full bg-red-100 text-red-800">
+                        Blocked
+                      </span>
+                    )}
+                  </h1>
+                  <p className="text-sm text-gray-500 font-mono">{commenter.user_id}</p>
+                </div>
+              </div>
+              <button
+                onClick={toggleBlocked}
+                disabled={saving}
+                className={`px-4 py-2 text-white rounded-md disabled:opacity-50 ${
+                  commenter.blocked ? 'bg-gray-600 hover:bg-gray-700' : 'bg-red-600 hover:bg-red-700'
+                }`}
+              >
+                {commenter.blocked ? 'Unblock' : 'Block'}
+              </button>
+            </div>
+            <dl className="grid grid-cols-1 gap-x-4 gap-y-6 sm:grid-cols-4">
+              <div>
+                <dt className="text-sm font-medium text-gray-500">Comments</dt>
+                <dd className="mt-1 text-sm text-gray-900">{commenter.comment_count}</dd>
+              </div>
+              <div>
+                <dt className="text-sm font-medium text-gray-500">Requests</dt>
+                <dd className="mt-1 text-sm text-gray-900">{commenter.request_count}</dd>
+              </div>
+              <div>
+                <dt className="text-sm font-medium text-gray-500">Succeeded</dt>
+                <dd className="mt-1 text-sm text-gray-900">{commenter.success_count}</dd>
+              </div>
+              <div>
+                <dt className="text-sm font-medium text-gray-500">First / Last Seen</dt>
+                <dd className="mt-1 text-sm text-gray-900">
+                  {new Date(commenter.first_seen_at).toLocaleString()}
+                  {' / '}
+                  {new Date(commenter.last_seen_at).toLocaleString()}
+                </dd>
+              </div>
+              {commenter.blocked && commenter.blocked_reason && (
+                <div className="sm:col-span-4">
+                  <dt className="text-sm font-medium text-gray-500">Blocked Reason</dt>
+                  <dd className="mt-1 text-sm text-red-600">{commenter.blocked_reason}</dd>
+                </div>
+              )}
+              <div className="sm:col-span-4">
+                <dt className="text-sm font-medium text-gray-500">Emails Used</dt>
+                <dd className="mt-1 text-sm text-gray-900">
+                  {commenter.emails && commenter.emails.length > 0 ? (
+                    <ul className="space-y-1">
+                      {commenter.emails.map((email) => (
+                        <li key={email.id}>
+                          {email.email}
+                          <span className="text-gray-500">
+                            {' '}
+                            · {email.use_count} times · last {new Date(email.last_used_at).toLocaleString()}
+                          </span>
+                        </li>
+                      ))}
+                    </ul>
+                  ) : (
+                    '-'
+                  )}
+                </dd>
+              </div>
+            </dl>
+          </div>
+
+          <div className="bg-white shadow rounded-lg p-6">
+            <h2 className="text-lg font-medium text-gray-900 mb-4">History</h2>
+            {comments.length === 0 ? (
+              <p className="text-sm text-gray-500">No comments.</p>
+            ) : (
+              <div className="space-y-4">
+                {comments.map((comment) => (
+                  <div key={comment.id} className="border-l-4 border-gray-200 pl-4">
+                    <div className="text-xs text-gray-500">
+                      <span className="font-mono">{comment.note_target}</span>
+                      {comment.comment_created_at && ` · ${new Date(comment.comment_created_at).toLocaleString()}`}
+                      {comment.depth > 0 && ' · reply'}
+                    </div>
+                    <div className="mt-1 text-sm text-gray-900">{comment.content}</div>
+                    {comment.task && (
+                      <div className="mt-1 text-xs">
+                        <Link href={`/tasks/${comment.task.id}`} className="text-blue-600 hover:text-blue-900">
+                          Task #{comment.task.id}
+                        </Link>
+                        <span className="text-gray-500">
+                          {' '}
+                          · {comment.task.request_type} · {comment.task.status}
+                          {comment.task.email && ` · ${comment.task.email}`}
+                        </span>
+                      </div>
+                    )}
+                  </div>
+                ))}
+              </div>
+            )}
+          </div>
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,166 @@
+'use client';
+
+import { useState, useEffect } from 'react';
+import { apiClient, Commenter } from '@/src/lib/api';
+import Link from 'next/link';
+
+export default function CommentersPage() {
+  const [commenters, setCommenters] = useState<Commenter[]>([]);
+  const [total, setTotal] = useState(0);
+  const [loading, setLoading] = useState(true);
+  const [query, setQuery] = useState('');
+  const [blockedOnly, setBlockedOnly] = useState(false);
+
+  useEffect(() => {
+    loadCommenters();
+  }, [blockedOnly]);
+
+  const loadCommenters = async () => {
+    try {
+      setLoading(true);
+      const data = await apiClient.listCommenters({
+        q: query || undefined,
+        blocked: blockedOnly ? true : undefined,
+        limit: 100,
+      });
+      setCommenters(data.commenters);
+      setTotal(data.total);
+    } catch (error) {
+      console.error('Failed to load commenters:', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gray-50 py-8">
+      <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8">
+        <div className="mb-8 flex justify-between items-center">
+          <div>
+            <h1 className="text-3xl font-bold text-gray-900">Commenters</h1>
+            <p className="mt-2 text-gray-600">Users who commented on watched notes ({total})</p>
+          </div>
+          <form
+            onSubmit={(e) => {
+              e.preventDefault();
+              loadCommenters();
+            }}
+            className="flex items-center gap-4"
+          >
+            <input
+              type="text"
+              value={query}
+              onChange={(e) => setQuery(e.target.value)}
+              placeholder="Name or user ID"
+              className="px-3 py-2 border border-gray-300 rounded-md text-sm"
+            />
+            <button
+              type="submit"
+              className="px-4 py-2 bg-blue-600 text-white rounded-md hover:bg-blue-700"
+            >
+              Search
+            </button>
+            <label className="flex items-center gap-2">
+              <input
+                type="checkbox"
+                checked={blockedOnly}
+                onChange={(e) => setBlockedOnly(e.target.checked)}
+                className="w-4 h-4 text-blue-600 rounded"
+              />
+              <span className="text-sm text-gray-700">Blocked only</span>
+            </label>
+          </form>
+        </div>
+
+        <div className="bg-white shadow rounded-lg overflow-hidden">
+          <div className="overflow-x-auto">
+            <table className="min-w-full divide-y divide-gray-200">
+              <thead className="bg-gray-50">
+                <tr>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
+                    User
+                  </th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
+                    Comments
+                  </th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
+                    Requests
+                  </th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
+                    Succeeded
+                  </th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
+                    Emails
+                  </th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
+                    Last Seen
+                  </th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
+                    Actions
+                  </th>
+                </tr>
+              </thead>
+              <tbody className="bg-white divide-y divide-gray-200">
+                {commenters.length === 0 ? (
+                  <tr>
+                    <td colSpan={7} className="px-6 py-12 text-center text-gray-500">
+                      {loading ? 'Loading...' : 'No commenters found.'}
+                    </td>
+                  </tr>
+                ) : (
+                  commenters.map((commenter) => (
+                    <tr key={commenter.id} className="hover:bg-gray-50">
+                      <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
+                        <div className="flex items-center gap-3">
+                          {commenter.avatar_url && (
+                            <img src={commenter.avatar_url} alt="" className="w-8 h-8 rounded-full" />
+                          )}
+                          <div>
+                            <div className="font-medium">
+                              {commenter.user_name || '-'}
+                              {commenter.blocked && (
+                                <span className="ml-2 px-2 inline-flex text-xs leading-5 font-semibold rounded-full bg-red-100 text-red-800">
+                                  Blocked
+                                </span>
+                              )}
+                            </div>
+                            <div className="text-xs text-gray-500 font-mono">{commenter.user_id}</div>
+                          </div>
+                        </div>
+                      </td>
+                      <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
+                        {commenter.comment_count}
+                      </td>
+                      <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
+                        {commenter.request_count}
+                      </td>
+                      <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
+                        {commenter.success_count}
+                      </td>
+                      <td className="px-6 py-4 text-sm text-gray-900">
+                        {commenter.emails && commenter.emails.length > 0
+                          ? commenter.emails.map((email) => email.email).join(', ')
+                          : '-'}
+                      </td>
+                      <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">
+                        {new Date(commenter.last_seen_at).toLocaleString()}
+                      </td>
+                      <td className="px-6 py-4 whitespace-nowrap text-sm font-medium">
+                        <Link
+                          href={`/commenters/${commenter.id}`}
+                          className="text-blue-600 hover:text-blue-900"
+                        >
+                          View History
+                        </Link>
+                      </td>
+                    </tr>
+                  ))
+                )}
+              </tbody>
+            </table>
+          </div>
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,211 @@
+'use client';
+
+import { useState, useEffect } from 'react';
+import { apiClient, EmailTemplate, RenderedEmail } from '@/src/lib/api';
+
+const VARIABLES = [
+  '{{.RequestType}}',
+  '{{.Prompt}}',
+  '{{.NoteTitle}}',
+  '{{.NoteTarget}}',
+  '{{.PreviewURL}}',
+  '{{.Expiry}}',
+  '{{.Error}}',
+  '{{range .Links}}{{.Index}} {{.Kind}} {{.URL}}{{end}}',
+];
+
+export default function EmailTemplatesPage() {
+  const [templates, setTemplates] = useState<EmailTemplate[]>([]);
+  const [selected, setSelected] = useState('result.zh');
+  const [draft, setDraft] = useState({ subject: '', html_body: '', text_body: '' });
+  const [preview, setPreview] = useState<RenderedEmail | null>(null);
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(true);
+  const [saving, setSaving] = useState(false);
+
+  const [name, locale] = selected.split('.');
+  const current = templates.find((t) => t.name === name && t.locale === locale);
+
+  useEffect(() => {
+    loadTemplates();
+  }, []);
+
+  useEffect(() => {
+    if (current) {
+      setDraft({ subject: current.subject, html_body: current.html_body, text_body: current.text_body });
+      setPreview(null);
+      setError('');
+    }
+  }, [selected, templates]);
+
+  const loadTemplates = async () => {
+    try {
+      setLoading(true);
+      const data = await apiClient.listEmailTemplates();
+      setTemplates(data.templates);
+    } catch (error) {
+      console.error('Failed to load email templates:', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const errorMessage = (error: unknown, fallback: string) =>
+    (error as any)?.response?.data?.message || fallback;
+
+  const handlePreview = async () => {
+    try {
+      setError('');
+      setPreview(await apiClient.previewEmailTemplate(name, locale, draft));
+    } catch (error) {
+      setPreview(null);
+      setError(errorMessage(error, 'Failed to render template'));
+    }
+  };
+
+  const handleSave = async () => {
+    try {
+      setSaving(true);
+      setError('');
+      const saved = await apiClient.updateEmailTemplate(name, locale, draft);
+      setTemplates(templates.map((t) => (t.name === name && t.locale === locale ? saved : t)));
+    } catch (error) {
+      setError(errorMessage(error, 'Failed to save template'));
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const handleReset = async () => {
+    if (!confirm('Discard the customized template and restore the built-in one?')) {
+      return;
+    }
+    try {
+      setSaving(true);
+      setError('');
+      const reset = await apiClient.resetEmailTemplate(name, locale);
+      setTemplates(templates.map((t) => (t.name === name && t.locale === locale ? reset : t)));
+    } catch (error) {
+      setError(errorMessage(error, 'Failed to reset template'));
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  if (loading) {
+    return (
+      <div className="min-h-screen bg-gray-50 py-8">
+        <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8 text-gray-500">Loading...</div>
+      </div>
+    );
+  }
+
+  return (
+    <div className="min-h-screen bg-gray-50 py-8">
+      <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8">
+        <div className="mb-8 flex justify-between items-center">
+          <div>
+            <h1 className="text-3xl font-bold text-gray-900">Email Templates</h1>
+            <p className="mt-2 text-gray-600">
+              Result and failure emails, chosen by the language of the comment
+            </p>
+          </div>
+          <select
+            value={selected}
+            onChange={(e) => setSelected(e.target.value)}
+            className="px-3 py-2 border border-gray-300 rounded-md text-sm"
+          >
+            {templates.map((t) => (
+              <option key={`${t.name}.${t.locale}`} value={`${t.name}.${t.locale}`}>
+                {t.name === 'result' ? 'Result' : 'Failure'} · {t.locale === 'zh' ? '中文' : 'English'}
+                {t.customized ? ' (customized)' : ''}
+              </option>
+            ))}
+          </select>
+        </div>
+
+        <div className="grid grid-cols-1 gap-6 lg:grid-cols-2">
+          <div className="bg-white shadow rounded-lg p-6 space-y-4">
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">Subject</label>
+              <input
+                type="text"
+                value={draft.subject}
+                onChange={(e) => setDraft({ ...draft, subject: e.target.value })}
+                className="w-full px-3 py-2 border border-gray-300 rounded-md font-mono text-sm"
+              />
+            </div>
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">HTML Body</label>
+              <textarea
+                value={draft.html_body}
+                onChange={(e) => setDraft({ ...draft, html_body: e.target.value })}
+                rows={14}
+                className="w-full px-3 py-2 border border-gray-300 rounded-md font-mono text-xs"
+              />
+            </div>
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">Text Body</label>
+              <textarea
+                value={draft.text_body}
+                onChange={(e) => setDraft({ ...draft, text_body: e.target.value })}
+                rows={10}
+                className="w-full px-3 py-2 border border-gray-300 rounded-md font-mono text-xs"
+              />
+            </div>
+            <p className="text-xs text-gray-500">
+              Variables: {VARIABLES.map((v) => (
+                <code key={v} className="mr-2">{v}</code>
+              ))}
+            </p>
+            {error && <p className="text-sm text-red-600">{error}</p>}
+            <div className="flex gap-3">
+              <button
+                onClick={handlePreview}
+                className="px-4 py-2 bg-gray-600 text-white rounded-md hover:bg-gray-700"
+              >
+                Preview
+              </button>
+              <button
+                onClick={handleSave}
+                disabled={saving}
+                className="px-4 py-2 bg-blue-600 text-white rounded-md hover:bg-blue-700 disabled:opacity-50"
+              >
+                Save
+              </button>
+              {current?.customized && (
+                <button
+                  onClick={handleReset}
+                  disabled={saving}
+                  className="px-4 py-2 bg-red-600 text-white rounded-md hover:bg-red-700 disabled:opacity-50"
+                >
+                  Reset to Default
+                </button>
+              )}
+            </div>
+          </div>
+
+          <div className="bg-white shadow rounded-lg p-6">
+            <h2 className="text-lg font-medium text-gray-900 mb-4">Preview</h2>
+            {preview ? (
+              <div className="space-y-4">
+                <div className="text-sm">
+                  <span className="font-medium text-gray-500">Subject: </span>
+                  {preview.subject}
+                </div>
+                <iframe
+                  srcDoc={preview.html}
+                  sandbox=""
+                  className="w-full h-96 border border-gray-200 rounded"
+                />
+                <pre className="text-xs text-gray-700 whitespace-pre-wrap bg-gray-50 p-3 rounded">{preview.text}</pre>
+              </div>
+            ) : (
+              <p className="text-sm text-gray-500">Click Preview to render the template with sample data.</p>
+            )}
+          </div>
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -42,6 +42,18 @@ export default function RootLayout({
                   >
                     Tasks
                   </Link>
+                  <Link
+                    href="/commenters"
+                    className="border-transparent text-gray-500 hover:border-gray-300 hover:text-gray-700 inline-flex items-center px-1 pt-1 border-b-2 text-sm font-medium"
+                  >
+                    Commenters
+                  </Link>
+                  <Link
+                    href="/email-templates"
+                    className="border-transparent text-gray-500 hover:border-gray-300 hover:text-gray-700 inline-flex items-center px-1 pt-1 border-b-2 text-sm font-medium"
+                  >
+                    Email Templates
+                  </Link>
                 </div>
               </div>
             </div>
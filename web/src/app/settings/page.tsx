@@ -1,10 +1,11 @@
 'use client';
 
 import { useState, useEffect } from 'react';
-import { apiClient, Setting } from '@/src/lib/api';
+import { apiClient, Note, Setting } from '@/src/lib/api';
 
 export default function SettingsPage() {
   const [settings, setSettings] = useState<Setting | null>(null);
+  const [notes, setNotes] = useState<Note[]>([]);
   const [loading, setLoading] = useState(true);
   const [saving, setSaving] = useState(false);
   const [message, setMessage] = useState<{ type: 'success' | 'error'; text: string } | null>(null);
@@ -16,8 +17,9 @@ export default function SettingsPage() {
   const loadSettings = async () => {
     try {
       setLoading(true);
-      const data = await apiClient.getSettings();
+      const [data, notesData] = await Promise.all([apiClient.getSettings(), apiClient.listNotes()]);
       setSettings(data);
+      setNotes(notesData.notes);
     } catch (error) {
       setMessage({ type: 'error', text: 'Failed to load settings' });
     } finally {
@@ -40,6 +42,25 @@ export default function SettingsPage() {
     }
   };
 
+  const handleUpdateNote = async (note: Note, data: { reply_enabled?: boolean; watched?: boolean }) => {
+    try {
+      const updated = await apiClient.updateNote(note.id, data);
+      setNotes(notes.map((n) => (n.id === updated.id ? updated : n)));
+    } catch (error) {
+      setMessage({ type: 'error', text: 'Failed to update note' });
+    }
+  };
+
+  const handleRunDiscovery = async () => {
+    try {
+      await apiClient.runDiscovery();
+      setMessage({ type: 'success', text: 'Discovery job enqueued successfully' });
+      setTimeout(() => setMessage(null), 3000);
+    } catch (error) {
+      setMessage({ type: 'error', text: 'Failed to run discovery' });
+    }
+  };
+
   const handleRunPoll = async () => {
     try {
       await apiClient.runPoll();
@@ -95,8 +116,44 @@ export default function SettingsPage() {
                 >
                   <option value="mock">Mock (Demo)</option>
                   <option value="mcp">MCP (Real)</option>
+                  <option value="replay">Replay (Recorded)</option>
                 </select>
               </div>
+              {settings.connector_mode === 'mcp' && (
+                <>
+                  <div>
+                    <label className="block text-sm font-medium text-gray-700 mb-1">MCP Server Command (stdio)</label>
+                    <input
+                      type="text"
+                      value={settings.mcp_server_cmd || ''}
+                      onChange={(e) => setSettings({ ...settings, mcp_server_cmd: e.target.value })}
+                      className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                      placeholder="npx -y xhs-mcp-server"
+                    />
+                    <p className="mt-1 text-xs text-gray-500">Takes precedence over the server URL when set</p>
+                  </div>
+                  <div>
+                    <label className="block text-sm font-medium text-gray-700 mb-1">MCP Server URL</label>
+                    <input
+                      type="text"
+                      value={settings.mcp_server_url || ''}
+                      onChange={(e) => setSettings({ ...settings, mcp_server_url: e.target.value })}
+                      className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                      placeholder="http://localhost:3000/mcp"
+                    />
+                  </div>
+                  <div>
+                    <label className="block text-sm font-medium text-gray-700 mb-1">MCP Auth</label>
+                    <input
+                      type="password"
+                      value={settings.mcp_auth || ''}
+                      onChange={(e) => setSettings({ ...settings, mcp_auth: e.target.value })}
+                      className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                      placeholder="Bearer your-token"
+                    />
+                  </div>
+                </>
+              )}
               <div>
                 <label className="block text-sm font-medium text-gray-700 mb-1">Note Target (URL or ID)</label>
                 <input
@@ -120,6 +177,97 @@ export default function SettingsPage() {
             </div>
           </div>
 
+          <div>
+            <h2 className="text-lg font-medium text-gray-900 mb-4">Notes</h2>
+            <div className="grid grid-cols-1 gap-4">
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Watch Account (user ID or profile URL)</label>
+                <input
+                  type="text"
+                  value={settings.watch_account || ''}
+                  onChange={(e) => setSettings({ ...settings, watch_account: e.target.value })}
+                  className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                  placeholder="https://www.xiaohongshu.com/user/profile/12345678"
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Discovery Interval (seconds)</label>
+                <input
+                  type="number"
+                  value={settings.discovery_interval_sec}
+                  onChange={(e) => setSettings({ ...settings, discovery_interval_sec: parseInt(e.target.value) || 3600 })}
+                  className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                  min="300"
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Retire Discovered Notes After (days, 0 = never)</label>
+                <input
+                  type="number"
+                  value={settings.note_max_age_days}
+                  onChange={(e) => setSettings({ ...settings, note_max_age_days: parseInt(e.target.value) || 0 })}
+                  className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                  min="0"
+                />
+              </div>
+              <div>
+                <div className="flex items-center justify-between mb-1">
+                  <label className="block text-sm font-medium text-gray-700">Watched Notes</label>
+                  <button
+                    onClick={handleRunDiscovery}
+                    className="text-sm text-blue-600 hover:text-blue-800"
+                  >
+                    Discover Now
+                  </button>
+                </div>
+                {notes.length === 0 ? (
+                  <p className="text-sm text-gray-500">No notes yet</p>
+                ) : (
+                  <ul className="divide-y divide-gray-200 border border-gray-200 rounded-md">
+                    {notes.map((note) => (
+                      <li key={note.id} className="flex items-center justify-between px-3 py-2">
+                        <div className="min-w-0 mr-4">
+                          <div className="text-sm text-gray-900 truncate">{note.title || note.note_target}</div>
+                          <div className="text-xs text-gray-500">
+                            {note.source}
+                            {note.published_at && ` · published ${new Date(note.published_at).toLocaleDateString()}`}
+                            {note.retired_at && ` · retired ${new Date(note.retired_at).toLocaleDateString()}`}
+                          </div>
+                          {note.consecutive_failures > 0 && (
+                            <div className="text-xs text-red-600 truncate" title={note.last_error}>
+                              {note.last_error_kind || 'error'} · {note.consecutive_failures} consecutive failures
+                              {note.next_poll_at && ` · next poll ${new Date(note.next_poll_at).toLocaleTimeString()}`}
+                            </div>
+                          )}
+                        </div>
+                        <div className="flex items-center space-x-4 text-sm text-gray-700">
+                          <label className="flex items-center">
+                            <input
+                              type="checkbox"
+                              checked={note.watched}
+                              onChange={() => handleUpdateNote(note, { watched: !note.watched })}
+                              className="mr-2"
+                            />
+                            Watched
+                          </label>
+                          <label className="flex items-center">
+                            <input
+                              type="checkbox"
+                              checked={note.reply_enabled}
+                              onChange={() => handleUpdateNote(note, { reply_enabled: !note.reply_enabled })}
+                              className="mr-2"
+                            />
+                            Replies
+                          </label>
+                        </div>
+                      </li>
+                    ))}
+                  </ul>
+                )}
+              </div>
+            </div>
+          </div>
+
           <div>
             <h2 className="text-lg font-medium text-gray-900 mb-4">LLM Configuration</h2>
             <div className="grid grid-cols-1 gap-4">
@@ -168,6 +316,53 @@ export default function SettingsPage() {
             </div>
           </div>
 
+          <div>
+            <h2 className="text-lg font-medium text-gray-900 mb-4">Budget</h2>
+            <div className="grid grid-cols-1 gap-4">
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Daily Budget (0 = unlimited)</label>
+                <input
+                  type="number"
+                  step="0.01"
+                  value={settings.daily_budget ?? 0}
+                  onChange={(e) => setSettings({ ...settings, daily_budget: parseFloat(e.target.value) || 0 })}
+                  className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                  min="0"
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Monthly Budget (0 = unlimited)</label>
+                <input
+                  type="number"
+                  step="0.01"
+                  value={settings.monthly_budget ?? 0}
+                  onChange={(e) => setSettings({ ...settings, monthly_budget: parseFloat(e.target.value) || 0 })}
+                  className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                  min="0"
+                />
+              </div>
+            </div>
+          </div>
+
+          <div>
+            <h2 className="text-lg font-medium text-gray-900 mb-4">Comment Replies</h2>
+            <div className="grid grid-cols-1 gap-4">
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Reply Templates (JSON)</label>
+                <textarea
+                  value={settings.reply_templates || ''}
+                  onChange={(e) => setSettings({ ...settings, reply_templates: e.target.value })}
+                  className="w-full px-3 py-2 border border-gray-300 rounded-md font-mono text-sm focus:outline-none focus:ring-2 focus:ring-blue-500"
+                  rows={5}
+                  placeholder={'{"received": "收到～正在为你生成{{.RequestLabel}}，完成后会发送到 {{.Email}}", "missing_email": "", "sent": "已发送到 {{.Email}}"}'}
+                />
+                <p className="mt-1 text-xs text-gray-500">
+                  Stages: received, missing_email, sent. An empty template disables the stage; leave blank to use the defaults.
+                </p>
+              </div>
+            </div>
+          </div>
+
           <div>
             <h2 className="text-lg font-medium text-gray-900 mb-4">SMTP Configuration</h2>
             <div className="grid grid-cols-1 gap-4">
@@ -202,6 +397,17 @@ export default function SettingsPage() {
                   placeholder="noreply@example.com"
                 />
               </div>
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">Alert Email</label>
+                <input
+                  type="email"
+                  value={settings.alert_email || ''}
+                  onChange={(e) => setSettings({ ...settings, alert_email: e.target.value })}
+                  className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                  placeholder="ops@example.com"
+                />
+                <p className="mt-1 text-xs text-gray-500">Receives an alert when the Xiaohongshu login expires</p>
+              </div>
             </div>
           </div>
 
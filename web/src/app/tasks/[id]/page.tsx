@@ -1,12 +1,15 @@
 'use client';
 
 import { useState, useEffect } from 'react';
-import { apiClient, Task } from '@/src/lib/api';
+import { apiClient, Task, Comment } from '@/src/lib/api';
 import Link from 'next/link';
 
 export default function TaskDetailPage({ params }: { params: { id: string } }) {
   const [task, setTask] = useState<Task | null>(null);
   const [loading, setLoading] = useState(true);
+  const [cancelling, setCancelling] = useState(false);
+  const [approving, setApproving] = useState(false);
+  const [thread, setThread] = useState<Comment | null>(null);
 
   useEffect(() => {
     loadTask();
@@ -14,6 +17,21 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
     return () => clearInterval(interval);
   }, [params.id]);
 
+  useEffect(() => {
+    if (task?.comment_id) {
+      loadThread(task.comment_id);
+    }
+  }, [task?.comment_id]);
+
+  const loadThread = async (commentId: number) => {
+    try {
+      const data = await apiClient.getComment(commentId);
+      setThread(data);
+    } catch (error) {
+      console.error('Failed to load comment thread:', error);
+    }
+  };
+
   const loadTask = async () => {
     try {
       setLoading(true);
@@ -26,12 +44,49 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
     }
   };
 
+  const cancelTask = async () => {
+    if (!confirm('Cancel this task?')) {
+      return;
+    }
+    try {
+      setCancelling(true);
+      const data = await apiClient.cancelTask(parseInt(params.id));
+      setTask(data);
+    } catch (error) {
+      console.error('Failed to cancel task:', error);
+      alert('Failed to cancel task');
+    } finally {
+      setCancelling(false);
+    }
+  };
+
+  const approveBudget = async () => {
+    if (!confirm('Submit this task even though it exceeds the budget?')) {
+      return;
+    }
+    try {
+      setApproving(true);
+      const data = await apiClient.approveTaskBudget(parseInt(params.id));
+      setTask(data);
+    } catch (error) {
+      console.error('Failed to approve task budget:', error);
+      alert('Failed to approve task budget');
+    } finally {
+      setApproving(false);
+    }
+  };
+
+  const isCancellable = (status: string) =>
+    ['PENDING', 'EXTRACTED', 'QUEUED_BUDGET', 'SUBMITTED', 'RUNNING'].includes(status);
+
   const getStatusColor = (status: string) => {
     switch (status) {
       case 'PENDING':
         return 'bg-gray-100 text-gray-800';
       case 'EXTRACTED':
         return 'bg-blue-100 text-blue-800';
+      case 'QUEUED_BUDGET':
+        return 'bg-amber-100 text-amber-800';
       case 'SUBMITTED':
         return 'bg-yellow-100 text-yellow-800';
       case 'RUNNING':
@@ -42,6 +97,8 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
         return 'bg-emerald-100 text-emerald-800';
       case 'FAILED':
         return 'bg-red-100 text-red-800';
+      case 'CANCELLED':
+        return 'bg-orange-100 text-orange-800';
       default:
         return 'bg-gray-100 text-gray-800';
     }
@@ -86,6 +143,24 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
               <span className="text-sm text-gray-500">
                 Updated: {new Date(task.updated_at).toLocaleString()}
               </span>
+              {task.status === 'QUEUED_BUDGET' && (
+                <button
+                  onClick={approveBudget}
+                  disabled={approving}
+                  className="ml-auto px-4 py-2 text-sm font-medium text-white bg-amber-600 rounded-md hover:bg-amber-700 disabled:opacity-50"
+                >
+                  {approving ? 'Approving...' : 'Approve Over Budget'}
+                </button>
+              )}
+              {isCancellable(task.status) && (
+                <button
+                  onClick={cancelTask}
+                  disabled={cancelling}
+                  className={`${task.status === 'QUEUED_BUDGET' ? '' : 'ml-auto '}px-4 py-2 text-sm font-medium text-white bg-red-600 rounded-md hover:bg-red-700 disabled:opacity-50`}
+                >
+                  {cancelling ? 'Cancelling...' : 'Cancel Task'}
+                </button>
+              )}
             </div>
           </div>
 
@@ -95,7 +170,7 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
               <div>
                 <dt className="text-sm font-medium text-gray-500">Type</dt>
                 <dd className="mt-1 text-sm text-gray-900">
-                  {task.request_type === 'image' ? '🖼️ Image' : '🎬 Video'}
+                  {task.request_type === 'image' ? '🖼️ Image' : task.request_type === 'edit' ? '✏️ Edit' : '🎬 Video'}
                 </dd>
               </div>
               <div>
@@ -108,6 +183,28 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
                   {task.prompt || '-'}
                 </dd>
               </div>
+              {task.comment?.images && task.comment.images.length > 0 && (
+                <div className="sm:col-span-2">
+                  <dt className="text-sm font-medium text-gray-500">Reference Images</dt>
+                  <dd className="mt-1 text-sm text-gray-900">
+                    <ul className="space-y-1">
+                      {task.comment.images.map((image) => (
+                        <li key={image.id}>
+                          <a
+                            href={image.url || image.source_url}
+                            target="_blank"
+                            rel="noopener noreferrer"
+                            className="text-blue-600 hover:text-blue-800 break-all"
+                          >
+                            {image.source_url}
+                          </a>
+                          {image.error && <span className="ml-2 text-red-600">{image.error}</span>}
+                        </li>
+                      ))}
+                    </ul>
+                  </dd>
+                </div>
+              )}
               <div>
                 <dt className="text-sm font-medium text-gray-500">Confidence</dt>
                 <dd className="mt-1 text-sm text-gray-900">
@@ -132,6 +229,19 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
                 <dt className="text-sm font-medium text-gray-500">Provider Job ID</dt>
                 <dd className="mt-1 text-sm text-gray-900 font-mono">{task.provider_job_id || '-'}</dd>
               </div>
+              <div>
+                <dt className="text-sm font-medium text-gray-500">Estimated Cost</dt>
+                <dd className="mt-1 text-sm text-gray-900">
+                  {task.estimated_cost !== undefined ? task.estimated_cost.toFixed(4) : '-'}
+                  {task.budget_approved && <span className="ml-2 text-amber-700">(approved over budget)</span>}
+                </dd>
+              </div>
+              <div>
+                <dt className="text-sm font-medium text-gray-500">Submitted At</dt>
+                <dd className="mt-1 text-sm text-gray-900">
+                  {task.submitted_at ? new Date(task.submitted_at).toLocaleString() : '-'}
+                </dd>
+              </div>
               <div className="sm:col-span-2">
                 <dt className="text-sm font-medium text-gray-500">Result URL</dt>
                 <dd className="mt-1 text-sm text-gray-900">
@@ -147,6 +257,31 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
                   ) : '-'}
                 </dd>
               </div>
+              {task.artifacts && task.artifacts.length > 0 && (
+                <div className="sm:col-span-2">
+                  <dt className="text-sm font-medium text-gray-500">Artifacts</dt>
+                  <dd className="mt-1 text-sm text-gray-900">
+                    <ul className="space-y-1">
+                      {task.artifacts.map((artifact) => (
+                        <li key={artifact.id}>
+                          <span className="text-gray-500 mr-2">
+                            {artifact.kind}
+                            {artifact.width && artifact.height ? ` ${artifact.width}x${artifact.height}` : ''}
+                          </span>
+                          <a
+                            href={artifact.url}
+                            target="_blank"
+                            rel="noopener noreferrer"
+                            className="text-blue-600 hover:text-blue-800 break-all"
+                          >
+                            {artifact.url}
+                          </a>
+                        </li>
+                      ))}
+                    </ul>
+                  </dd>
+                </div>
+              )}
               {task.error && (
                 <div className="sm:col-span-2">
                   <dt className="text-sm font-medium text-gray-500">Error</dt>
@@ -164,12 +299,38 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
               <dl className="grid grid-cols-1 gap-x-4 gap-y-6 sm:grid-cols-2">
                 <div>
                   <dt className="text-sm font-medium text-gray-500">User</dt>
-                  <dd className="mt-1 text-sm text-gray-900">{task.comment.user_name || '-'}</dd>
+                  <dd className="mt-1 text-sm text-gray-900">
+                    {task.comment.commenter_id ? (
+                      <Link
+                        href={`/commenters/${task.comment.commenter_id}`}
+                        className="text-blue-600 hover:text-blue-900"
+                      >
+                        {task.comment.user_name || task.comment.commenter?.user_id || '-'}
+                      </Link>
+                    ) : (
+                      task.comment.user_name || '-'
+                    )}
+                    {task.comment.commenter?.blocked && (
+                      <span className="ml-2 px-2 inline-flex text-xs leading-5 font-semibold rounded-full bg-red-100 text-red-800">
+                        Blocked
+                      </span>
+                    )}
+                  </dd>
                 </div>
                 <div>
                   <dt className="text-sm font-medium text-gray-500">Comment UID</dt>
                   <dd className="mt-1 text-sm text-gray-900 font-mono">{task.comment.comment_uid}</dd>
                 </div>
+                {task.comment.parent && (
+                  <div className="sm:col-span-2">
+                    <dt className="text-sm font-medium text-gray-500">In Reply To</dt>
+                    <dd className="mt-1 text-sm text-gray-600 border-l-4 border-gray-200 pl-3">
+                      <span className="font-medium">{task.comment.parent.user_name || '-'}</span>
+                      {': '}
+                      {task.comment.parent.content}
+                    </dd>
+                  </div>
+                )}
                 <div className="sm:col-span-2">
                   <dt className="text-sm font-medium text-gray-500">Content</dt>
                   <dd className="mt-1 text-sm text-gray-900 bg-gray-50 p-3 rounded">
@@ -180,6 +341,23 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
             </div>
           )}
 
+          {thread?.children && thread.children.length > 0 && (
+            <div className="bg-white shadow rounded-lg p-6">
+              <h2 className="text-lg font-medium text-gray-900 mb-4">Thread Replies</h2>
+              <div className="space-y-3">
+                {thread.children.map((child) => (
+                  <div key={child.id} className="border-l-4 border-gray-200 pl-4">
+                    <div className="text-xs text-gray-500">
+                      {child.user_name || '-'}
+                      {child.comment_created_at && ` · ${new Date(child.comment_created_at).toLocaleString()}`}
+                    </div>
+                    <div className="mt-1 text-sm text-gray-900">{child.content}</div>
+                  </div>
+                ))}
+              </div>
+            </div>
+          )}
+
           {task.deliveries && task.deliveries.length > 0 && (
             <div className="bg-white shadow rounded-lg p-6">
               <h2 className="text-lg font-medium text-gray-900 mb-4">Email Deliveries</h2>
@@ -219,6 +397,50 @@ export default function TaskDetailPage({ params }: { params: { id: string } }) {
               </div>
             </div>
           )}
+
+          {task.comment?.replies && task.comment.replies.length > 0 && (
+            <div className="bg-white shadow rounded-lg p-6">
+              <h2 className="text-lg font-medium text-gray-900 mb-4">Comment Replies</h2>
+              <div className="space-y-4">
+                {task.comment.replies.map((reply) => (
+                  <div key={reply.id} className="border-l-4 pl-4">
+                    <dl className="grid grid-cols-1 gap-x-4 gap-y-2 sm:grid-cols-3">
+                      <div>
+                        <dt className="text-xs font-medium text-gray-500">Stage</dt>
+                        <dd className="mt-1 text-sm text-gray-900">{reply.stage}</dd>
+                      </div>
+                      <div>
+                        <dt className="text-xs font-medium text-gray-500">Status</dt>
+                        <dd className="mt-1">
+                          <span className={`px-2 inline-flex text-xs leading-5 font-semibold rounded-full ${
+                            reply.status === 'SENT' ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'
+                          }`}>
+                            {reply.status}
+                          </span>
+                        </dd>
+                      </div>
+                      <div>
+                        <dt className="text-xs font-medium text-gray-500">Sent At</dt>
+                        <dd className="mt-1 text-sm text-gray-900">
+                          {reply.sent_at ? new Date(reply.sent_at).toLocaleString() : '-'}
+                        </dd>
+                      </div>
+                      <div className="sm:col-span-3">
+                        <dt className="text-xs font-medium text-gray-500">Content</dt>
+                        <dd className="mt-1 text-sm text-gray-900">{reply.content}</dd>
+                      </div>
+                      {reply.error && (
+                        <div className="sm:col-span-3">
+                          <dt className="text-xs font-medium text-gray-500">Error</dt>
+                          <dd className="mt-1 text-sm text-red-600">{reply.error}</dd>
+                        </div>
+                      )}
+                    </dl>
+                  </div>
+                ))}
+              </div>
+            </div>
+          )}
         </div>
       </div>
     </div>
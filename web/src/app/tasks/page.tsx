@@ -35,6 +35,8 @@ export default function TasksPage() {
         return 'bg-gray-100 text-gray-800';
       case 'EXTRACTED':
         return 'bg-blue-100 text-blue-800';
+      case 'QUEUED_BUDGET':
+        return 'bg-amber-100 text-amber-800';
       case 'SUBMITTED':
         return 'bg-yellow-100 text-yellow-800';
       case 'RUNNING':
@@ -45,13 +47,22 @@ export default function TasksPage() {
         return 'bg-emerald-100 text-emerald-800';
       case 'FAILED':
         return 'bg-red-100 text-red-800';
+      case 'CANCELLED':
+        return 'bg-orange-100 text-orange-800';
       default:
         return 'bg-gray-100 text-gray-800';
     }
   };
 
   const getRequestTypeIcon = (type: string) => {
-    return type === 'image' ? '🖼️' : '🎬';
+    switch (type) {
+      case 'image':
+        return '🖼️';
+      case 'edit':
+        return '✏️';
+      default:
+        return '🎬';
+    }
   };
 
   return (
@@ -21,6 +21,13 @@ export interface Setting {
   smtp_pass?: string;
   smtp_from?: string;
   provider_json: string;
+  daily_budget?: number;
+  monthly_budget?: number;
+  reply_templates?: string;
+  watch_account?: string;
+  discovery_interval_sec: number;
+  note_max_age_days: number;
+  alert_email?: string;
   created_at: string;
   updated_at: string;
 }
@@ -39,17 +46,24 @@ export interface Task {
   result_url?: string;
   error?: string;
   retry_count: number;
+  estimated_cost?: number;
+  budget_approved: boolean;
+  submitted_at?: string;
   created_at: string;
   updated_at: string;
-  comment?: {
+  comment?: Comment;
+  artifacts?: Array<{
     id: number;
-    note_target: string;
-    comment_uid: string;
-    user_name?: string;
-    content: string;
-    comment_created_at?: string;
-    ingested_at: string;
-  };
+    task_id: number;
+    kind: string;
+    object_key?: string;
+    url: string;
+    mime_type?: string;
+    size_bytes?: number;
+    width?: number;
+    height?: number;
+    sort_order: number;
+  }>;
   deliveries?: Array<{
     id: number;
     task_id: number;
@@ -60,6 +74,126 @@ export interface Task {
   }>;
 }
 
+export interface Comment {
+  id: number;
+  note_target: string;
+  comment_uid: string;
+  parent_id?: number;
+  depth: number;
+  commenter_id?: number;
+  user_name?: string;
+  content: string;
+  comment_created_at?: string;
+  ingested_at: string;
+  sub_comment_count: number;
+  sub_polled_at?: string;
+  images?: Array<{
+    id: number;
+    source_url: string;
+    object_key?: string;
+    url?: string;
+    mime_type?: string;
+    size_bytes?: number;
+    error?: string;
+    sort_order: number;
+  }>;
+  replies?: CommentReply[];
+  parent?: Comment;
+  children?: Comment[];
+  commenter?: Commenter;
+  task?: Task;
+}
+
+export interface Commenter {
+  id: number;
+  user_id: string;
+  user_name?: string;
+  avatar_url?: string;
+  comment_count: number;
+  request_count: number;
+  success_count: number;
+  blocked: boolean;
+  blocked_reason?: string;
+  blocked_at?: string;
+  first_seen_at: string;
+  last_seen_at: string;
+  created_at: string;
+  updated_at: string;
+  emails?: Array<{
+    id: number;
+    commenter_id: number;
+    email: string;
+    use_count: number;
+    first_used_at: string;
+    last_used_at: string;
+  }>;
+}
+
+export interface CommentersResponse {
+  commenters: Commenter[];
+  total: number;
+  limit: number;
+  offset: number;
+}
+
+export interface CommentReply {
+  id: number;
+  comment_id: number;
+  task_id?: number;
+  stage: string;
+  content: string;
+  reply_id?: string;
+  status: string;
+  error?: string;
+  attempts: number;
+  sent_at?: string;
+  created_at: string;
+}
+
+export interface Note {
+  id: number;
+  note_target: string;
+  last_cursor?: string;
+  last_polled_at?: string;
+  last_error?: string;
+  last_error_kind?: string;
+  consecutive_failures: number;
+  next_poll_at?: string;
+  reply_enabled: boolean;
+  watched: boolean;
+  source: string;
+  title?: string;
+  published_at?: string;
+  retired_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface BudgetUsage {
+  scope: string;
+  provider?: string;
+  window: string;
+  limit: number;
+  spent: number;
+  reset_at: string;
+}
+
+export interface EmailTemplate {
+  name: string;
+  locale: string;
+  subject: string;
+  html_body: string;
+  text_body: string;
+  customized: boolean;
+  updated_at?: string;
+}
+
+export interface RenderedEmail {
+  subject: string;
+  html: string;
+  text: string;
+}
+
 export interface TasksResponse {
   tasks: Task[];
   limit: number;
@@ -84,8 +218,13 @@ export const apiClient = {
     return response.data;
   },
 
-  runPoll: async (): Promise<{ message: string }> => {
-    const response = await api.post<{ message: string }>('/poll/run');
+  runPoll: async (): Promise<{ message: string; notes: number }> => {
+    const response = await api.post<{ message: string; notes: number }>('/poll/run');
+    return response.data;
+  },
+
+  runDiscovery: async (): Promise<{ message: string }> => {
+    const response = await api.post<{ message: string }>('/notes/discover');
     return response.data;
   },
 
@@ -99,6 +238,81 @@ export const apiClient = {
     return response.data;
   },
 
+  getComment: async (id: number): Promise<Comment> => {
+    const response = await api.get<Comment>(`/comments/${id}`);
+    return response.data;
+  },
+
+  cancelTask: async (id: number, reason?: string): Promise<Task> => {
+    const response = await api.post<Task>(`/tasks/${id}/cancel`, reason ? { reason } : {});
+    return response.data;
+  },
+
+  approveTaskBudget: async (id: number): Promise<Task> => {
+    const response = await api.post<Task>(`/tasks/${id}/approve-budget`);
+    return response.data;
+  },
+
+  listBudgets: async (): Promise<{ budgets: BudgetUsage[] }> => {
+    const response = await api.get<{ budgets: BudgetUsage[] }>('/budgets');
+    return response.data;
+  },
+
+  listNotes: async (): Promise<{ notes: Note[] }> => {
+    const response = await api.get<{ notes: Note[] }>('/notes');
+    return response.data;
+  },
+
+  updateNote: async (id: number, data: { reply_enabled?: boolean; watched?: boolean }): Promise<Note> => {
+    const response = await api.put<Note>(`/notes/${id}`, data);
+    return response.data;
+  },
+
+  listCommenters: async (
+    params: { q?: string; blocked?: boolean; limit?: number; offset?: number } = {}
+  ): Promise<CommentersResponse> => {
+    const response = await api.get<CommentersResponse>('/commenters', { params });
+    return response.data;
+  },
+
+  getCommenter: async (id: number): Promise<{ commenter: Commenter; comments: Comment[] }> => {
+    const response = await api.get<{ commenter: Commenter; comments: Comment[] }>(`/commenters/${id}`);
+    return response.data;
+  },
+
+  updateCommenter: async (id: number, data: { blocked: boolean; blocked_reason?: string }): Promise<Commenter> => {
+    const response = await api.put<Commenter>(`/commenters/${id}`, data);
+    return response.data;
+  },
+
+  listEmailTemplates: async (): Promise<{ templates: EmailTemplate[] }> => {
+    const response = await api.get<{ templates: EmailTemplate[] }>('/email-templates');
+    return response.data;
+  },
+
+  updateEmailTemplate: async (
+    name: string,
+    locale: string,
+    data: { subject: string; html_body: string; text_body: string }
+  ): Promise<EmailTemplate> => {
+    const response = await api.put<EmailTemplate>(`/email-templates/${name}/${locale}`, data);
+    return response.data;
+  },
+
+  resetEmailTemplate: async (name: string, locale: string): Promise<EmailTemplate> => {
+    const response = await api.delete<EmailTemplate>(`/email-templates/${name}/${locale}`);
+    return response.data;
+  },
+
+  previewEmailTemplate: async (
+    name: string,
+    locale: string,
+    data: { subject?: string; html_body?: string; text_body?: string } = {}
+  ): Promise<RenderedEmail> => {
+    const response = await api.post<RenderedEmail>(`/email-templates/${name}/${locale}/preview`, data);
+    return response.data;
+  },
+
   healthCheck: async (): Promise<{ status: string }> => {
     const response = await api.get<{ status: string }>('/healthz');
     return response.data;